@@ -0,0 +1,2 @@
+$GOPATH/bin/modelgen -s ./ -o ./
+gofmt -w *.go
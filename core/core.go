@@ -99,11 +99,36 @@ type NetworkDriver interface {
 	DeleteMaster(node ServiceInfo) error
 }
 
+// ReconcileAction describes a change made to the datapath to bring it in
+// line with the state store.
+type ReconcileAction struct {
+	Kind   string `json:"kind"`   // "endpoint" or "vtep"
+	ID     string `json:"id"`     // endpoint ID or peer host address
+	Action string `json:"action"` // "create" or "delete"
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Reconciler is implemented by network drivers that can compare the
+// datapath with the state store and repair the differences.
+type Reconciler interface {
+	// ReconcileEndpoints programs the endpoints with the given IDs that are
+	// missing or out of date, and removes the endpoints not in the list.
+	ReconcileEndpoints(epIDs []string) []ReconcileAction
+	// ReconcilePeerHosts creates the missing VTEPs for the given peer hosts,
+	// as registered in the state store, and removes the VTEPs of others.
+	ReconcilePeerHosts(peers []ServiceInfo) []ReconcileAction
+}
+
 // WatchState is used to provide a difference between core.State structs by
 // providing both the current and previous state.
 type WatchState struct {
 	Curr State
 	Prev State
+	// Revision of the store the change was made at, and key of the changed
+	// state. Only set by WatchAllStateFrom.
+	Revision uint64
+	Key      string
 }
 
 // StateDriver provides the mechanism for reading/writing state for networks,
@@ -122,6 +147,13 @@ type StateDriver interface {
 	Read(key string) ([]byte, error)
 	ReadAll(baseKey string) ([][]byte, error)
 	WatchAll(baseKey string, rsps chan [2][]byte) error
+	// ReadVersion returns the value stored at key along with its version.
+	// The version changes every time the key is written.
+	ReadVersion(key string) ([]byte, uint64, error)
+	// WriteIfVersion writes value to key only if the version of the stored
+	// value is still 'version'. A version of zero requires that the key does
+	// not exist. An error satisfying IsVersionConflict is returned otherwise.
+	WriteIfVersion(key string, value []byte, version uint64) error
 
 	WriteState(key string, value State,
 		marshal func(interface{}) ([]byte, error)) error
@@ -133,11 +165,51 @@ type StateDriver interface {
 	// It's a blocking call.
 	// XXX: This specification introduces a small time window where a few
 	// updates might be missed that occurred just before watch was started.
-	// May be watch shall return all existing state first and then subsequent
-	// updates. Revisit if this enhancement is needed.
+	// Use ListAllState followed by WatchAllStateFrom to avoid it.
 	WatchAllState(baseKey string, stateType State,
 		unmarshal func([]byte, interface{}) error, rsps chan WatchState) error
+	// ListAllState reads all state from baseKey like ReadAllState and also
+	// returns the revision of the store the state was read at.
+	ListAllState(baseKey string, stateType State,
+		unmarshal func([]byte, interface{}) error) ([]State, uint64, error)
+	// WatchAllStateFrom returns the changes to a state made after revision,
+	// each carrying the revision it was made at. It's a blocking call.
+	// A watch that fails can be resumed by passing the revision of the last
+	// change received, without missing or repeating changes. If the store no
+	// longer has the changes after revision, an error satisfying
+	// IsRevisionCompacted is returned and the state shall be listed again.
+	WatchAllStateFrom(baseKey string, stateType State,
+		unmarshal func([]byte, interface{}) error, revision uint64,
+		rsps chan WatchState) error
 	ClearState(key string) error
+	// ClearIfVersion removes key only if the version of the stored value is
+	// still 'version'. An error satisfying IsVersionConflict is returned
+	// otherwise, including when the key doesn't exist.
+	ClearIfVersion(key string, version uint64) error
+	// ReadStateVersion reads the state like ReadState and returns the version
+	// of the stored value, to be passed to WriteStateIfVersion.
+	ReadStateVersion(key string, value State,
+		unmarshal func([]byte, interface{}) error) (uint64, error)
+	// WriteStateIfVersion writes the state like WriteState, but only if the
+	// stored value has not changed since 'version' was read. This allows
+	// read-modify-write cycles on shared state without a global lock.
+	WriteStateIfVersion(key string, value State,
+		marshal func(interface{}) ([]byte, error), version uint64) error
+	// NewTxn starts a transaction on the state store. See Txn.
+	NewTxn() (Txn, error)
+}
+
+// Txn is a StateDriver that buffers the writes and clears made through it and
+// applies them to the underlying store as a unit on Commit. Reads of single
+// keys made through a Txn observe its buffered changes. ReadAll,
+// ReadAllState, ListAllState and the watches are passed through to the
+// underlying store and don't.
+// Commit fails with a version conflict, without applying any change, if a key
+// written by the transaction was modified after the transaction read it.
+type Txn interface {
+	StateDriver
+	Commit() error
+	Abort()
 }
 
 // Resource defines a allocatable unit. A resource is uniquely identified
@@ -145,6 +217,9 @@ type StateDriver interface {
 type Resource interface {
 	State
 	Init(rsrcCfg interface{}) error
+	// Reinit redefines the values of the resource, keeping the ones
+	// allocated. It fails if an allocated value would be left out.
+	Reinit(rsrcCfg interface{}) error
 	Deinit()
 	Description() string
 	Allocate() (interface{}, error)
@@ -158,6 +233,7 @@ type ResourceManager interface {
 	Init() error
 	Deinit()
 	DefineResource(id, desc string, rsrcCfg interface{}) error
+	RedefineResource(id, desc string, rsrcCfg interface{}) error
 	UndefineResource(id, desc string) error
 	AllocateResourceVal(id, desc string) (interface{}, error)
 	DeallocateResourceVal(id, desc string, value interface{}) error
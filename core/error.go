@@ -16,7 +16,10 @@ limitations under the License.
 package core
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path"
 	"runtime"
@@ -29,10 +32,38 @@ type errorStack struct {
 	fun  string
 }
 
+// ErrorKind classifies errors by how a client can handle them.
+type ErrorKind string
+
+const (
+	// KindUnknown is the kind of the errors that are not classified.
+	KindUnknown ErrorKind = ""
+	// KindNotFound errors are due to an object that doesn't exist.
+	KindNotFound ErrorKind = "not-found"
+	// KindConflict errors are due to the current state of an object, such
+	// as a value that is already in use.
+	KindConflict ErrorKind = "conflict"
+	// KindInvalid errors are due to an invalid request.
+	KindInvalid ErrorKind = "invalid"
+	// KindExhausted errors are due to a resource or a quota running out.
+	KindExhausted ErrorKind = "exhausted"
+	// KindUnavailable errors are transient, such as the state store being
+	// unreachable, and the request may succeed if retried.
+	KindUnavailable ErrorKind = "unavailable"
+	// KindUnauthenticated errors are due to a request without valid
+	// credentials.
+	KindUnauthenticated ErrorKind = "unauthenticated"
+	// KindForbidden errors are due to a client without the permission to
+	// make the request.
+	KindForbidden ErrorKind = "forbidden"
+)
+
 // Error is our custom error with description, file, and line.
 type Error struct {
 	desc  string
 	stack []errorStack
+	kind  ErrorKind
+	cause error
 }
 
 // Error() allows *core.Error to present the `error` interface.
@@ -53,13 +84,64 @@ func (e *Error) Error() string {
 }
 
 // Errorf returns an *Error based on the format specification provided.
+// An error formatted with the %w verb is wrapped, as by fmt.Errorf: the
+// *Error is of its kind and Unwrap returns it. Errors formatted otherwise only
+// lend their text, so that an internal failure isn't reported as one of a
+// known kind.
 func Errorf(f string, args ...interface{}) *Error {
+	err := fmt.Errorf(f, args...)
+	cause := errors.Unwrap(err)
+
+	e := newError(ErrorKindOf(cause), "%s", err)
+	e.cause = cause
+	return e
+}
+
+// NotFoundErrorf returns an *Error of kind KindNotFound.
+func NotFoundErrorf(f string, args ...interface{}) *Error {
+	return newError(KindNotFound, f, args...)
+}
+
+// ConflictErrorf returns an *Error of kind KindConflict.
+func ConflictErrorf(f string, args ...interface{}) *Error {
+	return newError(KindConflict, f, args...)
+}
+
+// InvalidErrorf returns an *Error of kind KindInvalid.
+func InvalidErrorf(f string, args ...interface{}) *Error {
+	return newError(KindInvalid, f, args...)
+}
+
+// ExhaustedErrorf returns an *Error of kind KindExhausted.
+func ExhaustedErrorf(f string, args ...interface{}) *Error {
+	return newError(KindExhausted, f, args...)
+}
+
+// UnavailableErrorf returns an *Error of kind KindUnavailable.
+func UnavailableErrorf(f string, args ...interface{}) *Error {
+	return newError(KindUnavailable, f, args...)
+}
+
+// UnauthenticatedErrorf returns an *Error of kind KindUnauthenticated.
+func UnauthenticatedErrorf(f string, args ...interface{}) *Error {
+	return newError(KindUnauthenticated, f, args...)
+}
+
+// ForbiddenErrorf returns an *Error of kind KindForbidden.
+func ForbiddenErrorf(f string, args ...interface{}) *Error {
+	return newError(KindForbidden, f, args...)
+}
+
+// newError returns an *Error with the stack of the caller of the exported
+// function that calls it.
+func newError(kind ErrorKind, f string, args ...interface{}) *Error {
 	e := &Error{
 		stack: []errorStack{},
 		desc:  fmt.Sprintf(f, args...),
+		kind:  kind,
 	}
 
-	i := 1
+	i := 2
 
 	for {
 		stack := errorStack{}
@@ -83,6 +165,50 @@ func Errorf(f string, args ...interface{}) *Error {
 	return e
 }
 
+// Kind returns the kind of the error.
+func (e *Error) Kind() ErrorKind {
+	return e.kind
+}
+
+// Unwrap returns the error wrapped by the error, if any.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// ErrorKindOf returns the kind of an error, which is the kind of the first
+// *Error it wraps. Other errors are classified by their cause where it's
+// known, such as a missing key or an unreachable server.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.kind
+	}
+
+	if ErrIfKeyExists(err) == nil {
+		return KindNotFound
+	}
+
+	switch netErr := err.(type) {
+	case *url.Error:
+		if _, ok := netErr.Err.(*net.OpError); ok || netErr.Timeout() {
+			return KindUnavailable
+		}
+	case *net.OpError:
+		return KindUnavailable
+	}
+
+	return KindUnknown
+}
+
+// IsTransient checks if the error is transient, so the failed request can be
+// retried.
+func IsTransient(err error) bool {
+	return ErrorKindOf(err) == KindUnavailable
+}
+
 // ErrIfKeyExists checks if the error message contains "Key not found".
 func ErrIfKeyExists(err error) error {
 	if err == nil || strings.Contains(err.Error(), "Key not found") {
@@ -91,3 +217,82 @@ func ErrIfKeyExists(err error) error {
 
 	return err
 }
+
+const (
+	versionConflictDesc = "Version conflict"
+
+	// MaxConflictRetries bounds the number of times RetryOnVersionConflict
+	// re-runs an update that keeps losing the race to other writers.
+	MaxConflictRetries = 32
+)
+
+// errVersionConflict is wrapped by the errors of failed conditional writes
+var errVersionConflict = errors.New(versionConflictDesc)
+
+// ErrVersionConflict returns the error used by state drivers when a
+// conditional write fails because the stored value has changed.
+func ErrVersionConflict(key string) *Error {
+	e := newError(KindConflict, "%s for key: %s", versionConflictDesc, key)
+	e.cause = errVersionConflict
+	return e
+}
+
+// IsVersionConflict checks if the error is due to a failed conditional write.
+func IsVersionConflict(err error) bool {
+	return ErrorKindOf(err) == KindConflict && errors.Is(err, errVersionConflict)
+}
+
+// RetryOnVersionConflict runs update until it succeeds, fails with an error
+// other than a version conflict, or MaxConflictRetries attempts are made.
+// update is expected to re-read the state it modifies on every attempt.
+func RetryOnVersionConflict(update func() error) error {
+	var err error
+
+	for i := 0; i < MaxConflictRetries; i++ {
+		err = update()
+		if !IsVersionConflict(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+const revisionCompactedDesc = "Revision compacted"
+
+// errRevisionCompacted is wrapped by the errors of watches that can't be
+// resumed
+var errRevisionCompacted = errors.New(revisionCompactedDesc)
+
+// ErrRevisionCompacted returns the error used by state drivers when a watch
+// can't be resumed because the changes after revision are no longer known.
+func ErrRevisionCompacted(revision uint64) *Error {
+	e := newError(KindConflict, "%s, revision: %d", revisionCompactedDesc, revision)
+	e.cause = errRevisionCompacted
+	return e
+}
+
+// IsRevisionCompacted checks if the error is due to a watch that can't be
+// resumed from the requested revision.
+func IsRevisionCompacted(err error) bool {
+	return ErrorKindOf(err) == KindConflict && errors.Is(err, errRevisionCompacted)
+}
+
+// conflictCause returns the error wrapped by the conflict errors with the
+// description desc, for errors received from another process
+func conflictCause(desc string) error {
+	switch {
+	case strings.HasPrefix(desc, versionConflictDesc):
+		return errVersionConflict
+	case strings.HasPrefix(desc, revisionCompactedDesc):
+		return errRevisionCompacted
+	default:
+		return nil
+	}
+}
+
+// ErrQuotaExceeded returns the error of kind KindExhausted used when creating
+// an object would take a tenant over its quota of the resource.
+func ErrQuotaExceeded(tenant, resource string, limit int) *Error {
+	return newError(KindExhausted, "Quota exceeded: tenant %s is limited to %d %s", tenant, limit, resource)
+}
@@ -56,3 +56,36 @@ func TestErrorStackTrace(t *testing.T) {
 		t.Fatalf("Stack trace yielded incorrect count: %d", len(lines))
 	}
 }
+
+func TestErrorfWrap(t *testing.T) {
+	wrapped := ConflictErrorf("vlan 10 is in use")
+
+	e := Errorf("%d%% of %s failed. Error: %-5w", 50, "vlans", wrapped)
+	if e.Kind() != KindConflict {
+		t.Fatalf("wrapping error is of kind %q, expected %q", e.Kind(), KindConflict)
+	}
+	if e.desc != "50% of vlans failed. Error: "+wrapped.Error() {
+		t.Fatalf("wrapping error has description %q", e.desc)
+	}
+	if e.Unwrap() != wrapped {
+		t.Fatalf("wrapping error unwraps to %v", e.Unwrap())
+	}
+
+	e = Errorf("failed. Error: %s", wrapped)
+	if e.Kind() != KindUnknown || e.Unwrap() != nil {
+		t.Fatalf("error formatting an error with %%s is of kind %q and wraps %v",
+			e.Kind(), e.Unwrap())
+	}
+
+	conflict := ErrVersionConflict("/a/b")
+	if !IsVersionConflict(Errorf("write failed. Error: %w", conflict)) {
+		t.Fatalf("error wrapping a version conflict is not a version conflict")
+	}
+	if IsVersionConflict(Errorf("write failed. Error: %s", conflict)) ||
+		IsVersionConflict(ConflictErrorf("%s", conflict)) {
+		t.Fatalf("error formatting a version conflict is a version conflict")
+	}
+	if !IsRevisionCompacted(fmt.Errorf("watch failed. Error: %w", ErrRevisionCompacted(10))) {
+		t.Fatalf("error wrapping a compacted revision is not a compacted revision")
+	}
+}
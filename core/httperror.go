@@ -0,0 +1,104 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// HTTPError is the json body of an error response.
+type HTTPError struct {
+	Kind  ErrorKind `json:"kind,omitempty"`
+	Error string    `json:"error"`
+}
+
+// HTTPStatus returns the http status code of the responses that fail with
+// errors of the kind.
+func (k ErrorKind) HTTPStatus() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindExhausted:
+		return http.StatusInsufficientStorage
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorKindOfHTTPStatus returns the kind of the errors of a response with
+// the http status code.
+func errorKindOfHTTPStatus(code int) ErrorKind {
+	switch code {
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict:
+		return KindConflict
+	case http.StatusBadRequest:
+		return KindInvalid
+	case http.StatusInsufficientStorage:
+		return KindExhausted
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return KindUnavailable
+	case http.StatusUnauthorized:
+		return KindUnauthenticated
+	case http.StatusForbidden:
+		return KindForbidden
+	default:
+		return KindUnknown
+	}
+}
+
+// WriteHTTPError writes the error response for err, with its http status
+// code and an HTTPError body.
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	kind := ErrorKindOf(err)
+	body, _ := json.Marshal(&HTTPError{Kind: kind, Error: err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(kind.HTTPStatus())
+	w.Write(body)
+}
+
+// DecodeHTTPError returns the error of an error response, from its HTTPError
+// body or its status code and text body if it doesn't have one.
+func DecodeHTTPError(statusCode int, body []byte) *Error {
+	httpErr := &HTTPError{}
+	if err := json.Unmarshal(body, httpErr); err != nil || httpErr.Error == "" {
+		httpErr.Kind = ""
+		httpErr.Error = strings.TrimSpace(string(body))
+	}
+	if httpErr.Kind == KindUnknown {
+		httpErr.Kind = errorKindOfHTTPStatus(statusCode)
+	}
+
+	e := newError(httpErr.Kind, "%s (status %d)", httpErr.Error, statusCode)
+	if httpErr.Kind == KindConflict {
+		e.cause = conflictCause(httpErr.Error)
+	}
+	return e
+}
@@ -0,0 +1,75 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	for _, test := range []struct {
+		err    error
+		kind   ErrorKind
+		status int
+	}{
+		{NotFoundErrorf("network %s not found", "net1"), KindNotFound, http.StatusNotFound},
+		{ConflictErrorf("address in use"), KindConflict, http.StatusConflict},
+		{InvalidErrorf("invalid vlan range"), KindInvalid, http.StatusBadRequest},
+		{ExhaustedErrorf("no vlans available"), KindExhausted, http.StatusInsufficientStorage},
+		{UnavailableErrorf("etcd unreachable"), KindUnavailable, http.StatusServiceUnavailable},
+		{UnauthenticatedErrorf("invalid token"), KindUnauthenticated, http.StatusUnauthorized},
+		{ForbiddenErrorf("read-only"), KindForbidden, http.StatusForbidden},
+		{Errorf("failed. Error: %w", ExhaustedErrorf("no vlans available")), KindExhausted,
+			http.StatusInsufficientStorage},
+		{Errorf("failed. Error: %s", ExhaustedErrorf("no vlans available")), KindUnknown,
+			http.StatusInternalServerError},
+		{Errorf("failed. Error: %s", errors.New("100: Key not found (/a/b)")), KindUnknown,
+			http.StatusInternalServerError},
+		{ErrVersionConflict("/a/b"), KindConflict, http.StatusConflict},
+		{ErrQuotaExceeded("default", "networks", 1), KindExhausted, http.StatusInsufficientStorage},
+		{errors.New("100: Key not found (/a/b)"), KindNotFound, http.StatusNotFound},
+		{Errorf("an error"), KindUnknown, http.StatusInternalServerError},
+	} {
+		if kind := ErrorKindOf(test.err); kind != test.kind {
+			t.Fatalf("error %q is of kind %q, expected %q", test.err, kind, test.kind)
+		}
+
+		w := httptest.NewRecorder()
+		WriteHTTPError(w, test.err)
+		if w.Code != test.status {
+			t.Fatalf("error %q has status %d, expected %d", test.err, w.Code, test.status)
+		}
+
+		err := DecodeHTTPError(w.Code, w.Body.Bytes())
+		if err.Kind() != test.kind || !strings.Contains(err.Error(), test.err.Error()) {
+			t.Fatalf("decoded error %q of kind %q from %q", err, err.Kind(), test.err)
+		}
+		if IsVersionConflict(err) != IsVersionConflict(test.err) {
+			t.Fatalf("decoded error %q is a version conflict: %v", err, IsVersionConflict(err))
+		}
+	}
+
+	if !IsTransient(DecodeHTTPError(http.StatusServiceUnavailable, []byte("unavailable"))) {
+		t.Fatalf("error of a 503 response with a text body is not transient")
+	}
+	if IsTransient(DecodeHTTPError(http.StatusInternalServerError, []byte("failed"))) {
+		t.Fatalf("error of a 500 response is transient")
+	}
+}
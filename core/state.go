@@ -22,4 +22,7 @@ type WatchableState interface {
 type CommonState struct {
 	StateDriver StateDriver `json:"-"`
 	ID          string      `json:"id"`
+	// Version of the stored state as of the last versioned read. It is only
+	// meaningful to states that support conditional writes.
+	Version uint64 `json:"-"`
 }
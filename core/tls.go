@@ -0,0 +1,128 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+)
+
+// TLSConfig is the certificate configuration of a TLS client or server.
+// CertFile and KeyFile are the PEM files of the certificate presented to the
+// peers, and CAFile has the CA certificates that verify the peers'
+// certificates. Clients verify servers with the system CAs when it's not set.
+type TLSConfig struct {
+	CAFile   string `json:"ca-file,omitempty"`
+	CertFile string `json:"cert-file,omitempty"`
+	KeyFile  string `json:"key-file,omitempty"`
+}
+
+// Enabled returns true if any of the files is set
+func (c *TLSConfig) Enabled() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.KeyFile != ""
+}
+
+// certificates returns the certificate presented to the peers, if set
+func (c *TLSConfig) certificates() ([]tls.Certificate, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil, nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, InvalidErrorf("both a certificate and a key file are needed")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, Errorf("error loading certificate %s. Error: %s", c.CertFile, err)
+	}
+
+	return []tls.Certificate{cert}, nil
+}
+
+// caPool returns the CA certificates in CAFile, nil if it's not set
+func (c *TLSConfig) caPool() (*x509.CertPool, error) {
+	if c.CAFile == "" {
+		return nil, nil
+	}
+
+	pem, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, InvalidErrorf("no CA certificates in %s", c.CAFile)
+	}
+
+	return pool, nil
+}
+
+// ClientTLSConfig returns the tls config of a client, which presents its
+// certificate, if set, for mutual TLS
+func (c *TLSConfig) ClientTLSConfig() (*tls.Config, error) {
+	certs, err := c.certificates()
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := c.caPool()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: certs,
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerTLSConfig returns the tls config of a server. Clients' certificates
+// are verified with the CAs in CAFile if they present one, and required if
+// requireClientCert is set.
+func (c *TLSConfig) ServerTLSConfig(requireClientCert bool) (*tls.Config, error) {
+	certs, err := c.certificates()
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, InvalidErrorf("a server needs a certificate and a key file")
+	}
+
+	pool, err := c.caPool()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: certs,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch {
+	case requireClientCert && pool == nil:
+		return nil, InvalidErrorf("a CA file is needed to verify client certificates")
+	case requireClientCert:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case pool != nil:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return cfg, nil
+}
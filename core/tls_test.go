@@ -0,0 +1,142 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a certificate for 127.0.0.1 and its key to dir,
+// signed by itself, so that it's its own CA
+func writeTestCert(t *testing.T, dir, name string) *TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key. Error: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate. Error: %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error encoding key. Error: %s", err)
+	}
+
+	cfg := &TLSConfig{
+		CAFile:   filepath.Join(dir, name+"-ca.pem"),
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	for file, content := range map[string][]byte{cfg.CAFile: certPem, cfg.CertFile: certPem, cfg.KeyFile: keyPem} {
+		if err := ioutil.WriteFile(file, content, 0600); err != nil {
+			t.Fatalf("error writing %s. Error: %s", file, err)
+		}
+	}
+
+	return cfg
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir. Error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	serverFiles := writeTestCert(t, dir, "server")
+	clientFiles := writeTestCert(t, dir, "client")
+
+	if _, err := (&TLSConfig{CAFile: serverFiles.CAFile}).ServerTLSConfig(false); err == nil {
+		t.Fatalf("server tls config created without a certificate")
+	}
+	if _, err := (&TLSConfig{CertFile: serverFiles.CertFile}).ClientTLSConfig(); err == nil {
+		t.Fatalf("client tls config created without a key")
+	}
+	noCA := &TLSConfig{CertFile: serverFiles.CertFile, KeyFile: serverFiles.KeyFile}
+	if _, err := noCA.ServerTLSConfig(true); err == nil {
+		t.Fatalf("server tls config requiring client certificates created without a CA")
+	}
+
+	// the server verifies the clients with the client CA
+	serverCfg, err := (&TLSConfig{CAFile: clientFiles.CAFile, CertFile: serverFiles.CertFile,
+		KeyFile: serverFiles.KeyFile}).ServerTLSConfig(true)
+	if err != nil {
+		t.Fatalf("error creating server tls config. Error: %s", err)
+	}
+	srvr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srvr.TLS = serverCfg
+	srvr.StartTLS()
+	defer srvr.Close()
+
+	for _, test := range []struct {
+		files   *TLSConfig
+		success bool
+	}{
+		{&TLSConfig{CAFile: serverFiles.CAFile, CertFile: clientFiles.CertFile,
+			KeyFile: clientFiles.KeyFile}, true},
+		{&TLSConfig{CAFile: serverFiles.CAFile}, false},
+		{&TLSConfig{CAFile: clientFiles.CAFile, CertFile: clientFiles.CertFile,
+			KeyFile: clientFiles.KeyFile}, false},
+	} {
+		clientCfg, err := test.files.ClientTLSConfig()
+		if err != nil {
+			t.Fatalf("error creating client tls config. Error: %s", err)
+		}
+		httpC := &http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg}}
+		resp, err := httpC.Get(srvr.URL)
+		if err == nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "client" {
+				t.Fatalf("server got client certificate %q", body)
+			}
+		}
+		if (err == nil) != test.success {
+			t.Fatalf("request with %+v succeeded: %v, expected %v. Error: %v",
+				test.files, err == nil, test.success, err)
+		}
+	}
+}
@@ -210,6 +210,15 @@ func getOvsPostName(intfName string) string {
 	return ovsPortName
 }
 
+// getEpPortName returns the endpoint port name of an OVS port
+func getEpPortName(ovsPortName string) string {
+	if useVethPair {
+		return strings.Replace(ovsPortName, "vport", "port", 1)
+	}
+
+	return ovsPortName
+}
+
 // CreatePort creates a port in ovs switch
 func (sw *OvsSwitch) CreatePort(intfName string, cfgEp *mastercfg.CfgEndpointState, pktTag int) error {
 	var ovsIntfType string
@@ -385,6 +394,34 @@ func (sw *OvsSwitch) DeletePort(epOper *OvsOperEndpointState) error {
 	return nil
 }
 
+// IsPortPresent checks if the OVS port of an endpoint exists, along with
+// its veth pair if one is used
+func (sw *OvsSwitch) IsPortPresent(intfName string) bool {
+	ovsPortName := getOvsPostName(intfName)
+	if !sw.ovsdbDriver.IsPortNamePresent(ovsPortName) {
+		return false
+	}
+
+	if useVethPair {
+		// the other end may have been moved to a container namespace
+		_, err := netlink.LinkByName(ovsPortName)
+		return err == nil
+	}
+
+	return true
+}
+
+// EndpointPorts returns the endpoint ID of the ports created for endpoints,
+// by endpoint port name
+func (sw *OvsSwitch) EndpointPorts() map[string]string {
+	ports := make(map[string]string)
+	for ovsPortName, epID := range sw.ovsdbDriver.GetEndpointPorts() {
+		ports[getEpPortName(ovsPortName)] = epID
+	}
+
+	return ports
+}
+
 // vxlanIfName returns formatted vxlan interface name
 func vxlanIfName(vtepIP string) string {
 	return fmt.Sprintf(vxlanIfNameFmt, strings.Replace(vtepIP, ".", "", -1))
@@ -452,6 +489,11 @@ func (sw *OvsSwitch) DeleteVtep(vtepIP string) error {
 	return sw.ovsdbDriver.DeleteVtep(intfName)
 }
 
+// Vteps returns the VTEP interface names by remote IP
+func (sw *OvsSwitch) Vteps() map[string]string {
+	return sw.ovsdbDriver.GetVteps()
+}
+
 // AddUplinkPort adds uplink port to the OVS
 func (sw *OvsSwitch) AddUplinkPort(intfName string) error {
 	var err error
@@ -461,7 +503,7 @@ func (sw *OvsSwitch) AddUplinkPort(intfName string) error {
 		log.Fatalf("Can not add uplink to OVS type %s.", sw.netType)
 	}
 
-	uplinkID := "uplink" + intfName
+	uplinkID := uplinkIDPrefix + intfName
 
 	// Check if port is already part of the OVS and add it
 	if !sw.ovsdbDriver.IsPortNamePresent(intfName) {
@@ -19,6 +19,7 @@ const (
 	vxlanBridgeName = "contivVxlanBridge"
 	portNameFmt     = "port%d"
 	vxlanIfNameFmt  = "vxif%s"
+	uplinkIDPrefix  = "uplink"
 
 	getPortName = true
 	getIntfName = false
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/contiv/libovsdb"
@@ -620,3 +621,41 @@ func (d *OvsdbDriver) IsVtepPresent(remoteIP string) (bool, string) {
 	// We could not find the interface name
 	return false, ""
 }
+
+// GetEndpointPorts returns the endpoint ID of the ports created for
+// endpoints, by port name
+func (d *OvsdbDriver) GetEndpointPorts() map[string]string {
+	ports := make(map[string]string)
+	for _, row := range d.cache[portTable] {
+		name, ok := row.Fields["name"].(string)
+		if !ok {
+			continue
+		}
+		if extIDs, ok := row.Fields["external_ids"].(libovsdb.OvsMap); ok {
+			epID, ok := extIDs.GoMap["endpoint-id"].(string)
+			if ok && !strings.HasPrefix(epID, uplinkIDPrefix) {
+				ports[name] = epID
+			}
+		}
+	}
+
+	return ports
+}
+
+// GetVteps returns the VTEP interface names by remote IP
+func (d *OvsdbDriver) GetVteps() map[string]string {
+	vteps := make(map[string]string)
+	for _, row := range d.cache[interfaceTable] {
+		name, ok := row.Fields["name"].(string)
+		if !ok {
+			continue
+		}
+		if options, ok := row.Fields["options"].(libovsdb.OvsMap); ok {
+			if remoteIP, ok := options.GoMap["remote_ip"].(string); ok {
+				vteps[remoteIP] = name
+			}
+		}
+	}
+
+	return vteps
+}
@@ -284,11 +284,13 @@ func (d *OvsDriver) CreateEndpoint(id string) error {
 		ContName:    cfgEp.ContName,
 		ServiceName: cfgEp.ServiceName,
 		IPAddress:   cfgEp.IPAddress,
+		IPv6Address: cfgEp.IPv6Address,
 		MacAddress:  cfgEp.MacAddress,
 		IntfName:    cfgEp.IntfName,
 		PortName:    intfName,
 		HomingHost:  cfgEp.HomingHost,
-		VtepIP:      cfgEp.VtepIP}
+		VtepIP:      cfgEp.VtepIP,
+		PktTagType:  cfgEpGroup.PktTagType}
 	operEp.StateDriver = d.oper.StateDriver
 	operEp.ID = id
 	err = operEp.Write()
@@ -317,22 +319,11 @@ func (d *OvsDriver) DeleteEndpoint(id string) (err error) {
 		epOper.Clear()
 	}()
 
-	// Get the network state
-	cfgNw := mastercfg.CfgNetworkState{}
-	cfgNw.StateDriver = d.oper.StateDriver
-	err = cfgNw.Read(epOper.NetID)
+	sw, err := d.endpointSwitch(&epOper)
 	if err != nil {
 		return err
 	}
 
-	// Find the switch based on network type
-	var sw *OvsSwitch
-	if cfgNw.PktTagType == "vxlan" {
-		sw = d.switchDb["vxlan"]
-	} else {
-		sw = d.switchDb["vlan"]
-	}
-
 	err = sw.DeletePort(&epOper)
 	if err != nil {
 		log.Errorf("Error deleting endpoint: %+v. Err: %v", epOper, err)
@@ -341,6 +332,29 @@ func (d *OvsDriver) DeleteEndpoint(id string) (err error) {
 	return nil
 }
 
+// endpointSwitch returns the switch the port of an endpoint is on. The
+// tagging the port was created with is kept in the oper state, so that the
+// port can be removed once the network is gone. Oper state that predates it
+// falls back to the tagging of the network.
+func (d *OvsDriver) endpointSwitch(epOper *OvsOperEndpointState) (*OvsSwitch, error) {
+	pktTagType := epOper.PktTagType
+	if pktTagType == "" {
+		cfgNw := mastercfg.CfgNetworkState{}
+		cfgNw.StateDriver = d.oper.StateDriver
+		err := cfgNw.Read(epOper.NetID)
+		if err != nil {
+			return nil, err
+		}
+		pktTagType = cfgNw.PktTagType
+	}
+
+	// Find the switch based on network type
+	if pktTagType == "vxlan" {
+		return d.switchDb["vxlan"], nil
+	}
+	return d.switchDb["vlan"], nil
+}
+
 // AddPeerHost adds VTEPs if necessary
 func (d *OvsDriver) AddPeerHost(node core.ServiceInfo) error {
 	// Nothing to do if this is our own IP
@@ -420,6 +420,69 @@ func TestOvsDriverDeleteEndpoint(t *testing.T) {
 	}
 }
 
+func TestOvsDriverDeleteEndpointNetworkDeleted(t *testing.T) {
+	driver := initOvsDriver(t)
+	defer func() { driver.Deinit() }()
+	id := deleteEpID
+
+	err := driver.CreateEndpoint(id)
+	if err != nil {
+		t.Fatalf("endpoint Creation failed. Error: %s", err)
+	}
+
+	// see TestOvsDriverDeleteEndpoint
+	time.Sleep(1 * time.Second)
+
+	// the network state is removed before its endpoints are deleted
+	cfgNw := &mastercfg.CfgNetworkState{}
+	cfgNw.StateDriver = driver.oper.StateDriver
+	cfgNw.ID = testOvsNwID
+	if err := cfgNw.Clear(); err != nil {
+		t.Fatalf("network state removal failed. Error: %s", err)
+	}
+
+	err = driver.DeleteEndpoint(id)
+	if err != nil {
+		t.Fatalf("endpoint Deletion failed. Error: %s", err)
+	}
+
+	output, err := exec.Command("ovs-vsctl", "list", "Port").CombinedOutput()
+	expectedPortName := fmt.Sprintf(portNameFmt, driver.oper.CurrPortNum)
+	if err != nil || strings.Contains(string(output), expectedPortName) {
+		t.Fatalf("port lookup succeeded after delete. Error: %s Output: %s", err, output)
+	}
+}
+
+func TestOvsDriverEndpointSwitch(t *testing.T) {
+	stateDriver := &state.FakeStateDriver{}
+	stateDriver.Init(nil)
+	vlanSwitch := &OvsSwitch{netType: "vlan"}
+	vxlanSwitch := &OvsSwitch{netType: "vxlan"}
+	driver := &OvsDriver{
+		switchDb: map[string]*OvsSwitch{"vlan": vlanSwitch, "vxlan": vxlanSwitch},
+	}
+	driver.oper.StateDriver = stateDriver
+
+	// the tagging recorded with the endpoint doesn't need the network
+	epOper := &OvsOperEndpointState{NetID: testOvsNwID, PktTagType: "vxlan"}
+	if sw, err := driver.endpointSwitch(epOper); err != nil || sw != vxlanSwitch {
+		t.Fatalf("endpoint switch lookup returned %v, %v. expected the vxlan switch", sw, err)
+	}
+
+	// older oper state falls back to the network
+	epOper.PktTagType = ""
+	if _, err := driver.endpointSwitch(epOper); err == nil {
+		t.Fatalf("endpoint switch lookup succeeded without the network")
+	}
+
+	if err := createCommonState(stateDriver); err != nil {
+		t.Fatalf("common state creation failed. Error: %s", err)
+	}
+	if sw, err := driver.endpointSwitch(epOper); err != nil || sw != vlanSwitch {
+		t.Fatalf("endpoint switch lookup returned %v, %v. expected the vlan switch", sw, err)
+	}
+}
+
 func TestOvsDriverAddUplink(t *testing.T) {
 	driver := initOvsDriver(t)
 	defer func() { driver.Deinit() }()
@@ -32,11 +32,13 @@ type OvsOperEndpointState struct {
 	ContUUID    string `json:"contUUID"`
 	AttachUUID  string `json:"attachUUID"`
 	IPAddress   string `json:"ipAddress"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
 	MacAddress  string `json:"macAddress"`
 	HomingHost  string `json:"homingHost"`
 	IntfName    string `json:"intfName"`
 	PortName    string `json:"portName"`
 	VtepIP      string `json:"vtepIP"`
+	PktTagType  string `json:"pktTagType,omitempty"`
 }
 
 // Matches matches the fields updated from configuration state
@@ -45,6 +47,7 @@ func (s *OvsOperEndpointState) Matches(c *mastercfg.CfgEndpointState) bool {
 		s.ContName == c.ContName &&
 		s.AttachUUID == c.AttachUUID &&
 		s.IPAddress == c.IPAddress &&
+		s.IPv6Address == c.IPv6Address &&
 		s.MacAddress == c.MacAddress &&
 		s.HomingHost == c.HomingHost &&
 		s.IntfName == c.IntfName &&
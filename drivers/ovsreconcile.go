@@ -0,0 +1,180 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drivers
+
+import (
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// This file implements core.Reconciler for the OvsDriver
+
+func newReconcileAction(kind, id, action, reason string, err error) core.ReconcileAction {
+	ra := core.ReconcileAction{
+		Kind:   kind,
+		ID:     id,
+		Action: action,
+		Reason: reason,
+	}
+	if err != nil {
+		ra.Error = err.Error()
+	}
+
+	return ra
+}
+
+// checkEndpoint returns why an endpoint needs to be programmed again, or an
+// empty string if it is up to date. It also tells if the endpoint has oper
+// state to be cleaned up first.
+func (d *OvsDriver) checkEndpoint(id string) (string, bool, error) {
+	cfgEp := &mastercfg.CfgEndpointState{}
+	cfgEp.StateDriver = d.oper.StateDriver
+	err := cfgEp.Read(id)
+	if err != nil {
+		return "", false, err
+	}
+
+	operEp := &OvsOperEndpointState{}
+	operEp.StateDriver = d.oper.StateDriver
+	err = operEp.Read(id)
+	if core.ErrIfKeyExists(err) != nil {
+		return "", false, err
+	} else if err != nil {
+		return "endpoint is not programmed", false, nil
+	}
+
+	if !operEp.Matches(cfgEp) {
+		return "endpoint is out of date", true, nil
+	}
+
+	sw, err := d.endpointSwitch(operEp)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !sw.IsPortPresent(operEp.PortName) {
+		return "port " + operEp.PortName + " is missing", true, nil
+	}
+
+	return "", false, nil
+}
+
+// ReconcileEndpoints programs the given endpoints that are missing or out
+// of date, and removes the endpoints and ports not in the list.
+func (d *OvsDriver) ReconcileEndpoints(epIDs []string) []core.ReconcileAction {
+	actions := []core.ReconcileAction{}
+
+	desired := make(map[string]bool)
+	for _, id := range epIDs {
+		desired[id] = true
+
+		reason, hasOper, err := d.checkEndpoint(id)
+		if err != nil {
+			log.Errorf("Error checking endpoint %s. Err: %v", id, err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		if hasOper {
+			// the port needs to be recreated, which the create skips if
+			// there is matching oper state
+			d.DeleteEndpoint(id)
+		}
+		err = d.CreateEndpoint(id)
+		actions = append(actions, newReconcileAction("endpoint", id, "create", reason, err))
+	}
+
+	// remove the endpoints programmed on this host that are gone
+	operEp := &OvsOperEndpointState{}
+	operEp.StateDriver = d.oper.StateDriver
+	operEps, err := operEp.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		log.Errorf("Error reading endpoint oper state. Err: %v", err)
+		return actions
+	}
+
+	programmed := make(map[string]bool)
+	for _, state := range operEps {
+		ep := state.(*OvsOperEndpointState)
+		programmed[ep.ID] = true
+		if desired[ep.ID] || ep.VtepIP != "" || ep.HomingHost != d.oper.ID {
+			continue
+		}
+
+		err = d.DeleteEndpoint(ep.ID)
+		actions = append(actions, newReconcileAction("endpoint", ep.ID, "delete",
+			"endpoint is not in the state store", err))
+	}
+
+	// remove the ports whose endpoint has no state at all
+	for _, sw := range d.switchDb {
+		for portName, epID := range sw.EndpointPorts() {
+			if desired[epID] || programmed[epID] {
+				continue
+			}
+
+			err = sw.DeletePort(&OvsOperEndpointState{PortName: portName})
+			actions = append(actions, newReconcileAction("endpoint", epID, "delete",
+				"port "+portName+" has no endpoint state", err))
+		}
+	}
+
+	return actions
+}
+
+// ReconcilePeerHosts creates the missing VTEPs for the given peer hosts and
+// removes the VTEPs of others. The peers are the ones registered in the state
+// store, so that the VTEPs of the peers whose events were missed are fixed
+// as well.
+func (d *OvsDriver) ReconcilePeerHosts(peerHosts []core.ServiceInfo) []core.ReconcileAction {
+	actions := []core.ReconcileAction{}
+
+	peers := make(map[string]bool)
+	for _, node := range peerHosts {
+		// Nothing to do for our own IP
+		if node.HostAddr != d.localIP {
+			peers[node.HostAddr] = true
+		}
+	}
+
+	sw := d.switchDb["vxlan"]
+	vteps := sw.Vteps()
+	for addr := range peers {
+		if vteps[addr] == vxlanIfName(addr) {
+			continue
+		}
+
+		err := sw.CreateVtep(addr)
+		actions = append(actions, newReconcileAction("vtep", addr, "create",
+			"VTEP of peer host is missing", err))
+	}
+
+	for addr := range vteps {
+		if peers[addr] {
+			continue
+		}
+
+		err := sw.DeleteVtep(addr)
+		actions = append(actions, newReconcileAction("vtep", addr, "delete",
+			"peer host is unknown", err))
+	}
+
+	return actions
+}
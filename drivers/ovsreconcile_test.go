@@ -0,0 +1,109 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drivers
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+)
+
+// actionsOf returns the actions of the given kind and action
+func actionsOf(actions []core.ReconcileAction, kind, action string) []core.ReconcileAction {
+	matched := []core.ReconcileAction{}
+	for _, ra := range actions {
+		if ra.Kind == kind && ra.Action == action {
+			matched = append(matched, ra)
+		}
+	}
+	return matched
+}
+
+func TestOvsDriverReconcileEndpoints(t *testing.T) {
+	driver := initOvsDriver(t)
+	defer func() { driver.Deinit() }()
+	id := createEpID
+
+	err := driver.CreateEndpoint(id)
+	if err != nil {
+		t.Fatalf("endpoint creation failed. Error: %s", err)
+	}
+	defer func() { driver.DeleteEndpoint(id) }()
+
+	// see TestOvsDriverDeleteEndpoint
+	time.Sleep(1 * time.Second)
+
+	actions := driver.ReconcileEndpoints([]string{id})
+	if len(actions) != 0 {
+		t.Fatalf("reconcile of a programmed endpoint made changes: %+v", actions)
+	}
+
+	// the port goes away behind the driver's back
+	portName := driver.getIntfName()
+	output, err := exec.Command("ovs-vsctl", "del-port", getOvsPostName(portName)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("port removal failed. Error: %s Output: %s", err, output)
+	}
+	time.Sleep(1 * time.Second)
+
+	actions = driver.ReconcileEndpoints([]string{id})
+	created := actionsOf(actions, "endpoint", "create")
+	if len(created) != 1 || created[0].ID != id || created[0].Error != "" {
+		t.Fatalf("reconcile didn't recreate the endpoint: %+v", actions)
+	}
+	time.Sleep(1 * time.Second)
+
+	// the endpoint is gone from the state store
+	actions = driver.ReconcileEndpoints([]string{})
+	deleted := actionsOf(actions, "endpoint", "delete")
+	if len(deleted) != 1 || deleted[0].ID != id || deleted[0].Error != "" {
+		t.Fatalf("reconcile didn't delete the endpoint: %+v", actions)
+	}
+
+	operEp := &OvsOperEndpointState{}
+	operEp.StateDriver = driver.oper.StateDriver
+	if err := operEp.Read(id); err == nil {
+		t.Fatalf("oper state of the deleted endpoint exists")
+	}
+}
+
+func TestOvsDriverReconcilePeerHosts(t *testing.T) {
+	driver := initOvsDriver(t)
+	defer func() { driver.Deinit() }()
+	peerIP := "10.1.1.2"
+
+	peers := []core.ServiceInfo{{HostAddr: peerIP}, {HostAddr: driver.localIP}}
+	actions := driver.ReconcilePeerHosts(peers)
+	if len(actions) != 1 || actions[0].Action != "create" || actions[0].ID != peerIP ||
+		actions[0].Error != "" {
+		t.Fatalf("reconcile didn't create the VTEP of the peer: %+v", actions)
+	}
+	time.Sleep(1 * time.Second)
+
+	actions = driver.ReconcilePeerHosts(peers)
+	if len(actions) != 0 {
+		t.Fatalf("reconcile of existing VTEPs made changes: %+v", actions)
+	}
+
+	// the peer is no longer registered
+	actions = driver.ReconcilePeerHosts([]core.ServiceInfo{})
+	if len(actions) != 1 || actions[0].Action != "delete" || actions[0].ID != peerIP ||
+		actions[0].Error != "" {
+		t.Fatalf("reconcile didn't delete the VTEP of the peer: %+v", actions)
+	}
+}
@@ -35,6 +35,9 @@ const (
 	cfgGlobalPath    = cfgGlobalPrefix + "%s"
 	operGlobalPrefix = baseGlobal + "oper/global/"
 	operGlobalPath   = operGlobalPrefix + "%s"
+
+	// maxIPv6SubnetPoolBits limits the IPv6 subnets of a pool to 2^16
+	maxIPv6SubnetPoolBits = 16
 )
 
 // Version constants. Used in managing state variance.
@@ -45,13 +48,17 @@ const (
 // AutoParams specifies various parameters for the auto allocation and resource
 // management for networks and endpoints.  This allows for hands-free
 // allocation of resources without having to specify these each time these
-// constructs gets created.
+// constructs gets created. The IPv6 subnet pool is optional.
 type AutoParams struct {
-	SubnetPool     string `json:"subnetPool"`
-	SubnetLen      uint   `json:"subnetLen"`
-	AllocSubnetLen uint   `json:"AllocSubnetLen"`
-	VLANs          string `json:"VLANs"`
-	VXLANs         string `json:"VXLANs"`
+	SubnetPool         string `json:"subnetPool"`
+	SubnetLen          uint   `json:"subnetLen"`
+	AllocSubnetLen     uint   `json:"AllocSubnetLen"`
+	IPv6SubnetPool     string `json:"ipv6SubnetPool,omitempty"`
+	IPv6SubnetLen      uint   `json:"ipv6SubnetLen,omitempty"`
+	AllocIPv6SubnetLen uint   `json:"allocIPv6SubnetLen,omitempty"`
+	VLANs              string `json:"VLANs"`
+	VXLANs             string `json:"VXLANs"`
+	MACPrefix          string `json:"macPrefix,omitempty"`
 }
 
 // DeployParams specifies parameters that decides the deployment choices
@@ -60,6 +67,14 @@ type DeployParams struct {
 	DefaultNetwork string `json:"defaultNetwork"`
 }
 
+// QuotaParams limits the number of objects of a tenant, zero being no limit.
+type QuotaParams struct {
+	MaxNetworks       int `json:"maxNetworks,omitempty"`
+	MaxEndpoints      int `json:"maxEndpoints,omitempty"`
+	MaxEndpointGroups int `json:"maxEndpointGroups,omitempty"`
+	MaxVLANs          int `json:"maxVLANs,omitempty"`
+}
+
 // Cfg is the configuration of a tenant.
 type Cfg struct {
 	core.CommonState
@@ -67,10 +82,13 @@ type Cfg struct {
 	Tenant      string       `json:"tenant"`
 	Auto        AutoParams   `json:"auto"`
 	Deploy      DeployParams `json:"deploy"`
+	Quota       QuotaParams  `json:"quota"`
 	NwInfraMode string       `json:"nw-infra-mode"`
 }
 
-// Oper encapsulates operations on a tenant.
+// Oper encapsulates operations on a tenant. FreeVXLANsStart is the lowest
+// vxlan of the tenant's ranges, the vxlans of its vxlan resource are offsets
+// from it.
 type Oper struct {
 	core.CommonState
 	Tenant          string `json:"tenant"`
@@ -88,7 +106,7 @@ func (gc *Cfg) checkErrors() error {
 	var err error
 
 	if net.ParseIP(gc.Auto.SubnetPool) == nil {
-		return core.Errorf("invalid ip address pool %s", gc.Auto.SubnetPool)
+		return core.InvalidErrorf("invalid ip address pool %s", gc.Auto.SubnetPool)
 	}
 
 	_, err = netutils.ParseTagRanges(gc.Auto.VLANs, "vlan")
@@ -103,13 +121,28 @@ func (gc *Cfg) checkErrors() error {
 
 	if gc.Deploy.DefaultNetType != "vlan" &&
 		gc.Deploy.DefaultNetType != "vxlan" {
-		return core.Errorf("unsupported net type %s", gc.Deploy.DefaultNetType)
+		return core.InvalidErrorf("unsupported net type %s", gc.Deploy.DefaultNetType)
 	}
 
 	if gc.Auto.SubnetLen > gc.Auto.AllocSubnetLen {
-		return core.Errorf("subnet size %d is smaller than subnets (%d) to be allocated from it",
+		return core.InvalidErrorf("subnet size %d is smaller than subnets (%d) to be allocated from it",
 			gc.Auto.SubnetLen, gc.Auto.AllocSubnetLen)
 	}
+
+	if gc.Auto.IPv6SubnetPool != "" {
+		if !netutils.IsIPv6(gc.Auto.IPv6SubnetPool) {
+			return core.InvalidErrorf("invalid ipv6 address pool %s", gc.Auto.IPv6SubnetPool)
+		}
+		if gc.Auto.IPv6SubnetLen > gc.Auto.AllocIPv6SubnetLen || gc.Auto.AllocIPv6SubnetLen > 128 {
+			return core.InvalidErrorf("invalid length %d of subnets of ipv6 pool %s/%d",
+				gc.Auto.AllocIPv6SubnetLen, gc.Auto.IPv6SubnetPool, gc.Auto.IPv6SubnetLen)
+		}
+		if gc.Auto.AllocIPv6SubnetLen-gc.Auto.IPv6SubnetLen > maxIPv6SubnetPoolBits {
+			return core.InvalidErrorf("ipv6 pool %s/%d has more than 2^%d subnets of length %d",
+				gc.Auto.IPv6SubnetPool, gc.Auto.IPv6SubnetLen, maxIPv6SubnetPoolBits,
+				gc.Auto.AllocIPv6SubnetLen)
+		}
+	}
 	return err
 }
 
@@ -218,34 +251,55 @@ func deriveAvailableVLANs(stateDriver core.StateDriver) (*bitset.BitSet, error)
 	return availableVLANs, nil
 }
 
-func (gc *Cfg) initVXLANBitset(vxlans string) (*resources.AutoVXLANCfgResource,
-	uint, error) {
+// GetAvailableVLANs returns the vlans that are not assigned to any tenant
+func GetAvailableVLANs(stateDriver core.StateDriver) (*bitset.BitSet, error) {
+	return deriveAvailableVLANs(stateDriver)
+}
 
-	vxlanRsrcCfg := &resources.AutoVXLANCfgResource{}
-	vxlanRsrcCfg.VXLANs = netutils.CreateBitset(14)
+// initVXLANs returns the vxlans of the ranges as offsets from the lowest
+// vxlan, which is returned as well.
+func initVXLANs(vxlans string) (*bitset.BitSet, uint, error) {
+	vxlanBitset := netutils.CreateBitset(14)
 
-	vxlanRange := netutils.TagRange{}
 	vxlanRanges, err := netutils.ParseTagRanges(vxlans, "vxlan")
 	if err != nil {
 		return nil, 0, err
 	}
-	// XXX: REVISIT, we seem to accept one contiguous vxlan range
-	vxlanRange = vxlanRanges[0]
 
-	freeVXLANsStart := uint(vxlanRange.Min)
-	for vxlan := vxlanRange.Min; vxlan <= vxlanRange.Max; vxlan++ {
-		vxlanRsrcCfg.VXLANs.Set(uint(vxlan - vxlanRange.Min))
+	start := vxlanRanges[0].Min
+	for _, vxlanRange := range vxlanRanges {
+		if vxlanRange.Min < start {
+			start = vxlanRange.Min
+		}
 	}
 
-	availableVLANs, err := deriveAvailableVLANs(gc.StateDriver)
+	for _, vxlanRange := range vxlanRanges {
+		for vxlan := vxlanRange.Min; vxlan <= vxlanRange.Max; vxlan++ {
+			vxlanBitset.Set(uint(vxlan - start))
+		}
+	}
+
+	return vxlanBitset, uint(start), nil
+}
+
+// initVXLANBitset returns the resource config for the vxlans, reserving
+// local vlans from the ones available.
+func (gc *Cfg) initVXLANBitset(vxlans string, availableVLANs *bitset.BitSet) (
+	*resources.AutoVXLANCfgResource, uint, error) {
+
+	vxlanRsrcCfg := &resources.AutoVXLANCfgResource{}
+
+	vxlanBitset, freeVXLANsStart, err := initVXLANs(vxlans)
 	if err != nil {
 		return nil, 0, err
 	}
+	vxlanRsrcCfg.VXLANs = vxlanBitset
+	availableVLANs = availableVLANs.Clone()
 
-	localVLANsReqd := vxlanRange.Max - vxlanRange.Min + 1
+	localVLANsReqd := int(vxlanBitset.Count())
 	if count := availableVLANs.Count(); int(count) < localVLANsReqd {
-		return nil, 0, core.Errorf("Available free local vlans (%d) is less than possible vxlans (%d)",
-			count, vxlanRange.Max-vxlanRange.Min)
+		return nil, 0, core.ExhaustedErrorf("Available free local vlans (%d) is less than possible vxlans (%d)",
+			count, localVLANsReqd-1)
 	} else if int(count) > localVLANsReqd {
 		//only reserve the #vxlan amount of bits
 		var clearBitMarker uint
@@ -363,6 +417,43 @@ func (gc *Cfg) FreeSubnet(ra core.ResourceManager, subnetIP string) error {
 			Len: gc.Auto.AllocSubnetLen})
 }
 
+// AllocIPv6Subnet allocates a subnet from the IPv6 subnet pool. Returns the
+// subnet address, the subnet length being AllocIPv6SubnetLen.
+func (gc *Cfg) AllocIPv6Subnet(ra core.ResourceManager) (string, error) {
+	if gc.Auto.IPv6SubnetPool == "" {
+		return "", core.InvalidErrorf("tenant %s has no ipv6 subnet pool", gc.Tenant)
+	}
+
+	subnetID, err := ra.AllocateResourceVal(gc.Tenant, resources.AutoIPv6SubnetResource)
+	if err != nil {
+		return "", err
+	}
+
+	return netutils.GetIPv6SubnetIP(gc.Auto.IPv6SubnetPool, gc.Auto.IPv6SubnetLen,
+		gc.Auto.AllocIPv6SubnetLen, subnetID.(uint))
+}
+
+// FreeIPv6Subnet releases a subnet of the IPv6 subnet pool.
+func (gc *Cfg) FreeIPv6Subnet(ra core.ResourceManager, subnetIP string) error {
+	subnetID, err := netutils.GetIPv6SubnetNumber(gc.Auto.IPv6SubnetPool, gc.Auto.IPv6SubnetLen,
+		gc.Auto.AllocIPv6SubnetLen, subnetIP)
+	if err != nil {
+		return err
+	}
+
+	return ra.DeallocateResourceVal(gc.Tenant, resources.AutoIPv6SubnetResource, subnetID)
+}
+
+// ipv6SubnetBitset returns the numbers of the subnets of the IPv6 pool, or
+// an empty set if there is no pool.
+func (gc *Cfg) ipv6SubnetBitset() *bitset.BitSet {
+	if gc.Auto.IPv6SubnetPool == "" {
+		return bitset.New(0)
+	}
+
+	return netutils.CreateBitset(gc.Auto.AllocIPv6SubnetLen - gc.Auto.IPv6SubnetLen).Complement()
+}
+
 // Process validates, implements, and writes the state.
 func (gc *Cfg) Process(ra core.ResourceManager) error {
 	var err error
@@ -373,7 +464,7 @@ func (gc *Cfg) Process(ra core.ResourceManager) error {
 
 	err = gc.checkErrors()
 	if err != nil {
-		return core.Errorf("process failed on error checks %s", err)
+		return core.Errorf("process failed on error checks %w", err)
 	}
 
 	tenant := gc.Tenant
@@ -390,6 +481,14 @@ func (gc *Cfg) Process(ra core.ResourceManager) error {
 		return err
 	}
 
+	// Only define an ipv6 subnet resource if a pool was specified
+	if gc.Auto.IPv6SubnetPool != "" {
+		err = ra.DefineResource(tenant, resources.AutoIPv6SubnetResource, gc.ipv6SubnetBitset())
+		if err != nil {
+			return err
+		}
+	}
+
 	// Only define a vlan resource if a valid range was specified
 	if gc.Auto.VLANs != "" {
 		var vlanRsrcCfg *bitset.BitSet
@@ -406,8 +505,15 @@ func (gc *Cfg) Process(ra core.ResourceManager) error {
 	// Only define a vxlan resource if a valid range was specified
 	var freeVXLANsStart uint
 	if gc.Auto.VXLANs != "" {
+		var availableVLANs *bitset.BitSet
+		availableVLANs, err = deriveAvailableVLANs(gc.StateDriver)
+		if err != nil {
+			return err
+		}
+
 		var vxlanRsrcCfg *resources.AutoVXLANCfgResource
-		vxlanRsrcCfg, freeVXLANsStart, err = gc.initVXLANBitset(gc.Auto.VXLANs)
+		vxlanRsrcCfg, freeVXLANsStart, err = gc.initVXLANBitset(gc.Auto.VXLANs,
+			availableVLANs)
 		if err != nil {
 			return err
 		}
@@ -430,6 +536,161 @@ func (gc *Cfg) Process(ra core.ResourceManager) error {
 	return nil
 }
 
+// Update redefines the resources of the tenant for the ranges in gc,
+// keeping the values in use. prev is the config the resources were defined
+// with. Ranges can grow, but can only shrink if no value in use is left out.
+func (gc *Cfg) Update(ra core.ResourceManager, prev *Cfg) error {
+	var err error
+
+	if gc.Version != VersionBeta1 {
+		return core.Errorf("unsupported version %s", gc.Version)
+	}
+
+	err = gc.checkErrors()
+	if err != nil {
+		return core.Errorf("update failed on error checks %w", err)
+	}
+
+	tenant := gc.Tenant
+	if tenant == "" {
+		return core.Errorf("null tenant")
+	}
+	if tenant != prev.Tenant {
+		return core.Errorf("tenant %q can not be updated with config of tenant %q",
+			prev.Tenant, tenant)
+	}
+
+	if gc.Auto.SubnetPool != prev.Auto.SubnetPool ||
+		gc.Auto.SubnetLen != prev.Auto.SubnetLen ||
+		gc.Auto.AllocSubnetLen != prev.Auto.AllocSubnetLen {
+		subnetRsrcCfg := &resources.AutoSubnetCfgResource{
+			SubnetPool:     net.ParseIP(gc.Auto.SubnetPool),
+			SubnetPoolLen:  gc.Auto.SubnetLen,
+			AllocSubnetLen: gc.Auto.AllocSubnetLen}
+		err = ra.RedefineResource(tenant, resources.AutoSubnetResource, subnetRsrcCfg)
+		if err != nil {
+			return core.Errorf("failed to update subnet pool. Error: %w", err)
+		}
+	}
+
+	err = gc.updateIPv6SubnetPool(ra, prev)
+	if err != nil {
+		return err
+	}
+
+	// vlans taken by neither a tenant nor as local vlans. This is read
+	// before any change, as the resources read here are not the ones
+	// updated by a transaction.
+	availableVLANs, err := deriveAvailableVLANs(gc.StateDriver)
+	if err != nil {
+		return err
+	}
+
+	vlans := netutils.CreateBitset(12)
+	if gc.Auto.VLANs != "" {
+		vlans, err = gc.initVLANBitset(gc.Auto.VLANs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if gc.Auto.VLANs != prev.Auto.VLANs {
+		prevVLANs := netutils.CreateBitset(12)
+		if prev.Auto.VLANs != "" {
+			prevVLANs, err = gc.initVLANBitset(prev.Auto.VLANs)
+			if err != nil {
+				return err
+			}
+		}
+
+		added := vlans.Difference(prevVLANs)
+		if taken := added.Difference(availableVLANs); taken.Any() {
+			return core.ConflictErrorf("failed to update vlans. %d of the vlans added are in use by other tenants or as local vlans",
+				taken.Count())
+		}
+
+		if prev.Auto.VLANs == "" {
+			err = ra.DefineResource(tenant, resources.AutoVLANResource, vlans)
+		} else {
+			err = ra.RedefineResource(tenant, resources.AutoVLANResource, vlans)
+		}
+		if err != nil {
+			return core.Errorf("failed to update vlans. Error: %w", err)
+		}
+	}
+
+	if gc.Auto.VXLANs == prev.Auto.VXLANs {
+		return nil
+	}
+
+	g := &Oper{}
+	g.StateDriver = gc.StateDriver
+	err = g.Read(tenant)
+	if err != nil {
+		return err
+	}
+
+	if prev.Auto.VXLANs == "" {
+		var vxlanRsrcCfg *resources.AutoVXLANCfgResource
+		vxlanRsrcCfg, g.FreeVXLANsStart, err = gc.initVXLANBitset(gc.Auto.VXLANs,
+			availableVLANs.Difference(vlans))
+		if err != nil {
+			return err
+		}
+		err = ra.DefineResource(tenant, resources.AutoVXLANResource, vxlanRsrcCfg)
+	} else {
+		vxlans, freeVXLANsStart := netutils.CreateBitset(14), g.FreeVXLANsStart
+		if gc.Auto.VXLANs != "" {
+			vxlans, freeVXLANsStart, err = initVXLANs(gc.Auto.VXLANs)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = ra.RedefineResource(tenant, resources.AutoVXLANResource,
+			&resources.AutoVXLANReinitCfg{
+				VXLANs:     vxlans,
+				LocalVLANs: availableVLANs.Difference(vlans),
+				Shift:      int(freeVXLANsStart) - int(g.FreeVXLANsStart),
+			})
+		g.FreeVXLANsStart = freeVXLANsStart
+	}
+	if err != nil {
+		return core.Errorf("failed to update vxlans. Error: %w", err)
+	}
+
+	return g.Write()
+}
+
+// updateIPv6SubnetPool redefines the IPv6 subnet pool of the tenant. The
+// subnets are numbered from the pool address, so once defined the pool can
+// be resized but keeps its address and the length of its subnets.
+func (gc *Cfg) updateIPv6SubnetPool(ra core.ResourceManager, prev *Cfg) error {
+	var err error
+
+	if gc.Auto.IPv6SubnetPool == prev.Auto.IPv6SubnetPool &&
+		gc.Auto.IPv6SubnetLen == prev.Auto.IPv6SubnetLen &&
+		gc.Auto.AllocIPv6SubnetLen == prev.Auto.AllocIPv6SubnetLen {
+		return nil
+	}
+
+	if prev.Auto.IPv6SubnetPool == "" {
+		err = ra.DefineResource(gc.Tenant, resources.AutoIPv6SubnetResource, gc.ipv6SubnetBitset())
+	} else if gc.Auto.IPv6SubnetPool == "" ||
+		!net.ParseIP(gc.Auto.IPv6SubnetPool).Equal(net.ParseIP(prev.Auto.IPv6SubnetPool)) ||
+		gc.Auto.AllocIPv6SubnetLen != prev.Auto.AllocIPv6SubnetLen {
+		return core.InvalidErrorf("ipv6 subnet pool %s/%d with subnets of length %d can only be resized",
+			prev.Auto.IPv6SubnetPool, prev.Auto.IPv6SubnetLen, prev.Auto.AllocIPv6SubnetLen)
+	} else {
+		err = ra.RedefineResource(gc.Tenant, resources.AutoIPv6SubnetResource, gc.ipv6SubnetBitset())
+	}
+	if err != nil {
+		return core.Errorf("failed to update ipv6 subnet pool. Error: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteResources deletes associated resources
 func (gc *Cfg) DeleteResources(ra core.ResourceManager) error {
 	tenant := gc.Tenant
@@ -442,6 +703,13 @@ func (gc *Cfg) DeleteResources(ra core.ResourceManager) error {
 		log.Errorf("Error deleting subnet resource. Err: %v", err)
 	}
 
+	if gc.Auto.IPv6SubnetPool != "" {
+		err = ra.UndefineResource(tenant, resources.AutoIPv6SubnetResource)
+		if err != nil {
+			log.Errorf("Error deleting ipv6 subnet resource. Err: %v", err)
+		}
+	}
+
 	err = ra.UndefineResource(tenant, resources.AutoVLANResource)
 	if err != nil {
 		log.Errorf("Error deleting vlan resource. Err: %v", err)
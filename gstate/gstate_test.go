@@ -409,3 +409,262 @@ func TestAutoDefaultNetwork(t *testing.T) {
 		t.Fatalf("Error: '%s' could not unassign default network", err)
 	}
 }
+
+func TestGlobalConfigUpdate(t *testing.T) {
+	cfgData := []byte(`
+        {
+            "Version" : "0.01",
+            "Tenant"  : "default",
+            "Auto" : {
+                "SubnetPool"        : "11.5.0.0",
+                "SubnetLen"         : 16,
+                "AllocSubnetLen"    : 24,
+                "VLANs"             : "1-10",
+                "VXLANs"            : "15000-15100"
+            },
+            "Deploy" : {
+                "DefaultNetType"    : "vxlan"
+            }
+        }`)
+
+	gc, err := Parse(cfgData)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s' \n", err, cfgData)
+	}
+
+	gstateSD.Init(nil)
+	defer func() { gstateSD.Deinit() }()
+	gc.StateDriver = gstateSD
+	rm, err := resources.NewStateResourceManager(gstateSD)
+	if err != nil {
+		t.Fatalf("Failed to instantiate resource manager. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	err = gc.Process(rm)
+	if err != nil {
+		t.Fatalf("error '%s' processing config %v \n", err, gc)
+	}
+
+	vlan, err := gc.AllocVLAN(rm)
+	if err != nil {
+		t.Fatalf("error - allocating vlan - %s \n", err)
+	}
+	vxlan, localVLAN, err := gc.AllocVXLAN(rm)
+	if err != nil {
+		t.Fatalf("error - allocating vxlan - %s \n", err)
+	}
+	subnet, err := gc.AllocSubnet(rm)
+	if err != nil {
+		t.Fatalf("error - allocating subnet - %s \n", err)
+	}
+
+	// shrinks that leave out values in use are rejected
+	invalidAutos := []AutoParams{
+		{SubnetPool: "11.5.0.0", SubnetLen: 16, AllocSubnetLen: 24,
+			VLANs: "5-10", VXLANs: "15000-15100"},
+		{SubnetPool: "11.5.0.0", SubnetLen: 16, AllocSubnetLen: 24,
+			VLANs: "1-10", VXLANs: "15050-15100"},
+		{SubnetPool: "12.5.0.0", SubnetLen: 16, AllocSubnetLen: 24,
+			VLANs: "1-10", VXLANs: "15000-15100"},
+		{SubnetPool: "11.5.0.0", SubnetLen: 16, AllocSubnetLen: 25,
+			VLANs: "1-10", VXLANs: "15000-15100"},
+	}
+	for _, auto := range invalidAutos {
+		newGc := *gc
+		newGc.Auto = auto
+		err = newGc.Update(rm, gc)
+		if err == nil {
+			t.Fatalf("update to %+v succeeded with values in use", auto)
+		}
+	}
+
+	// the local vlans of the vxlans can not be added to the vlans
+	newGc := *gc
+	newGc.Auto.VLANs = "1-20"
+	err = newGc.Update(rm, gc)
+	if err == nil {
+		t.Fatalf("update to vlans %s succeeded with local vlans in use", newGc.Auto.VLANs)
+	}
+
+	// grow all the ranges
+	newGc = *gc
+	newGc.Auto = AutoParams{SubnetPool: "11.4.0.0", SubnetLen: 15,
+		AllocSubnetLen: 24, VLANs: "1-10,500-510", VXLANs: "14000-15100"}
+	err = newGc.Update(rm, gc)
+	if err != nil {
+		t.Fatalf("error '%s' growing ranges to %+v \n", err, newGc.Auto)
+	}
+
+	for i := 0; i < 9; i++ {
+		if _, err = newGc.AllocVLAN(rm); err != nil {
+			t.Fatalf("error - allocating vlan after update - %s \n", err)
+		}
+	}
+	if newVLAN, err := newGc.AllocVLAN(rm); err != nil || newVLAN != 500 {
+		t.Fatalf("error - expecting vlan 500 after update, allocated %d. Err: %v", newVLAN, err)
+	}
+
+	newVXLAN, newLocalVLAN, err := newGc.AllocVXLAN(rm)
+	if err != nil {
+		t.Fatalf("error - allocating vxlan after update - %s \n", err)
+	}
+	if newVXLAN != 14000 || newLocalVLAN == localVLAN {
+		t.Fatalf("error - expecting vxlan 14000 and a new local vlan, allocated %d, %d",
+			newVXLAN, newLocalVLAN)
+	}
+
+	newSubnet, err := newGc.AllocSubnet(rm)
+	if err != nil {
+		t.Fatalf("error - allocating subnet after update - %s \n", err)
+	}
+	if newSubnet != "11.4.0.0" {
+		t.Fatalf("error - expecting subnet 11.4.0.0 after update, allocated %s", newSubnet)
+	}
+
+	// the values allocated before the update are still allocated
+	if err = newGc.FreeVLAN(rm, vlan); err != nil {
+		t.Fatalf("error freeing vlan %d - err '%s' \n", vlan, err)
+	}
+	if err = newGc.FreeVXLAN(rm, vxlan, localVLAN); err != nil {
+		t.Fatalf("error freeing vxlan %d - err '%s' \n", vxlan, err)
+	}
+	if err = newGc.FreeSubnet(rm, subnet); err != nil {
+		t.Fatalf("error freeing subnet %s - err '%s' \n", subnet, err)
+	}
+	if vxlan, _, err = newGc.AllocVXLAN(rm); err != nil || vxlan != 14001 {
+		t.Fatalf("error - expecting vxlan 14001, allocated %d. Err: %v", vxlan, err)
+	}
+}
+
+func TestGlobalConfigMultipleVXLANRanges(t *testing.T) {
+	cfgData := []byte(`
+        {
+            "Version" : "0.01",
+            "Tenant"  : "default",
+            "Auto" : {
+                "SubnetPool"        : "11.5.0.0",
+                "SubnetLen"         : 16,
+                "AllocSubnetLen"    : 24,
+                "VLANs"             : "1-10",
+                "VXLANs"            : "15000-15001,12000-12001"
+            },
+            "Deploy" : {
+                "DefaultNetType"    : "vxlan"
+            }
+        }`)
+
+	gc, err := Parse(cfgData)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s' \n", err, cfgData)
+	}
+
+	gstateSD.Init(nil)
+	defer func() { gstateSD.Deinit() }()
+	gc.StateDriver = gstateSD
+	rm, err := resources.NewStateResourceManager(gstateSD)
+	if err != nil {
+		t.Fatalf("Failed to instantiate resource manager. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	err = gc.Process(rm)
+	if err != nil {
+		t.Fatalf("error '%s' processing config %v \n", err, gc)
+	}
+
+	localVLANs := map[uint]bool{}
+	for _, expVXLAN := range []uint{12000, 12001, 15000, 15001} {
+		vxlan, localVLAN, err := gc.AllocVXLAN(rm)
+		if err != nil {
+			t.Fatalf("error - allocating vxlan - %s \n", err)
+		}
+		if vxlan != expVXLAN {
+			t.Fatalf("error - expecting vxlan %d but allocated %d \n", expVXLAN, vxlan)
+		}
+		if localVLAN == 0 || localVLANs[localVLAN] {
+			t.Fatalf("error - invalid or duplicate local vlan %d allocated \n", localVLAN)
+		}
+		localVLANs[localVLAN] = true
+	}
+
+	_, _, err = gc.AllocVXLAN(rm)
+	if err == nil {
+		t.Fatalf("allocated a vxlan beyond the configured ranges \n")
+	}
+}
+
+func TestGlobalConfigIPv6SubnetPool(t *testing.T) {
+	cfgData := []byte(`
+        {
+            "Version" : "0.01",
+            "Tenant"  : "default",
+            "Auto" : {
+                "SubnetPool"         : "11.5.0.0",
+                "SubnetLen"          : 16,
+                "AllocSubnetLen"     : 24,
+                "ipv6SubnetPool"     : "2001:db8::",
+                "ipv6SubnetLen"      : 63,
+                "allocIPv6SubnetLen" : 64,
+                "VLANs"              : "1-10",
+                "VXLANs"             : "15000-15100"
+            },
+            "Deploy" : {
+                "DefaultNetType"    : "vxlan"
+            }
+        }`)
+
+	gc, err := Parse(cfgData)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s' \n", err, cfgData)
+	}
+
+	gstateSD.Init(nil)
+	defer func() { gstateSD.Deinit() }()
+	gc.StateDriver = gstateSD
+	rm, err := resources.NewStateResourceManager(gstateSD)
+	if err != nil {
+		t.Fatalf("Failed to instantiate resource manager. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	err = gc.Process(rm)
+	if err != nil {
+		t.Fatalf("error '%s' processing config %v \n", err, gc)
+	}
+
+	for _, expSubnet := range []string{"2001:db8::", "2001:db8:0:1::"} {
+		subnet, err := gc.AllocIPv6Subnet(rm)
+		if err != nil || subnet != expSubnet {
+			t.Fatalf("error - expecting ipv6 subnet %s, allocated %s. Err: %v", expSubnet, subnet, err)
+		}
+	}
+	if subnet, err := gc.AllocIPv6Subnet(rm); err == nil {
+		t.Fatalf("allocated ipv6 subnet %s from an exhausted pool", subnet)
+	}
+
+	// the pool can not be moved, but grows keeping its subnets
+	newGc := *gc
+	newGc.Auto.IPv6SubnetPool = "2001:db9::"
+	if err = newGc.Update(rm, gc); err == nil {
+		t.Fatalf("moved an ipv6 subnet pool with subnets in use")
+	}
+
+	newGc = *gc
+	newGc.Auto.IPv6SubnetLen = 62
+	if err = newGc.Update(rm, gc); err != nil {
+		t.Fatalf("error '%s' growing the ipv6 subnet pool \n", err)
+	}
+	subnet, err := newGc.AllocIPv6Subnet(rm)
+	if err != nil || subnet != "2001:db8:0:2::" {
+		t.Fatalf("error - expecting ipv6 subnet 2001:db8:0:2::, allocated %s. Err: %v", subnet, err)
+	}
+
+	if err = newGc.FreeIPv6Subnet(rm, "2001:db8::"); err != nil {
+		t.Fatalf("error freeing ipv6 subnet - err '%s' \n", err)
+	}
+	subnet, err = newGc.AllocIPv6Subnet(rm)
+	if err != nil || subnet != "2001:db8::" {
+		t.Fatalf("error - expecting the freed ipv6 subnet, allocated %s. Err: %v", subnet, err)
+	}
+}
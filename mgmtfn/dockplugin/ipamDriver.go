@@ -24,9 +24,30 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/contiv/netplugin/netmaster/master"
 	"github.com/contiv/netplugin/netplugin/cluster"
+	"github.com/contiv/netplugin/utils/netutils"
 	"github.com/docker/libnetwork/ipams/remote/api"
 )
 
+const (
+	// leaseKeyOption is the address request option naming the lease key
+	leaseKeyOption = "contiv.io/lease-key"
+	// macAddressOption is the option docker passes with a configured MAC,
+	// which makes the lease key when no lease key option is set
+	macAddressOption = "com.docker.network.endpoint.macaddress"
+)
+
+// requestLeaseKey returns the lease key for the address request, if any
+func requestLeaseKey(options map[string]string) string {
+	if leaseKey := options[leaseKeyOption]; leaseKey != "" {
+		return leaseKey
+	}
+	if mac := options[macAddressOption]; mac != "" {
+		return "mac:" + mac
+	}
+
+	return ""
+}
+
 // getDefaultAddressSpaces
 func getDefaultAddressSpaces() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -145,11 +166,18 @@ func requestAddress() func(http.ResponseWriter, *http.Request) {
 
 		// Build an alloc request to be sent to master
 		allocReq := master.AddressAllocRequest{
-			NetworkID:            areq.PoolID,
-			PreferredIPv4Address: areq.Address,
+			NetworkID: areq.PoolID,
 		}
 
+		subnetIP := strings.Split(areq.PoolID, "/")[0]
 		subnetLen := strings.Split(areq.PoolID, "/")[1]
+		isIPv6 := netutils.IsIPv6(subnetIP)
+		if isIPv6 {
+			allocReq.PreferredIPv6Address = areq.Address
+		} else {
+			allocReq.PreferredIPv4Address = areq.Address
+			allocReq.LeaseKey = requestLeaseKey(areq.Options)
+		}
 
 		var addr string
 		if areq.Address != "" {
@@ -164,6 +192,9 @@ func requestAddress() func(http.ResponseWriter, *http.Request) {
 			}
 
 			addr = allocResp.IPv4Address
+			if isIPv6 {
+				addr = allocResp.IPv6Address
+			}
 		}
 
 		// build response
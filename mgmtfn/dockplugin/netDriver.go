@@ -214,6 +214,8 @@ func createEndpoint(hostname string) func(http.ResponseWriter, *http.Request) {
 				Container:   cereq.EndpointID,
 				Host:        hostname,
 				IPAddress:   strings.Split(cereq.Interface.Address, "/")[0],
+				IPv6Address: strings.Split(cereq.Interface.AddressIPv6, "/")[0],
+				MacAddress:  cereq.Interface.MacAddress,
 				ServiceName: serviceName,
 			},
 		}
@@ -336,7 +338,8 @@ func join() func(http.ResponseWriter, *http.Request) {
 				SrcName:   ep.PortName,
 				DstPrefix: "eth",
 			},
-			Gateway: nw.Gateway,
+			Gateway:     nw.Gateway,
+			GatewayIPv6: nw.IPv6Gateway,
 		}
 
 		log.Infof("Sending JoinResponse: {%+v}, InterfaceName: %s", joinResp, ep.PortName)
@@ -40,6 +40,7 @@ type CNIPodAttr struct {
 
 // RspAddPod contains the response to the AddPod
 type RspAddPod struct {
-	EndpointID string `json:"endpointid,omitempty"`
-	IPAddress  string `json:"ipaddress,omitempty"`
+	EndpointID  string `json:"endpointid,omitempty"`
+	IPAddress   string `json:"ipaddress,omitempty"`
+	IPv6Address string `json:"ipv6address,omitempty"`
 }
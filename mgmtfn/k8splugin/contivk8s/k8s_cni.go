@@ -50,6 +50,31 @@ func getPodInfo(ppInfo *cniapi.CNIPodAttr) error {
 	return nil
 }
 
+// cniIPConfig is the ip configuration of a cni result
+type cniIPConfig struct {
+	IP string `json:"ip"`
+}
+
+// cniResult is the result of adding a pod, as expected by the cni spec
+type cniResult struct {
+	CNIVersion string       `json:"cniVersion"`
+	IP4        *cniIPConfig `json:"ip4,omitempty"`
+	IP6        *cniIPConfig `json:"ip6,omitempty"`
+}
+
+// getCNIResult builds the cni result with the addresses of the endpoint
+func getCNIResult(result *cniapi.RspAddPod) ([]byte, error) {
+	cniResp := cniResult{CNIVersion: "0.1.0"}
+	if result.IPAddress != "" {
+		cniResp.IP4 = &cniIPConfig{IP: result.IPAddress}
+	}
+	if result.IPv6Address != "" {
+		cniResp.IP6 = &cniIPConfig{IP: result.IPv6Address}
+	}
+
+	return json.MarshalIndent(cniResp, "", "  ")
+}
+
 func addPodToContiv(nc *clients.NWClient, pInfo *cniapi.CNIPodAttr) {
 
 	// Add to contiv network
@@ -57,13 +82,15 @@ func addPodToContiv(nc *clients.NWClient, pInfo *cniapi.CNIPodAttr) {
 	if err != nil {
 		log.Fatalf("EP create failed -- %s", err)
 	} else {
-		log.Infof("EP created IP: %s\n", result.IPAddress)
+		log.Infof("EP created IP: %s, IPv6: %s\n", result.IPAddress, result.IPv6Address)
 	}
 
-	// Write the ip address of the created endpoint to stdout
-	fmt.Printf("{\n\"cniVersion\": \"0.1.0\",\n")
-	fmt.Printf("\"ip4\": {\n")
-	fmt.Printf("\"ip\": \"%s\"\n}\n}\n", result.IPAddress)
+	// Write the ip addresses of the created endpoint to stdout
+	out, err := getCNIResult(result)
+	if err != nil {
+		log.Fatalf("Error building cni result -- %s", err)
+	}
+	fmt.Printf("%s\n", out)
 }
 
 func deletePodFromContiv(nc *clients.NWClient, pInfo *cniapi.CNIPodAttr) {
@@ -190,3 +190,28 @@ func TestDelpod(m *testing.T) {
 	os.Setenv("CNI_COMMAND", "DEL")
 	main()
 }
+
+// TestCNIResult tests the cni result for ipv4, ipv6 and dual-stack pods
+func TestCNIResult(t *testing.T) {
+	testResults := map[cniapi.RspAddPod]string{
+		cniapi.RspAddPod{IPAddress: utPodIP}:                                `{"cniVersion":"0.1.0","ip4":{"ip":"44.55.66.77/22"}}`,
+		cniapi.RspAddPod{IPv6Address: "2001:db8::1/64"}:                     `{"cniVersion":"0.1.0","ip6":{"ip":"2001:db8::1/64"}}`,
+		cniapi.RspAddPod{IPAddress: utPodIP, IPv6Address: "2001:db8::1/64"}: `{"cniVersion":"0.1.0","ip4":{"ip":"44.55.66.77/22"},"ip6":{"ip":"2001:db8::1/64"}}`,
+	}
+
+	for result, exp := range testResults {
+		out, err := getCNIResult(&result)
+		if err != nil {
+			t.Fatalf("Error building cni result for %+v. Err: %v", result, err)
+		}
+
+		cniResp := cniResult{}
+		if err := json.Unmarshal(out, &cniResp); err != nil {
+			t.Fatalf("Error parsing cni result %s. Err: %v", out, err)
+		}
+		compact, _ := json.Marshal(cniResp)
+		if string(compact) != exp {
+			t.Fatalf("Expected cni result %s, got %s", exp, compact)
+		}
+	}
+}
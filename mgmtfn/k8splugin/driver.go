@@ -42,12 +42,14 @@ type epSpec struct {
 	Network    string `json:"network,omitempty"`
 	Group      string `json:"group,omitempty"`
 	EndpointID string `json:"endpointid,omitempty"`
+	LeaseKey   string `json:"leasekey,omitempty"`
 }
 
 // epAttr contains the assigned attributes of the created ep
 type epAttr struct {
-	IPAddress string
-	PortName  string
+	IPAddress   string
+	IPv6Address string
+	PortName    string
 }
 
 // netdGetEndpoint is a utility that reads the EP oper state
@@ -132,6 +134,7 @@ func createEP(req *epSpec) (*epAttr, error) {
 			Container:   req.EndpointID,
 			Host:        pluginHost,
 			ServiceName: req.Group,
+			LeaseKey:    req.LeaseKey,
 		},
 	}
 
@@ -167,7 +170,12 @@ func createEP(req *epSpec) (*epAttr, error) {
 
 	epResponse := epAttr{}
 	epResponse.PortName = ep.PortName
-	epResponse.IPAddress = ep.IPAddress + "/" + strconv.Itoa(int(nw.SubnetLen))
+	if ep.IPAddress != "" {
+		epResponse.IPAddress = ep.IPAddress + "/" + strconv.Itoa(int(nw.SubnetLen))
+	}
+	if ep.IPv6Address != "" {
+		epResponse.IPv6Address = ep.IPv6Address + "/" + strconv.Itoa(int(nw.IPv6SubnetLen))
+	}
 
 	return &epResponse, nil
 }
@@ -206,7 +214,7 @@ func nsToPID(ns string) (int, error) {
 }
 
 // setIfAttrs sets the required attributes for the container interface
-func setIfAttrs(ifname, netns, cidr, newname string) error {
+func setIfAttrs(ifname, netns string, cidrs []string, newname string) error {
 
 	// convert netns to pid that netlink needs
 	pid, err := nsToPID(netns)
@@ -249,24 +257,26 @@ func setIfAttrs(ifname, netns, cidr, newname string) error {
 	}
 	log.Infof("Output from rename: %v", rename)
 
-	// set the ip address
-	assignIP, err := osexec.Command(nsenterPath, "-t", nsPid, "-n", "-F", "--", ipPath,
-		"address", "add", cidr, "dev", newname).CombinedOutput()
+	// set the ip addresses
+	for _, cidr := range cidrs {
+		assignIP, err := osexec.Command(nsenterPath, "-t", nsPid, "-n", "-F", "--", ipPath,
+			"address", "add", cidr, "dev", newname).CombinedOutput()
 
-	if err != nil {
-		log.Errorf("unable to assign ip %s to %s. Error: %s",
-			cidr, newname, err)
-		return nil
+		if err != nil {
+			log.Errorf("unable to assign ip %s to %s. Error: %s",
+				cidr, newname, err)
+			return nil
+		}
+		log.Infof("Output from ip assign: %v", assignIP)
 	}
-	log.Infof("Output from ip assign: %v", assignIP)
 
 	// Finally, mark the link up
 	bringUp, err := osexec.Command(nsenterPath, "-t", nsPid, "-n", "-F", "--", ipPath,
 		"link", "set", "dev", newname, "up").CombinedOutput()
 
 	if err != nil {
-		log.Errorf("unable to assign ip %s to %s. Error: %s",
-			cidr, newname, err)
+		log.Errorf("unable to assign ip %v to %s. Error: %s",
+			cidrs, newname, err)
 		return nil
 	}
 	log.Infof("Output from ip assign: %v", bringUp)
@@ -294,6 +304,12 @@ func getEPSpec(pInfo *cniapi.CNIPodAttr) (*epSpec, error) {
 	resp.Group = epg
 	resp.EndpointID = pInfo.InfraContainerID
 
+	// pods asking for a sticky ip keep their address across restarts
+	sticky, _ := kubeAPIClient.GetPodLabel(pInfo.K8sNameSpace, pInfo.Name, "sticky-ip")
+	if sticky == "true" {
+		resp.LeaseKey = pInfo.K8sNameSpace + "/" + pInfo.Name
+	}
+
 	return &resp, nil
 }
 
@@ -329,13 +345,20 @@ func addPod(r *http.Request) (interface{}, error) {
 	}
 
 	// Set interface attributes for the new port
-	err = setIfAttrs(ep.PortName, pInfo.NwNameSpace, ep.IPAddress, pInfo.IntfName)
+	cidrs := []string{}
+	for _, cidr := range []string{ep.IPAddress, ep.IPv6Address} {
+		if cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	err = setIfAttrs(ep.PortName, pInfo.NwNameSpace, cidrs, pInfo.IntfName)
 	if err != nil {
 		log.Errorf("Error setting interface attributes. Err: %v", err)
 		return resp, err
 	}
 
 	resp.IPAddress = ep.IPAddress
+	resp.IPv6Address = ep.IPv6Address
 	resp.EndpointID = pInfo.InfraContainerID
 	return resp, nil
 }
@@ -26,6 +26,26 @@ var NetmasterFlags = []cli.Flag{
 		Usage:  "The hostname of the netmaster",
 		EnvVar: "NETMASTER",
 	},
+	cli.StringFlag{
+		Name:   "token",
+		Usage:  "The token to authenticate with the netmaster",
+		EnvVar: "NETMASTER_TOKEN",
+	},
+	cli.StringFlag{
+		Name:   "tls-ca",
+		Usage:  "The CA certificate file to verify an https netmaster with",
+		EnvVar: "NETMASTER_TLS_CA",
+	},
+	cli.StringFlag{
+		Name:   "tls-cert",
+		Usage:  "The client certificate file to authenticate with an https netmaster",
+		EnvVar: "NETMASTER_TLS_CERT",
+	},
+	cli.StringFlag{
+		Name:   "tls-key",
+		Usage:  "The key file of the client certificate",
+		EnvVar: "NETMASTER_TLS_KEY",
+	},
 }
 
 // Commands are all the commands that go into `contivctl`, the end-user tool.
@@ -105,9 +125,37 @@ var Commands = []cli.Command{
 						Name:  "gateway, g",
 						Usage: "Gateway - REQUIRED",
 					},
+					cli.StringFlag{
+						Name:  "ipv6-subnet",
+						Usage: "IPv6 subnet CIDR, for a dual-stack network",
+					},
+					cli.StringFlag{
+						Name:  "ipv6-gateway",
+						Usage: "IPv6 gateway",
+					},
+					cli.StringFlag{
+						Name:  "exclude, x",
+						Usage: "IP addresses and ranges not to allocate (10.1.1.1-10.1.1.10,10.1.1.20)",
+					},
+					cli.StringSliceFlag{
+						Name:  "reserve, r",
+						Usage: "IP address reserved for an endpoint or service (name=10.1.1.30), may be repeated",
+						Value: &cli.StringSlice{},
+					},
+					cli.IntFlag{
+						Name:  "ip-lease-hold-time",
+						Usage: "Seconds a released address stays held for its lease (default 600)",
+					},
 				},
 				Action: createNetwork,
 			},
+			{
+				Name:      "usage",
+				Usage:     "Show the address utilisation of networks",
+				ArgsUsage: "[network]",
+				Flags:     []cli.Flag{tenantFlag, allFlag, jsonFlag},
+				Action:    showNetworkUsage,
+			},
 		},
 	},
 	{
@@ -124,7 +172,13 @@ var Commands = []cli.Command{
 				Name:      "delete",
 				Usage:     "Delete a tenant",
 				ArgsUsage: "[tenant]",
-				Action:    deleteTenant,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "cascade, c",
+						Usage: "Delete the networks, groups, policies, apps and services of the tenant too",
+					},
+				},
+				Action: deleteTenant,
 			},
 			{
 				Name:      "create",
@@ -148,9 +202,29 @@ var Commands = []cli.Command{
 						Name:  "vxlans, x",
 						Usage: "Vxlan range - REQUIRED",
 					},
+					cli.StringFlag{
+						Name:  "mac-prefix",
+						Usage: "Prefix of the endpoint MAC addresses, e.g. 02:02",
+					},
+					cli.StringFlag{
+						Name:  "ipv6-subnet-pool",
+						Usage: "IPv6 subnet CIDR to give the networks IPv6 subnets from",
+					},
+					cli.IntFlag{
+						Name:  "ipv6-subnet-len",
+						Usage: "IPv6 subnet length",
+						Value: 64,
+					},
 				},
 				Action: createTenant,
 			},
+			{
+				Name:      "usage",
+				Usage:     "Show the resource and quota utilisation of tenants",
+				ArgsUsage: "[tenant]",
+				Flags:     []cli.Flag{jsonFlag},
+				Action:    showTenantUsage,
+			},
 		},
 	},
 	{
@@ -9,9 +9,12 @@ import (
 	"os"
 
 	"github.com/codegangsta/cli"
+	"github.com/contiv/netplugin/core"
 )
 
-var client = &http.Client{}
+// apiPrefix is the path prefix of the version of the netmaster REST API
+// that netctl uses
+const apiPrefix = "/api/v1"
 
 func handleBasicError(ctx *cli.Context, err error) {
 	if err != nil {
@@ -24,23 +27,68 @@ func baseURL(ctx *cli.Context) string {
 }
 
 func policyURL(ctx *cli.Context) string {
-	return fmt.Sprintf("%s/api/policys/", baseURL(ctx))
+	return fmt.Sprintf("%s%s/policys/", baseURL(ctx), apiPrefix)
 }
 
 func epgURL(ctx *cli.Context) string {
-	return fmt.Sprintf("%s/api/endpointGroups/", baseURL(ctx))
+	return fmt.Sprintf("%s%s/endpointGroups/", baseURL(ctx), apiPrefix)
 }
 
 func networkURL(ctx *cli.Context) string {
-	return fmt.Sprintf("%s/api/networks/", baseURL(ctx))
+	return fmt.Sprintf("%s%s/networks/", baseURL(ctx), apiPrefix)
 }
 
 func tenantURL(ctx *cli.Context) string {
-	return fmt.Sprintf("%s/api/tenants/", baseURL(ctx))
+	return fmt.Sprintf("%s%s/tenants/", baseURL(ctx), apiPrefix)
 }
 
 func ruleURL(ctx *cli.Context) string {
-	return fmt.Sprintf("%s/api/rules/", baseURL(ctx))
+	return fmt.Sprintf("%s%s/rules/", baseURL(ctx), apiPrefix)
+}
+
+func resourceUsageURL(ctx *cli.Context) string {
+	return fmt.Sprintf("%s%s/resource-usage", baseURL(ctx), apiPrefix)
+}
+
+func ipUsageURL(ctx *cli.Context) string {
+	return fmt.Sprintf("%s%s/ip-usage", baseURL(ctx), apiPrefix)
+}
+
+// doRequest makes a request to the netmaster, authenticated with the token
+// if one is set
+func doRequest(ctx *cli.Context, method, url string, body []byte) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	handleBasicError(ctx, err)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := ctx.GlobalString("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient(ctx).Do(req)
+	handleBasicError(ctx, err)
+
+	return resp
+}
+
+// httpClient returns the client of the requests to the netmaster, with the
+// certificates of the tls flags for an https netmaster
+func httpClient(ctx *cli.Context) *http.Client {
+	tlsFiles := core.TLSConfig{
+		CAFile:   ctx.GlobalString("tls-ca"),
+		CertFile: ctx.GlobalString("tls-cert"),
+		KeyFile:  ctx.GlobalString("tls-key"),
+	}
+	if !tlsFiles.Enabled() {
+		return http.DefaultClient
+	}
+
+	tlsCfg, err := tlsFiles.ClientTLSConfig()
+	handleBasicError(ctx, err)
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
 }
 
 func writeBody(resp *http.Response, ctx *cli.Context) {
@@ -56,26 +104,17 @@ func postMap(ctx *cli.Context, url string, jsonMap map[string]interface{}) {
 	content, err := json.Marshal(jsonMap)
 	handleBasicError(ctx, err)
 
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(content))
-	handleBasicError(ctx, err)
-
+	resp := doRequest(ctx, "POST", url, content)
 	respCheck(resp, ctx)
 }
 
 func deleteURL(ctx *cli.Context, url string) {
-	req, err := http.NewRequest("DELETE", url, nil)
-	handleBasicError(ctx, err)
-
-	resp, err := client.Do(req)
-	handleBasicError(ctx, err)
-
+	resp := doRequest(ctx, "DELETE", url, nil)
 	respCheck(resp, ctx)
 }
 
 func getList(ctx *cli.Context, url string) []map[string]interface{} {
-	resp, err := client.Get(url)
-	handleBasicError(ctx, err)
-
+	resp := doRequest(ctx, "GET", url, nil)
 	respCheck(resp, ctx)
 
 	content, err := ioutil.ReadAll(resp.Body)
@@ -87,3 +126,17 @@ func getList(ctx *cli.Context, url string) []map[string]interface{} {
 
 	return list
 }
+
+func getMap(ctx *cli.Context, url string) map[string]interface{} {
+	resp := doRequest(ctx, "GET", url, nil)
+	respCheck(resp, ctx)
+
+	content, err := ioutil.ReadAll(resp.Body)
+	handleBasicError(ctx, err)
+
+	obj := map[string]interface{}{}
+
+	handleBasicError(ctx, json.Unmarshal(content, &obj))
+
+	return obj
+}
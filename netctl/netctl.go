@@ -197,6 +197,20 @@ func createNetwork(ctx *cli.Context) {
 		"gateway":     gateway,
 	}
 
+	if ipv6Subnet := ctx.String("ipv6-subnet"); ipv6Subnet != "" {
+		out["ipv6Subnet"] = ipv6Subnet
+		out["ipv6Gateway"] = ctx.String("ipv6-gateway")
+	}
+	if exclusions := ctx.String("exclude"); exclusions != "" {
+		out["ipExclusions"] = exclusions
+	}
+	if reservations := ctx.StringSlice("reserve"); len(reservations) > 0 {
+		out["ipReservations"] = reservations
+	}
+	if holdTime := ctx.Int("ip-lease-hold-time"); holdTime > 0 {
+		out["ipLeaseHoldTime"] = holdTime
+	}
+
 	postMap(ctx, url, out)
 }
 
@@ -276,6 +290,13 @@ func createTenant(ctx *cli.Context) {
 		"vlans":      vlans,
 		"vxlans":     vxlans,
 	}
+	if macPrefix := ctx.String("mac-prefix"); macPrefix != "" {
+		args["macPrefix"] = macPrefix
+	}
+	if ipv6SubnetPool := ctx.String("ipv6-subnet-pool"); ipv6SubnetPool != "" {
+		args["ipv6SubnetPool"] = ipv6SubnetPool
+		args["ipv6SubnetLen"] = ctx.Int("ipv6-subnet-len")
+	}
 
 	postMap(ctx, url, args)
 }
@@ -288,6 +309,9 @@ func deleteTenant(ctx *cli.Context) {
 	logrus.Infof("Deleting tenant %s", tenant)
 
 	url := fmt.Sprintf("%s%s/", tenantURL(ctx), tenant)
+	if ctx.Bool("cascade") {
+		url += "?cascade=true"
+	}
 	deleteURL(ctx, url)
 }
 
@@ -317,6 +341,137 @@ func listTenants(ctx *cli.Context) {
 	}
 }
 
+func showTenantUsage(ctx *cli.Context) {
+	if len(ctx.Args()) > 1 {
+		errExit(ctx, exitHelp, "Invalid Arguments", true)
+	}
+
+	url := resourceUsageURL(ctx)
+	if tenant := ctx.Args().First(); tenant != "" {
+		url = fmt.Sprintf("%s/%s", url, tenant)
+	}
+
+	report := getMap(ctx, url)
+
+	if ctx.Bool("json") {
+		dumpJSON(ctx, report)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	defer writer.Flush()
+	writer.Write([]byte("Tenant\tResource\tTotal\tUsed\tFree\n"))
+	writer.Write([]byte("------\t--------\t-----\t----\t----\n"))
+
+	writeUsage := func(usage map[string]interface{}, tenant string) {
+		for _, resource := range []string{"VLANs", "VXLANs", "LocalVLANs", "Subnets"} {
+			counts, _ := usage[resource].(map[string]interface{})
+			writer.Write(
+				[]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\n",
+					tenant,
+					resource,
+					counts["Total"],
+					counts["Used"],
+					counts["Free"],
+				)))
+		}
+
+		// objects limited by the quota, without a total if unlimited
+		for _, resource := range []string{"Networks", "Endpoints", "EndpointGroups", "VLANQuota"} {
+			counts, _ := usage[resource].(map[string]interface{})
+			limit, _ := counts["Limit"].(float64)
+			used, _ := counts["Used"].(float64)
+			total, free := "-", "-"
+			if limit > 0 {
+				total, free = fmt.Sprintf("%v", limit), fmt.Sprintf("%v", limit-used)
+			}
+			writer.Write(
+				[]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\n",
+					tenant,
+					resource,
+					total,
+					used,
+					free,
+				)))
+		}
+	}
+
+	tenants, _ := report["Tenants"].([]interface{})
+	for _, tenant := range tenants {
+		usage, _ := tenant.(map[string]interface{})
+		writeUsage(usage, fmt.Sprintf("%v", usage["Tenant"]))
+	}
+
+	if ctx.Args().First() == "" {
+		global, _ := report["Global"].(map[string]interface{})
+		writeUsage(global, "(all)")
+		writer.Write([]byte(fmt.Sprintf("(none)\tVLANs\t\t\t%v\n", report["UnassignedVLANs"])))
+	}
+}
+
+func showNetworkUsage(ctx *cli.Context) {
+	if len(ctx.Args()) > 1 {
+		errExit(ctx, exitHelp, "Invalid Arguments", true)
+	}
+
+	tenant := ctx.String("tenant")
+	network := ctx.Args().First()
+
+	url := ipUsageURL(ctx)
+	if network != "" {
+		url = fmt.Sprintf("%s/%s.%s", url, network, tenant)
+	}
+
+	list := getList(ctx, url)
+
+	filtered := []map[string]interface{}{}
+
+	if ctx.Bool("all") || network != "" {
+		filtered = list
+	} else {
+		for _, usage := range list {
+			if strings.HasSuffix(fmt.Sprintf("%v", usage["NetworkID"]), "."+tenant) {
+				filtered = append(filtered, usage)
+			}
+		}
+	}
+
+	if ctx.Bool("json") {
+		dumpList(ctx, filtered)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	defer writer.Flush()
+	writer.Write([]byte("Network\tSubnet\tTotal\tUsed\tFree\tIPv6 Subnet\tIPv6 Used\n"))
+	writer.Write([]byte("-------\t------\t-----\t----\t----\t-----------\t---------\n"))
+
+	for _, usage := range filtered {
+		ipv6Allocated, _ := usage["IPv6Allocated"].([]interface{})
+		writer.Write(
+			[]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				usage["NetworkID"],
+				usage["Subnet"],
+				usage["Total"],
+				usage["Used"],
+				usage["Free"],
+				usage["IPv6Subnet"],
+				len(ipv6Allocated),
+			)))
+	}
+
+	// list the addresses when showing a single network
+	if network != "" && len(filtered) == 1 {
+		writer.Write([]byte("\nAllocated addresses:\n"))
+		for _, key := range []string{"Allocated", "IPv6Allocated"} {
+			addresses, _ := filtered[0][key].([]interface{})
+			for _, address := range addresses {
+				writer.Write([]byte(fmt.Sprintf("%v\n", address)))
+			}
+		}
+	}
+}
+
 func createEndpointGroup(ctx *cli.Context) {
 	argCheck(2, ctx)
 
@@ -394,7 +549,11 @@ func listEndpointGroups(ctx *cli.Context) {
 }
 
 func dumpList(ctx *cli.Context, list []map[string]interface{}) {
-	content, err := json.MarshalIndent(list, "", "  ")
+	dumpJSON(ctx, list)
+}
+
+func dumpJSON(ctx *cli.Context, obj interface{}) {
+	content, err := json.MarshalIndent(obj, "", "  ")
 	if err != nil {
 		errExit(ctx, exitIO, err.Error(), false)
 	}
@@ -0,0 +1,191 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit keeps a log of the configuration changes made through
+// netmaster, with who made them and their result.
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+)
+
+// Operation is the kind of change of a record
+type Operation string
+
+const (
+	// OpCreate is the creation of an object
+	OpCreate Operation = "create"
+	// OpUpdate is a change of an existing object
+	OpUpdate Operation = "update"
+	// OpDelete is the deletion of an object
+	OpDelete Operation = "delete"
+	// OpApply is a configuration applied as a whole, such as an intent
+	OpApply Operation = "apply"
+)
+
+const (
+	// ResultSuccess is the result of the changes that succeeded
+	ResultSuccess = "success"
+	// ResultFailure is the result of the changes that failed
+	ResultFailure = "failure"
+)
+
+// Caller is the client that made a change. Changes netmaster makes by
+// itself have the caller Netmaster.
+type Caller struct {
+	Name    string `json:"name"`
+	Role    string `json:"role,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Netmaster is the caller of the changes that no client requested
+var Netmaster = Caller{Name: "netmaster"}
+
+// Record is the record of a change of an object of a kind, with the state
+// of the object before and after it
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Caller    Caller          `json:"caller"`
+	Kind      string          `json:"kind"`
+	Key       string          `json:"key"`
+	Tenant    string          `json:"tenant,omitempty"`
+	Operation Operation       `json:"operation"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Result    string          `json:"result"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Filter selects the records in a time range, of a tenant. Zero values
+// select all records.
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Tenant string
+}
+
+// Match returns true if the filter selects the record
+func (f *Filter) Match(rec *Record) bool {
+	return (f.Since.IsZero() || !rec.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || rec.Time.Before(f.Until)) &&
+		(f.Tenant == "" || rec.Tenant == f.Tenant)
+}
+
+// Sink keeps the records
+type Sink interface {
+	Write(rec *Record) error
+	// Query returns the records the filter selects, oldest first
+	Query(filter *Filter) ([]*Record, error)
+}
+
+var (
+	sinkMutex sync.RWMutex
+	sink      Sink
+)
+
+// SetSink sets the sink of the records. Records are dropped without one.
+func SetSink(s Sink) {
+	sinkMutex.Lock()
+	defer sinkMutex.Unlock()
+
+	sink = s
+}
+
+// State returns the json encoding of the state of an object for a record
+func State(obj interface{}) json.RawMessage {
+	if obj == nil {
+		return nil
+	}
+
+	state, err := json.Marshal(obj)
+	if err != nil {
+		log.Errorf("Error encoding audit state of %+v. Err: %v", obj, err)
+		return nil
+	}
+
+	return state
+}
+
+// Log writes the record of a change to the sink, with the current time and
+// the result of the change, err being its error. A change is not undone if
+// its record can't be written, so errors are only logged.
+func Log(rec *Record, err error) {
+	rec.Time = time.Now()
+	rec.Result = ResultSuccess
+	if err != nil {
+		rec.Result = ResultFailure
+		rec.Error = err.Error()
+	}
+
+	sinkMutex.RLock()
+	defer sinkMutex.RUnlock()
+
+	if sink == nil {
+		return
+	}
+	if err := sink.Write(rec); err != nil {
+		log.Errorf("Error writing audit record %+v. Err: %v", rec, err)
+	}
+}
+
+// Query returns the records the filter selects, oldest first
+func Query(filter *Filter) ([]*Record, error) {
+	sinkMutex.RLock()
+	defer sinkMutex.RUnlock()
+
+	if sink == nil {
+		return []*Record{}, nil
+	}
+
+	return sink.Query(filter)
+}
+
+// sortRecords sorts records oldest first
+func sortRecords(recs []*Record) {
+	sort.Stable(byTime(recs))
+}
+
+type byTime []*Record
+
+func (t byTime) Len() int           { return len(t) }
+func (t byTime) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byTime) Less(i, j int) bool { return t[i].Time.Before(t[j].Time) }
+
+// ListHandler serves the records selected by the since, until and tenant
+// query parameters. The times are in RFC 3339 format.
+func ListHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	filter := &Filter{Tenant: r.URL.Query().Get("tenant")}
+
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+
+		var err error
+		if *t, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, core.InvalidErrorf("invalid %s time %q. Error: %s", param, value, err)
+		}
+	}
+
+	return Query(filter)
+}
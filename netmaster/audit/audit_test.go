@@ -0,0 +1,147 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/contiv/netplugin/state"
+)
+
+func testRecords(t *testing.T, sink Sink) {
+	SetSink(sink)
+	defer SetSink(nil)
+
+	start := time.Now()
+	Log(&Record{Caller: Caller{Name: "ops"}, Kind: "network", Key: "blue:net1", Tenant: "blue",
+		Operation: OpCreate, After: State(map[string]string{"networkName": "net1"})}, nil)
+	Log(&Record{Caller: Netmaster, Kind: "network", Key: "red:net1", Tenant: "red",
+		Operation: OpCreate}, errors.New("subnet in use"))
+	Log(&Record{Caller: Caller{Name: "ops"}, Kind: "network", Key: "blue:net1", Tenant: "blue",
+		Operation: OpDelete, Before: State(map[string]string{"networkName": "net1"})}, nil)
+
+	recs, err := Query(&Filter{})
+	if err != nil {
+		t.Fatalf("error querying records. Error: %s", err)
+	}
+	if len(recs) != 3 || recs[0].Operation != OpCreate || recs[2].Operation != OpDelete {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+	if recs[0].Result != ResultSuccess || string(recs[0].After) != `{"networkName":"net1"}` {
+		t.Fatalf("unexpected record %+v", recs[0])
+	}
+	if recs[1].Result != ResultFailure || recs[1].Error != "subnet in use" {
+		t.Fatalf("unexpected record %+v", recs[1])
+	}
+
+	if recs, err = Query(&Filter{Tenant: "blue"}); err != nil || len(recs) != 2 {
+		t.Fatalf("unexpected records %+v of tenant blue. Error: %v", recs, err)
+	}
+	if recs, err = Query(&Filter{Since: start.Add(time.Hour)}); err != nil || len(recs) != 0 {
+		t.Fatalf("unexpected records %+v in the future. Error: %v", recs, err)
+	}
+	if recs, err = Query(&Filter{Since: start, Until: time.Now().Add(time.Second)}); err != nil || len(recs) != 3 {
+		t.Fatalf("unexpected records %+v since %s. Error: %v", recs, start, err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("error creating temp dir. Error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	testRecords(t, NewFileSink(filepath.Join(dir, "audit.log"), 1<<20, 2))
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("error creating temp dir. Error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// each record gets a file of its own
+	path := filepath.Join(dir, "audit.log")
+	sink := NewFileSink(path, 1, 2)
+	for _, key := range []string{"net1", "net2", "net3", "net4"} {
+		if err := sink.Write(&Record{Time: time.Now(), Kind: "network", Key: key}); err != nil {
+			t.Fatalf("error writing record. Error: %s", err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("more backups than configured. Error: %v", err)
+	}
+
+	recs, err := sink.Query(&Filter{})
+	if err != nil {
+		t.Fatalf("error querying records. Error: %s", err)
+	}
+	if len(recs) != 3 || recs[0].Key != "net2" || recs[2].Key != "net4" {
+		t.Fatalf("unexpected records %+v after rotation", recs)
+	}
+}
+
+func TestStateSink(t *testing.T) {
+	stateDriver := &state.FakeStateDriver{}
+	stateDriver.Init(nil)
+	defer stateDriver.Deinit()
+
+	sink := NewStateSink(stateDriver, 10)
+	if recs, err := sink.Query(&Filter{}); err != nil || len(recs) != 0 {
+		t.Fatalf("unexpected records %+v in empty store. Error: %v", recs, err)
+	}
+
+	testRecords(t, sink)
+}
+
+func TestStateSinkRetention(t *testing.T) {
+	stateDriver := &state.FakeStateDriver{}
+	stateDriver.Init(nil)
+	defer stateDriver.Deinit()
+
+	// the oldest records are overwritten once the sink is full
+	sink := NewStateSink(stateDriver, 3)
+	for _, key := range []string{"net1", "net2", "net3", "net4", "net5"} {
+		if err := sink.Write(&Record{Time: time.Now(), Kind: "network", Key: key}); err != nil {
+			t.Fatalf("error writing record. Error: %s", err)
+		}
+	}
+
+	recs, err := sink.Query(&Filter{})
+	if err != nil {
+		t.Fatalf("error querying records. Error: %s", err)
+	}
+	if len(recs) != 3 || recs[0].Key != "net3" || recs[2].Key != "net5" {
+		t.Fatalf("unexpected records %+v after the sink filled up", recs)
+	}
+
+	// another sink on the same store takes the next slot
+	other := NewStateSink(stateDriver, 3)
+	if err := other.Write(&Record{Time: time.Now(), Kind: "network", Key: "net6"}); err != nil {
+		t.Fatalf("error writing record. Error: %s", err)
+	}
+	if recs, err = sink.Query(&Filter{}); err != nil || len(recs) != 3 || recs[0].Key != "net4" {
+		t.Fatalf("unexpected records %+v with two sinks. Error: %v", recs, err)
+	}
+}
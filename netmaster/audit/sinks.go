@@ -0,0 +1,238 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/contiv/netplugin/core"
+)
+
+// FileSink writes the records to a file, one json record per line. The file
+// is rotated when it grows past MaxSize bytes, keeping MaxBackups previous
+// files with the suffixes .1, .2... the higher the older.
+type FileSink struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+
+	mutex sync.Mutex
+}
+
+// NewFileSink returns a sink writing to the file at path
+func NewFileSink(path string, maxSize int64, maxBackups int) *FileSink {
+	return &FileSink{Path: path, MaxSize: maxSize, MaxBackups: maxBackups}
+}
+
+// Write appends the record to the file, rotating it first if it's full
+func (s *FileSink) Write(rec *Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if info, err := os.Stat(s.Path); err == nil && info.Size()+int64(len(line)) >= s.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// backup returns the path of the nth previous file
+func (s *FileSink) backup(n int) string {
+	return fmt.Sprintf("%s.%d", s.Path, n)
+}
+
+// rotate moves the file to the first backup, dropping the oldest backup
+func (s *FileSink) rotate() error {
+	if s.MaxBackups <= 0 {
+		return os.Remove(s.Path)
+	}
+
+	for n := s.MaxBackups - 1; n > 0; n-- {
+		if err := os.Rename(s.backup(n), s.backup(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(s.Path, s.backup(1))
+}
+
+// Query reads the records from the backups and the file
+func (s *FileSink) Query(filter *Filter) ([]*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	recs := []*Record{}
+	paths := []string{}
+	for n := s.MaxBackups; n > 0; n-- {
+		paths = append(paths, s.backup(n))
+	}
+	paths = append(paths, s.Path)
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fileRecs, err := readRecords(file, filter)
+		file.Close()
+		if err != nil {
+			return nil, core.Errorf("error reading audit records from %s. Error: %s", path, err)
+		}
+		recs = append(recs, fileRecs...)
+	}
+
+	return recs, nil
+}
+
+// readRecords returns the records of a file the filter selects
+func readRecords(file *os.File, filter *Filter) ([]*Record, error) {
+	recs := []*Record{}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return recs, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		rec := &Record{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return nil, err
+		}
+		if filter.Match(rec) {
+			recs = append(recs, rec)
+		}
+	}
+}
+
+const (
+	// stateSinkPath is the state store prefix of the records of a StateSink
+	stateSinkPath = "/contiv.io/audit/"
+	// stateSinkSeqPath is the state store key of the number of records
+	// written by the StateSinks
+	stateSinkSeqPath = "/contiv.io/audit-seq"
+)
+
+// StateSink writes the records to the state store. It keeps the last
+// MaxRecords records, in slots that are reused in turn, so the log doesn't
+// grow without bound. The number of records written is kept in the store,
+// so that the netmasters sharing it take turns.
+type StateSink struct {
+	StateDriver core.StateDriver
+	MaxRecords  uint64
+}
+
+// NewStateSink returns a sink writing to the state store, keeping at most
+// maxRecords records
+func NewStateSink(stateDriver core.StateDriver, maxRecords uint64) *StateSink {
+	return &StateSink{StateDriver: stateDriver, MaxRecords: maxRecords}
+}
+
+// Write writes the record to the state store, over the oldest record if
+// the sink is full
+func (s *StateSink) Write(rec *Record) error {
+	if s.MaxRecords == 0 {
+		return core.Errorf("audit state sink keeps no records")
+	}
+
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	seq, err := s.nextSeq()
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%020d", stateSinkPath, seq%s.MaxRecords)
+	return s.StateDriver.Write(key, value)
+}
+
+// nextSeq counts a record written, returning its sequence number
+func (s *StateSink) nextSeq() (uint64, error) {
+	var seq uint64
+
+	err := core.RetryOnVersionConflict(func() error {
+		value, version, err := s.StateDriver.ReadVersion(stateSinkSeqPath)
+		if core.ErrIfKeyExists(err) != nil {
+			return err
+		}
+
+		seq = 0
+		if err != nil {
+			version = 0
+		} else if seq, err = strconv.ParseUint(string(value), 10, 64); err != nil {
+			return core.Errorf("invalid audit record count %q", value)
+		}
+
+		return s.StateDriver.WriteIfVersion(stateSinkSeqPath,
+			[]byte(strconv.FormatUint(seq+1, 10)), version)
+	})
+
+	return seq, err
+}
+
+// Query reads the records kept in the state store
+func (s *StateSink) Query(filter *Filter) ([]*Record, error) {
+	values, err := s.StateDriver.ReadAll(stateSinkPath)
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+	if err != nil {
+		return []*Record{}, nil
+	}
+
+	recs := []*Record{}
+	for _, value := range values {
+		rec := &Record{}
+		if err := json.Unmarshal(value, rec); err != nil {
+			return nil, core.Errorf("error parsing audit record. Error: %s", err)
+		}
+		if filter.Match(rec) {
+			recs = append(recs, rec)
+		}
+	}
+
+	sortRecords(recs)
+	return recs, nil
+}
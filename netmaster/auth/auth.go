@@ -0,0 +1,266 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth authenticates the clients of the netmaster REST API and
+// authorizes their requests by role, per tenant.
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/contiv/netplugin/core"
+)
+
+// Role is the set of requests an identity is allowed to make
+type Role string
+
+const (
+	// RoleAdmin is allowed all requests
+	RoleAdmin Role = "admin"
+	// RoleTenantAdmin is allowed to read and change the objects of its
+	// tenant, but not the tenant itself
+	RoleTenantAdmin Role = "tenant-admin"
+	// RoleReadOnly is allowed to read the objects of its tenant, or all
+	// objects if it has no tenant
+	RoleReadOnly Role = "read-only"
+	// RoleNode is the role of netplugin, allowed the netplugin requests and
+	// to read all objects
+	RoleNode Role = "node"
+)
+
+// Access is the kind of access a request needs
+type Access int
+
+const (
+	// AccessRead is the access of the requests that only read objects
+	AccessRead Access = iota
+	// AccessWrite is the access of the requests that change objects
+	AccessWrite
+	// AccessNode is the access of the requests netplugin makes for the
+	// endpoints of its node
+	AccessNode
+)
+
+// Identity is an authenticated client. Tenant scopes the role to a tenant.
+type Identity struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Credential is an identity with the credentials it authenticates with, a
+// bearer token or the common name of its client certificate
+type Credential struct {
+	Identity
+	Token      string `json:"token,omitempty"`
+	CommonName string `json:"commonName,omitempty"`
+}
+
+// Authenticator returns the identity of the client of a request. It returns
+// nil with no error if the request has none of its kind of credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// validate fails if the credential has no name or credentials, or if its
+// role is unknown or its tenant doesn't match its role
+func (c *Credential) validate() error {
+	if c.Name == "" {
+		return core.InvalidErrorf("credential without a name")
+	}
+	if c.Token == "" && c.CommonName == "" {
+		return core.InvalidErrorf("credential %s has no token or common name", c.Name)
+	}
+
+	switch c.Role {
+	case RoleAdmin, RoleNode:
+		if c.Tenant != "" {
+			return core.InvalidErrorf("credential %s with role %s can't have a tenant", c.Name, c.Role)
+		}
+	case RoleTenantAdmin:
+		if c.Tenant == "" {
+			return core.InvalidErrorf("credential %s with role %s needs a tenant", c.Name, c.Role)
+		}
+	case RoleReadOnly:
+	default:
+		return core.InvalidErrorf("credential %s has unknown role %q", c.Name, c.Role)
+	}
+
+	return nil
+}
+
+// ReadCredentials reads the json list of credentials in a file
+func ReadCredentials(file string) ([]Credential, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := []Credential{}
+	if err := json.Unmarshal(content, &creds); err != nil {
+		return nil, core.InvalidErrorf("error parsing credentials file %s. Error: %s", file, err)
+	}
+
+	return creds, nil
+}
+
+// NewAuthenticator returns an authenticator of the bearer tokens and client
+// certificates of the credentials. Requests without either are rejected.
+func NewAuthenticator(creds []Credential) (Authenticator, error) {
+	tokens := &TokenAuthenticator{}
+	certs := &CertAuthenticator{identities: map[string]*Identity{}}
+
+	for i := range creds {
+		cred := &creds[i]
+		if err := cred.validate(); err != nil {
+			return nil, err
+		}
+
+		if cred.Token != "" {
+			if id, _ := tokens.identity(cred.Token); id != nil {
+				return nil, core.InvalidErrorf("credentials %s and %s have the same token", id.Name, cred.Name)
+			}
+			tokens.creds = append(tokens.creds, cred)
+		}
+		if cred.CommonName != "" {
+			if id := certs.identities[cred.CommonName]; id != nil {
+				return nil, core.InvalidErrorf("credentials %s and %s have the same common name", id.Name, cred.Name)
+			}
+			certs.identities[cred.CommonName] = &cred.Identity
+		}
+	}
+
+	return authenticators{tokens, certs}, nil
+}
+
+// authenticators authenticates requests with the first authenticator that
+// finds credentials in them
+type authenticators []Authenticator
+
+// Authenticate returns the identity of the client of a request
+func (a authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	for _, authenticator := range a {
+		id, err := authenticator.Authenticate(r)
+		if err != nil || id != nil {
+			return id, err
+		}
+	}
+
+	return nil, core.UnauthenticatedErrorf("request has no credentials")
+}
+
+// TokenAuthenticator authenticates the requests with a bearer token in their
+// Authorization header
+type TokenAuthenticator struct {
+	creds []*Credential
+}
+
+// identity returns the identity with the token. Tokens are compared in
+// constant time, so that their content can't be guessed from the time a
+// request takes.
+func (a *TokenAuthenticator) identity(token string) (*Identity, error) {
+	var found *Identity
+	for _, cred := range a.creds {
+		if subtle.ConstantTimeCompare([]byte(cred.Token), []byte(token)) == 1 {
+			found = &cred.Identity
+		}
+	}
+	if found == nil {
+		return nil, core.UnauthenticatedErrorf("invalid token")
+	}
+
+	return found, nil
+}
+
+// Authenticate returns the identity with the token of the request
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, nil
+	}
+
+	return a.identity(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+}
+
+// CertAuthenticator authenticates the requests made with a verified client
+// certificate, by the common name of the certificate
+type CertAuthenticator struct {
+	identities map[string]*Identity
+}
+
+// Authenticate returns the identity with the common name of the client
+// certificate of the request
+func (a *CertAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	id := a.identities[name]
+	if id == nil {
+		return nil, core.UnauthenticatedErrorf("client certificate %q has no credential", name)
+	}
+
+	return id, nil
+}
+
+// Authorize fails if the identity isn't allowed the access to the tenants.
+// Requests with no tenants access objects of no single tenant, such as the
+// global config or the tenants themselves, and need an unscoped role.
+func (id *Identity) Authorize(access Access, tenants []string) error {
+	allowed := false
+	switch id.Role {
+	case RoleAdmin:
+		allowed = true
+	case RoleNode:
+		allowed = access == AccessRead || access == AccessNode
+	case RoleTenantAdmin:
+		allowed = access != AccessNode && id.inScope(tenants)
+	case RoleReadOnly:
+		allowed = access == AccessRead && id.inScope(tenants)
+	}
+
+	if !allowed {
+		if id.Tenant != "" {
+			return core.ForbiddenErrorf("%s with role %s of tenant %s is not allowed the request",
+				id.Name, id.Role, id.Tenant)
+		}
+		return core.ForbiddenErrorf("%s with role %s is not allowed the request", id.Name, id.Role)
+	}
+
+	return nil
+}
+
+// inScope returns true if all the tenants are in the scope of the identity
+func (id *Identity) inScope(tenants []string) bool {
+	if id.Tenant == "" {
+		return true
+	}
+	if len(tenants) == 0 {
+		return false
+	}
+
+	for _, tenant := range tenants {
+		if tenant != id.Tenant {
+			return false
+		}
+	}
+
+	return true
+}
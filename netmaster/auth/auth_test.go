@@ -0,0 +1,137 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"testing"
+
+	"github.com/contiv/netplugin/core"
+)
+
+var testCreds = []Credential{
+	{Identity: Identity{Name: "ops", Role: RoleAdmin}, Token: "ops-token"},
+	{Identity: Identity{Name: "node1", Role: RoleNode}, Token: "node-token"},
+	{Identity: Identity{Name: "blue-admin", Role: RoleTenantAdmin, Tenant: "blue"},
+		CommonName: "blue-admin.example.com"},
+	{Identity: Identity{Name: "auditor", Role: RoleReadOnly}, Token: "auditor-token"},
+}
+
+func newTestRequest(token, commonName string) *http.Request {
+	r, _ := http.NewRequest("GET", "http://netmaster/api/v1/networks/", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if commonName != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator, err := NewAuthenticator(testCreds)
+	if err != nil {
+		t.Fatalf("error creating authenticator. Error: %s", err)
+	}
+
+	for _, test := range []struct {
+		token      string
+		commonName string
+		name       string
+	}{
+		{"ops-token", "", "ops"},
+		{"node-token", "", "node1"},
+		{"", "blue-admin.example.com", "blue-admin"},
+		{"invalid-token", "", ""},
+		{"", "red-admin.example.com", ""},
+		{"", "", ""},
+	} {
+		id, err := authenticator.Authenticate(newTestRequest(test.token, test.commonName))
+		if test.name == "" {
+			if core.ErrorKindOf(err) != core.KindUnauthenticated {
+				t.Fatalf("token %q common name %q authenticated as %+v, err %v",
+					test.token, test.commonName, id, err)
+			}
+			continue
+		}
+		if err != nil || id.Name != test.name {
+			t.Fatalf("token %q common name %q authenticated as %+v, expected %s. Error: %v",
+				test.token, test.commonName, id, test.name, err)
+		}
+	}
+}
+
+func TestInvalidCredentials(t *testing.T) {
+	for _, creds := range [][]Credential{
+		{{Identity: Identity{Role: RoleAdmin}, Token: "token"}},
+		{{Identity: Identity{Name: "ops", Role: RoleAdmin}}},
+		{{Identity: Identity{Name: "ops", Role: "superuser"}, Token: "token"}},
+		{{Identity: Identity{Name: "ops", Role: RoleAdmin, Tenant: "blue"}, Token: "token"}},
+		{{Identity: Identity{Name: "blue", Role: RoleTenantAdmin}, Token: "token"}},
+		{{Identity: Identity{Name: "ops", Role: RoleAdmin}, Token: "token"},
+			{Identity: Identity{Name: "node1", Role: RoleNode}, Token: "token"}},
+	} {
+		if _, err := NewAuthenticator(creds); err == nil {
+			t.Fatalf("authenticator created with invalid credentials %+v", creds)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	admin := &Identity{Name: "ops", Role: RoleAdmin}
+	node := &Identity{Name: "node1", Role: RoleNode}
+	blueAdmin := &Identity{Name: "blue-admin", Role: RoleTenantAdmin, Tenant: "blue"}
+	blueReader := &Identity{Name: "blue-reader", Role: RoleReadOnly, Tenant: "blue"}
+	auditor := &Identity{Name: "auditor", Role: RoleReadOnly}
+
+	for _, test := range []struct {
+		id      *Identity
+		access  Access
+		tenants []string
+		allowed bool
+	}{
+		{admin, AccessWrite, nil, true},
+		{admin, AccessNode, nil, true},
+		{node, AccessNode, nil, true},
+		{node, AccessRead, nil, true},
+		{node, AccessWrite, []string{"blue"}, false},
+		{blueAdmin, AccessWrite, []string{"blue"}, true},
+		{blueAdmin, AccessRead, []string{"blue"}, true},
+		{blueAdmin, AccessWrite, []string{"blue", "red"}, false},
+		{blueAdmin, AccessWrite, nil, false},
+		{blueAdmin, AccessRead, nil, false},
+		{blueAdmin, AccessNode, []string{"blue"}, false},
+		{blueReader, AccessRead, []string{"blue"}, true},
+		{blueReader, AccessWrite, []string{"blue"}, false},
+		{blueReader, AccessRead, []string{"red"}, false},
+		{auditor, AccessRead, nil, true},
+		{auditor, AccessRead, []string{"red"}, true},
+		{auditor, AccessWrite, []string{"red"}, false},
+	} {
+		err := test.id.Authorize(test.access, test.tenants)
+		if test.allowed && err != nil {
+			t.Fatalf("%+v not allowed access %d to %v. Error: %s", test.id, test.access, test.tenants, err)
+		}
+		if !test.allowed && core.ErrorKindOf(err) != core.KindForbidden {
+			t.Fatalf("%+v allowed access %d to %v, err %v", test.id, test.access, test.tenants, err)
+		}
+	}
+}
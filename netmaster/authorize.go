@@ -0,0 +1,258 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/audit"
+	"github.com/contiv/netplugin/netmaster/auth"
+	"github.com/contiv/netplugin/netmaster/intent"
+	"github.com/contiv/netplugin/netmaster/master"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+)
+
+// initAuth reads the credentials of the REST API clients. Without them,
+// the requests are not authenticated.
+func (d *daemon) initAuth() error {
+	if d.opts.credentials == "" {
+		log.Warnf("No credentials file, the REST API is not authenticated")
+		return nil
+	}
+
+	creds, err := auth.ReadCredentials(d.opts.credentials)
+	if err != nil {
+		return err
+	}
+
+	d.authenticator, err = auth.NewAuthenticator(creds)
+	return err
+}
+
+// authenticate returns the identity of the client of a request, writing the
+// error response if it's not authenticated. The identity is kept in the
+// request context, as its caller.
+func (d *daemon) authenticate(w http.ResponseWriter, r *http.Request) (*auth.Identity, bool) {
+	id, err := d.authenticator.Authenticate(r)
+	if err != nil {
+		log.Warnf("Rejecting %s %s from %s. Error: %s", r.Method, r.URL, r.RemoteAddr, err)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		core.WriteHTTPError(w, err)
+		return nil, false
+	}
+
+	context.Set(r, callerKey, audit.Caller{Name: id.Name, Role: string(id.Role), Address: r.RemoteAddr})
+	return id, true
+}
+
+// callerKey is the request context key of the caller of a request
+const callerKey = "netmaster-caller"
+
+// requestCaller returns the caller of a request. Callers aren't named when
+// the requests are not authenticated.
+func requestCaller(r *http.Request) audit.Caller {
+	if caller, ok := context.Get(r, callerKey).(audit.Caller); ok {
+		return caller
+	}
+
+	return audit.Caller{Name: "anonymous", Address: r.RemoteAddr}
+}
+
+// authorized serves the requests of the clients allowed the access to the
+// tenants the request accesses, as returned by tenants.
+func (d *daemon) authorized(access auth.Access, tenants func(r *http.Request) ([]string, error),
+	handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.authenticator == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		id, ok := d.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		var reqTenants []string
+		if tenants != nil {
+			var err error
+			if reqTenants, err = tenants(r); err != nil {
+				core.WriteHTTPError(w, err)
+				return
+			}
+		}
+
+		if err := id.Authorize(access, reqTenants); err != nil {
+			log.Warnf("Rejecting %s %s from %s. Error: %s", r.Method, r.URL, id.Name, err)
+			core.WriteHTTPError(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// authorizedModel serves the requests for the contivModel objects of the
+// clients allowed them. The tenant of an object is the first element of its
+// key. The global config and the tenants themselves belong to no tenant,
+// so that tenant admins can't change their own quotas. Tenant scoped
+// clients get the objects of their tenant only, when listing objects.
+func (d *daemon) authorizedModel(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.authenticator == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		id, ok := d.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		// paths are /api/<objects>/<key>/, optionally versioned
+		path := strings.TrimPrefix(r.URL.Path, master.APIVersionPrefix+"/")
+		path = strings.TrimPrefix(path, "/api/")
+		elems := strings.Split(strings.Trim(path, "/"), "/")
+		objects, key := elems[0], ""
+		if len(elems) > 1 {
+			key = elems[1]
+		}
+
+		access := auth.AccessWrite
+		if r.Method == "GET" {
+			access = auth.AccessRead
+		}
+
+		var tenants []string
+		list := false
+		switch {
+		case objects == "globals" || (objects == "tenants" && access == auth.AccessWrite):
+		case key != "":
+			tenants = []string{strings.Split(key, ":")[0]}
+		case id.Tenant != "":
+			tenants = []string{id.Tenant}
+			list = access == auth.AccessRead
+		}
+
+		if err := id.Authorize(access, tenants); err != nil {
+			log.Warnf("Rejecting %s %s from %s. Error: %s", r.Method, r.URL, id.Name, err)
+			core.WriteHTTPError(w, err)
+			return
+		}
+
+		if !list {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		resp := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		handler.ServeHTTP(resp, r)
+		if err := writeTenantObjects(w, resp, id.Tenant); err != nil {
+			log.Errorf("Error writing the objects of tenant %s. Err: %v", id.Tenant, err)
+		}
+	}
+}
+
+// bufferedResponse keeps a response to write it later
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+// writeTenantObjects writes a response with a list of objects, keeping the
+// objects of the tenant only
+func writeTenantObjects(w http.ResponseWriter, resp *bufferedResponse, tenant string) error {
+	objs := []map[string]interface{}{}
+	if resp.status != http.StatusOK || json.Unmarshal(resp.body.Bytes(), &objs) != nil {
+		w.WriteHeader(resp.status)
+		_, err := w.Write(resp.body.Bytes())
+		return err
+	}
+
+	tenantObjs := []map[string]interface{}{}
+	for _, obj := range objs {
+		if obj["tenantName"] == tenant {
+			tenantObjs = append(tenantObjs, obj)
+		}
+	}
+
+	return writeJSON(w, http.StatusOK, tenantObjs)
+}
+
+// varTenant returns the tenants of the requests of a route whose variable
+// is the tenant name
+func varTenant(name string) func(r *http.Request) ([]string, error) {
+	return func(r *http.Request) ([]string, error) {
+		return []string{mux.Vars(r)[name]}, nil
+	}
+}
+
+// queryTenant returns the tenant of the tenant query parameter of a request,
+// if it has one
+func queryTenant(r *http.Request) ([]string, error) {
+	if tenant := r.URL.Query().Get("tenant"); tenant != "" {
+		return []string{tenant}, nil
+	}
+
+	return nil, nil
+}
+
+// intentTenants returns the tenants of the config of an intent request.
+// Configs with hosts, infra networks or host bindings have no tenants, as
+// they change the config of no single tenant.
+func intentTenants(r *http.Request) ([]string, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	cfg := &intent.Config{}
+	if err := json.Unmarshal(body, cfg); err != nil {
+		return nil, core.InvalidErrorf("parsing json failed. Error: %s", err)
+	}
+
+	if len(cfg.Hosts) > 0 || len(cfg.InfraNetworks) > 0 || len(cfg.HostBindings) > 0 {
+		return nil, nil
+	}
+
+	tenants := []string{}
+	for _, tenant := range cfg.Tenants {
+		tenants = append(tenants, tenant.Name)
+	}
+
+	return tenants, nil
+}
@@ -17,29 +17,81 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/events"
 	"github.com/contiv/netplugin/netmaster/intent"
 	"github.com/contiv/netplugin/netmaster/master"
 )
 
 // Client provides the methods for issuing post and get requests to netmaster
 type Client struct {
-	url   string
-	httpC *http.Client
+	url    string
+	token  string
+	scheme string
+	httpC  *http.Client
 }
 
 // New instantiates a new netmaster client
 func New(url string) *Client {
-	return &Client{url: url, httpC: http.DefaultClient}
+	return &Client{url: url, scheme: "http", httpC: http.DefaultClient}
+}
+
+// SetTLSConfig makes the requests over https, with the tls config
+func (c *Client) SetTLSConfig(tlsCfg *tls.Config) {
+	c.scheme = "https"
+	c.httpC = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+}
+
+// SetToken sets the token the requests are authenticated with
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// do makes a request, authenticated with the token if there's one
+func (c *Client) do(method, rsrc string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.formURL(rsrc), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.httpC.Do(req)
 }
 
 func (c *Client) formURL(rsrc string) string {
-	return fmt.Sprintf("http://%s/%s", c.url, rsrc)
+	scheme := c.scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s%s/%s", scheme, c.url, master.APIVersionPrefix, strings.TrimPrefix(rsrc, "/"))
+}
+
+// responseError returns the error of a failed request from its response, of
+// the kind of the error netmaster failed it with
+func responseError(resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return core.Errorf("Response status: %q. Error reading response body: %s", resp.Status, err)
+	}
+
+	return core.DecodeHTTPError(resp.StatusCode, body)
 }
 
 func (c *Client) doPost(rsrc string, cfg *intent.Config) error {
@@ -53,12 +105,13 @@ func (c *Client) doPost(rsrc string, cfg *intent.Config) error {
 		return core.Errorf("json marshalling failed. Error: %s", err)
 	}
 
-	if resp, err = c.httpC.Post(c.formURL(rsrc), "application/json", bytes.NewReader(body)); err != nil {
+	if resp, err = c.do("POST", rsrc, body); err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return core.Errorf("Response status: %q. Response body: %+v", resp.Status, resp.Body)
+		return responseError(resp)
 	}
 
 	return nil
@@ -72,12 +125,13 @@ func (c *Client) doGet(rsrc string) ([]byte, error) {
 		resp *http.Response
 	)
 
-	if resp, err = c.httpC.Get(c.formURL(rsrc)); err != nil {
+	if resp, err = c.do("GET", rsrc, nil); err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, core.Errorf("Response status: %q. Response Body: %+v", resp.Status, resp.Body)
+		return nil, responseError(resp)
 	}
 
 	if body, err = ioutil.ReadAll(resp.Body); err != nil {
@@ -126,3 +180,83 @@ func (c *Client) GetNetwork(id string) ([]byte, error) {
 func (c *Client) GetAllNetworks() ([]byte, error) {
 	return c.doGet(master.GetNetworksRESTEndpoint)
 }
+
+// eventsRetryInterval is the time a failed event stream is resumed after
+const eventsRetryInterval = time.Second
+
+// WatchEvents sends the events the filter selects to the evs channel, until
+// stop is closed. The events start after revision, or at the current
+// revision if it's zero. Streams that fail are resumed from the last event,
+// except when the events after it are no longer known, in which case an
+// error satisfying core.IsRevisionCompacted is returned and the states
+// shall be read again.
+func (c *Client) WatchEvents(filter *events.Filter, revision uint64, evs chan *events.Event,
+	stop chan struct{}) error {
+	for {
+		var err error
+		revision, err = c.streamEvents(filter, revision, evs, stop)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if !core.IsTransient(err) && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		time.Sleep(eventsRetryInterval)
+	}
+}
+
+// streamEvents sends the events of a stream starting after revision, until
+// the stream fails. It returns the revision of the last event.
+func (c *Client) streamEvents(filter *events.Filter, revision uint64, evs chan *events.Event,
+	stop chan struct{}) (uint64, error) {
+	query := url.Values{}
+	if len(filter.Kinds) > 0 {
+		query.Set("kind", strings.Join(filter.Kinds, ","))
+	}
+	if filter.Tenant != "" {
+		query.Set("tenant", filter.Tenant)
+	}
+	if revision != 0 {
+		query.Set("revision", strconv.FormatUint(revision, 10))
+	}
+
+	req, err := http.NewRequest("GET", c.formURL(master.GetEventsRESTEndpoint)+"?"+query.Encode(), nil)
+	if err != nil {
+		return revision, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Cancel = stop
+
+	resp, err := c.httpC.Do(req)
+	if err != nil {
+		return revision, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return revision, responseError(resp)
+	}
+
+	decoder := events.NewDecoder(resp.Body)
+	for {
+		ev, err := decoder.Decode()
+		if decoder.Revision() != 0 {
+			revision = decoder.Revision()
+		}
+		if err != nil {
+			return revision, err
+		}
+
+		select {
+		case evs <- ev:
+		case <-stop:
+			return revision, nil
+		}
+	}
+}
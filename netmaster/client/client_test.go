@@ -1,12 +1,16 @@
 package client
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/events"
 	"github.com/contiv/netplugin/netmaster/intent"
 )
 
@@ -174,3 +178,137 @@ func TestGetSuccess(t *testing.T) {
 		t.Fatalf("unexpected response. Exptd: %s, Rcvd: %s", getSuccessRespStr, resp)
 	}
 }
+
+func TestFailureErrorKind(t *testing.T) {
+	var (
+		nmc       *Client
+		srvr      *httptest.Server
+		transport *http.Transport
+		httpC     *http.Client
+		srvrErr   error
+	)
+
+	srvr = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			core.WriteHTTPError(w, srvrErr)
+		}))
+	defer srvr.Close()
+
+	transport = &http.Transport{
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			return url.Parse(srvr.URL)
+		},
+	}
+	httpC = &http.Client{Transport: transport}
+
+	nmc = &Client{url: srvr.URL, httpC: httpC}
+
+	srvrErr = core.NotFoundErrorf("network not found")
+	_, err := nmc.doGet("test-endpoint")
+	if core.ErrorKindOf(err) != core.KindNotFound || core.IsTransient(err) {
+		t.Fatalf("unexpected error %q of kind %q", err, core.ErrorKindOf(err))
+	}
+
+	srvrErr = core.UnavailableErrorf("etcd is not reachable")
+	err = nmc.doPost("test-endpoint", &intent.Config{})
+	if !core.IsTransient(err) || !strings.Contains(err.Error(), "etcd is not reachable") {
+		t.Fatalf("unexpected error %q of kind %q", err, core.ErrorKindOf(err))
+	}
+}
+
+func TestToken(t *testing.T) {
+	var (
+		nmc       *Client
+		srvr      *httptest.Server
+		transport *http.Transport
+		httpC     *http.Client
+	)
+
+	srvr = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				core.WriteHTTPError(w, core.UnauthenticatedErrorf("invalid token"))
+			}
+		}))
+	defer srvr.Close()
+
+	transport = &http.Transport{
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			return url.Parse(srvr.URL)
+		},
+	}
+	httpC = &http.Client{Transport: transport}
+
+	nmc = &Client{url: srvr.URL, httpC: httpC}
+	err := nmc.doPost("test-endpoint", &intent.Config{})
+	if core.ErrorKindOf(err) != core.KindUnauthenticated {
+		t.Fatalf("unexpected error %v of kind %q", err, core.ErrorKindOf(err))
+	}
+
+	nmc.SetToken("test-token")
+	if err = nmc.doPost("test-endpoint", &intent.Config{}); err != nil {
+		t.Fatalf("post with the token failed. Error: %s", err)
+	}
+	if _, err = nmc.doGet("test-endpoint"); err != nil {
+		t.Fatalf("get with the token failed. Error: %s", err)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	var (
+		nmc       *Client
+		srvr      *httptest.Server
+		transport *http.Transport
+		httpC     *http.Client
+		requests  int
+	)
+
+	// the first stream fails after an event and is resumed from it, the
+	// second fails as the events after the next one are no longer known
+	srvr = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch requests {
+			case 1:
+				if r.URL.Query().Get("revision") != "" || r.URL.Query().Get("tenant") != "blue" {
+					t.Errorf("unexpected first stream request %s", r.URL)
+				}
+				fmt.Fprintf(w, "event: ready\nid: 5\ndata: {\"revision\":5}\n\n")
+				fmt.Fprintf(w, "event: create\nid: 6\ndata: {\"type\":\"create\",\"revision\":6}\n\n")
+			default:
+				if r.URL.Query().Get("revision") != "6" {
+					t.Errorf("stream resumed with request %s, expected revision 6", r.URL)
+				}
+				fmt.Fprintf(w, "event: ready\nid: 6\ndata: {\"revision\":6}\n\n")
+				fmt.Fprintf(w, ": keepalive\n\n")
+				fmt.Fprintf(w, "event: delete\nid: 7\ndata: {\"type\":\"delete\",\"revision\":7}\n\n")
+				body, _ := json.Marshal(&core.HTTPError{Kind: core.KindConflict,
+					Error: core.ErrRevisionCompacted(7).Error()})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", body)
+			}
+		}))
+	defer srvr.Close()
+
+	transport = &http.Transport{
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			return url.Parse(srvr.URL)
+		},
+	}
+	httpC = &http.Client{Transport: transport}
+
+	nmc = &Client{url: srvr.URL, httpC: httpC}
+	evs := make(chan *events.Event, 10)
+	err := nmc.WatchEvents(&events.Filter{Tenant: "blue"}, 0, evs, make(chan struct{}))
+	if !core.IsRevisionCompacted(err) || core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("unexpected error %v of kind %q", err, core.ErrorKindOf(err))
+	}
+
+	close(evs)
+	revisions := []uint64{}
+	for ev := range evs {
+		revisions = append(revisions, ev.Revision)
+	}
+	if len(revisions) != 2 || revisions[0] != 6 || revisions[1] != 7 {
+		t.Fatalf("got events of revisions %v, expected 6 and 7", revisions)
+	}
+}
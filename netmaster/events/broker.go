@@ -0,0 +1,173 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+// watchRetryInterval is the time a failed watch is resumed after
+const watchRetryInterval = time.Second
+
+// Broker watches the config states and serves the events of their changes
+// to the subscribers. It keeps the last events, so that subscribers can
+// resume from the revision of the last event they got.
+type Broker struct {
+	stateDriver core.StateDriver
+	historySize int
+
+	mutex   sync.Mutex
+	history []*Event
+	// the history has all the events after base, up to revision
+	base     uint64
+	revision uint64
+	// closed on the next change
+	changed chan struct{}
+}
+
+// NewBroker returns a broker of the events of the states in the store,
+// keeping the last historySize events
+func NewBroker(stateDriver core.StateDriver, historySize int) *Broker {
+	return &Broker{
+		stateDriver: stateDriver,
+		historySize: historySize,
+		changed:     make(chan struct{}),
+	}
+}
+
+// Run watches the config states, resuming the watch when it fails. It
+// doesn't return.
+func (b *Broker) Run() {
+	synced := false
+	for {
+		if !synced {
+			if err := b.resync(); err != nil {
+				log.Errorf("Error reading the states to watch. Err: %v", err)
+				time.Sleep(watchRetryInterval)
+				continue
+			}
+			synced = true
+		}
+
+		rsps := make(chan core.WatchState)
+		errs := make(chan error, 1)
+		go func(revision uint64) {
+			errs <- b.stateDriver.WatchAllStateFrom(mastercfg.StateConfigPath, &rawState{},
+				unmarshalRaw, revision, rsps)
+		}(b.Revision())
+
+		err := b.publishAll(rsps, errs)
+		if core.IsRevisionCompacted(err) {
+			log.Infof("Missed state changes, restarting the events from the current state")
+			synced = false
+			continue
+		}
+
+		log.Errorf("The events watch failed, resuming from revision %d. Error: %s", b.Revision(), err)
+		time.Sleep(watchRetryInterval)
+	}
+}
+
+// resync restarts the history at the current revision of the store. The
+// subscribers at earlier revisions have to read the states again.
+func (b *Broker) resync() error {
+	_, revision, err := b.stateDriver.ListAllState(mastercfg.StateConfigPath, &rawState{}, unmarshalRaw)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.history = nil
+	b.base = revision
+	b.advance(revision)
+	return nil
+}
+
+// publishAll publishes the changes of a watch until it fails
+func (b *Broker) publishAll(rsps chan core.WatchState, errs chan error) error {
+	for {
+		select {
+		case rsp := <-rsps:
+			b.publish(rsp)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// publish adds the event of a change to the history
+func (b *Broker) publish(rsp core.WatchState) {
+	ev, ok, err := newEvent(rsp)
+	if err != nil {
+		log.Errorf("Error publishing the change at revision %d. Err: %v", rsp.Revision, err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if ok {
+		b.history = append(b.history, ev)
+		if len(b.history) > b.historySize {
+			b.base = b.history[0].Revision
+			b.history = b.history[1:]
+		}
+	}
+	b.advance(rsp.Revision)
+}
+
+// advance moves to the revision and wakes up the subscribers. Must be called
+// with the mutex held.
+func (b *Broker) advance(revision uint64) {
+	b.revision = revision
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Revision returns the revision of the last change seen
+func (b *Broker) Revision() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.revision
+}
+
+// Since returns the events after revision, up to the returned revision, and
+// a channel closed on the next change. It fails with an ErrRevisionCompacted
+// error if the events after revision are no longer known.
+func (b *Broker) Since(revision uint64) ([]*Event, uint64, <-chan struct{}, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if revision < b.base {
+		return nil, 0, nil, core.ErrRevisionCompacted(revision)
+	}
+
+	evs := []*Event{}
+	for _, ev := range b.history {
+		if ev.Revision > revision {
+			evs = append(evs, ev)
+		}
+	}
+
+	return evs, b.revision, b.changed, nil
+}
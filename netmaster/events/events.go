@@ -0,0 +1,158 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events streams the changes of the networks, endpoints, endpoint
+// groups and policies to the clients of netmaster, as server-sent events.
+package events
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+// Type is the kind of change of an event
+type Type string
+
+const (
+	// TypeCreate is the creation of a state
+	TypeCreate Type = "create"
+	// TypeUpdate is a change of an existing state
+	TypeUpdate Type = "update"
+	// TypeDelete is the deletion of a state
+	TypeDelete Type = "delete"
+)
+
+// Event is the change of a state of a kind, one of the mastercfg config
+// kinds, made at a revision of the state store. State is the json encoding
+// of the state after the change, or of the deleted state, and Prev the state
+// before an update.
+type Event struct {
+	Type     Type            `json:"type"`
+	Kind     string          `json:"kind"`
+	ID       string          `json:"id"`
+	Tenant   string          `json:"tenant,omitempty"`
+	Revision uint64          `json:"revision"`
+	State    json.RawMessage `json:"state"`
+	Prev     json.RawMessage `json:"prev,omitempty"`
+}
+
+// Filter selects the events of some kinds, of a tenant. Zero values select
+// all events.
+type Filter struct {
+	Kinds  []string
+	Tenant string
+}
+
+// Match returns true if the filter selects the event
+func (f *Filter) Match(ev *Event) bool {
+	if f.Tenant != "" && ev.Tenant != f.Tenant {
+		return false
+	}
+	if len(f.Kinds) == 0 {
+		return true
+	}
+
+	for _, kind := range f.Kinds {
+		if ev.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rawState keeps the encoding of a state, to decode it once its kind is
+// known from its key
+type rawState struct {
+	core.CommonState
+	value []byte
+}
+
+func (s *rawState) Write() error {
+	return core.Errorf("not supported")
+}
+
+func (s *rawState) Read(id string) error {
+	return core.Errorf("not supported")
+}
+
+func (s *rawState) ReadAll() ([]core.State, error) {
+	return nil, core.Errorf("not supported")
+}
+
+func (s *rawState) Clear() error {
+	return core.Errorf("not supported")
+}
+
+// unmarshalRaw is the unmarshal function of the rawStates read from the store
+func unmarshalRaw(value []byte, v interface{}) error {
+	*v.(**rawState) = &rawState{value: value}
+	return nil
+}
+
+// stateTenant returns the tenant of a config state
+func stateTenant(state core.State) string {
+	switch s := state.(type) {
+	case *mastercfg.CfgNetworkState:
+		return s.Tenant
+	case *mastercfg.EndpointGroupState:
+		return s.Tenant
+	case *mastercfg.CfgEndpointState:
+		// networks are named <network>.<tenant>
+		return s.NetID[strings.LastIndex(s.NetID, ".")+1:]
+	case *mastercfg.EpgPolicy:
+		// policies are named <tenant>:<group>:<tenant>:<policy>
+		return strings.Split(s.ID, ":")[0]
+	}
+
+	return ""
+}
+
+// newEvent returns the event of a change of a state. ok is false for the
+// changes of the states of other kinds.
+func newEvent(rsp core.WatchState) (ev *Event, ok bool, err error) {
+	kind, state, ok := mastercfg.ConfigStateOfKey(rsp.Key)
+	if !ok || (rsp.Curr == nil && rsp.Prev == nil) {
+		return nil, false, nil
+	}
+
+	ev = &Event{Type: TypeUpdate, Kind: kind, Revision: rsp.Revision}
+	switch {
+	case rsp.Curr == nil:
+		ev.Type = TypeDelete
+		ev.State = rsp.Prev.(*rawState).value
+	case rsp.Prev == nil:
+		ev.Type = TypeCreate
+		ev.State = rsp.Curr.(*rawState).value
+	default:
+		ev.State = rsp.Curr.(*rawState).value
+		ev.Prev = rsp.Prev.(*rawState).value
+	}
+
+	common := core.CommonState{}
+	if err := json.Unmarshal(ev.State, state); err != nil {
+		return nil, false, core.Errorf("error decoding %s state %s. Error: %s", kind, rsp.Key, err)
+	}
+	if err := json.Unmarshal(ev.State, &common); err != nil {
+		return nil, false, core.Errorf("error decoding %s state %s. Error: %s", kind, rsp.Key, err)
+	}
+	ev.ID = common.ID
+	ev.Tenant = stateTenant(state)
+
+	return ev, true, nil
+}
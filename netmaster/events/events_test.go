@@ -0,0 +1,195 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/state"
+)
+
+func newTestBroker(t *testing.T, historySize int) (*Broker, *state.FakeStateDriver) {
+	stateDriver := &state.FakeStateDriver{}
+	stateDriver.Init(nil)
+
+	// the broker has started once it gets to the revision of a first change
+	broker := NewBroker(stateDriver, historySize)
+	go broker.Run()
+	stateDriver.Write(mastercfg.StateConfigPath+"test", []byte("{}"))
+	waitRevision(t, broker, 1)
+
+	return broker, stateDriver
+}
+
+// waitRevision waits for the broker to get to a revision of the store
+func waitRevision(t *testing.T, broker *Broker, revision uint64) {
+	for i := 0; i < 100; i++ {
+		if _, current, _, err := broker.Since(revision); err == nil && current >= revision {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("broker didn't get to revision %d", revision)
+}
+
+func writeTestStates(t *testing.T, stateDriver core.StateDriver) uint64 {
+	nwCfg := &mastercfg.CfgNetworkState{Tenant: "blue", NetworkName: "net1"}
+	nwCfg.ID = "net1.blue"
+	nwCfg.StateDriver = stateDriver
+	epCfg := &mastercfg.CfgEndpointState{NetID: "net1.blue", IPAddress: "10.1.1.2"}
+	epCfg.ID = "net1.blue-ep1"
+	epCfg.StateDriver = stateDriver
+	epgCfg := &mastercfg.EndpointGroupState{Tenant: "red", Name: "web"}
+	epgCfg.ID = "red:web"
+	epgCfg.StateDriver = stateDriver
+
+	for _, write := range []func() error{nwCfg.Write, epCfg.Write, epgCfg.Write} {
+		if err := write(); err != nil {
+			t.Fatalf("error writing state. Error: %s", err)
+		}
+	}
+
+	epCfg.IPAddress = "10.1.1.3"
+	if err := epCfg.Write(); err != nil {
+		t.Fatalf("error writing state. Error: %s", err)
+	}
+	if err := nwCfg.Clear(); err != nil {
+		t.Fatalf("error clearing state. Error: %s", err)
+	}
+
+	_, revision, err := stateDriver.ListAllState(mastercfg.StateConfigPath, &rawState{}, unmarshalRaw)
+	if err != nil {
+		t.Fatalf("error listing states. Error: %s", err)
+	}
+	return revision
+}
+
+func TestBrokerEvents(t *testing.T) {
+	broker, stateDriver := newTestBroker(t, 100)
+	defer stateDriver.Deinit()
+
+	start := broker.Revision()
+	waitRevision(t, broker, writeTestStates(t, stateDriver))
+
+	evs, _, _, err := broker.Since(start)
+	if err != nil {
+		t.Fatalf("error getting events. Error: %s", err)
+	}
+
+	expEvents := []Event{
+		{Type: TypeCreate, Kind: mastercfg.NetworkKind, ID: "net1.blue", Tenant: "blue"},
+		{Type: TypeCreate, Kind: mastercfg.EndpointKind, ID: "net1.blue-ep1", Tenant: "blue"},
+		{Type: TypeCreate, Kind: mastercfg.EndpointGroupKind, ID: "red:web", Tenant: "red"},
+		{Type: TypeUpdate, Kind: mastercfg.EndpointKind, ID: "net1.blue-ep1", Tenant: "blue"},
+		{Type: TypeDelete, Kind: mastercfg.NetworkKind, ID: "net1.blue", Tenant: "blue"},
+	}
+	if len(evs) != len(expEvents) {
+		t.Fatalf("got %d events, expected %d", len(evs), len(expEvents))
+	}
+	for i, exp := range expEvents {
+		ev := evs[i]
+		if ev.Type != exp.Type || ev.Kind != exp.Kind || ev.ID != exp.ID || ev.Tenant != exp.Tenant {
+			t.Fatalf("event %d is %+v, expected %+v", i, ev, exp)
+		}
+		if i > 0 && ev.Revision <= evs[i-1].Revision {
+			t.Fatalf("event %d revision %d is not after %d", i, ev.Revision, evs[i-1].Revision)
+		}
+	}
+	if evs[3].Prev == nil {
+		t.Fatalf("update event has no previous state")
+	}
+
+	// resuming from an event gets the events after it
+	if resumed, _, _, err := broker.Since(evs[2].Revision); err != nil || len(resumed) != 2 {
+		t.Fatalf("unexpected events %+v resumed after %d. Error: %v", resumed, evs[2].Revision, err)
+	}
+}
+
+func TestBrokerCompacted(t *testing.T) {
+	broker, stateDriver := newTestBroker(t, 2)
+	defer stateDriver.Deinit()
+
+	start := broker.Revision()
+	waitRevision(t, broker, writeTestStates(t, stateDriver))
+
+	if _, _, _, err := broker.Since(start); !core.IsRevisionCompacted(err) {
+		t.Fatalf("events since compacted revision %d returned err %v", start, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	ev := &Event{Kind: mastercfg.NetworkKind, Tenant: "blue"}
+	for _, test := range []struct {
+		filter Filter
+		match  bool
+	}{
+		{Filter{}, true},
+		{Filter{Tenant: "blue"}, true},
+		{Filter{Tenant: "red"}, false},
+		{Filter{Kinds: []string{mastercfg.EndpointKind, mastercfg.NetworkKind}}, true},
+		{Filter{Kinds: []string{mastercfg.EndpointKind}, Tenant: "blue"}, false},
+	} {
+		if test.filter.Match(ev) != test.match {
+			t.Fatalf("filter %+v match of %+v is not %v", test.filter, ev, test.match)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	broker, stateDriver := newTestBroker(t, 100)
+	defer stateDriver.Deinit()
+
+	srv := httptest.NewServer(http.HandlerFunc(broker.StreamHandler))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?kind=endpoint,endpointGroup&tenant=blue")
+	if err != nil {
+		t.Fatalf("error getting the stream. Error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream failed with status %s", resp.Status)
+	}
+
+	writeTestStates(t, stateDriver)
+
+	decoder := NewDecoder(resp.Body)
+	for _, expType := range []Type{TypeCreate, TypeUpdate} {
+		ev, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("error decoding event. Error: %s", err)
+		}
+		if ev.Type != expType || ev.Kind != mastercfg.EndpointKind || decoder.Revision() != ev.Revision {
+			t.Fatalf("unexpected event %+v, expected an endpoint %s", ev, expType)
+		}
+	}
+
+	for _, query := range []string{"?kind=host", "?revision=latest"} {
+		resp, err := http.Get(srv.URL + query)
+		if err != nil {
+			t.Fatalf("error getting the stream. Error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("stream %s got status %s", query, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+// Names of the server-sent events of a stream, besides the state change
+// events named by their type. A stream starts with a ready event at the
+// revision it starts after, and ends with an error event if it fails.
+const (
+	readyEvent = "ready"
+	errorEvent = "error"
+)
+
+// keepaliveInterval is the interval of the comments written to idle streams,
+// so that proxies don't close them
+const keepaliveInterval = 30 * time.Second
+
+// streamRequest returns the filter and the revision of a stream request
+func (b *Broker) streamRequest(r *http.Request) (*Filter, uint64, error) {
+	filter := &Filter{Tenant: r.URL.Query().Get("tenant")}
+	if kinds := r.URL.Query().Get("kind"); kinds != "" {
+		for _, kind := range strings.Split(kinds, ",") {
+			if !validKind(kind) {
+				return nil, 0, core.InvalidErrorf("invalid kind %q, the kinds are %s", kind,
+					strings.Join(mastercfg.ConfigKinds(), ", "))
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("revision")
+	}
+	if value == "" {
+		return filter, b.Revision(), nil
+	}
+
+	revision, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, 0, core.InvalidErrorf("invalid revision %q. Error: %s", value, err)
+	}
+
+	return filter, revision, nil
+}
+
+// validKind returns true if kind is a kind of events
+func validKind(kind string) bool {
+	for _, k := range mastercfg.ConfigKinds() {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StreamHandler streams the events selected by the kind and tenant query
+// parameters as server-sent events, kinds being comma separated. The events
+// start after the revision query parameter, or the Last-Event-ID header of a
+// resumed stream, and at the current revision without them.
+func (b *Broker) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	filter, revision, err := b.streamRequest(r)
+	if err != nil {
+		core.WriteHTTPError(w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		core.WriteHTTPError(w, core.Errorf("streaming is not supported"))
+		return
+	}
+	closed := w.(http.CloseNotifier).CloseNotify()
+
+	evs, current, changed, err := b.Since(revision)
+	if err != nil {
+		core.WriteHTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := writeMessage(w, readyEvent, revision, map[string]uint64{"revision": revision}); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		for _, ev := range evs {
+			if !filter.Match(ev) {
+				continue
+			}
+			if err := writeMessage(w, string(ev.Type), ev.Revision, ev); err != nil {
+				log.Infof("Events stream to %s ended. Error: %s", r.RemoteAddr, err)
+				return
+			}
+		}
+		flusher.Flush()
+
+		if !waitChange(w, flusher, changed, closed, keepalive.C) {
+			return
+		}
+
+		if evs, current, changed, err = b.Since(current); err != nil {
+			writeError(w, err)
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// waitChange waits for the next change, writing keepalive comments to the
+// stream meanwhile. It returns false if the client is gone.
+func waitChange(w io.Writer, flusher http.Flusher, changed <-chan struct{},
+	closed <-chan bool, keepalive <-chan time.Time) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-closed:
+			return false
+		case <-keepalive:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return false
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeMessage writes a server-sent event of a revision, with the json
+// encoding of data
+func writeMessage(w io.Writer, event string, revision uint64, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", event, revision, body)
+	return err
+}
+
+// writeError writes the error event of a failed stream
+func writeError(w io.Writer, err error) {
+	body, _ := json.Marshal(&core.HTTPError{Kind: core.ErrorKindOf(err), Error: err.Error()})
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", errorEvent, body); err != nil {
+		log.Errorf("Error writing the events stream error. Err: %v", err)
+	}
+}
+
+// Decoder reads the events of a stream
+type Decoder struct {
+	reader *bufio.Reader
+	// revision of the last event read
+	revision uint64
+}
+
+// NewDecoder returns a decoder of the stream read from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{reader: bufio.NewReader(r)}
+}
+
+// Revision returns the revision of the last event read, which a stream is
+// resumed from
+func (d *Decoder) Revision() uint64 {
+	return d.revision
+}
+
+// Decode returns the next event of the stream. The error event of a failed
+// stream is returned as an error of its kind.
+func (d *Decoder) Decode() (*Event, error) {
+	for {
+		event, id, data, err := d.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			if d.revision, err = strconv.ParseUint(id, 10, 64); err != nil {
+				return nil, core.Errorf("invalid event id %q. Error: %s", id, err)
+			}
+		}
+
+		switch event {
+		case readyEvent:
+			continue
+		case errorEvent:
+			httpErr := &core.HTTPError{}
+			if err := json.Unmarshal(data, httpErr); err != nil {
+				return nil, core.Errorf("invalid error event %q. Error: %s", data, err)
+			}
+			return nil, core.DecodeHTTPError(httpErr.Kind.HTTPStatus(), data)
+		}
+
+		ev := &Event{}
+		if err := json.Unmarshal(data, ev); err != nil {
+			return nil, core.Errorf("invalid event %q. Error: %s", data, err)
+		}
+		return ev, nil
+	}
+}
+
+// readMessage reads the fields of the next server-sent event, skipping the
+// comments
+func (d *Decoder) readMessage() (event, id string, data []byte, err error) {
+	for {
+		line, err := d.reader.ReadString('\n')
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && (event != "" || data != nil):
+			return event, id, data, nil
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: ")...)
+		}
+	}
+}
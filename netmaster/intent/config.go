@@ -20,6 +20,7 @@ package intent
 // ConfigGlobal keeps track of settings that are globally applicable
 type ConfigGlobal struct {
 	NwInfraType string
+	MACPrefix   string
 }
 
 // ConfigHost keeps track of the host's properties; A host is a node where
@@ -45,7 +46,13 @@ type ConfigEP struct {
 	Host        string
 	AttachUUID  string
 	IPAddress   string
+	IPv6Address string
+	MacAddress  string
 	ServiceName string
+
+	// stable identity, such as a pod name, whose address is kept across
+	// endpoint restarts
+	LeaseKey string
 }
 
 // ConfigNetwork is a multi-destination isolated containment of endpoints
@@ -59,6 +66,18 @@ type ConfigNetwork struct {
 	SubnetCIDR string
 	Gateway    string
 
+	// IPv6 subnet and gateway, for IPv6-only or dual-stack networks
+	IPv6SubnetCIDR string
+	IPv6Gateway    string
+
+	// IPv4 addresses that are never allocated, such as 10.1.1.1-10.1.1.10,
+	// and addresses reserved for an endpoint or service name
+	IPExclusions   string
+	IPReservations map[string]string
+
+	// seconds for which a released address stays held for its lease
+	IPLeaseHoldTime int
+
 	// eps associated with the network
 	Endpoints []ConfigEP
 }
@@ -72,6 +91,17 @@ type ConfigTenant struct {
 	AllocSubnetLen uint
 	VLANs          string
 	VXLANs         string
+	MACPrefix      string
+
+	// optional pool of the IPv6 subnets of networks, such as 2001:db8::/48
+	IPv6SubnetPool     string
+	AllocIPv6SubnetLen uint
+
+	// quotas on the tenant's objects, zero being no limit
+	MaxNetworks       int
+	MaxEndpoints      int
+	MaxEndpointGroups int
+	MaxVLANs          int
 
 	Networks []ConfigNetwork
 }
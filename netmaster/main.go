@@ -16,22 +16,31 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/drivers"
+	"github.com/contiv/netplugin/netmaster/audit"
+	"github.com/contiv/netplugin/netmaster/auth"
+	"github.com/contiv/netplugin/netmaster/events"
 	"github.com/contiv/netplugin/netmaster/intent"
 	"github.com/contiv/netplugin/netmaster/master"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/netmaster/objApi"
+	"github.com/contiv/netplugin/netmaster/webhook"
 	"github.com/contiv/netplugin/resources"
 	"github.com/contiv/netplugin/state"
 	"github.com/contiv/netplugin/utils"
@@ -48,8 +57,34 @@ type cliOpts struct {
 	storeURL    string
 	listenURL   string
 	clusterMode string
+	credentials string
+	auditLog    string
+
+	// certificates of the REST API, and of the requests to the state store
+	tls               core.TLSConfig
+	requireClientCert bool
+	storeTLS          core.TLSConfig
 }
 
+const (
+	// state store key of the lease held by the leader netmaster
+	leaderLeaseKey = "/contiv.io/lock/netmaster/leader"
+	leaderLeaseTTL = 15 * time.Second
+
+	// header marking requests forwarded to the leader, to avoid loops
+	forwardedHeader = "X-Netmaster-Forwarded"
+
+	// size the audit log file is rotated at, and rotated files kept
+	auditFileMaxSize    = 10 * 1024 * 1024
+	auditFileMaxBackups = 5
+	// audit records kept in the state store, past which the oldest are
+	// overwritten
+	auditStateMaxRecords = 10000
+
+	// state changes kept for the event streams to resume from
+	eventHistorySize = 1000
+)
+
 type httpAPIFunc func(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error)
 
 var flagSet *flag.FlagSet
@@ -63,6 +98,27 @@ type daemon struct {
 	opts          cliOpts
 	apiController *objApi.APIController
 	stateDriver   core.StateDriver
+	eventBroker   *events.Broker
+	webhooks      *webhook.Dispatcher
+
+	// authenticator of the REST API clients, nil if they are not
+	// authenticated
+	authenticator auth.Authenticator
+
+	// tls config of the REST API, nil when it's served over http, and the
+	// transport of the requests forwarded to the leader
+	tlsConfig        *tls.Config
+	forwardTransport http.RoundTripper
+
+	// address other netmasters forward requests to, when leader
+	advertiseAddr string
+	leaderLease   *state.Lease
+
+	// followerRouter serves the requests a follower can handle itself;
+	// router serves all requests once this netmaster is the leader.
+	followerRouter *mux.Router
+	router         *mux.Router
+	routerMutex    sync.RWMutex
 }
 
 func initStateDriver(opts *cliOpts) (core.StateDriver, error) {
@@ -71,11 +127,15 @@ func initStateDriver(opts *cliOpts) (core.StateDriver, error) {
 	switch opts.stateStore {
 	case utils.EtcdNameStr:
 		url := "http://127.0.0.1:4001"
+		if opts.storeTLS.Enabled() {
+			url = "https://127.0.0.1:4001"
+		}
 		if opts.storeURL != "" {
 			url = opts.storeURL
 		}
 		etcdCfg := &state.EtcdStateDriverConfig{}
 		etcdCfg.Etcd.Machines = []string{url}
+		etcdCfg.Etcd.TLS = opts.storeTLS
 		cfg = &core.Config{V: etcdCfg}
 	case utils.ConsulNameStr:
 		url := "http://127.0.0.1:8500"
@@ -84,6 +144,7 @@ func initStateDriver(opts *cliOpts) (core.StateDriver, error) {
 		}
 		consulCfg := &state.ConsulStateDriverConfig{}
 		consulCfg.Consul = api.Config{Address: url}
+		consulCfg.TLS = opts.storeTLS
 		cfg = &core.Config{V: consulCfg}
 	default:
 		return nil, core.Errorf("Unsupported state-store %q", opts.stateStore)
@@ -125,6 +186,43 @@ func (d *daemon) parseOpts() error {
 		"docker",
 		"{docker, kubernetes}")
 
+	flagSet.StringVar(&d.opts.credentials,
+		"credentials",
+		"",
+		"JSON file with the tokens and client certificate names of the REST API clients, with their roles. The REST API is not authenticated without it.")
+	flagSet.StringVar(&d.opts.auditLog,
+		"audit-log",
+		"state",
+		"Where to keep the audit log of the configuration changes: 'state' for the latest changes in the state store, 'none', or the path of a file")
+	flagSet.StringVar(&d.opts.tls.CertFile,
+		"tls-cert",
+		"",
+		"Certificate file to serve the REST API over https with. It's also the client certificate of the requests forwarded to the leader.")
+	flagSet.StringVar(&d.opts.tls.KeyFile,
+		"tls-key",
+		"",
+		"Key file of the tls-cert certificate")
+	flagSet.StringVar(&d.opts.tls.CAFile,
+		"tls-ca",
+		"",
+		"CA certificate file to verify the client certificates and the leader's certificate with")
+	flagSet.BoolVar(&d.opts.requireClientCert,
+		"tls-require-client-cert",
+		false,
+		"Require the REST API clients to present a certificate, for mutual TLS")
+	flagSet.StringVar(&d.opts.storeTLS.CertFile,
+		"store-tls-cert",
+		"",
+		"Client certificate file of the requests to the state store")
+	flagSet.StringVar(&d.opts.storeTLS.KeyFile,
+		"store-tls-key",
+		"",
+		"Key file of the store-tls-cert certificate")
+	flagSet.StringVar(&d.opts.storeTLS.CAFile,
+		"store-tls-ca",
+		"",
+		"CA certificate file to verify the state store's certificate with")
+
 	if err := flagSet.Parse(os.Args[1:]); err != nil {
 		return err
 	}
@@ -132,7 +230,8 @@ func (d *daemon) parseOpts() error {
 	return nil
 }
 
-func (d *daemon) registerService() {
+// initAdvertiseAddr sets the address other nodes reach this netmaster at.
+func (d *daemon) initAdvertiseAddr() {
 	// Create an objdb client
 	objdbClient := client.NewClient()
 
@@ -142,15 +241,33 @@ func (d *daemon) registerService() {
 		log.Fatalf("Error getting locla IP address. Err: %v", err)
 	}
 
+	_, portStr, err := net.SplitHostPort(d.opts.listenURL)
+	if err != nil {
+		log.Fatalf("Error parsing listen url %q. Err: %v", d.opts.listenURL, err)
+	}
+	if _, err = strconv.Atoi(portStr); err != nil {
+		log.Fatalf("Error parsing listen port %q. Err: %v", portStr, err)
+	}
+
+	d.advertiseAddr = net.JoinHostPort(localIP, portStr)
+}
+
+func (d *daemon) registerService() {
+	// Create an objdb client
+	objdbClient := client.NewClient()
+
+	localIP, portStr, _ := net.SplitHostPort(d.advertiseAddr)
+	port, _ := strconv.Atoi(portStr)
+
 	// service info
 	srvInfo := objdb.ServiceInfo{
 		ServiceName: "netmaster",
 		HostAddr:    localIP,
-		Port:        9999,
+		Port:        port,
 	}
 
 	// Register the node with service registry
-	err = objdbClient.RegisterService(srvInfo)
+	err := objdbClient.RegisterService(srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 	}
@@ -177,6 +294,14 @@ func (d *daemon) execOpts() {
 		log.Fatalf("Failed to set cluster-mode. Error: %s", err)
 	}
 
+	if err := d.initAuth(); err != nil {
+		log.Fatalf("Failed to init authentication. Error: %s", err)
+	}
+
+	if err := d.initTLS(); err != nil {
+		log.Fatalf("Failed to init TLS. Error: %s", err)
+	}
+
 	sd, err := initStateDriver(&d.opts)
 	if err != nil {
 		log.Fatalf("Failed to init state-store. Error: %s", err)
@@ -186,62 +311,373 @@ func (d *daemon) execOpts() {
 		log.Fatalf("Failed to init resource manager. Error: %s", err)
 	}
 
+	switch d.opts.auditLog {
+	case "none":
+		log.Warnf("The configuration changes are not audited")
+	case "state":
+		audit.SetSink(audit.NewStateSink(sd, auditStateMaxRecords))
+	default:
+		audit.SetSink(audit.NewFileSink(d.opts.auditLog, auditFileMaxSize, auditFileMaxBackups))
+	}
+
+	d.eventBroker = events.NewBroker(sd, eventHistorySize)
+	go d.eventBroker.Run()
+
 	d.stateDriver = sd
 }
 
+// initTLS sets the tls config of the REST API, when it has a certificate
+func (d *daemon) initTLS() error {
+	if !d.opts.tls.Enabled() {
+		if d.opts.requireClientCert {
+			return core.InvalidErrorf("client certificates need tls-cert, tls-key and tls-ca")
+		}
+		return nil
+	}
+
+	var err error
+	if d.tlsConfig, err = d.opts.tls.ServerTLSConfig(d.opts.requireClientCert); err != nil {
+		return err
+	}
+
+	clientTLS, err := d.opts.tls.ClientTLSConfig()
+	if err != nil {
+		return err
+	}
+	d.forwardTransport = &http.Transport{TLSClientConfig: clientTLS}
+
+	return nil
+}
+
 func (d *daemon) ListenAndServe() {
+	d.initAdvertiseAddr()
+
+	d.followerRouter = mux.NewRouter()
+	d.registerWebuiHandler(d.followerRouter)
+	d.addReadRoutes(d.followerRouter)
+
+	d.leaderLease = state.NewLease(d.stateDriver, leaderLeaseKey, d.advertiseAddr,
+		leaderLeaseTTL)
+
+	// only the holder of the leader lease commits state transactions
+	master.SetTxnFence(d.leaderLease.Fence)
+	go d.runLeaderElection()
+
+	log.Infof("Netmaster listening on %s", d.opts.listenURL)
+
+	if d.tlsConfig == nil {
+		if err := http.ListenAndServe(d.opts.listenURL, d); err != nil {
+			log.Fatalf("Error listening for http requests. Error: %s", err)
+		}
+		return
+	}
+
+	server := &http.Server{Addr: d.opts.listenURL, Handler: d, TLSConfig: d.tlsConfig}
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		log.Fatalf("Error listening for https requests. Error: %s", err)
+	}
+
+}
+
+// readRoutes returns the REST routes that only read the state store. Most of
+// them read the state of all tenants.
+func (d *daemon) readRoutes() []master.APIRoute {
+	return []master.APIRoute{
+		{Method: "GET", Path: fmt.Sprintf("/%s/{id}", master.GetEndpointRESTEndpoint),
+			Summary:  "Get the oper state of an endpoint",
+			Response: []drivers.OvsOperEndpointState{}, Handler: get(false, d.endpoints)},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetEndpointsRESTEndpoint),
+			Summary:  "List the oper state of the endpoints",
+			Response: []drivers.OvsOperEndpointState{}, Handler: get(true, d.endpoints)},
+		{Method: "GET", Path: fmt.Sprintf("/%s/{id}", master.GetNetworkRESTEndpoint),
+			Summary:  "Get the state of a network",
+			Response: []mastercfg.CfgNetworkState{}, Handler: get(false, d.networks)},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetNetworksRESTEndpoint),
+			Summary:  "List the state of the networks",
+			Response: []mastercfg.CfgNetworkState{}, Handler: get(true, d.networks)},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetIPLeasesRESTEndpoint),
+			Summary:  "List the address leases",
+			Response: []master.IPLeaseInfo{}, Handler: makeHTTPHandler(master.ListIPLeasesHandler)},
+		{Method: "GET", Path: fmt.Sprintf("/%s/{id}", master.GetResourceUsageRESTEndpoint),
+			Summary:  "Get the resource utilisation of a tenant",
+			Response: master.ResourceUsageReport{}, Handler: makeHTTPHandler(master.ResourceUsageHandler),
+			Tenants: varTenant("id")},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetResourceUsageRESTEndpoint),
+			Summary:  "Get the resource utilisation of all tenants",
+			Response: master.ResourceUsageReport{}, Handler: makeHTTPHandler(master.ResourceUsageHandler)},
+		{Method: "GET", Path: fmt.Sprintf("/%s/{id}", master.GetIPUsageRESTEndpoint),
+			Summary:  "Get the address utilisation of a network",
+			Response: []master.NetworkIPUsage{}, Handler: makeHTTPHandler(master.IPUsageHandler)},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetIPUsageRESTEndpoint),
+			Summary:  "Get the address utilisation of all networks",
+			Response: []master.NetworkIPUsage{}, Handler: makeHTTPHandler(master.IPUsageHandler)},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetEventsRESTEndpoint),
+			Summary: "Stream the changes of the networks, endpoints, endpoint groups and policies " +
+				"as server-sent events, selected by the kind and tenant query parameters " +
+				"and starting after the revision query parameter",
+			Response: events.Event{}, Handler: d.eventBroker.StreamHandler,
+			Tenants: queryTenant},
+	}
+}
+
+// leaderRoutes returns the REST routes that only the leader serves.
+func (d *daemon) leaderRoutes() []master.APIRoute {
+	return []master.APIRoute{
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.DesiredConfigRESTEndpoint),
+			Summary: "Replace the configuration with the desired configuration",
+			Request: intent.Config{}, Handler: post(master.DesiredConfigRESTEndpoint, d.desiredConfig),
+			Access: auth.AccessWrite},
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.AddConfigRESTEndpoint),
+			Summary: "Add to the configuration",
+			Request: intent.Config{}, Handler: post(master.AddConfigRESTEndpoint, d.addConfig),
+			Access: auth.AccessWrite, Tenants: intentTenants},
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.DelConfigRESTEndpoint),
+			Summary: "Delete from the configuration",
+			Request: intent.Config{}, Handler: post(master.DelConfigRESTEndpoint, d.delConfig),
+			Access: auth.AccessWrite, Tenants: intentTenants},
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.HostBindingConfigRESTEndpoint),
+			Summary: "Bind endpoints to hosts",
+			Request: intent.Config{}, Handler: post(master.HostBindingConfigRESTEndpoint, d.hostBindingsConfig),
+			Access: auth.AccessWrite},
+		{Method: "POST", Path: "/plugin/allocAddress",
+			Summary: "Allocate an address in a network",
+			Request: master.AddressAllocRequest{}, Response: master.AddressAllocResponse{},
+			Handler: makeHTTPHandler(master.AllocAddressHandler), Access: auth.AccessNode},
+		{Method: "POST", Path: "/plugin/releaseAddress",
+			Summary: "Release an address of a network",
+			Request: master.AddressReleaseRequest{}, Response: "",
+			Handler: makeHTTPHandler(master.ReleaseAddressHandler), Access: auth.AccessNode},
+		{Method: "POST", Path: "/plugin/createEndpoint",
+			Summary: "Create an endpoint",
+			Request: master.CreateEndpointRequest{}, Response: master.CreateEndpointResponse{},
+			Handler: makeHTTPHandler(master.CreateEndpointHandler), Access: auth.AccessNode},
+		{Method: "POST", Path: "/plugin/deleteEndpoint",
+			Summary: "Delete an endpoint",
+			Request: master.DeleteEndpointRequest{}, Response: master.DeleteEndpointResponse{},
+			Handler: makeHTTPHandler(master.DeleteEndpointHandler), Access: auth.AccessNode},
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.ExpireIPLeaseRESTEndpoint),
+			Summary: "Remove an address lease",
+			Request: master.ExpireIPLeaseRequest{}, Response: "",
+			Handler: makeHTTPHandler(master.ExpireIPLeaseHandler), Access: auth.AccessWrite},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.GetAuditRESTEndpoint),
+			Summary: "List the audit records of the configuration changes, " +
+				"selected by the since and until times and tenant query parameters",
+			Response: []audit.Record{}, Handler: makeHTTPHandler(audit.ListHandler),
+			Tenants: queryTenant},
+		{Method: "POST", Path: fmt.Sprintf("/%s", master.WebhooksRESTEndpoint),
+			Summary: "Register a webhook, notified of the lifecycle events of the endpoints, networks and hosts",
+			Request: webhook.Webhook{}, Response: webhook.Webhook{},
+			Handler: makeHTTPHandler(webhook.RegisterHandler), Access: auth.AccessWrite},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.WebhooksRESTEndpoint),
+			Summary:  "List the webhooks",
+			Response: []webhook.Webhook{}, Handler: makeHTTPHandler(webhook.ListHandler)},
+		{Method: "DELETE", Path: fmt.Sprintf("/%s/{id}", master.WebhooksRESTEndpoint),
+			Summary:  "Remove a webhook",
+			Response: "", Handler: makeHTTPHandler(webhook.DeleteHandler), Access: auth.AccessWrite},
+		{Method: "GET", Path: fmt.Sprintf("/%s", master.WebhookDeadLettersRESTEndpoint),
+			Summary:  "List the notifications that couldn't be delivered to the webhooks",
+			Response: []webhook.DeadLetter{}, Handler: makeHTTPHandler(webhook.ListDeadLettersHandler)},
+		{Method: "DELETE", Path: fmt.Sprintf("/%s/{id}", master.WebhookDeadLettersRESTEndpoint),
+			Summary:  "Remove a notification that couldn't be delivered",
+			Response: "", Handler: makeHTTPHandler(webhook.DeleteDeadLetterHandler), Access: auth.AccessWrite},
+	}
+}
+
+// addAPIRoutes adds routes under master.APIVersionPrefix, and under their
+// unversioned paths as deprecated aliases.
+func (d *daemon) addAPIRoutes(router *mux.Router, routes []master.APIRoute) {
+	for _, route := range routes {
+		handler := d.authorized(route.Access, route.Tenants, route.Handler)
+		for _, r := range []*mux.Route{
+			router.Path(master.APIVersionPrefix + route.Path).Handler(handler),
+			router.Path(route.Path).Handler(deprecated(handler)),
+		} {
+			r.Methods(route.Method)
+			if route.Request != nil {
+				r.Headers("Content-Type", "application/json")
+			}
+		}
+	}
+}
+
+// addReadRoutes adds the REST routes that only read the state store, along
+// with the description of the API.
+func (d *daemon) addReadRoutes(router *mux.Router) {
+	d.addAPIRoutes(router, d.readRoutes())
+
+	routes := append(d.readRoutes(), d.leaderRoutes()...)
+	routes = append(routes, objApi.APIRoutes()...)
+	spec := master.NewAPISpec(routes)
+	router.Path(fmt.Sprintf("%s/%s", master.APIVersionPrefix, master.APISpecRESTEndpoint)).
+		Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := writeJSON(w, http.StatusOK, spec); err != nil {
+			log.Errorf("Error generating json. Err: %v", err)
+		}
+	})
+}
+
+// deprecated serves a deprecated unversioned path, pointing the clients to
+// the versioned one.
+func deprecated(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := master.APIVersionPrefix + r.URL.Path
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			successor = master.APIVersionPrefix + strings.TrimPrefix(r.URL.Path, "/api")
+		}
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// versionedModel serves the versioned paths of the contivModel objects
+// with the router of their unversioned paths under /api/.
+func versionedModel(modelRouter http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/api" + strings.TrimPrefix(r.URL.Path, master.APIVersionPrefix)
+		modelRouter.ServeHTTP(w, r)
+	}
+}
+
+// runLeaderElection keeps acquiring or renewing the leader lease, and takes
+// over as the leader when it's acquired. A leader that loses the lease exits,
+// as its in-memory state can no longer be trusted. Its state transactions
+// are fenced by the lease, so none commits after the lease is lost.
+func (d *daemon) runLeaderElection() {
+	for {
+		held, err := d.leaderLease.TryAcquire()
+		if err != nil {
+			log.Errorf("Error acquiring leader lease. Err: %v", err)
+		}
+
+		d.routerMutex.RLock()
+		isLeader := d.router != nil
+		d.routerMutex.RUnlock()
+
+		if held && !isLeader {
+			log.Infof("Netmaster %s became the leader", d.advertiseAddr)
+			d.becomeLeader()
+		} else if !held && isLeader {
+			log.Fatalf("Netmaster %s lost the leader lease, exiting", d.advertiseAddr)
+		}
+
+		time.Sleep(leaderLeaseTTL / 3)
+	}
+}
+
+// becomeLeader initializes the in-memory state that only the leader keeps
+// and starts serving all REST calls.
+func (d *daemon) becomeLeader() {
+	// complete the state transactions interrupted by a previous leader. This
+	// is done only once the lease is held, so that no other netmaster is
+	// committing transactions at the same time.
+	if err := state.RecoverTxns(d.stateDriver); err != nil {
+		log.Fatalf("Failed to recover state transactions. Error: %s", err)
+	}
+
 	router := mux.NewRouter()
 
-	// Create a new api controller
-	d.apiController = objApi.NewAPIController(router)
+	// Create a new api controller, with the routes of the model objects
+	modelRouter := mux.NewRouter()
+	d.apiController = objApi.NewAPIController(modelRouter)
 
 	// initialize policy manager
 	mastercfg.InitPolicyMgr(d.stateDriver)
 
-	// Register netmaster service
+	// Register netmaster service, only the leader is advertised to the
+	// netplugins
 	d.registerService()
 
 	// register web ui handlers
-	registerWebuiHandler(router)
+	d.registerWebuiHandler(router)
 
 	// Add REST routes
-	s := router.Headers("Content-Type", "application/json").Methods("Post").Subrouter()
-	s.HandleFunc(fmt.Sprintf("/%s", master.DesiredConfigRESTEndpoint),
-		post(d.desiredConfig))
-	s.HandleFunc(fmt.Sprintf("/%s", master.AddConfigRESTEndpoint),
-		post(d.addConfig))
-	s.HandleFunc(fmt.Sprintf("/%s", master.DelConfigRESTEndpoint),
-		post(d.delConfig))
-	s.HandleFunc(fmt.Sprintf("/%s", master.HostBindingConfigRESTEndpoint),
-		post(d.hostBindingsConfig))
-
-	s.HandleFunc("/plugin/allocAddress", makeHTTPHandler(master.AllocAddressHandler))
-	s.HandleFunc("/plugin/releaseAddress", makeHTTPHandler(master.ReleaseAddressHandler))
-	s.HandleFunc("/plugin/createEndpoint", makeHTTPHandler(master.CreateEndpointHandler))
-	s.HandleFunc("/plugin/deleteEndpoint", makeHTTPHandler(master.DeleteEndpointHandler))
-
-	s = router.Methods("Get").Subrouter()
-	s.HandleFunc(fmt.Sprintf("/%s/%s", master.GetEndpointRESTEndpoint, "{id}"),
-		get(false, d.endpoints))
-	s.HandleFunc(fmt.Sprintf("/%s", master.GetEndpointsRESTEndpoint),
-		get(true, d.endpoints))
-	s.HandleFunc(fmt.Sprintf("/%s/%s", master.GetNetworkRESTEndpoint, "{id}"),
-		get(false, d.networks))
-	s.HandleFunc(fmt.Sprintf("/%s", master.GetNetworksRESTEndpoint),
-		get(true, d.networks))
+	d.addAPIRoutes(router, d.leaderRoutes())
+	d.addReadRoutes(router)
 
-	log.Infof("Netmaster listening on %s", d.opts.listenURL)
+	// the model object routes come last, as they take all paths under /api/
+	d.apiController.AuditRequests(requestCaller)
+	router.PathPrefix(master.APIVersionPrefix + "/").
+		HandlerFunc(d.authorizedModel(versionedModel(modelRouter)))
+	router.PathPrefix("/api/").HandlerFunc(deprecated(d.authorizedModel(modelRouter)))
+
+	d.routerMutex.Lock()
+	d.router = router
+	d.routerMutex.Unlock()
 
 	go objApi.CreateDefaultTenant()
 
-	if err := http.ListenAndServe(d.opts.listenURL, router); err != nil {
-		log.Fatalf("Error listening for http requests. Error: %s", err)
+	// notify the webhooks of the changes made while leader
+	d.webhooks = webhook.NewDispatcher(d.stateDriver)
+	go d.webhooks.WatchEvents(d.eventBroker, d.eventBroker.Revision())
+	go d.watchHosts()
+}
+
+// watchHosts notifies the webhooks of the hosts whose netplugin goes away
+func (d *daemon) watchHosts() {
+	objdbClient := client.NewClient()
+	srvEvents := make(chan objdb.WatchServiceEvent, 1)
+	if err := objdbClient.WatchService("netplugin", srvEvents, make(chan bool, 1)); err != nil {
+		log.Errorf("Could not start a watch on netplugin service, hosts going away are not notified. Err: %v", err)
+		return
+	}
+
+	for srvEvent := range srvEvents {
+		if srvEvent.EventType == objdb.WatchServiceEventDel {
+			log.Infof("Host %s went away", srvEvent.ServiceInfo.HostAddr)
+			d.webhooks.Notify(webhook.HostDown, "", srvEvent.ServiceInfo)
+		}
+	}
+}
+
+// ServeHTTP serves all requests on the leader. Followers serve the requests
+// that only read the state store and forward the others to the leader.
+func (d *daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.routerMutex.RLock()
+	router := d.router
+	d.routerMutex.RUnlock()
+
+	if router != nil && d.leaderLease.IsHeld() {
+		router.ServeHTTP(w, r)
+		return
+	}
+
+	var match mux.RouteMatch
+	if d.followerRouter.Match(r, &match) {
+		d.followerRouter.ServeHTTP(w, r)
+		return
+	}
+
+	leader := d.leaderLease.Holder()
+	if leader == "" || leader == d.advertiseAddr || r.Header.Get(forwardedHeader) != "" {
+		core.WriteHTTPError(w, core.UnavailableErrorf("netmaster leader is not available"))
+		return
+	}
+
+	scheme := "http"
+	if d.tlsConfig != nil {
+		scheme = "https"
 	}
 
+	// the leader can't authenticate the client certificate of a forwarded
+	// request, so the client is redirected to it instead
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		target := *r.URL
+		target.Scheme, target.Host = scheme, leader
+		http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+		return
+	}
+
+	log.Debugf("Forwarding %s %s to leader %s", r.Method, r.URL, leader)
+	r.Header.Set(forwardedHeader, d.advertiseAddr)
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: scheme, Host: leader})
+	proxy.Transport = d.forwardTransport
+	proxy.ServeHTTP(w, r)
 }
 
 // registerWebuiHandler registers handlers for serving web UI
-func registerWebuiHandler(router *mux.Router) {
+func (d *daemon) registerWebuiHandler(router *mux.Router) {
 	// Setup the router to serve the web UI
 	goPath := os.Getenv("GOPATH")
 	if goPath != "" {
@@ -269,8 +705,9 @@ func registerWebuiHandler(router *mux.Router) {
 		})
 	}
 
-	// proxy Handler
-	router.PathPrefix("/proxy/").HandlerFunc(proxyHandler)
+	// proxy Handler, which reaches any address, so it's for admins only
+	router.PathPrefix("/proxy/").
+		HandlerFunc(d.authorized(auth.AccessWrite, nil, http.HandlerFunc(proxyHandler)))
 }
 
 // proxyHandler acts as a simple reverse proxy to access containers via http
@@ -304,8 +741,8 @@ func makeHTTPHandler(handlerFunc httpAPIFunc) http.HandlerFunc {
 			// Log error
 			log.Errorf("Handler for %s %s returned error: %s", r.Method, r.URL, err)
 
-			// Send HTTP response
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			// Send HTTP response with the status of the error
+			core.WriteHTTPError(w, err)
 		} else {
 			// Send HTTP response as Json
 			err = writeJSON(w, http.StatusOK, resp)
@@ -329,21 +766,35 @@ func writeJSON(w http.ResponseWriter, code int, v interface{}) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
-func post(hook func(cfg *intent.Config) error) func(http.ResponseWriter, *http.Request) {
+// post serves the posts of intent configs to the endpoint, recording them in
+// the audit log
+func post(endpoint string, hook func(cfg *intent.Config) error) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cfg := &intent.Config{}
 		decoder := json.NewDecoder(r.Body)
 		if err := decoder.Decode(cfg); err != nil {
-			http.Error(w,
-				core.Errorf("parsing json failed. Error: %s", err).Error(),
-				http.StatusInternalServerError)
+			core.WriteHTTPError(w,
+				core.InvalidErrorf("parsing json failed. Error: %s", err))
 			return
 		}
 
-		if err := hook(cfg); err != nil {
-			http.Error(w,
-				err.Error(),
-				http.StatusInternalServerError)
+		err := hook(cfg)
+
+		tenant := ""
+		if len(cfg.Tenants) == 1 {
+			tenant = cfg.Tenants[0].Name
+		}
+		audit.Log(&audit.Record{
+			Caller:    requestCaller(r),
+			Kind:      "intent",
+			Key:       endpoint,
+			Tenant:    tenant,
+			Operation: audit.OpApply,
+			After:     audit.State(cfg),
+		}, err)
+
+		if err != nil {
+			core.WriteHTTPError(w, err)
 			return
 		}
 
@@ -397,22 +848,19 @@ func get(getAll bool, hook func(id string) ([]core.State, error)) func(http.Resp
 		if getAll {
 			idStr = "all"
 		} else if idStr, ok = mux.Vars(r)["id"]; !ok {
-			http.Error(w,
-				core.Errorf("Failed to find the id string in the request.").Error(),
-				http.StatusInternalServerError)
+			core.WriteHTTPError(w,
+				core.InvalidErrorf("Failed to find the id string in the request."))
+			return
 		}
 
 		if states, err = hook(idStr); err != nil {
-			http.Error(w,
-				err.Error(),
-				http.StatusInternalServerError)
+			core.WriteHTTPError(w, err)
 			return
 		}
 
 		if resp, err = json.Marshal(states); err != nil {
-			http.Error(w,
-				core.Errorf("marshalling json failed. Error: %s", err).Error(),
-				http.StatusInternalServerError)
+			core.WriteHTTPError(w,
+				core.Errorf("marshalling json failed. Error: %s", err))
 			return
 		}
 
@@ -17,15 +17,16 @@ package master
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
-	"sync"
 
+	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/netmaster/intent"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/utils"
+	"github.com/contiv/netplugin/utils/netutils"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -34,18 +35,22 @@ import (
 type AddressAllocRequest struct {
 	NetworkID            string // Unique identifier for the network
 	PreferredIPv4Address string // Preferred address
+	PreferredIPv6Address string // Preferred address, when NetworkID is an IPv6 subnet
+	LeaseKey             string // Stable identity the IPv4 address is leased to, if any
 }
 
 // AddressAllocResponse is the response from netmaster
 type AddressAllocResponse struct {
 	NetworkID   string // Unique identifier for the network
 	IPv4Address string // Allocated address
+	IPv6Address string // Allocated address, when NetworkID is an IPv6 subnet
 }
 
 // AddressReleaseRequest is the release request from netplugin
 type AddressReleaseRequest struct {
 	NetworkID   string // Unique identifier for the network
 	IPv4Address string // Allocated address
+	IPv6Address string // Allocated IPv6 address
 }
 
 // CreateEndpointRequest has the endpoint create request from netplugin
@@ -76,8 +81,11 @@ type DeleteEndpointResponse struct {
 	EndpointConfig mastercfg.CfgEndpointState // Endpoint config
 }
 
-// Global mutex for address allocation
-var addrMutex sync.Mutex
+// ExpireIPLeaseRequest is the request to remove an address lease
+type ExpireIPLeaseRequest struct {
+	NetworkID string // network of the lease
+	LeaseKey  string // stable identity the address is leased to
+}
 
 // AllocAddressHandler allocates addresses
 func AllocAddressHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
@@ -87,15 +95,11 @@ func AllocAddressHandler(w http.ResponseWriter, r *http.Request, vars map[string
 	err := json.NewDecoder(r.Body).Decode(&allocReq)
 	if err != nil {
 		log.Errorf("Error decoding AllocAddressHandler. Err %v", err)
-		return nil, err
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
 	}
 
 	log.Infof("Received AddressAllocRequest: %+v", allocReq)
 
-	// Take a global lock for address allocation
-	addrMutex.Lock()
-	defer addrMutex.Unlock()
-
 	// Get hold of the state driver
 	stateDriver, err := utils.GetStateDriver()
 	if err != nil {
@@ -106,6 +110,7 @@ func AllocAddressHandler(w http.ResponseWriter, r *http.Request, vars map[string
 	subnetIP := strings.Split(allocReq.NetworkID, "/")[0]
 	subnetLen := strings.Split(allocReq.NetworkID, "/")[1]
 	networkID := ""
+	isIPv6 := netutils.IsIPv6(subnetIP)
 
 	// find the network from networkID
 	readNet := &mastercfg.CfgNetworkState{}
@@ -123,11 +128,16 @@ func AllocAddressHandler(w http.ResponseWriter, r *http.Request, vars map[string
 		if nw.SubnetIP == subnetIP && fmt.Sprintf("%d", nw.SubnetLen) == subnetLen {
 			networkID = nw.ID
 		}
+		if isIPv6 && nw.IPv6Subnet != "" &&
+			net.ParseIP(nw.IPv6Subnet).Equal(net.ParseIP(subnetIP)) &&
+			fmt.Sprintf("%d", nw.IPv6SubnetLen) == subnetLen {
+			networkID = nw.ID
+		}
 	}
 
 	if networkID == "" {
 		log.Errorf("Could not find the network for: %s", allocReq.NetworkID)
-		return nil, errors.New("Network not found")
+		return nil, core.NotFoundErrorf("network for %s not found", allocReq.NetworkID)
 	}
 
 	// find the network from network id
@@ -139,8 +149,23 @@ func AllocAddressHandler(w http.ResponseWriter, r *http.Request, vars map[string
 		return nil, err
 	}
 
+	if isIPv6 {
+		addr, err := networkAllocIPAMAddress(nwCfg, allocReq.PreferredIPv6Address, "", true)
+		if err != nil {
+			log.Errorf("Failed to allocate IPv6 address. Err: %v", err)
+			return nil, err
+		}
+
+		aresp := AddressAllocResponse{
+			NetworkID:   allocReq.NetworkID,
+			IPv6Address: addr + "/" + fmt.Sprintf("%d", nwCfg.IPv6SubnetLen),
+		}
+
+		return aresp, nil
+	}
+
 	// Alloc addresses
-	addr, err := networkAllocAddress(nwCfg, allocReq.PreferredIPv4Address)
+	addr, err := networkAllocIPAMAddress(nwCfg, allocReq.PreferredIPv4Address, allocReq.LeaseKey, false)
 	if err != nil {
 		log.Errorf("Failed to allocate address. Err: %v", err)
 		return nil, err
@@ -163,7 +188,7 @@ func ReleaseAddressHandler(w http.ResponseWriter, r *http.Request, vars map[stri
 	err := json.NewDecoder(r.Body).Decode(&relReq)
 	if err != nil {
 		log.Errorf("Error decoding ReleaseAddressHandler. Err %v", err)
-		return nil, err
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
 	}
 
 	log.Infof("Received AddressReleaseRequest: %+v", relReq)
@@ -176,14 +201,21 @@ func ReleaseAddressHandler(w http.ResponseWriter, r *http.Request, vars map[stri
 	// find the network from network id
 	nwCfg := &mastercfg.CfgNetworkState{}
 	nwCfg.StateDriver = stateDriver
-	err = nwCfg.Read(relReq.NetworkID)
-	if err != nil {
-		log.Errorf("network %s is not operational", relReq.NetworkID)
-		return nil, err
-	}
+	nwCfg.ID = relReq.NetworkID
 
 	// release addresses
-	err = networkReleaseAddress(nwCfg, relReq.IPv4Address)
+	err = updateNetworkState(nwCfg, func() error {
+		if relReq.IPv4Address != "" {
+			err := networkReleaseAddress(nwCfg, relReq.IPv4Address)
+			if err != nil {
+				return err
+			}
+		}
+		if relReq.IPv6Address != "" {
+			return networkReleaseIPv6Address(nwCfg, relReq.IPv6Address)
+		}
+		return nil
+	})
 	if err != nil {
 		log.Errorf("Failed to release address. Err: %v", err)
 		return nil, err
@@ -200,13 +232,10 @@ func CreateEndpointHandler(w http.ResponseWriter, r *http.Request, vars map[stri
 	err := json.NewDecoder(r.Body).Decode(&epReq)
 	if err != nil {
 		log.Errorf("Error decoding AllocAddressHandler. Err %v", err)
-		return nil, err
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
 	}
 
 	log.Infof("Received CreateEndpointRequest: %+v", epReq)
-	// Take a global lock for address allocation
-	addrMutex.Lock()
-	defer addrMutex.Unlock()
 
 	// Gte the state driver
 	stateDriver, err := utils.GetStateDriver()
@@ -248,7 +277,7 @@ func DeleteEndpointHandler(w http.ResponseWriter, r *http.Request, vars map[stri
 	err := json.NewDecoder(r.Body).Decode(&epdelReq)
 	if err != nil {
 		log.Errorf("Error decoding AllocAddressHandler. Err %v", err)
-		return nil, err
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
 	}
 
 	log.Infof("Received DeleteEndpointRequest: %+v", epdelReq)
@@ -278,3 +307,63 @@ func DeleteEndpointHandler(w http.ResponseWriter, r *http.Request, vars map[stri
 	// done. return resp
 	return delResp, nil
 }
+
+// ListIPLeasesHandler lists the address leases of all networks
+func ListIPLeasesHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	return ListIPLeases(stateDriver)
+}
+
+// ExpireIPLeaseHandler handles requests to remove an address lease
+func ExpireIPLeaseHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	var expireReq ExpireIPLeaseRequest
+
+	// Get object from the request
+	err := json.NewDecoder(r.Body).Decode(&expireReq)
+	if err != nil {
+		log.Errorf("Error decoding ExpireIPLeaseHandler. Err %v", err)
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
+	}
+
+	log.Infof("Received ExpireIPLeaseRequest: %+v", expireReq)
+
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ExpireIPLease(stateDriver, expireReq.NetworkID, expireReq.LeaseKey)
+	if err != nil {
+		log.Errorf("Error expiring lease %s of network %s. Err: %v",
+			expireReq.LeaseKey, expireReq.NetworkID, err)
+		return nil, err
+	}
+
+	return "success", nil
+}
+
+// ResourceUsageHandler reports the resource and quota utilisation of all
+// tenants, or of the tenant in the request
+func ResourceUsageHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetResourceUsage(stateDriver, vars["id"])
+}
+
+// IPUsageHandler reports the address utilisation of all networks, or of the
+// network in the request
+func IPUsageHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetNetworkIPUsage(stateDriver, vars["id"])
+}
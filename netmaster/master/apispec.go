@@ -0,0 +1,300 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/auth"
+)
+
+const (
+	// APIVersion is the version of the REST API served under APIVersionPrefix
+	APIVersion = "v1"
+	// APIVersionPrefix is the path prefix of the versioned REST API. The
+	// routes are also served without it, as deprecated aliases.
+	APIVersionPrefix = "/api/" + APIVersion
+	// APISpecRESTEndpoint is the REST endpoint to request the OpenAPI
+	// description of the versioned REST API
+	APISpecRESTEndpoint = "openapi.json"
+)
+
+// APIRoute is a REST route of netmaster. Path is the path of the route under
+// APIVersionPrefix, with its variables in braces. Request and Response are
+// values of the types of the request and response bodies, if the route has
+// them, which describe the route in the OpenAPI description of the API.
+// Handler serves the route; routes served by another router have none.
+// Access is the access the route needs, to the tenants Tenants returns for
+// a request; routes without Tenants access objects of no single tenant.
+type APIRoute struct {
+	Method   string
+	Path     string
+	Summary  string
+	Request  interface{}
+	Response interface{}
+	Handler  http.HandlerFunc
+	Access   auth.Access
+	Tenants  func(r *http.Request) ([]string, error)
+}
+
+// APISchema is the JSON schema of a type in an OpenAPI description
+type APISchema map[string]interface{}
+
+// APIParameter is a path parameter of an operation
+type APIParameter struct {
+	Name     string    `json:"name"`
+	In       string    `json:"in"`
+	Required bool      `json:"required"`
+	Schema   APISchema `json:"schema"`
+}
+
+// APIContent is a request or response body of an operation
+type APIContent struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]APISchema `json:"content,omitempty"`
+}
+
+// APIOperation is the description of a route
+type APIOperation struct {
+	Summary     string                 `json:"summary,omitempty"`
+	OperationID string                 `json:"operationId"`
+	Parameters  []APIParameter         `json:"parameters,omitempty"`
+	RequestBody *APIContent            `json:"requestBody,omitempty"`
+	Responses   map[string]*APIContent `json:"responses"`
+}
+
+// APIInfo is the title and version of an API
+type APIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// APIServer is the base path of the API paths
+type APIServer struct {
+	URL string `json:"url"`
+}
+
+// APIComponents has the schemas of the named types, which other schemas
+// refer to, and the ways clients authenticate
+type APIComponents struct {
+	Schemas         map[string]APISchema `json:"schemas"`
+	SecuritySchemes map[string]APISchema `json:"securitySchemes"`
+}
+
+// APISpec is an OpenAPI 3.0 description of the REST API
+type APISpec struct {
+	OpenAPI    string                              `json:"openapi"`
+	Info       APIInfo                             `json:"info"`
+	Servers    []APIServer                         `json:"servers"`
+	Paths      map[string]map[string]*APIOperation `json:"paths"`
+	Components APIComponents                       `json:"components"`
+	Security   []map[string][]string               `json:"security"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// NewAPISpec returns the OpenAPI description of the routes, with the schemas
+// of their request and response types
+func NewAPISpec(routes []APIRoute) *APISpec {
+	spec := &APISpec{
+		OpenAPI: "3.0.0",
+		Info: APIInfo{
+			Title: "netmaster",
+			Description: "The paths are also served without the " + APIVersionPrefix +
+				" prefix, as deprecated aliases.",
+			Version: APIVersion,
+		},
+		Servers: []APIServer{{URL: APIVersionPrefix}},
+		Paths:   map[string]map[string]*APIOperation{},
+		Components: APIComponents{
+			Schemas: map[string]APISchema{},
+			SecuritySchemes: map[string]APISchema{
+				"bearerToken": {"type": "http", "scheme": "bearer"},
+			},
+		},
+		// clients also authenticate with their tls certificate
+		Security: []map[string][]string{{"bearerToken": {}}, {}},
+	}
+
+	errSchema := spec.schemaOf(reflect.TypeOf(core.HTTPError{}))
+	for _, route := range routes {
+		op := &APIOperation{
+			Summary:     route.Summary,
+			OperationID: apiOperationID(route.Method, route.Path),
+			Responses: map[string]*APIContent{
+				"default": {
+					Description: "error",
+					Content:     map[string]APISchema{"application/json": errSchema},
+				},
+			},
+		}
+
+		for _, elem := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(elem, "{") && strings.HasSuffix(elem, "}") {
+				op.Parameters = append(op.Parameters, APIParameter{
+					Name:     strings.Trim(elem, "{}"),
+					In:       "path",
+					Required: true,
+					Schema:   APISchema{"type": "string"},
+				})
+			}
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &APIContent{
+				Required: true,
+				Content: map[string]APISchema{
+					"application/json": spec.schemaOf(reflect.TypeOf(route.Request)),
+				},
+			}
+		}
+
+		op.Responses["200"] = &APIContent{Description: "success"}
+		if route.Response != nil {
+			op.Responses["200"].Content = map[string]APISchema{
+				"application/json": spec.schemaOf(reflect.TypeOf(route.Response)),
+			}
+		}
+
+		if spec.Paths[route.Path] == nil {
+			spec.Paths[route.Path] = map[string]*APIOperation{}
+		}
+		spec.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	return spec
+}
+
+// apiOperationID returns the ID of the operation of a route, such as
+// getResourceUsageId for GET /resource-usage/{id}
+func apiOperationID(method, path string) string {
+	words := strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	id := strings.ToLower(method)
+	for _, word := range words {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+
+	return id
+}
+
+// apiSchemaName returns the name of the schema of a named type, such as
+// master.CreateEndpointRequest
+func apiSchemaName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// schemaOf returns the schema of the json encoding of a type. Named structs
+// are added to the components and referred to.
+func (spec *APISpec) schemaOf(t reflect.Type) APISchema {
+	if t == timeType {
+		return APISchema{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.Interface &&
+		(t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType)) {
+		// the type has its own encoding
+		return APISchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return spec.schemaOf(t.Elem())
+	case reflect.Bool:
+		return APISchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return APISchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return APISchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return APISchema{"type": "number"}
+	case reflect.String:
+		return APISchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return APISchema{"type": "string", "format": "byte"}
+		}
+		return APISchema{"type": "array", "items": spec.schemaOf(t.Elem())}
+	case reflect.Map:
+		return APISchema{"type": "object", "additionalProperties": spec.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return spec.structSchema(t)
+		}
+
+		name := apiSchemaName(t)
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			// mark the schema as added, for the types that refer to themselves
+			spec.Components.Schemas[name] = nil
+			spec.Components.Schemas[name] = spec.structSchema(t)
+		}
+		return APISchema{"$ref": "#/components/schemas/" + name}
+	default:
+		// interfaces can hold any value
+		return APISchema{}
+	}
+}
+
+// structSchema returns the schema of a struct with its fields as properties
+func (spec *APISpec) structSchema(t reflect.Type) APISchema {
+	props := map[string]APISchema{}
+	spec.addFieldSchemas(t, props)
+
+	return APISchema{"type": "object", "properties": props}
+}
+
+// addFieldSchemas adds the schemas of the fields of a struct to props, with
+// the fields of embedded structs as they are encoded
+func (spec *APISpec) addFieldSchemas(t reflect.Type, props map[string]APISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			spec.addFieldSchemas(fieldType, props)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = spec.schemaOf(field.Type)
+	}
+}
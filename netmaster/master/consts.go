@@ -35,4 +35,20 @@ const (
 	GetNetworkRESTEndpoint = "network"
 	//GetNetworksRESTEndpoint is the REST endpoint to request info of all networks
 	GetNetworksRESTEndpoint = "networks"
+	//GetIPLeasesRESTEndpoint is the REST endpoint to request the address leases
+	GetIPLeasesRESTEndpoint = "ip-leases"
+	//ExpireIPLeaseRESTEndpoint is the REST endpoint to post address lease removals
+	ExpireIPLeaseRESTEndpoint = "expire-ip-lease"
+	//GetResourceUsageRESTEndpoint is the REST endpoint to request the resource and quota utilisation of tenants
+	GetResourceUsageRESTEndpoint = "resource-usage"
+	//GetIPUsageRESTEndpoint is the REST endpoint to request the address utilisation of networks
+	GetIPUsageRESTEndpoint = "ip-usage"
+	//GetAuditRESTEndpoint is the REST endpoint to request the audit log of the configuration changes
+	GetAuditRESTEndpoint = "audit"
+	//GetEventsRESTEndpoint is the REST endpoint to stream the state change events
+	GetEventsRESTEndpoint = "events"
+	//WebhooksRESTEndpoint is the REST endpoint to register, list and remove webhooks
+	WebhooksRESTEndpoint = "webhooks"
+	//WebhookDeadLettersRESTEndpoint is the REST endpoint to request the notifications webhooks didn't get
+	WebhookDeadLettersRESTEndpoint = "webhook-dead-letters"
 )
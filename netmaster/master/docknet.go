@@ -0,0 +1,59 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/samalba/dockerclient"
+)
+
+// docknetCreate is the body of a docker network create request. It adds
+// the IPv6 flag missing from dockerclient's NetworkCreate.
+type docknetCreate struct {
+	dockerclient.NetworkCreate
+	EnableIPv6 bool `json:"EnableIPv6"`
+}
+
+// postDocknetCreate sends a network create request to docker
+func postDocknetCreate(docker *dockerclient.DockerClient, nwCreate *docknetCreate) error {
+	data, err := json.Marshal(nwCreate)
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("%s/%s/networks/create", docker.URL.String(), dockerclient.APIVersion)
+	resp, err := docker.HTTPClient.Post(uri, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return core.UnavailableErrorf("%v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusConflict {
+			return core.ConflictErrorf("%s: %s", resp.Status, msg)
+		}
+		return core.Errorf("%s: %s", resp.Status, msg)
+	}
+
+	return nil
+}
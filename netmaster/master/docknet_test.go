@@ -0,0 +1,64 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/samalba/dockerclient"
+)
+
+func TestPostDocknetCreate(t *testing.T) {
+	var body map[string]interface{}
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+dockerclient.APIVersion+"/networks/create" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("error decoding request. Error: %s", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	docker, err := dockerclient.NewDockerClient(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("error creating docker client. Error: %s", err)
+	}
+
+	nwCreate := &docknetCreate{
+		NetworkCreate: dockerclient.NetworkCreate{Name: "net1", Driver: driverName},
+		EnableIPv6:    true,
+	}
+	err = postDocknetCreate(docker, nwCreate)
+	if err != nil {
+		t.Fatalf("error creating docker network. Error: %s", err)
+	}
+	if body["name"] != "net1" || body["driver"] != driverName || body["EnableIPv6"] != true {
+		t.Fatalf("unexpected request body %+v", body)
+	}
+
+	status = http.StatusConflict
+	err = postDocknetCreate(docker, nwCreate)
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
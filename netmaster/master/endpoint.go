@@ -16,13 +16,13 @@ limitations under the License.
 package master
 
 import (
-	"fmt"
 	"net"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/netmaster/intent"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/utils"
+	"github.com/contiv/netplugin/utils/netutils"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -31,25 +31,33 @@ func validateEndpointConfig(stateDriver core.StateDriver, tenant *intent.ConfigT
 	var err error
 
 	if tenant.Name == "" {
-		return core.Errorf("null tenant name")
+		return core.InvalidErrorf("null tenant name")
 	}
 
 	for _, network := range tenant.Networks {
 		if network.Name == "" {
-			core.Errorf("null network name")
+			return core.InvalidErrorf("null network name")
 		}
 
 		for _, ep := range network.Endpoints {
 			if ep.Container == "" {
-				return core.Errorf("invalid container name for the endpoint")
+				return core.InvalidErrorf("invalid container name for the endpoint")
 			}
 			if ep.IPAddress != "" {
 				if network.SubnetCIDR != "" {
 					log.Errorf("found ep with ip for auto-allocated net")
-					return core.Errorf("found ep with ip for auto-allocated net")
+					return core.InvalidErrorf("found ep with ip for auto-allocated net")
 				}
 				if net.ParseIP(ep.IPAddress) == nil {
-					return core.Errorf("invalid ep IP")
+					return core.InvalidErrorf("invalid ep IP")
+				}
+			}
+			if ep.IPv6Address != "" && !netutils.IsIPv6(ep.IPv6Address) {
+				return core.InvalidErrorf("invalid ep IPv6 address")
+			}
+			if ep.MacAddress != "" {
+				if _, err := netutils.ParseUnicastMAC(ep.MacAddress); err != nil {
+					return err
 				}
 			}
 		}
@@ -61,18 +69,48 @@ func validateEndpointConfig(stateDriver core.StateDriver, tenant *intent.ConfigT
 func allocSetEpAddress(ep *intent.ConfigEP, epCfg *mastercfg.CfgEndpointState,
 	nwCfg *mastercfg.CfgNetworkState) (err error) {
 
-	ipAddress, err := networkAllocAddress(nwCfg, ep.IPAddress)
+	// the subnets of the network decide the addresses to allocate
+	err = nwCfg.Read(nwCfg.ID)
 	if err != nil {
-		log.Errorf("Error allocating IP address. Err: %v", err)
+		log.Errorf("Error reading network %s. Err: %v", nwCfg.ID, err)
 		return
 	}
 
-	epCfg.IPAddress = ipAddress
+	if nwCfg.SubnetIP != "" {
+		reqAddr := ep.IPAddress
+		if reqAddr == "" {
+			reqAddr = networkReservedAddress(nwCfg, ep)
+		} else {
+			err = checkReservedAddress(nwCfg, reqAddr, ep)
+			if err != nil {
+				return
+			}
+		}
+
+		epCfg.IPAddress, err = networkAllocAddress(nwCfg, reqAddr, ep.LeaseKey)
+		if err != nil {
+			log.Errorf("Error allocating IP address. Err: %v", err)
+			return
+		}
+	}
+
+	if nwCfg.IPv6Subnet != "" {
+		epCfg.IPv6Address, err = networkAllocIPv6Address(nwCfg, ep.IPv6Address)
+		if err != nil {
+			log.Errorf("Error allocating IPv6 address. Err: %v", err)
+			return
+		}
+	}
+
+	if epCfg.IPAddress == "" && epCfg.IPv6Address == "" {
+		return core.InvalidErrorf("network %s has no subnet to allocate addresses from", nwCfg.ID)
+	}
 
-	// Set mac address which is derived from IP address
-	ipAddr := net.ParseIP(ipAddress)
-	macAddr := fmt.Sprintf("02:02:%02x:%02x:%02x:%02x", ipAddr[12], ipAddr[13], ipAddr[14], ipAddr[15])
-	epCfg.MacAddress = macAddr
+	epCfg.MacAddress, err = allocEndpointMAC(nwCfg.StateDriver, nwCfg.Tenant, ep.MacAddress)
+	if err != nil {
+		log.Errorf("Error allocating mac address. Err: %v", err)
+		return
+	}
 
 	return
 }
@@ -89,33 +127,75 @@ func CreateEndpoint(stateDriver core.StateDriver, nwCfg *mastercfg.CfgNetworkSta
 		return epCfg, nil
 	}
 
+	// allocate the endpoint's address and write its state as a unit
+	epExists := false
+	err = runInTxn(stateDriver, func(txn core.Txn) error {
+		epCfg = &mastercfg.CfgEndpointState{}
+		epCfg.StateDriver = txn
+		err := epCfg.Read(getEpName(nwCfg.ID, ep))
+		if err == nil {
+			// created concurrently
+			epExists = true
+			return nil
+		}
+
+		return createEndpointState(txn, nwCfg, ep, epCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	epCfg.StateDriver = stateDriver
+
+	if epExists {
+		log.Infof("endpoint %s was created concurrently", epCfg.ID)
+	}
+
+	return epCfg, nil
+}
+
+// createEndpointState allocates the resources for an endpoint and writes its
+// state, using the passed transaction
+func createEndpointState(txn core.Txn, nwCfg *mastercfg.CfgNetworkState,
+	ep *intent.ConfigEP, epCfg *mastercfg.CfgEndpointState) error {
+	var err error
+
+	epCfg.ID = getEpName(nwCfg.ID, ep)
 	epCfg.NetID = nwCfg.ID
 	epCfg.ContName = ep.Container
 	epCfg.AttachUUID = ep.AttachUUID
 	epCfg.HomingHost = ep.Host
 	epCfg.ServiceName = ep.ServiceName
 
+	err = updateTenantUsage(txn, nwCfg.Tenant, usageEndpoints, 1, 0)
+	if err != nil {
+		return err
+	}
+
 	// Allocate addresses
-	err = allocSetEpAddress(ep, epCfg, nwCfg)
+	txnNwCfg := &mastercfg.CfgNetworkState{}
+	txnNwCfg.StateDriver = txn
+	txnNwCfg.ID = nwCfg.ID
+	err = allocSetEpAddress(ep, epCfg, txnNwCfg)
 	if err != nil {
 		log.Errorf("error allocating and/or reserving IP. Error: %s", err)
-		return nil, err
+		return err
 	}
 
 	// Set endpoint group
 	epCfg.EndpointGroupID, err = getEndpointGroupID(ep.ServiceName, nwCfg.NetworkName, nwCfg.Tenant)
 	if err != nil {
 		log.Errorf("Error getting endpoint group for %s.%s. Err: %v", ep.ServiceName, nwCfg.ID, err)
-		return nil, err
+		return err
 	}
 
-	err = epCfg.Write()
+	// Only create the endpoint if no one else did so concurrently
+	err = epCfg.WriteIfUnchanged()
 	if err != nil {
 		log.Errorf("error writing ep config. Error: %s", err)
-		return nil, err
+		return err
 	}
 
-	return epCfg, nil
+	return nil
 }
 
 // CreateEndpoints creates the endpoints for a given tenant.
@@ -143,13 +223,14 @@ func CreateEndpoints(stateDriver core.StateDriver, tenant *intent.ConfigTenant)
 				return err
 			}
 
-			nwCfg.EpCount++
-		}
-
-		err = nwCfg.Write()
-		if err != nil {
-			log.Errorf("error writing nw config. Error: %s", err)
-			return err
+			err = updateNetworkState(nwCfg, func() error {
+				nwCfg.EpCount++
+				return nil
+			})
+			if err != nil {
+				log.Errorf("error writing nw config. Error: %s", err)
+				return err
+			}
 		}
 	}
 
@@ -158,43 +239,79 @@ func CreateEndpoints(stateDriver core.StateDriver, tenant *intent.ConfigTenant)
 
 func freeEndpointResources(epCfg *mastercfg.CfgEndpointState,
 	nwCfg *mastercfg.CfgNetworkState) error {
-	if epCfg.IPAddress == "" {
+	if epCfg.MacAddress != "" {
+		err := releaseEndpointMAC(nwCfg.StateDriver, epCfg.MacAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	if epCfg.IPAddress == "" && epCfg.IPv6Address == "" {
 		return nil
 	}
 
-	return networkReleaseAddress(nwCfg, epCfg.IPAddress)
+	if epCfg.IPAddress != "" {
+		err := networkReleaseAddress(nwCfg, epCfg.IPAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	if epCfg.IPv6Address != "" {
+		err := networkReleaseIPv6Address(nwCfg, epCfg.IPv6Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nwCfg.Write()
 }
 
-// DeleteEndpointID deletes an endpoint by ID.
-func DeleteEndpointID(stateDriver core.StateDriver, epID string) (*mastercfg.CfgEndpointState, error) {
+// deleteEndpointState frees the resources of an endpoint and removes its
+// state as a unit
+func deleteEndpointState(stateDriver core.StateDriver, epID string) (*mastercfg.CfgEndpointState, error) {
 	epCfg := &mastercfg.CfgEndpointState{}
-	epCfg.StateDriver = stateDriver
-	err := epCfg.Read(epID)
-	if err != nil {
-		return nil, err
-	}
+	err := runInTxn(stateDriver, func(txn core.Txn) error {
+		epCfg = &mastercfg.CfgEndpointState{}
+		epCfg.StateDriver = txn
+		err := epCfg.Read(epID)
+		if err != nil {
+			return err
+		}
 
-	nwCfg := &mastercfg.CfgNetworkState{}
-	nwCfg.StateDriver = stateDriver
-	err = nwCfg.Read(epCfg.NetID)
-	if err != nil {
-		return nil, err
-	}
+		nwCfg := &mastercfg.CfgNetworkState{}
+		nwCfg.StateDriver = txn
+		err = nwCfg.Read(epCfg.NetID)
+		if err != nil {
+			return err
+		}
 
-	err = freeEndpointResources(epCfg, nwCfg)
-	if err != nil {
-		return nil, err
-	}
+		err = freeEndpointResources(epCfg, nwCfg)
+		if err != nil {
+			log.Errorf("error writing nw config. Error: %s", err)
+			return err
+		}
 
-	err = epCfg.Clear()
+		err = updateTenantUsage(txn, nwCfg.Tenant, usageEndpoints, -1, 0)
+		if err != nil {
+			return err
+		}
+
+		return epCfg.Clear()
+	})
 	if err != nil {
-		log.Errorf("error writing nw config. Error: %s", err)
 		return nil, err
 	}
+	epCfg.StateDriver = stateDriver
 
-	err = nwCfg.Write()
+	return epCfg, nil
+}
+
+// DeleteEndpointID deletes an endpoint by ID.
+func DeleteEndpointID(stateDriver core.StateDriver, epID string) (*mastercfg.CfgEndpointState, error) {
+	epCfg, err := deleteEndpointState(stateDriver, epID)
 	if err != nil {
-		log.Errorf("error writing nw config. Error: %s", err)
+		log.Errorf("error deleting ep state. Error: %s", err)
 		return nil, err
 	}
 
@@ -230,22 +347,11 @@ func DeleteEndpoints(stateDriver core.StateDriver, tenant *intent.ConfigTenant)
 				continue
 			}
 
-			err = freeEndpointResources(epCfg, nwCfg)
+			_, err = deleteEndpointState(stateDriver, epCfg.ID)
 			if err != nil {
+				log.Errorf("error deleting ep state. Error: %s", err)
 				continue
 			}
-
-			err = epCfg.Clear()
-			if err != nil {
-				log.Errorf("error writing ep config. Error: %s", err)
-				return err
-			}
-		}
-
-		err = nwCfg.Write()
-		if err != nil {
-			log.Errorf("error writing nw config. Error: %s", err)
-			return err
 		}
 	}
 
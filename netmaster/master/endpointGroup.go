@@ -17,19 +17,93 @@ package master
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 
+	"github.com/contiv/netplugin/contivModel"
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/gstate"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/resources"
 	"github.com/contiv/netplugin/utils"
-	"github.com/contiv/objmodel/contivModel"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+const (
+	// endpoint group IDs are carried in 15 bits of the openflow metadata
+	maxEndpointGroupID = 0x7fff
+
+	// endpoint group IDs are allocated from a single, global resource
+	epgResourceID = "global"
+)
+
+// InitEndpointGroupIDs defines the endpoint group ID resource, with the IDs of
+// the existing endpoint groups marked as in use. It replaces the resource
+// if it already exists, so that it's rebuilt on every startup.
+func InitEndpointGroupIDs() error {
+	// Get the state driver
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return err
+	}
+
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return err
+	}
+	rm := core.ResourceManager(tempRm)
+
+	epgIDs := &resources.IDPoolCfg{
+		Ranges: []resources.IDRange{{Min: 1, Max: maxEndpointGroupID}},
+	}
+
+	readEpg := &mastercfg.EndpointGroupState{}
+	readEpg.StateDriver = stateDriver
+	epgCfgs, err := readEpg.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return err
+	}
+	for _, epgCfg := range epgCfgs {
+		id, err := strconv.Atoi(epgCfg.(*mastercfg.EndpointGroupState).ID)
+		if err != nil || id <= 0 || id > maxEndpointGroupID {
+			log.Errorf("invalid endpoint group ID %q", epgCfg.(*mastercfg.EndpointGroupState).ID)
+			continue
+		}
+		epgIDs.InUse = append(epgIDs.InUse, uint(id))
+	}
+
+	// the resource doesn't exist on first startup
+	rm.UndefineResource(epgResourceID, resources.AutoEPGResource)
+
+	return rm.DefineResource(epgResourceID, resources.AutoEPGResource, epgIDs)
+}
+
+// AllocEndpointGroupID allocates a unique endpoint group ID.
+func AllocEndpointGroupID() (int, error) {
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return 0, err
+	}
+
+	epgID, err := tempRm.AllocateResourceVal(epgResourceID, resources.AutoEPGResource)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(epgID.(uint)), nil
+}
+
+// FreeEndpointGroupID releases an endpoint group ID.
+func FreeEndpointGroupID(epgID int) error {
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return err
+	}
+
+	return tempRm.DeallocateResourceVal(epgResourceID, resources.AutoEPGResource,
+		uint(epgID))
+}
+
 // getEndpointGroupID returns endpoint group Id for a service
 // It autocreates the endpoint group if it doesnt exist
 func getEndpointGroupID(serviceName, networkName, tenantName string) (int, error) {
@@ -45,7 +119,7 @@ func getEndpointGroupID(serviceName, networkName, tenantName string) (int, error
 	// See if the epg exists
 	epg := contivModel.FindEndpointGroup(epgKey)
 	if epg == nil {
-		return 0, core.Errorf("EPG not created")
+		return 0, core.NotFoundErrorf("EPG not created")
 	}
 
 	// return endpoint group id
@@ -81,36 +155,34 @@ func CreateEndpointGroup(tenantName, networkName, groupName string, epgID int) e
 
 	// params for docker network
 	if GetClusterMode() == "docker" {
-		subnetCIDR := fmt.Sprintf("%s/%d", nwCfg.SubnetIP, nwCfg.SubnetLen)
-
 		// Create each EPG as a docker network
-		err = createDockNet(tenantName, networkName, groupName, subnetCIDR, nwCfg.Gateway)
+		err = createDockNet(tenantName, networkName, groupName, nwCfg)
 		if err != nil {
 			log.Errorf("Error creating docker network for group %s.%s. Err: %v", networkName, groupName, err)
 			return err
 		}
 	}
 
-	// Get resource manager
-	tempRm, err := resources.GetStateResourceManager()
+	err = createEndpointGroupState(stateDriver, &gCfg, nwCfg, groupName, epgID)
 	if err != nil {
+		if GetClusterMode() == "docker" {
+			deleteDockNet(tenantName, networkName, groupName)
+		}
 		return err
 	}
-	rm := core.ResourceManager(tempRm)
 
-	// Create epGroup state
-	epgCfg := &mastercfg.EndpointGroupState{
-		Name:        groupName,
-		Tenant:      tenantName,
-		NetworkName: networkName,
-		PktTagType:  nwCfg.PktTagType,
-		PktTag:      nwCfg.PktTag,
-		ExtPktTag:   nwCfg.ExtPktTag,
-	}
+	return nil
+}
 
-	epgCfg.StateDriver = stateDriver
-	epgCfg.ID = strconv.Itoa(epgID)
-	log.Debugf("##Create EpGroup %v network %v tagtype %v", groupName, networkName, nwCfg.PktTagType)
+// createEndpointGroupState allocates the resources for an endpoint group and
+// writes its state, counting it against the tenant's quota, as a unit
+func createEndpointGroupState(stateDriver core.StateDriver, gCfg *gstate.Cfg,
+	nwCfg *mastercfg.CfgNetworkState, groupName string, epgID int) error {
+	// Get resource manager
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return err
+	}
 
 	// if aci mode allocate per-epg vlan. otherwise, stick to per-network vlan
 	aciMode, rErr := IsAciConfigured()
@@ -118,28 +190,56 @@ func CreateEndpointGroup(tenantName, networkName, groupName string, epgID int) e
 		return rErr
 	}
 
-	// Special handling for ACI mode
-	if aciMode {
-		if epgCfg.PktTagType != "vlan" {
-			log.Errorf("Network type must be VLAN for ACI mode")
-			return errors.New("Network type must be VLAN for ACI mode")
+	log.Debugf("##Create EpGroup %v network %v tagtype %v", groupName, nwCfg.NetworkName, nwCfg.PktTagType)
+
+	return runInTxn(stateDriver, func(txn core.Txn) error {
+		rm := core.ResourceManager(tempRm.InTxn(txn))
+
+		// Create epGroup state
+		epgCfg := &mastercfg.EndpointGroupState{
+			Name:        groupName,
+			Tenant:      gCfg.Tenant,
+			NetworkName: nwCfg.NetworkName,
+			PktTagType:  nwCfg.PktTagType,
+			PktTag:      nwCfg.PktTag,
+			ExtPktTag:   nwCfg.ExtPktTag,
+		}
+
+		epgCfg.StateDriver = txn
+		epgCfg.ID = strconv.Itoa(epgID)
+
+		// Special handling for ACI mode
+		if aciMode {
+			if epgCfg.PktTagType != "vlan" {
+				log.Errorf("Network type must be VLAN for ACI mode")
+				return errors.New("Network type must be VLAN for ACI mode")
+			}
+
+			pktTag, err := gCfg.AllocVLAN(rm)
+			if err != nil {
+				return err
+			}
+			epgCfg.PktTag = int(pktTag)
+			log.Debugf("ACI -- Allocated vlan %v for epg %v", pktTag, groupName)
+
 		}
 
-		pktTag, err := gCfg.AllocVLAN(rm)
+		err := updateTenantUsage(txn, gCfg.Tenant, usageEndpointGroups, 1, endpointGroupVLAN(epgCfg))
 		if err != nil {
 			return err
 		}
-		epgCfg.PktTag = int(pktTag)
-		log.Debugf("ACI -- Allocated vlan %v for epg %v", pktTag, groupName)
 
-	}
+		return epgCfg.Write()
+	})
+}
 
-	err = epgCfg.Write()
-	if err != nil {
-		return err
+// endpointGroupVLAN returns the vlan of an endpoint group, or zero
+func endpointGroupVLAN(epgCfg *mastercfg.EndpointGroupState) int {
+	if epgCfg.PktTagType != "vlan" {
+		return 0
 	}
 
-	return nil
+	return epgCfg.PktTag
 }
 
 // DeleteEndpointGroup handles endpoint group deletes
@@ -172,23 +272,30 @@ func DeleteEndpointGroup(epgID int) error {
 		return aErr
 	}
 
-	if aciMode {
-		tempRm, rErr := resources.GetStateResourceManager()
-		if rErr != nil {
-			return rErr
-		}
-		rm := core.ResourceManager(tempRm)
-		if epgCfg.PktTagType == "vlan" {
-			err = gCfg.FreeVLAN(rm, uint(epgCfg.PktTag))
+	tempRm, rErr := resources.GetStateResourceManager()
+	if rErr != nil {
+		return rErr
+	}
+
+	err = runInTxn(stateDriver, func(txn core.Txn) error {
+		if aciMode && epgCfg.PktTagType == "vlan" {
+			rm := core.ResourceManager(tempRm.InTxn(txn))
+			err := gCfg.FreeVLAN(rm, uint(epgCfg.PktTag))
 			if err != nil {
 				return err
 			}
 			log.Debugf("Freed vlan %v\n", epgCfg.PktTag)
 		}
-	}
 
-	// Delete endpoint group
-	err = epgCfg.Clear()
+		err := updateTenantUsage(txn, epgCfg.Tenant, usageEndpointGroups, -1, endpointGroupVLAN(epgCfg))
+		if err != nil {
+			return err
+		}
+
+		// Delete endpoint group
+		epgCfg.StateDriver = txn
+		return epgCfg.Clear()
+	})
 	if err != nil {
 		log.Errorf("error writing epGroup config. Error: %v", err)
 		return err
@@ -0,0 +1,168 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"sort"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/utils/netutils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// defaultIPLeaseHoldTime is the time a released address is held for its
+// lease, for networks that don't set one
+const defaultIPLeaseHoldTime = 10 * time.Minute
+
+// IPLeaseInfo describes the address lease of a network
+type IPLeaseInfo struct {
+	NetworkID string    // network the address belongs to
+	LeaseKey  string    // stable identity of the endpoint
+	IPAddress string    // leased address
+	InUse     bool      // true if an endpoint uses the address
+	Expires   time.Time // time a held address is released, if not in use
+}
+
+// ipLeaseHoldTime returns the time a released address of the network is held
+func ipLeaseHoldTime(nwCfg *mastercfg.CfgNetworkState) time.Duration {
+	if nwCfg.IPLeaseHoldTime > 0 {
+		return time.Duration(nwCfg.IPLeaseHoldTime) * time.Second
+	}
+
+	return defaultIPLeaseHoldTime
+}
+
+// releaseIPLease frees the address held for a lease
+func releaseIPLease(nwCfg *mastercfg.CfgNetworkState, lease *mastercfg.IPLease) {
+	ipAddrValue, err := netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, lease.IPAddress)
+	if err != nil {
+		log.Errorf("error getting host id of leased address %s. Error: %s", lease.IPAddress, err)
+		return
+	}
+
+	nwCfg.IPAllocMap.Clear(ipAddrValue)
+}
+
+// expireIPLeases frees the held addresses whose lease expired by now
+func expireIPLeases(nwCfg *mastercfg.CfgNetworkState, now time.Time) {
+	for leaseKey, lease := range nwCfg.IPLeases {
+		if lease.Expires.IsZero() || lease.Expires.After(now) {
+			continue
+		}
+
+		log.Infof("lease %s of address %s in network %s expired", leaseKey,
+			lease.IPAddress, nwCfg.ID)
+		releaseIPLease(nwCfg, lease)
+		delete(nwCfg.IPLeases, leaseKey)
+	}
+}
+
+// holdIPLease starts the hold time of the lease of an address that is being
+// released. An address that is already held keeps its hold time, as both the
+// endpoint and the docker IPAM driver release it. It returns false if the
+// address is not leased.
+func holdIPLease(nwCfg *mastercfg.CfgNetworkState, ipAddress string, now time.Time) bool {
+	for _, lease := range nwCfg.IPLeases {
+		if lease.IPAddress != ipAddress {
+			continue
+		}
+		if lease.Expires.IsZero() {
+			lease.Expires = now.Add(ipLeaseHoldTime(nwCfg))
+		}
+		return true
+	}
+
+	return false
+}
+
+// heldIPLease returns the key of the lease an address is held for, if any
+func heldIPLease(nwCfg *mastercfg.CfgNetworkState, ipAddress string) (string, bool) {
+	for leaseKey, lease := range nwCfg.IPLeases {
+		if lease.IPAddress == ipAddress && !lease.Expires.IsZero() {
+			return leaseKey, true
+		}
+	}
+
+	return "", false
+}
+
+// ListIPLeases returns the address leases of all networks
+func ListIPLeases(stateDriver core.StateDriver) ([]IPLeaseInfo, error) {
+	readNet := &mastercfg.CfgNetworkState{}
+	readNet.StateDriver = stateDriver
+	nwCfgs, err := readNet.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	leases := []IPLeaseInfo{}
+	now := time.Now()
+	for _, state := range nwCfgs {
+		nwCfg := state.(*mastercfg.CfgNetworkState)
+		for leaseKey, lease := range nwCfg.IPLeases {
+			// expired leases are only removed on the next allocation
+			if !lease.Expires.IsZero() && !lease.Expires.After(now) {
+				continue
+			}
+
+			leases = append(leases, IPLeaseInfo{
+				NetworkID: nwCfg.ID,
+				LeaseKey:  leaseKey,
+				IPAddress: lease.IPAddress,
+				InUse:     lease.Expires.IsZero(),
+				Expires:   lease.Expires,
+			})
+		}
+	}
+
+	sort.Sort(ipLeasesByKey(leases))
+	return leases, nil
+}
+
+// ExpireIPLease removes the lease of a network. A held address is freed,
+// while an address in use stays with its endpoint.
+func ExpireIPLease(stateDriver core.StateDriver, networkID, leaseKey string) error {
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = stateDriver
+	nwCfg.ID = networkID
+
+	return updateNetworkState(nwCfg, func() error {
+		lease, ok := nwCfg.IPLeases[leaseKey]
+		if !ok {
+			return core.NotFoundErrorf("lease %s not found in network %s", leaseKey, networkID)
+		}
+
+		if !lease.Expires.IsZero() {
+			releaseIPLease(nwCfg, lease)
+		}
+		delete(nwCfg.IPLeases, leaseKey)
+		return nil
+	})
+}
+
+type ipLeasesByKey []IPLeaseInfo
+
+func (l ipLeasesByKey) Len() int      { return len(l) }
+func (l ipLeasesByKey) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l ipLeasesByKey) Less(i, j int) bool {
+	if l[i].NetworkID != l[j].NetworkID {
+		return l[i].NetworkID < l[j].NetworkID
+	}
+	return l[i].LeaseKey < l[j].LeaseKey
+}
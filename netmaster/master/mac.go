@@ -0,0 +1,289 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/gstate"
+	"github.com/contiv/netplugin/netmaster/intent"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/resources"
+	"github.com/contiv/netplugin/utils/netutils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// defaultMACPrefix is the prefix of the endpoint mac addresses, when neither
+// the global config nor the tenant sets one
+const defaultMACPrefix = "02:02"
+
+// staticMACID is the ID of the static mac resource
+const staticMACID = "static"
+
+// MAC addresses are allocated from a resource per prefix, whose ID is the
+// prefix. Tenants with the same prefix share the resource, and prefixes can't
+// overlap unless they are the same, so that addresses are unique across the
+// whole L2 domain. A static address with one of the prefixes is allocated
+// from its resource, so it's not allocated again, and the other static
+// addresses are tracked by the static mac resource.
+
+// newMACResource returns a mac resource with the description, to be read
+func newMACResource(stateDriver core.StateDriver, desc string) *resources.AutoMACCfgResource {
+	macRsrc := &resources.AutoMACCfgResource{}
+	macRsrc.SetDescription(desc)
+	macRsrc.StateDriver = stateDriver
+	return macRsrc
+}
+
+// readMACPrefixes returns the prefixes of the mac resources
+func readMACPrefixes(stateDriver core.StateDriver) ([]string, error) {
+	readMAC := newMACResource(stateDriver, resources.AutoMACResource)
+	macRsrcs, err := readAllOrNone(readMAC.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes := []string{}
+	for _, rsrc := range macRsrcs {
+		prefixes = append(prefixes, rsrc.(*resources.AutoMACCfgResource).ID)
+	}
+
+	return prefixes, nil
+}
+
+// validateMACPrefix fails if the prefix is invalid or overlaps the prefix of
+// a mac resource without being the same
+func validateMACPrefix(stateDriver core.StateDriver, prefix string) error {
+	prefix, err := netutils.ParseMACPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	prefixes, err := readMACPrefixes(stateDriver)
+	if err != nil {
+		return err
+	}
+
+	for _, usedPrefix := range prefixes {
+		if usedPrefix != prefix && netutils.MACPrefixesOverlap(usedPrefix, prefix) {
+			return core.ConflictErrorf("mac prefix %s overlaps mac prefix %s in use", prefix, usedPrefix)
+		}
+	}
+
+	return nil
+}
+
+// globalMACPrefix returns the mac prefix of the global config
+func globalMACPrefix(stateDriver core.StateDriver) (string, error) {
+	masterGc := &mastercfg.GlobConfig{}
+	masterGc.StateDriver = stateDriver
+	err := masterGc.Read("config")
+	if core.ErrIfKeyExists(err) != nil {
+		return "", err
+	}
+
+	if err != nil || masterGc.MACPrefix == "" {
+		return defaultMACPrefix, nil
+	}
+
+	return masterGc.MACPrefix, nil
+}
+
+// tenantMACPrefix returns the mac prefix of the tenant's endpoints
+func tenantMACPrefix(stateDriver core.StateDriver, tenantName string) (string, error) {
+	gCfg := &gstate.Cfg{}
+	gCfg.StateDriver = stateDriver
+	err := gCfg.Read(tenantName)
+	if err != nil {
+		log.Errorf("error reading tenant cfg state. Error: %s", err)
+		return "", err
+	}
+
+	if gCfg.Auto.MACPrefix != "" {
+		return gCfg.Auto.MACPrefix, nil
+	}
+
+	return globalMACPrefix(stateDriver)
+}
+
+// tenantMACPrefixCfg returns the mac prefix of the tenant to keep in its
+// config. It fails if the prefix overlaps a prefix in use.
+func tenantMACPrefixCfg(stateDriver core.StateDriver, tenant *intent.ConfigTenant) (string, error) {
+	if tenant.MACPrefix == "" {
+		return "", nil
+	}
+
+	err := validateMACPrefix(stateDriver, tenant.MACPrefix)
+	if err != nil {
+		log.Errorf("error validating mac prefix '%s'. Error: %s", tenant.MACPrefix, err)
+		return "", err
+	}
+
+	return netutils.ParseMACPrefix(tenant.MACPrefix)
+}
+
+// macPrefixOf returns the prefix of the address among prefixes, if any
+func macPrefixOf(mac string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if netutils.MACHasPrefix(mac, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// readMACResource returns the mac resource of the description and ID,
+// defining it if it doesn't exist with the addresses of the existing
+// endpoints that belong to it in use. These are the addresses with the
+// prefix for an auto-mac resource, and the addresses with none of the
+// prefixes for the static mac resource.
+func readMACResource(stateDriver core.StateDriver, desc, id string) (*resources.AutoMACCfgResource, error) {
+	macRsrc := newMACResource(stateDriver, desc)
+	err := macRsrc.Read(id)
+	if core.ErrIfKeyExists(err) != nil || err == nil {
+		return macRsrc, err
+	}
+
+	prefixes := []string{id}
+	if desc == resources.StaticMACResource {
+		prefixes, err = readMACPrefixes(stateDriver)
+	} else {
+		err = validateMACPrefix(stateDriver, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	readEp := &mastercfg.CfgEndpointState{}
+	readEp.StateDriver = stateDriver
+	epCfgs, err := readAllOrNone(readEp.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := []string{}
+	for _, state := range epCfgs {
+		epCfg := state.(*mastercfg.CfgEndpointState)
+		if epCfg.MacAddress == "" {
+			continue
+		}
+		_, belongs := macPrefixOf(epCfg.MacAddress, prefixes)
+		if desc == resources.StaticMACResource {
+			belongs = !belongs
+		}
+		if belongs {
+			inUse = append(inUse, epCfg.MacAddress)
+		}
+	}
+
+	macRsrc.ID = id
+	err = macRsrc.Init(inUse)
+	if err != nil {
+		return nil, err
+	}
+
+	return macRsrc, nil
+}
+
+// allocStaticMAC allocates a mac address set for an endpoint, from the
+// resource of its prefix or the static mac resource. It fails if the
+// address is already allocated.
+func allocStaticMAC(stateDriver core.StateDriver, reqMAC string) (string, error) {
+	mac, err := netutils.ParseUnicastMAC(reqMAC)
+	if err != nil {
+		return "", err
+	}
+
+	prefixes, err := readMACPrefixes(stateDriver)
+	if err != nil {
+		return "", err
+	}
+
+	desc, id := resources.StaticMACResource, staticMACID
+	if prefix, ok := macPrefixOf(mac, prefixes); ok {
+		desc, id = resources.AutoMACResource, prefix
+	}
+
+	macRsrc, err := readMACResource(stateDriver, desc, id)
+	if err != nil {
+		log.Errorf("error reading %s resource %s. Error: %s", desc, id, err)
+		return "", err
+	}
+	err = macRsrc.AllocateMAC(mac)
+	if err != nil {
+		return "", err
+	}
+
+	return mac, nil
+}
+
+// allocEndpointMAC allocates the mac address of an endpoint of the tenant,
+// reqMAC being the address set for the endpoint if any
+func allocEndpointMAC(stateDriver core.StateDriver, tenantName, reqMAC string) (string, error) {
+	if reqMAC != "" {
+		return allocStaticMAC(stateDriver, reqMAC)
+	}
+
+	prefix, err := tenantMACPrefix(stateDriver, tenantName)
+	if err != nil {
+		return "", err
+	}
+
+	macRsrc, err := readMACResource(stateDriver, resources.AutoMACResource, prefix)
+	if err != nil {
+		log.Errorf("error reading mac resource for prefix %s. Error: %s", prefix, err)
+		return "", err
+	}
+
+	mac, err := macRsrc.Allocate()
+	if err != nil {
+		return "", err
+	}
+
+	return mac.(string), nil
+}
+
+// releaseEndpointMAC releases the mac address of an endpoint. The address
+// is released from the static mac resource too, in case it was allocated
+// before a prefix that includes it was in use.
+func releaseEndpointMAC(stateDriver core.StateDriver, mac string) error {
+	prefixes, err := readMACPrefixes(stateDriver)
+	if err != nil {
+		return err
+	}
+
+	descIDs := map[string]string{resources.StaticMACResource: staticMACID}
+	if prefix, ok := macPrefixOf(mac, prefixes); ok {
+		descIDs[resources.AutoMACResource] = prefix
+	}
+
+	for desc, id := range descIDs {
+		macRsrc := newMACResource(stateDriver, desc)
+		err = macRsrc.Read(id)
+		if core.ErrIfKeyExists(err) != nil {
+			return err
+		} else if err != nil {
+			continue
+		}
+		err = macRsrc.Deallocate(mac)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
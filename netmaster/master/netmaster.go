@@ -32,25 +32,57 @@ import (
 )
 
 const (
-	defaultInfraNetName = "infra"
-	defaultSkyDNSImage  = "skynetservices/skydns:latest"
+	defaultInfraNetName  = "infra"
+	defaultSkyDNSImage   = "skynetservices/skydns:latest"
+	defaultIPv6SubnetLen = 64
 )
 
 // Run Time config of netmaster
 type nmRunTimeConf struct {
 	clusterMode string
+	txnFence    func(txn core.Txn) error
 }
 
 var masterRTCfg nmRunTimeConf
 
 func checkPktTagType(pktTagType string) error {
 	if pktTagType != "" && pktTagType != "vlan" && pktTagType != "vxlan" {
-		return core.Errorf("invalid pktTagType")
+		return core.InvalidErrorf("invalid pktTagType")
 	}
 
 	return nil
 }
 
+// runInTxn runs fn in a state transaction and commits it, fenced by the
+// transaction fence if one is set. fn is run again in a new transaction if
+// the commit fails due to a concurrent update.
+func runInTxn(stateDriver core.StateDriver, fn func(txn core.Txn) error) error {
+	return core.RetryOnVersionConflict(func() error {
+		txn, err := stateDriver.NewTxn()
+		if err != nil {
+			return err
+		}
+
+		err = fn(txn)
+		if err == nil && masterRTCfg.txnFence != nil {
+			err = masterRTCfg.txnFence(txn)
+		}
+		if err != nil {
+			txn.Abort()
+			return err
+		}
+
+		return txn.Commit()
+	})
+}
+
+// SetTxnFence sets the check run on the state transactions before they are
+// committed, which keeps a netmaster that lost the leadership from
+// committing them
+func SetTxnFence(fence func(txn core.Txn) error) {
+	masterRTCfg.txnFence = fence
+}
+
 // SetClusterMode sets the cluster mode for the contiv plugin
 func SetClusterMode(cm string) error {
 	switch cm {
@@ -58,7 +90,7 @@ func SetClusterMode(cm string) error {
 	case "kubernetes":
 		break
 	default:
-		return core.Errorf("%s not a valid cluster mode {docker | kubernetes}", cm)
+		return core.InvalidErrorf("%s not a valid cluster mode {docker | kubernetes}", cm)
 	}
 
 	masterRTCfg.clusterMode = cm
@@ -72,7 +104,7 @@ func GetClusterMode() string {
 
 func validateTenantConfig(tenant *intent.ConfigTenant) error {
 	if tenant.Name == "" {
-		return core.Errorf("invalid tenant name")
+		return core.InvalidErrorf("invalid tenant name")
 	}
 
 	if err := checkPktTagType(tenant.DefaultNetType); err != nil {
@@ -81,7 +113,14 @@ func validateTenantConfig(tenant *intent.ConfigTenant) error {
 
 	if tenant.SubnetPool != "" {
 		if _, _, err := net.ParseCIDR(tenant.SubnetPool); err != nil {
-			return err
+			return core.InvalidErrorf("invalid subnet pool %s. Error: %s", tenant.SubnetPool, err)
+		}
+	}
+
+	if tenant.IPv6SubnetPool != "" {
+		subnetIP, _, err := netutils.ParseCIDR(tenant.IPv6SubnetPool)
+		if err != nil || !netutils.IsIPv6(subnetIP) {
+			return core.InvalidErrorf("invalid ipv6 subnet pool %s", tenant.IPv6SubnetPool)
 		}
 	}
 
@@ -99,6 +138,18 @@ func validateTenantConfig(tenant *intent.ConfigTenant) error {
 		}
 	}
 
+	if tenant.MACPrefix != "" {
+		if _, err := netutils.ParseMACPrefix(tenant.MACPrefix); err != nil {
+			log.Errorf("error parsing mac prefix '%s'. Error: %s", tenant.MACPrefix, err)
+			return err
+		}
+	}
+
+	if tenant.MaxNetworks < 0 || tenant.MaxEndpoints < 0 ||
+		tenant.MaxEndpointGroups < 0 || tenant.MaxVLANs < 0 {
+		return core.InvalidErrorf("invalid quota for tenant %s", tenant.Name)
+	}
+
 	return nil
 }
 
@@ -108,6 +159,16 @@ func CreateGlobal(stateDriver core.StateDriver, gc *intent.ConfigGlobal) error {
 	masterGc := &mastercfg.GlobConfig{}
 	masterGc.StateDriver = stateDriver
 	masterGc.NwInfraType = gc.NwInfraType
+
+	if gc.MACPrefix != "" {
+		err := validateMACPrefix(stateDriver, gc.MACPrefix)
+		if err != nil {
+			log.Errorf("error validating mac prefix '%s'. Error: %s", gc.MACPrefix, err)
+			return err
+		}
+		masterGc.MACPrefix, _ = netutils.ParseMACPrefix(gc.MACPrefix)
+	}
+
 	err := masterGc.Write()
 	return err
 }
@@ -136,6 +197,13 @@ func CreateTenant(stateDriver core.StateDriver, tenant *intent.ConfigTenant) err
 	gCfg.Auto.VLANs = tenant.VLANs
 	gCfg.Auto.VXLANs = tenant.VXLANs
 	gCfg.Auto.AllocSubnetLen = tenant.AllocSubnetLen
+	setTenantIPv6SubnetPool(gCfg, tenant)
+	gCfg.Quota = tenantQuota(tenant)
+
+	gCfg.Auto.MACPrefix, err = tenantMACPrefixCfg(stateDriver, tenant)
+	if err != nil {
+		return err
+	}
 
 	tempRm, err := resources.GetStateResourceManager()
 	if err != nil {
@@ -162,7 +230,77 @@ func CreateTenant(stateDriver core.StateDriver, tenant *intent.ConfigTenant) err
 		return err
 	}
 
-	return nil
+	return initTenantUsage(stateDriver, tenant.Name)
+}
+
+// UpdateTenant updates the tenant's state according to the passed
+// ConfigTenant. Its vlan, vxlan and subnet pools are resized keeping the
+// values in use, and the update fails if a value in use would be left out.
+// The tenant's default net type is kept when none is passed.
+func UpdateTenant(stateDriver core.StateDriver, tenant *intent.ConfigTenant) error {
+	err := validateTenantConfig(tenant)
+	if err != nil {
+		return err
+	}
+
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return err
+	}
+
+	return runInTxn(stateDriver, func(txn core.Txn) error {
+		prevCfg := &gstate.Cfg{}
+		prevCfg.StateDriver = txn
+		err := prevCfg.Read(tenant.Name)
+		if err != nil {
+			log.Errorf("error reading tenant '%s'. Error: %s", tenant.Name, err)
+			return err
+		}
+
+		gCfg := &gstate.Cfg{}
+		gCfg.StateDriver = txn
+		gCfg.Version = prevCfg.Version
+		gCfg.Tenant = tenant.Name
+		gCfg.NwInfraMode = prevCfg.NwInfraMode
+		gCfg.Deploy.DefaultNetType = tenant.DefaultNetType
+		if gCfg.Deploy.DefaultNetType == "" {
+			gCfg.Deploy.DefaultNetType = prevCfg.Deploy.DefaultNetType
+		}
+		gCfg.Deploy.DefaultNetwork = tenant.DefaultNetwork
+		gCfg.Auto.SubnetPool, gCfg.Auto.SubnetLen, _ = netutils.ParseCIDR(tenant.SubnetPool)
+		gCfg.Auto.VLANs = tenant.VLANs
+		gCfg.Auto.VXLANs = tenant.VXLANs
+		gCfg.Auto.AllocSubnetLen = tenant.AllocSubnetLen
+		setTenantIPv6SubnetPool(gCfg, tenant)
+		gCfg.Quota = tenantQuota(tenant)
+
+		gCfg.Auto.MACPrefix, err = tenantMACPrefixCfg(txn, tenant)
+		if err != nil {
+			return err
+		}
+
+		err = gCfg.Update(tempRm.InTxn(txn), prevCfg)
+		if err != nil {
+			log.Errorf("Error updating the config %+v. Error: %s", gCfg, err)
+			return err
+		}
+
+		return gCfg.Write()
+	})
+}
+
+// setTenantIPv6SubnetPool sets the IPv6 subnet pool of the tenant config.
+// The subnets are /64 unless a length is set.
+func setTenantIPv6SubnetPool(gCfg *gstate.Cfg, tenant *intent.ConfigTenant) {
+	if tenant.IPv6SubnetPool == "" {
+		return
+	}
+
+	gCfg.Auto.IPv6SubnetPool, gCfg.Auto.IPv6SubnetLen, _ = netutils.ParseCIDR(tenant.IPv6SubnetPool)
+	gCfg.Auto.AllocIPv6SubnetLen = tenant.AllocIPv6SubnetLen
+	if gCfg.Auto.AllocIPv6SubnetLen == 0 {
+		gCfg.Auto.AllocIPv6SubnetLen = defaultIPv6SubnetLen
+	}
 }
 
 func startServiceContainer(tenantName string) error {
@@ -286,6 +424,14 @@ func DeleteTenantID(stateDriver core.StateDriver, tenantID string) error {
 		log.Errorf("error deleting oper for tenant %q: Error: %s", tenantID, err)
 	}
 
+	usage := &mastercfg.TenantUsageState{}
+	usage.StateDriver = stateDriver
+	usage.ID = tenantID
+	err = usage.Clear()
+	if err != nil {
+		log.Errorf("error deleting usage for tenant %q: Error: %s", tenantID, err)
+	}
+
 	return err
 }
 
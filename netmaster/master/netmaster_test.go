@@ -19,14 +19,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/gstate"
 	"github.com/contiv/netplugin/netmaster/intent"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/resources"
 	"github.com/contiv/netplugin/state"
 	"github.com/contiv/netplugin/utils"
+	"github.com/contiv/netplugin/utils/netutils"
 )
 
 var fakeDriver *state.FakeStateDriver
@@ -436,6 +442,38 @@ func TestConfigPktTagOutOfRange(t *testing.T) {
     }]}`)
 	applyVerifyRangeTag(t, CfgBytes, true)
 
+	CfgBytes = []byte(`{
+    "Tenants" : [{
+        "Name"                  : "tenant9",
+        "DefaultNetType"        : "vxlan",
+        "SubnetPool"            : "11.1.0.0/16",
+        "AllocSubnetLen"        : 24,
+        "Vlans"                 : "1201-1500",
+        "Vxlans"                : "5001-5100,2001-2100",
+        "Networks"  : [{
+            "Name"              : "net9",
+            "PktTag"            : 5050,
+            "PktTagType"        : "vxlan"
+        }]
+    }]}`)
+	applyVerifyRangeTag(t, CfgBytes, false)
+
+	CfgBytes = []byte(`{
+    "Tenants" : [{
+        "Name"                  : "tenant10",
+        "DefaultNetType"        : "vxlan",
+        "SubnetPool"            : "11.1.0.0/16",
+        "AllocSubnetLen"        : 24,
+        "Vlans"                 : "1201-1500",
+        "Vxlans"                : "5001-5100,2001-2100",
+        "Networks"  : [{
+            "Name"              : "net10",
+            "PktTag"            : 3000,
+            "PktTagType"        : "vxlan"
+        }]
+    }]}`)
+	applyVerifyRangeTag(t, CfgBytes, true)
+
 }
 
 func applyVerifyRangeTag(t *testing.T, cfgBytes []byte, shouldFail bool) {
@@ -481,3 +519,1043 @@ func applyVerifyRangeTag(t *testing.T, cfgBytes []byte, shouldFail bool) {
 	}
 
 }
+
+func TestConcurrentAddressAlloc(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	const numAllocators = 4
+	const numAllocs = 7
+
+	// each allocator works off its own copy of the network state, the way
+	// two netmasters or two concurrent requests would
+	addrs := make(chan string, numAllocators*numAllocs)
+	errs := make(chan error, numAllocators)
+	for i := 0; i < numAllocators; i++ {
+		go func() {
+			nwCfg := &mastercfg.CfgNetworkState{}
+			nwCfg.StateDriver = fakeDriver
+			err := nwCfg.Read("orange.tenant-one")
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			for j := 0; j < numAllocs; j++ {
+				addr, err := networkAllocAddress(nwCfg, "", "")
+				if err != nil {
+					errs <- err
+					return
+				}
+				addrs <- addr
+			}
+			errs <- nil
+		}()
+	}
+
+	for i := 0; i < numAllocators; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("error allocating address. Error: %s", err)
+		}
+	}
+	close(addrs)
+
+	allocated := map[string]bool{}
+	for addr := range addrs {
+		if allocated[addr] {
+			t.Fatalf("address %s was allocated more than once", addr)
+		}
+		allocated[addr] = true
+	}
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	if err := nwCfg.Read("orange.tenant-one"); err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	if count := nwCfg.IPAllocMap.Count(); count < numAllocators*numAllocs {
+		t.Fatalf("expected at least %d addresses in use, found %d",
+			numAllocators*numAllocs, count)
+	}
+}
+
+func TestUpdateNetworkStateConflict(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	if err := nwCfg.Read("orange.tenant-one"); err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+
+	// the first attempt loses the race to another writer, after adding to
+	// the maps of the state
+	attempts := 0
+	err := updateNetworkState(nwCfg, func() error {
+		attempts++
+		if attempts == 1 {
+			other := &mastercfg.CfgNetworkState{}
+			other.StateDriver = fakeDriver
+			if err := other.Read(nwCfg.ID); err != nil {
+				return err
+			}
+			if err := other.Write(); err != nil {
+				return err
+			}
+
+			nwCfg.IPAMAddrs = map[string]bool{"11.1.0.5": true}
+			nwCfg.IPReservations = map[string]string{"stale": "11.1.0.6"}
+			nwCfg.IPLeases = map[string]*mastercfg.IPLease{"stale": {IPAddress: "11.1.0.7"}}
+			return nil
+		}
+
+		if nwCfg.IPAMAddrs != nil || nwCfg.IPReservations != nil || nwCfg.IPLeases != nil {
+			t.Fatalf("retry started from the state of the failed attempt %+v", nwCfg)
+		}
+		nwCfg.IPReservations = map[string]string{"fresh": "11.1.0.8"}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("update took %d attempts. Error: %v", attempts, err)
+	}
+
+	readNw := &mastercfg.CfgNetworkState{}
+	readNw.StateDriver = fakeDriver
+	if err := readNw.Read(nwCfg.ID); err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	if readNw.IPAMAddrs != nil || readNw.IPLeases != nil ||
+		!reflect.DeepEqual(readNw.IPReservations, map[string]string{"fresh": "11.1.0.8"}) {
+		t.Fatalf("stale allocations of the failed attempt were written: %+v", readNw)
+	}
+}
+
+func TestCreateNetworkFailureFreesResources(t *testing.T) {
+	// the pool only has room for a single subnet
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/24",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange"
+        },
+        {
+            "Name"                  : "purple"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	cfg := &intent.Config{}
+	err := json.Unmarshal(cfgBytes, cfg)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s'\n", err, cfgBytes)
+	}
+
+	_, err = resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	tenant := cfg.Tenants[0]
+	err = CreateTenant(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' creating tenant\n", err)
+	}
+
+	err = CreateNetworks(fakeDriver, &tenant)
+	if err == nil {
+		t.Fatalf("CreateNetworks did not fail on subnet exhaustion\n")
+	}
+
+	verifyKeys(t, []string{"orange"})
+	verifyKeysDoNotExist(t, []string{"purple"})
+
+	// the vlan allocated for the failed network must have been released
+	vlanOper := &resources.AutoVLANOperResource{}
+	vlanOper.StateDriver = fakeDriver
+	err = vlanOper.Read("tenant-one")
+	if err != nil {
+		t.Fatalf("error reading vlan resource. Error: %s", err)
+	}
+	if count := 18 - vlanOper.FreeVLANs.Count(); count != 1 {
+		t.Fatalf("expected 1 vlan in use, found %d", count)
+	}
+}
+
+func TestEndpointGroupIDs(t *testing.T) {
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	_, err := resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	// an endpoint group that existed before the restart
+	epgCfg := &mastercfg.EndpointGroupState{}
+	epgCfg.StateDriver = fakeDriver
+	epgCfg.ID = "2"
+	if err := epgCfg.Write(); err != nil {
+		t.Fatalf("error writing epg state. Error: %s", err)
+	}
+
+	if err := InitEndpointGroupIDs(); err != nil {
+		t.Fatalf("error initializing epg IDs. Error: %s", err)
+	}
+
+	expIDs := []int{1, 3, 4}
+	for _, expID := range expIDs {
+		epgID, err := AllocEndpointGroupID()
+		if err != nil {
+			t.Fatalf("error allocating epg ID. Error: %s", err)
+		}
+		if epgID != expID {
+			t.Fatalf("allocated epg ID %d, expected %d", epgID, expID)
+		}
+	}
+
+	if err := FreeEndpointGroupID(3); err != nil {
+		t.Fatalf("error freeing epg ID. Error: %s", err)
+	}
+	if epgID, _ := AllocEndpointGroupID(); epgID != 3 {
+		t.Fatalf("allocated epg ID %d, expected the freed ID 3", epgID)
+	}
+
+	// the IDs in use are rebuilt from the endpoint group state
+	if err := InitEndpointGroupIDs(); err != nil {
+		t.Fatalf("error initializing epg IDs. Error: %s", err)
+	}
+	if epgID, _ := AllocEndpointGroupID(); epgID != 1 {
+		t.Fatalf("allocated epg ID %d after rebuild, expected 1", epgID)
+	}
+}
+
+func TestNetworkIPExclusionsAndReservations(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange",
+            "SubnetCIDR"            : "12.1.1.0/24",
+            "Gateway"               : "12.1.1.254",
+            "IPExclusions"          : "12.1.1.1-12.1.1.3,12.1.1.6",
+            "IPReservations"        : {
+                "web"               : "12.1.1.4",
+                "db"                : "12.1.1.5"
+            },
+            "Endpoints" : [{
+                "Container"         : "myContainer1"
+            },
+            {
+                "Container"         : "web"
+            }]
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	expAddrs := map[string]string{
+		"orange.tenant-one-myContainer1": "12.1.1.7",
+		"orange.tenant-one-web":          "12.1.1.4",
+	}
+	for epID, expAddr := range expAddrs {
+		epCfg := &mastercfg.CfgEndpointState{}
+		epCfg.StateDriver = fakeDriver
+		err := epCfg.Read(epID)
+		if err != nil {
+			t.Fatalf("error reading endpoint %s. Error: %s", epID, err)
+		}
+		if epCfg.IPAddress != expAddr {
+			t.Fatalf("expected endpoint %s to get %s, got %s", epID, expAddr, epCfg.IPAddress)
+		}
+	}
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err := nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	ep := &intent.ConfigEP{Container: "myContainer2", ServiceName: "db"}
+	if addr := networkReservedAddress(nwCfg, ep); addr != "12.1.1.5" {
+		t.Fatalf("expected 12.1.1.5 to be reserved for service db, got %q", addr)
+	}
+	if addr, err := networkAllocAddress(nwCfg, "12.1.1.2", ""); err == nil {
+		t.Fatalf("allocated excluded address %s", addr)
+	}
+
+	// releasing an excluded address must not make it available
+	err = networkReleaseAddress(nwCfg, "12.1.1.6")
+	if err != nil {
+		t.Fatalf("error releasing address. Error: %s", err)
+	}
+	addr, err := networkAllocAddress(nwCfg, "", "")
+	if err != nil || addr != "12.1.1.8" {
+		t.Fatalf("expected 12.1.1.8 to be allocated, got %s. Error: %v", addr, err)
+	}
+	if addr, err = networkAllocAddress(nwCfg, "12.1.1.7", ""); core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict allocating 12.1.1.7 which is in use, got %s. Error: %v", addr, err)
+	}
+
+	_, err = resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	// the address reserved for a service goes to a single endpoint of it
+	epCfg := &mastercfg.CfgEndpointState{}
+	err = allocSetEpAddress(&intent.ConfigEP{Container: "db1", ServiceName: "db"}, epCfg, nwCfg)
+	if err != nil || epCfg.IPAddress != "12.1.1.5" {
+		t.Fatalf("expected endpoint db1 to get 12.1.1.5, got %s. Error: %v", epCfg.IPAddress, err)
+	}
+	err = allocSetEpAddress(&intent.ConfigEP{Container: "db2", ServiceName: "db"},
+		&mastercfg.CfgEndpointState{}, nwCfg)
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict for a second endpoint of service db, got %v", err)
+	}
+
+	// an address reserved for another name can not be requested
+	err = allocSetEpAddress(&intent.ConfigEP{Container: "app1", IPAddress: "12.1.1.4"},
+		&mastercfg.CfgEndpointState{}, nwCfg)
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict requesting the address reserved for web, got %v", err)
+	}
+}
+
+func TestIPLeases(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange",
+            "SubnetCIDR"            : "12.1.1.0/24",
+            "Gateway"               : "12.1.1.254",
+            "IPLeaseHoldTime"       : 60
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err := nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+
+	createEp := func(container, leaseKey string) string {
+		epCfg, err := CreateEndpoint(fakeDriver, nwCfg,
+			&intent.ConfigEP{Container: container, LeaseKey: leaseKey})
+		if err != nil {
+			t.Fatalf("error creating endpoint %s. Error: %s", container, err)
+		}
+		return epCfg.IPAddress
+	}
+	deleteEp := func(container string) {
+		_, err := DeleteEndpointID(fakeDriver, "orange.tenant-one-"+container)
+		if err != nil {
+			t.Fatalf("error deleting endpoint %s. Error: %s", container, err)
+		}
+	}
+
+	addr := createEp("db-0-a", "db-0")
+	deleteEp("db-0-a")
+
+	// the held address is not handed to other endpoints
+	if other := createEp("web-a", ""); other == addr {
+		t.Fatalf("held address %s was allocated to another endpoint", addr)
+	}
+
+	if newAddr := createEp("db-0-b", "db-0"); newAddr != addr {
+		t.Fatalf("expected lease db-0 to get %s again, got %s", addr, newAddr)
+	}
+
+	leases, err := ListIPLeases(fakeDriver)
+	if err != nil {
+		t.Fatalf("error listing leases. Error: %s", err)
+	}
+	if len(leases) != 1 || leases[0].LeaseKey != "db-0" || leases[0].IPAddress != addr ||
+		!leases[0].InUse {
+		t.Fatalf("unexpected leases %+v", leases)
+	}
+
+	// a forcibly expired lease frees its held address
+	deleteEp("db-0-b")
+	err = ExpireIPLease(fakeDriver, "orange.tenant-one", "db-0")
+	if err != nil {
+		t.Fatalf("error expiring lease. Error: %s", err)
+	}
+	if other := createEp("web-b", ""); other != addr {
+		t.Fatalf("expected expired lease address %s to be allocated, got %s", addr, other)
+	}
+
+	// an address leased by the docker IPAM driver stays held once both the
+	// endpoint and the IPAM driver release it
+	err = nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	addr, err = networkAllocIPAMAddress(nwCfg, "", "mac:02:02:0c:01:01:10", false)
+	if err != nil {
+		t.Fatalf("error allocating IPAM address. Error: %s", err)
+	}
+	epCfg, err := CreateEndpoint(fakeDriver, nwCfg,
+		&intent.ConfigEP{Container: "cache-a", IPAddress: addr})
+	if err != nil {
+		t.Fatalf("error claiming IPAM address %s. Error: %s", addr, err)
+	}
+	if epCfg.IPAddress != addr {
+		t.Fatalf("expected endpoint to claim %s, got %s", addr, epCfg.IPAddress)
+	}
+	deleteEp("cache-a")
+	err = updateNetworkState(nwCfg, func() error {
+		return networkReleaseAddress(nwCfg, addr)
+	})
+	if err != nil {
+		t.Fatalf("error releasing IPAM address. Error: %s", err)
+	}
+	hostID, _ := netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, addr)
+	if !nwCfg.IPAllocMap.Test(hostID) {
+		t.Fatalf("held address %s was freed by the second release", addr)
+	}
+
+	// a requested address held for another lease is refused
+	_, err = networkAllocIPAMAddress(nwCfg, addr, "", false)
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected conflict requesting held address %s, got %v", addr, err)
+	}
+	_, err = networkAllocIPAMAddress(nwCfg, addr, "mac:02:02:0c:01:01:11", false)
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected conflict requesting held address %s, got %v", addr, err)
+	}
+	newAddr, err := networkAllocIPAMAddress(nwCfg, addr, "mac:02:02:0c:01:01:10", false)
+	if err != nil || newAddr != addr {
+		t.Fatalf("expected lease to get %s again, got %s. Error: %v", addr, newAddr, err)
+	}
+
+	// held addresses are freed once the hold time passes
+	addr = createEp("db-1-a", "db-1")
+	deleteEp("db-1-a")
+	err = nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	expireIPLeases(nwCfg, time.Now().Add(2*time.Minute))
+	if _, ok := nwCfg.IPLeases["db-1"]; ok {
+		t.Fatalf("lease db-1 did not expire")
+	}
+	hostID, _ = netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, addr)
+	if nwCfg.IPAllocMap.Test(hostID) {
+		t.Fatalf("address %s of expired lease is still in use", addr)
+	}
+}
+
+func TestIPv6AddressAlloc(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange",
+            "IPv6SubnetCIDR"        : "2001:db8::/126",
+            "IPv6Gateway"           : "2001:db8::3"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err := nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+	if nwCfg.SubnetIP != "" {
+		t.Fatalf("ipv6 only network got ipv4 subnet %s", nwCfg.SubnetIP)
+	}
+
+	for _, expAddr := range []string{"2001:db8::1", "2001:db8::2"} {
+		addr, err := networkAllocIPv6Address(nwCfg, "")
+		if err != nil || addr != expAddr {
+			t.Fatalf("expected %s to be allocated, got %s. Error: %v", expAddr, addr, err)
+		}
+	}
+	if addr, err := networkAllocIPv6Address(nwCfg, ""); err == nil {
+		t.Fatalf("allocated %s from an exhausted subnet", addr)
+	}
+	if addr, err := networkAllocIPv6Address(nwCfg, "2001:db8::1"); err == nil {
+		t.Fatalf("allocated %s which is in use", addr)
+	}
+
+	err = updateNetworkState(nwCfg, func() error {
+		return networkReleaseIPv6Address(nwCfg, "2001:db8::2")
+	})
+	if err != nil {
+		t.Fatalf("error releasing address. Error: %s", err)
+	}
+
+	// an address handed out by the docker IPAM driver is claimed once by
+	// the endpoint created with it
+	addr, err := networkAllocIPAMAddress(nwCfg, "", "", true)
+	if err != nil || addr != "2001:db8::2" {
+		t.Fatalf("expected 2001:db8::2 to be allocated, got %s. Error: %v", addr, err)
+	}
+	addr, err = networkAllocIPv6Address(nwCfg, "2001:db8:0::2")
+	if err != nil || addr != "2001:db8::2" {
+		t.Fatalf("expected 2001:db8::2 to be claimed, got %s. Error: %v", addr, err)
+	}
+	if addr, err = networkAllocIPv6Address(nwCfg, "2001:db8::2"); err == nil {
+		t.Fatalf("claimed %s twice", addr)
+	}
+
+	// the ipv4 handoff works the same way
+	nwCfg.SubnetIP, nwCfg.SubnetLen = "12.1.1.0", 24
+	netutils.InitSubnetBitset(&nwCfg.IPAllocMap, nwCfg.SubnetLen)
+	err = nwCfg.Write()
+	if err != nil {
+		t.Fatalf("error writing network state. Error: %s", err)
+	}
+	addr, err = networkAllocIPAMAddress(nwCfg, "", "", false)
+	if err != nil || addr != "12.1.1.1" {
+		t.Fatalf("expected 12.1.1.1 to be allocated, got %s. Error: %v", addr, err)
+	}
+	addr, err = networkAllocAddress(nwCfg, "12.1.1.1", "")
+	if err != nil || addr != "12.1.1.1" || len(nwCfg.IPAMAddrs) != 0 {
+		t.Fatalf("expected 12.1.1.1 to be claimed, got %s. Error: %v", addr, err)
+	}
+}
+
+func TestIPv6SubnetPool(t *testing.T) {
+	// the pool only has room for two subnets
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "IPv6SubnetPool"            : "2001:db8:1::/63",
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange",
+            "SubnetCIDR"            : "12.1.1.0/24",
+            "Gateway"               : "12.1.1.254"
+        },
+        {
+            "Name"                  : "purple"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	expSubnets := map[string]string{
+		"orange.tenant-one": "2001:db8:1::",
+		"purple.tenant-one": "2001:db8:1:1::",
+	}
+	for netID, expSubnet := range expSubnets {
+		nwCfg := &mastercfg.CfgNetworkState{}
+		nwCfg.StateDriver = fakeDriver
+		err := nwCfg.Read(netID)
+		if err != nil {
+			t.Fatalf("error reading network %s. Error: %s", netID, err)
+		}
+		if nwCfg.SubnetIP == "" || nwCfg.IPv6Subnet != expSubnet || nwCfg.IPv6SubnetLen != 64 {
+			t.Fatalf("expected network %s to be dual-stack with ipv6 subnet %s/64, got %s/%d",
+				netID, expSubnet, nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen)
+		}
+	}
+
+	_, err := resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	// the subnet of a deleted network is reused
+	err = DeleteNetworkID(fakeDriver, "orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error deleting network. Error: %s", err)
+	}
+	err = CreateNetwork(intent.ConfigNetwork{Name: "green"}, fakeDriver, "tenant-one")
+	if err != nil {
+		t.Fatalf("error creating network. Error: %s", err)
+	}
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err = nwCfg.Read("green.tenant-one")
+	if err != nil || nwCfg.IPv6Subnet != "2001:db8:1::" {
+		t.Fatalf("expected network green to get ipv6 subnet 2001:db8:1::, got %s. Error: %v",
+			nwCfg.IPv6Subnet, err)
+	}
+
+	err = CreateNetwork(intent.ConfigNetwork{Name: "blue"}, fakeDriver, "tenant-one")
+	if core.ErrorKindOf(err) != core.KindExhausted {
+		t.Fatalf("expected network create to fail on an exhausted ipv6 pool, got %v", err)
+	}
+}
+
+func TestTenantQuota(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "MaxNetworks"               : 2,
+        "MaxEndpoints"              : 2,
+        "MaxVLANs"                  : 1,
+        "Networks"  : [{
+            "Name"                  : "orange"
+        },
+        {
+            "Name"                  : "purple"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	cfg := &intent.Config{}
+	err := json.Unmarshal(cfgBytes, cfg)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s'\n", err, cfgBytes)
+	}
+
+	_, err = resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	tenant := cfg.Tenants[0]
+	err = CreateTenant(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' creating tenant\n", err)
+	}
+
+	// the second vlan network exceeds the vlan quota
+	err = CreateNetworks(fakeDriver, &tenant)
+	if core.ErrorKindOf(err) != core.KindExhausted {
+		t.Fatalf("expected a quota exceeded error, got %v", err)
+	}
+	verifyKeysDoNotExist(t, []string{"purple"})
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err = nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+
+	for _, container := range []string{"myContainer1", "myContainer2", "myContainer3"} {
+		_, err = CreateEndpoint(fakeDriver, nwCfg, &intent.ConfigEP{Container: container})
+		if container != "myContainer3" && err != nil {
+			t.Fatalf("error creating endpoint %s. Error: %s", container, err)
+		}
+	}
+	if core.ErrorKindOf(err) != core.KindExhausted {
+		t.Fatalf("expected a quota exceeded error, got %v", err)
+	}
+
+	report, err := GetResourceUsage(fakeDriver, "")
+	if err != nil {
+		t.Fatalf("error getting resource usage. Error: %s", err)
+	}
+	usages := report.Tenants
+	if len(usages) != 1 || usages[0].Networks.Used != 1 || usages[0].Endpoints.Used != 2 ||
+		usages[0].VLANQuota.Used != 1 || usages[0].Endpoints.Limit != 2 {
+		t.Fatalf("unexpected tenant usage %+v", usages)
+	}
+	if report.Global.Endpoints.Used != 2 {
+		t.Fatalf("unexpected global usage %+v", report.Global)
+	}
+
+	// deleted objects no longer count against the quota
+	_, err = DeleteEndpointID(fakeDriver, "orange.tenant-one-myContainer1")
+	if err != nil {
+		t.Fatalf("error deleting endpoint. Error: %s", err)
+	}
+	_, err = CreateEndpoint(fakeDriver, nwCfg, &intent.ConfigEP{Container: "myContainer3"})
+	if err != nil {
+		t.Fatalf("error creating endpoint after a delete. Error: %s", err)
+	}
+
+	err = DeleteNetworkID(fakeDriver, "orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error deleting network. Error: %s", err)
+	}
+	report, err = GetResourceUsage(fakeDriver, "tenant-one")
+	if err != nil {
+		t.Fatalf("error getting resource usage. Error: %s", err)
+	}
+	usages = report.Tenants
+	if len(usages) != 1 || usages[0].Networks.Used != 0 || usages[0].VLANQuota.Used != 0 {
+		t.Fatalf("unexpected tenant usage after network delete %+v", usages)
+	}
+	err = CreateNetworks(fakeDriver, &tenant)
+	if core.ErrorKindOf(err) != core.KindExhausted {
+		t.Fatalf("expected the second network to exceed the vlan quota, got %v", err)
+	}
+	verifyKeys(t, []string{"nets/orange"})
+}
+
+func TestUpdateTenantKeepsNetType(t *testing.T) {
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	_, err := resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	tenant := intent.ConfigTenant{
+		Name:           "tenant-one",
+		DefaultNetType: "vxlan",
+		SubnetPool:     "11.1.0.0/16",
+		AllocSubnetLen: 24,
+		VLANs:          "11-28",
+		VXLANs:         "1001-2000",
+	}
+	err = CreateTenant(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' creating tenant\n", err)
+	}
+
+	// an update without a net type keeps the tenant's
+	tenant.DefaultNetType = ""
+	tenant.VXLANs = "1001-3000"
+	err = UpdateTenant(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' updating tenant\n", err)
+	}
+
+	gCfg := &gstate.Cfg{}
+	gCfg.StateDriver = fakeDriver
+	err = gCfg.Read("tenant-one")
+	if err != nil {
+		t.Fatalf("error reading tenant config. Error: %s", err)
+	}
+	if gCfg.Deploy.DefaultNetType != "vxlan" || gCfg.Auto.VXLANs != "1001-3000" {
+		t.Fatalf("unexpected tenant config after update %+v", gCfg)
+	}
+}
+
+func TestResourceUsage(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vxlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Vxlans"                    : "10001-10100",
+        "Networks"  : [{
+            "Name"                  : "orange"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	cfg := &intent.Config{}
+	err := json.Unmarshal(cfgBytes, cfg)
+	if err != nil {
+		t.Fatalf("error '%s' parsing config '%s'\n", err, cfgBytes)
+	}
+
+	_, err = resources.NewStateResourceManager(fakeDriver)
+	if err != nil {
+		log.Fatalf("state store initialization failed. Error: %s", err)
+	}
+	defer func() { resources.ReleaseStateResourceManager() }()
+
+	tenant := cfg.Tenants[0]
+	err = CreateTenant(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' creating tenant\n", err)
+	}
+	err = CreateNetworks(fakeDriver, &tenant)
+	if err != nil {
+		t.Fatalf("error '%s' creating networks\n", err)
+	}
+
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = fakeDriver
+	err = nwCfg.Read("orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error reading network state. Error: %s", err)
+	}
+
+	epCfg, err := CreateEndpoint(fakeDriver, nwCfg, &intent.ConfigEP{Container: "myContainer1"})
+	if err != nil {
+		t.Fatalf("error creating endpoint. Error: %s", err)
+	}
+
+	report, err := GetResourceUsage(fakeDriver, "")
+	if err != nil {
+		t.Fatalf("error getting resource usage. Error: %s", err)
+	}
+	if len(report.Tenants) != 1 {
+		t.Fatalf("unexpected tenants in resource usage %+v", report)
+	}
+
+	usage := report.Tenants[0]
+	if usage.VLANs.Total != 18 || usage.VLANs.Used != 0 || usage.VLANs.Free != 18 {
+		t.Fatalf("unexpected vlan usage %+v", usage.VLANs)
+	}
+	if usage.VXLANs.Total != 100 || usage.VXLANs.Used != 1 ||
+		usage.VXLANs.Allocated[0] != strconv.Itoa(nwCfg.ExtPktTag) {
+		t.Fatalf("unexpected vxlan usage %+v, network vxlan %d", usage.VXLANs, nwCfg.ExtPktTag)
+	}
+	if usage.LocalVLANs.Total < 100 || usage.LocalVLANs.Used != 1 {
+		t.Fatalf("unexpected local vlan usage %+v", usage.LocalVLANs)
+	}
+	if usage.Subnets.Total != 256 || usage.Subnets.Used != 1 ||
+		usage.Subnets.Allocated[0] != nwCfg.SubnetIP+"/24" {
+		t.Fatalf("unexpected subnet usage %+v, network subnet %s", usage.Subnets, nwCfg.SubnetIP)
+	}
+	if report.Global.VXLANs.Used != 1 || report.Global.VXLANs.Allocated != nil {
+		t.Fatalf("unexpected global vxlan usage %+v", report.Global.VXLANs)
+	}
+	if report.UnassignedVLANs != 4094-usage.VLANs.Total-usage.LocalVLANs.Total {
+		t.Fatalf("unexpected unassigned vlans %d", report.UnassignedVLANs)
+	}
+
+	_, err = GetResourceUsage(fakeDriver, "tenant-two")
+	if err == nil {
+		t.Fatalf("resource usage of a missing tenant succeeded")
+	}
+
+	ipUsages, err := GetNetworkIPUsage(fakeDriver, "orange.tenant-one")
+	if err != nil {
+		t.Fatalf("error getting address usage. Error: %s", err)
+	}
+	ipUsage := ipUsages[0]
+	if ipUsage.Total != 254 || ipUsage.Used != uint(len(ipUsage.Allocated)) ||
+		ipUsage.Free != ipUsage.Total-ipUsage.Used {
+		t.Fatalf("unexpected address usage %+v", ipUsage)
+	}
+	found := false
+	for _, ipAddress := range ipUsage.Allocated {
+		found = found || ipAddress == epCfg.IPAddress
+	}
+	if !found {
+		t.Fatalf("endpoint address %s not in address usage %+v", epCfg.IPAddress, ipUsage)
+	}
+}
+
+func TestEndpointMACAllocation(t *testing.T) {
+	cfgBytes := []byte(`{
+    "Tenants" : [{
+        "Name"                      : "tenant-one",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.1.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "11-28",
+        "Networks"  : [{
+            "Name"                  : "orange",
+            "SubnetCIDR"            : "12.1.1.0/24",
+            "Gateway"               : "12.1.1.254"
+        }]
+    },
+    {
+        "Name"                      : "tenant-two",
+        "DefaultNetType"            : "vlan",
+        "SubnetPool"                : "11.2.0.0/16",
+        "AllocSubnetLen"            : 24,
+        "Vlans"                     : "29-40",
+        "MACPrefix"                 : "0A:00:27",
+        "Networks"  : [{
+            "Name"                  : "purple",
+            "SubnetCIDR"            : "12.1.2.0/24",
+            "Gateway"               : "12.1.2.254"
+        }]
+    }]}`)
+
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	applyConfig(t, cfgBytes)
+
+	createEp := func(network, container, mac string) (string, error) {
+		nwCfg := &mastercfg.CfgNetworkState{}
+		nwCfg.StateDriver = fakeDriver
+		err := nwCfg.Read(network)
+		if err != nil {
+			t.Fatalf("error reading network state. Error: %s", err)
+		}
+		epCfg, err := CreateEndpoint(fakeDriver, nwCfg,
+			&intent.ConfigEP{Container: container, MacAddress: mac})
+		if err != nil {
+			return "", err
+		}
+		return epCfg.MacAddress, nil
+	}
+
+	for _, ep := range []struct{ network, container, expMAC string }{
+		{"orange.tenant-one", "myContainer1", "02:02:00:00:00:01"},
+		{"orange.tenant-one", "myContainer2", "02:02:00:00:00:02"},
+		{"purple.tenant-two", "myContainer3", "0a:00:27:00:00:01"},
+	} {
+		mac, err := createEp(ep.network, ep.container, "")
+		if err != nil {
+			t.Fatalf("error creating endpoint %s. Error: %s", ep.container, err)
+		}
+		if mac != ep.expMAC {
+			t.Fatalf("endpoint %s got mac %s, expected %s", ep.container, mac, ep.expMAC)
+		}
+	}
+
+	// a static address is kept, and not allocated again
+	mac, err := createEp("orange.tenant-one", "myContainer4", "02:02:00:00:00:03")
+	if err != nil || mac != "02:02:00:00:00:03" {
+		t.Fatalf("error creating endpoint with a static mac. mac: %s, Error: %v", mac, err)
+	}
+	_, err = createEp("purple.tenant-two", "myContainer5", "02:02:00:00:00:01")
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict error for a duplicate mac, got %v", err)
+	}
+	_, err = createEp("purple.tenant-two", "myContainer5", "01:00:5e:00:00:01")
+	if core.ErrorKindOf(err) != core.KindInvalid {
+		t.Fatalf("expected an invalid error for a multicast mac, got %v", err)
+	}
+	if mac, _ = createEp("orange.tenant-one", "myContainer6", ""); mac != "02:02:00:00:00:04" {
+		t.Fatalf("expected mac 02:02:00:00:00:04, got %s", mac)
+	}
+
+	// static addresses with none of the prefixes are tracked too
+	if _, err = createEp("orange.tenant-one", "myContainer8", "0c:00:00:00:00:01"); err != nil {
+		t.Fatalf("error creating endpoint with a static mac. Error: %s", err)
+	}
+	_, err = createEp("purple.tenant-two", "myContainer9", "0c:00:00:00:00:01")
+	if core.ErrorKindOf(err) != core.KindConflict {
+		t.Fatalf("expected a conflict error for a duplicate static mac, got %v", err)
+	}
+	_, err = DeleteEndpointID(fakeDriver, "orange.tenant-one-myContainer8")
+	if err != nil {
+		t.Fatalf("error deleting endpoint. Error: %s", err)
+	}
+	if _, err = createEp("purple.tenant-two", "myContainer9", "0c:00:00:00:00:01"); err != nil {
+		t.Fatalf("error creating endpoint with a released static mac. Error: %s", err)
+	}
+
+	// a released address can be set again
+	_, err = DeleteEndpointID(fakeDriver, "orange.tenant-one-myContainer1")
+	if err != nil {
+		t.Fatalf("error deleting endpoint. Error: %s", err)
+	}
+	if _, err = createEp("purple.tenant-two", "myContainer7", "02:02:00:00:00:01"); err != nil {
+		t.Fatalf("error creating endpoint with a released mac. Error: %s", err)
+	}
+
+	// prefixes can't overlap the ones in use
+	err = CreateGlobal(fakeDriver, &intent.ConfigGlobal{MACPrefix: "0a"})
+	if err == nil {
+		t.Fatalf("global mac prefix overlapping a prefix in use was accepted")
+	}
+	err = CreateGlobal(fakeDriver, &intent.ConfigGlobal{MACPrefix: "0a:00:27"})
+	if err != nil {
+		t.Fatalf("error setting global mac prefix. Error: %s", err)
+	}
+}
+
+func TestAPISpec(t *testing.T) {
+	spec := NewAPISpec([]APIRoute{
+		{Method: "POST", Path: "/plugin/createEndpoint",
+			Request: CreateEndpointRequest{}, Response: CreateEndpointResponse{}},
+		{Method: "GET", Path: "/networks/{id}", Response: []mastercfg.CfgNetworkState{}},
+	})
+
+	op := spec.Paths["/plugin/createEndpoint"]["post"]
+	if op == nil || op.OperationID != "postPluginCreateEndpoint" {
+		t.Fatalf("createEndpoint operation not as expected: %+v", op)
+	}
+	reqSchema := op.RequestBody.Content["application/json"]
+	if reqSchema["$ref"] != "#/components/schemas/master.CreateEndpointRequest" {
+		t.Fatalf("unexpected request schema %v", reqSchema)
+	}
+	props := spec.Components.Schemas["master.CreateEndpointRequest"]["properties"].(map[string]APISchema)
+	if props["ConfigEP"]["$ref"] != "#/components/schemas/intent.ConfigEP" {
+		t.Fatalf("unexpected ConfigEP schema %v", props["ConfigEP"])
+	}
+	if op.Responses["default"].Content["application/json"]["$ref"] != "#/components/schemas/core.HTTPError" {
+		t.Fatalf("unexpected error schema %v", op.Responses["default"])
+	}
+
+	op = spec.Paths["/networks/{id}"]["get"]
+	if op == nil || len(op.Parameters) != 1 || op.Parameters[0].Name != "id" {
+		t.Fatalf("networks operation not as expected: %+v", op)
+	}
+	props = spec.Components.Schemas["mastercfg.CfgNetworkState"]["properties"].(map[string]APISchema)
+	if _, ok := props["id"]; !ok {
+		t.Fatalf("embedded state fields missing from %v", props)
+	}
+	if _, ok := props["StateDriver"]; ok {
+		t.Fatalf("ignored field StateDriver in %v", props)
+	}
+
+	if _, err := json.Marshal(spec); err != nil {
+		t.Fatalf("error encoding the spec. Error: %s", err)
+	}
+}
@@ -16,11 +16,10 @@ limitations under the License.
 package master
 
 import (
-	"errors"
 	"fmt"
 	"net"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/gstate"
@@ -62,12 +61,12 @@ func validateNetworkConfig(tenant *intent.ConfigTenant) error {
 	var err error
 
 	if tenant.Name == "" {
-		return core.Errorf("null tenant name")
+		return core.InvalidErrorf("null tenant name")
 	}
 
 	for _, network := range tenant.Networks {
 		if network.Name == "" {
-			core.Errorf("null network name")
+			return core.InvalidErrorf("null network name")
 		}
 
 		err = checkPktTagType(network.PktTagType)
@@ -75,25 +74,70 @@ func validateNetworkConfig(tenant *intent.ConfigTenant) error {
 			return err
 		}
 
-		if network.SubnetCIDR != "" {
-			_, _, err = netutils.ParseCIDR(network.SubnetCIDR)
-			if err != nil {
-				return err
-			}
+		err = validateNetworkSubnets(network)
+		if err != nil {
+			return err
 		}
 
-		if network.Gateway != "" {
-			if net.ParseIP(network.Gateway) == nil {
-				return core.Errorf("invalid IP")
-			}
+		if network.IPLeaseHoldTime < 0 {
+			return core.InvalidErrorf("invalid ip lease hold time %d", network.IPLeaseHoldTime)
 		}
 	}
 
 	return err
 }
 
-// createDockNet Creates a network in docker daemon
-func createDockNet(tenantName, networkName, serviceName, subnetCIDR, gateway string) error {
+// validateNetworkSubnets checks the IPv4 and IPv6 subnets and gateways of a
+// network
+func validateNetworkSubnets(network intent.ConfigNetwork) error {
+	if network.SubnetCIDR != "" {
+		subnetIP, _, err := netutils.ParseCIDR(network.SubnetCIDR)
+		if err != nil {
+			return err
+		}
+		if net.ParseIP(subnetIP).To4() == nil {
+			return core.InvalidErrorf("invalid IPv4 subnet %s", network.SubnetCIDR)
+		}
+	}
+
+	if network.Gateway != "" {
+		if net.ParseIP(network.Gateway) == nil {
+			return core.InvalidErrorf("invalid IP")
+		}
+	}
+
+	if network.IPv6SubnetCIDR != "" {
+		subnetIP, _, err := netutils.ParseCIDR(network.IPv6SubnetCIDR)
+		if err != nil {
+			return err
+		}
+		if !netutils.IsIPv6(subnetIP) {
+			return core.InvalidErrorf("invalid IPv6 subnet %s", network.IPv6SubnetCIDR)
+		}
+	}
+
+	if (network.IPExclusions != "" || len(network.IPReservations) > 0) &&
+		network.SubnetCIDR == "" {
+		return core.InvalidErrorf("ip exclusions and reservations require an IPv4 subnet")
+	}
+
+	if network.IPv6Gateway != "" {
+		if network.IPv6SubnetCIDR == "" {
+			return core.InvalidErrorf("IPv6 gateway %s without an IPv6 subnet", network.IPv6Gateway)
+		}
+		subnetIP, subnetLen, _ := netutils.ParseCIDR(network.IPv6SubnetCIDR)
+		if !netutils.IsIPv6InSubnet(subnetIP, subnetLen, network.IPv6Gateway) {
+			return core.InvalidErrorf("invalid IPv6 gateway %s for subnet %s",
+				network.IPv6Gateway, network.IPv6SubnetCIDR)
+		}
+	}
+
+	return nil
+}
+
+// createDockNet Creates a network in docker daemon, with the IPv4 and IPv6
+// subnets of the network
+func createDockNet(tenantName, networkName, serviceName string, nwCfg *mastercfg.CfgNetworkState) error {
 	// do nothing in test mode
 	if testMode {
 		return nil
@@ -106,7 +150,7 @@ func createDockNet(tenantName, networkName, serviceName, subnetCIDR, gateway str
 	docker, err := dockerclient.NewDockerClient("unix:///var/run/docker.sock", nil)
 	if err != nil {
 		log.Errorf("Unable to connect to docker. Error %v", err)
-		return errors.New("Unable to connect to docker")
+		return core.UnavailableErrorf("Unable to connect to docker")
 	}
 
 	// Check if the network already exists
@@ -115,29 +159,41 @@ func createDockNet(tenantName, networkName, serviceName, subnetCIDR, gateway str
 		return nil
 	} else if err == nil && nw.Driver != driverName {
 		log.Errorf("Network name %s used by another driver %s", docknetName, nw.Driver)
-		return errors.New("Network name used by another driver")
+		return core.ConflictErrorf("Network name used by another driver")
 	}
 
 	// Build network parameters
-	nwCreate := dockerclient.NetworkCreate{
-		Name:           docknetName,
-		CheckDuplicate: true,
-		Driver:         driverName,
-		IPAM: dockerclient.IPAM{
-			Driver: driverName,
-			Config: []dockerclient.IPAMConfig{
-				dockerclient.IPAMConfig{
-					Subnet:  subnetCIDR,
-					Gateway: gateway,
-				},
+	ipamConfig := []dockerclient.IPAMConfig{}
+	if nwCfg.SubnetIP != "" {
+		ipamConfig = append(ipamConfig, dockerclient.IPAMConfig{
+			Subnet:  fmt.Sprintf("%s/%d", nwCfg.SubnetIP, nwCfg.SubnetLen),
+			Gateway: nwCfg.Gateway,
+		})
+	}
+	if nwCfg.IPv6Subnet != "" {
+		ipamConfig = append(ipamConfig, dockerclient.IPAMConfig{
+			Subnet:  fmt.Sprintf("%s/%d", nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen),
+			Gateway: nwCfg.IPv6Gateway,
+		})
+	}
+
+	nwCreate := docknetCreate{
+		NetworkCreate: dockerclient.NetworkCreate{
+			Name:           docknetName,
+			CheckDuplicate: true,
+			Driver:         driverName,
+			IPAM: dockerclient.IPAM{
+				Driver: driverName,
+				Config: ipamConfig,
 			},
 		},
+		EnableIPv6: nwCfg.IPv6Subnet != "",
 	}
 
 	log.Infof("Creating docker network: %+v", nwCreate)
 
 	// Create network
-	_, err = docker.CreateNetwork(&nwCreate)
+	err = postDocknetCreate(docker, &nwCreate)
 	if err != nil {
 		log.Errorf("Error creating network %s. Err: %v", docknetName, err)
 		return err
@@ -160,7 +216,7 @@ func deleteDockNet(tenantName, networkName, serviceName string) error {
 	docker, err := dockerclient.NewDockerClient("unix:///var/run/docker.sock", nil)
 	if err != nil {
 		log.Errorf("Unable to connect to docker. Error %v", err)
-		return errors.New("Unable to connect to docker")
+		return core.UnavailableErrorf("Unable to connect to docker")
 	}
 
 	log.Infof("Deleting docker network: %+v", docknetName)
@@ -189,16 +245,20 @@ func isTagInRange(pktTag uint, pktTagType string, pktTagRange string, stateDrive
 			return false
 		}
 
-		// Vxlan bitset allocation is reverse offseted by the start of the range
-		// So, if we were allocating vlan 2001, when the allowed range was 2000-3000;
-		// We would internally allocate bitset (2001-2000) = 1 for it.
-		startOffset64, err := strconv.Atoi(strings.Split(pktTagRange, "-")[0])
-		startOffset := uint(startOffset64)
-
+		// Vxlan bitset allocation is reverse offseted by the start of the lowest
+		// range. So, if we were allocating vxlan 2001, when the allowed ranges were
+		// 2000-3000,5000-6000; We would internally allocate bitset (2001-2000) = 1 for it.
+		tagRanges, err := netutils.ParseTagRanges(pktTagRange, "vxlan")
 		if err != nil {
-			log.Errorf("Error in startOffset conversion pktTagRange: %s err: %s", pktTagRange, err)
+			log.Errorf("Error parsing pktTagRange: %s err: %s", pktTagRange, err)
 			return false
 		}
+		startOffset := uint(tagRanges[0].Min)
+		for _, tagRange := range tagRanges {
+			if uint(tagRange.Min) < startOffset {
+				startOffset = uint(tagRange.Min)
+			}
+		}
 		if startOffset > pktTag {
 			return false
 		}
@@ -218,21 +278,10 @@ func isTagInRange(pktTag uint, pktTagType string, pktTagRange string, stateDrive
 
 // CreateNetwork creates a network from intent
 func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, tenantName string) error {
-	var extPktTag, pktTag uint
-
-	gCfg := gstate.Cfg{}
-	gCfg.StateDriver = stateDriver
-	err := gCfg.Read(tenantName)
-	if err != nil {
-		log.Errorf("error reading tenant cfg state. Error: %s", err)
-		return err
-	}
-
 	tempRm, err := resources.GetStateResourceManager()
 	if err != nil {
 		return err
 	}
-	rm := core.ResourceManager(tempRm)
 
 	// Create network state
 	networkID := network.Name + "." + tenantName
@@ -243,10 +292,70 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		return nil
 	}
 
+	// allocate the network's resources and write its state as a unit
+	nwExists := false
+	err = runInTxn(stateDriver, func(txn core.Txn) error {
+		nwCfg = &mastercfg.CfgNetworkState{}
+		nwCfg.StateDriver = txn
+		if nwCfg.Read(networkID) == nil {
+			// created concurrently
+			nwExists = true
+			return nil
+		}
+
+		return createNetworkState(txn, tempRm.InTxn(txn), network, tenantName, nwCfg)
+	})
+	if err != nil || nwExists {
+		return err
+	}
+	nwCfg.StateDriver = stateDriver
+
+	if GetClusterMode() == "docker" {
+		// Create the network in docker
+		err = createDockNet(tenantName, network.Name, "", nwCfg)
+		if err != nil {
+			log.Errorf("Error creating network %s in docker. Err: %v", nwCfg.ID, err)
+			rollbackNetworkState(stateDriver, networkID)
+			return err
+		}
+
+		// Attach service container endpoint to the network
+		err = attachServiceContainer(tenantName, network.Name, stateDriver)
+		if err != nil {
+			log.Errorf("Error attaching service container to network: %s. Err: %v",
+				networkID, err)
+			deleteDockNet(tenantName, network.Name, "")
+			rollbackNetworkState(stateDriver, networkID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createNetworkState allocates the resources for a network and writes its
+// state, using the passed transaction
+func createNetworkState(txn core.Txn, rm core.ResourceManager, network intent.ConfigNetwork,
+	tenantName string, nwCfg *mastercfg.CfgNetworkState) error {
+	var extPktTag, pktTag uint
+
+	err := validateNetworkSubnets(network)
+	if err != nil {
+		return err
+	}
+
+	gCfg := gstate.Cfg{}
+	gCfg.StateDriver = txn
+	err = gCfg.Read(tenantName)
+	if err != nil {
+		log.Errorf("error reading tenant cfg state. Error: %s", err)
+		return err
+	}
+
 	subnetIP, subnetLen, _ := netutils.ParseCIDR(network.SubnetCIDR)
 
 	// construct and update network state
-	nwCfg = &mastercfg.CfgNetworkState{
+	*nwCfg = mastercfg.CfgNetworkState{
 		Tenant:      tenantName,
 		NetworkName: network.Name,
 		PktTagType:  network.PktTagType,
@@ -255,12 +364,23 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		Gateway:     network.Gateway,
 	}
 
-	nwCfg.ID = networkID
-	nwCfg.StateDriver = stateDriver
+	nwCfg.ID = network.Name + "." + tenantName
+	nwCfg.StateDriver = txn
+	nwCfg.IPLeaseHoldTime = network.IPLeaseHoldTime
+
+	if network.IPv6SubnetCIDR != "" {
+		nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen, _ = netutils.ParseCIDR(network.IPv6SubnetCIDR)
+		nwCfg.IPv6Gateway = network.IPv6Gateway
+		nwCfg.IPv6AllocMap = map[string]bool{}
+		if nwCfg.IPv6Gateway != "" {
+			nwCfg.IPv6AllocMap[net.ParseIP(nwCfg.IPv6Gateway).String()] = true
+		}
+	}
 
 	if network.PktTagType == "" {
 		nwCfg.PktTagType = gCfg.Deploy.DefaultNetType
 	}
+
 	if network.PktTag == 0 {
 		if nwCfg.PktTagType == "vlan" {
 			pktTag, err = gCfg.AllocVLAN(rm)
@@ -277,7 +397,7 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		nwCfg.ExtPktTag = int(extPktTag)
 		nwCfg.PktTag = int(pktTag)
 	} else if network.PktTagType == "vxlan" {
-		if !isTagInRange(uint(network.PktTag), "vxlan", gCfg.Auto.VXLANs, stateDriver, tenantName) {
+		if !isTagInRange(uint(network.PktTag), "vxlan", gCfg.Auto.VXLANs, txn, tenantName) {
 			return fmt.Errorf("vxlan %d does not adhere to tenant's vxlan range %s", network.PktTag, gCfg.Auto.VXLANs)
 		}
 
@@ -285,13 +405,20 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		nwCfg.PktTag = network.PktTag
 	} else if network.PktTagType == "vlan" {
 
-		if !isTagInRange(uint(network.PktTag), "vlan", gCfg.Auto.VLANs, stateDriver, tenantName) {
+		if !isTagInRange(uint(network.PktTag), "vlan", gCfg.Auto.VLANs, txn, tenantName) {
 			return fmt.Errorf("vlan %d does not adhere to tenant's vlan range %s", network.PktTag, gCfg.Auto.VLANs)
 		}
 		nwCfg.PktTag = network.PktTag
 	}
 
-	if nwCfg.SubnetIP == "" {
+	err = updateTenantUsage(txn, tenantName, usageNetworks, 1, networkVLAN(nwCfg))
+	if err != nil {
+		return err
+	}
+
+	// IPv4 subnets are only allocated to networks without any subnet, IPv6
+	// only networks are left without one
+	if nwCfg.SubnetIP == "" && nwCfg.IPv6Subnet == "" {
 		nwCfg.SubnetLen = gCfg.Auto.AllocSubnetLen
 		nwCfg.SubnetIP, err = gCfg.AllocSubnet(rm)
 		if err != nil {
@@ -300,6 +427,17 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		nwCfg.SubnetIsAllocated = true
 	}
 
+	// networks of tenants with an IPv6 subnet pool get an IPv6 subnet too
+	if nwCfg.IPv6Subnet == "" && gCfg.Auto.IPv6SubnetPool != "" {
+		nwCfg.IPv6SubnetLen = gCfg.Auto.AllocIPv6SubnetLen
+		nwCfg.IPv6Subnet, err = gCfg.AllocIPv6Subnet(rm)
+		if err != nil {
+			return err
+		}
+		nwCfg.IPv6SubnetIsAllocated = true
+		nwCfg.IPv6AllocMap = map[string]bool{}
+	}
+
 	defaultNwName, err := gCfg.AssignDefaultNetwork(network.Name)
 	if err != nil {
 		log.Errorf("error assigning the default network. Error: %s", err)
@@ -319,31 +457,35 @@ func CreateNetwork(network intent.ConfigNetwork, stateDriver core.StateDriver, t
 		}
 	}
 
-	netutils.InitSubnetBitset(&nwCfg.IPAllocMap, nwCfg.SubnetLen)
-	err = nwCfg.Write()
-	if err != nil {
-		return err
-	}
+	if nwCfg.SubnetIP != "" {
+		netutils.InitSubnetBitset(&nwCfg.IPAllocMap, nwCfg.SubnetLen)
 
-	if GetClusterMode() == "docker" {
-		// Create the network in docker
-		subnetCIDR := fmt.Sprintf("%s/%d", nwCfg.SubnetIP, nwCfg.SubnetLen)
-		err = createDockNet(tenantName, network.Name, "", subnetCIDR, nwCfg.Gateway)
+		err = initNetworkReservations(nwCfg, network)
 		if err != nil {
-			log.Errorf("Error creating network %s in docker. Err: %v", nwCfg.ID, err)
 			return err
 		}
+	}
 
-		// Attach service container endpoint to the network
-		err = attachServiceContainer(tenantName, network.Name, stateDriver)
-		if err != nil {
-			log.Errorf("Error attaching service container to network: %s. Err: %v",
-				networkID, err)
-			return err
-		}
+	// only create the network if no one else did so concurrently
+	return nwCfg.WriteIfUnchanged()
+}
+
+// networkVLAN returns the vlan of a vlan network, or zero
+func networkVLAN(nwCfg *mastercfg.CfgNetworkState) int {
+	if nwCfg.PktTagType != "vlan" {
+		return 0
 	}
 
-	return nil
+	return nwCfg.PktTag
+}
+
+// rollbackNetworkState undoes the state changes of a network create that
+// failed after its state was committed
+func rollbackNetworkState(stateDriver core.StateDriver, networkID string) {
+	err := deleteNetworkState(stateDriver, networkID)
+	if err != nil {
+		log.Errorf("Error rolling back network %s. Err: %v", networkID, err)
+	}
 }
 
 func attachServiceContainer(tenantName, networkName string, stateDriver core.StateDriver) error {
@@ -390,29 +532,20 @@ func attachServiceContainer(tenantName, networkName string, stateDriver core.Sta
 	epInfo, ok := ninfo.Containers[cinfo.Id]
 	if !ok {
 		log.Errorf("Could not find container %s in network info", cinfo.Id)
-		return errors.New("Endpoint not found")
+		return core.NotFoundErrorf("Endpoint not found")
 	}
 
-	// read network Config
+	// set the dns server Info in the network config
 	nwCfg := &mastercfg.CfgNetworkState{}
-	networkID := networkName + "." + tenantName
+	nwCfg.ID = networkName + "." + tenantName
 	nwCfg.StateDriver = stateDriver
-	err = nwCfg.Read(networkID)
-	if err != nil {
-		return err
-	}
-
-	// set the dns server Info
-	nwCfg.DNSServer = strings.Split(epInfo.IPv4Address, "/")[0]
-	log.Infof("Dns server for network %s: %s", networkName, nwCfg.DNSServer)
+	dnsServer := strings.Split(epInfo.IPv4Address, "/")[0]
+	log.Infof("Dns server for network %s: %s", networkName, dnsServer)
 
-	// write the network config
-	err = nwCfg.Write()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return updateNetworkState(nwCfg, func() error {
+		nwCfg.DNSServer = dnsServer
+		return nil
+	})
 }
 
 // detachServiceContainer detaches the service container's endpoint during network delete
@@ -421,7 +554,7 @@ func detachServiceContainer(tenantName, networkName string) error {
 	docker, err := utils.GetDockerClient()
 	if err != nil {
 		log.Errorf("Unable to connect to docker. Error %v", err)
-		return errors.New("Unable to connect to docker")
+		return core.UnavailableErrorf("Unable to connect to docker")
 	}
 
 	dnsContName := tenantName + "dns"
@@ -495,14 +628,7 @@ func CreateNetworks(stateDriver core.StateDriver, tenant *intent.ConfigTenant) e
 	return err
 }
 
-func freeNetworkResources(stateDriver core.StateDriver, nwCfg *mastercfg.CfgNetworkState, gCfg *gstate.Cfg) (err error) {
-
-	tempRm, err := resources.GetStateResourceManager()
-	if err != nil {
-		return err
-	}
-	rm := core.ResourceManager(tempRm)
-
+func freeNetworkResources(rm core.ResourceManager, nwCfg *mastercfg.CfgNetworkState, gCfg *gstate.Cfg) (err error) {
 	if nwCfg.PktTagType == "vlan" {
 		err = gCfg.FreeVLAN(rm, uint(nwCfg.PktTag))
 		if err != nil {
@@ -524,6 +650,14 @@ func freeNetworkResources(stateDriver core.StateDriver, nwCfg *mastercfg.CfgNetw
 		}
 	}
 
+	if nwCfg.IPv6SubnetIsAllocated {
+		log.Infof("freeing ipv6 subnet %s/%d", nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen)
+		err = gCfg.FreeIPv6Subnet(rm, nwCfg.IPv6Subnet)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := gCfg.UnassignNetwork(nwCfg.ID); err != nil {
 		return err
 	}
@@ -531,6 +665,46 @@ func freeNetworkResources(stateDriver core.StateDriver, nwCfg *mastercfg.CfgNetw
 	return err
 }
 
+// deleteNetworkState frees the resources of a network and removes its state
+// as a unit
+func deleteNetworkState(stateDriver core.StateDriver, netID string) error {
+	tempRm, err := resources.GetStateResourceManager()
+	if err != nil {
+		return err
+	}
+
+	return runInTxn(stateDriver, func(txn core.Txn) error {
+		nwCfg := &mastercfg.CfgNetworkState{}
+		nwCfg.StateDriver = txn
+		err := nwCfg.Read(netID)
+		if err != nil {
+			log.Errorf("network %s is not operational", netID)
+			return err
+		}
+
+		gCfg := &gstate.Cfg{}
+		gCfg.StateDriver = txn
+		err = gCfg.Read(nwCfg.Tenant)
+		if err != nil {
+			log.Errorf("error reading tenant info for %q. Error: %s", nwCfg.Tenant, err)
+			return err
+		}
+
+		// Free resource associated with the network
+		err = freeNetworkResources(tempRm.InTxn(txn), nwCfg, gCfg)
+		if err != nil {
+			return err
+		}
+
+		err = updateTenantUsage(txn, nwCfg.Tenant, usageNetworks, -1, networkVLAN(nwCfg))
+		if err != nil {
+			return err
+		}
+
+		return nwCfg.Clear()
+	})
+}
+
 // DeleteNetworkID removes a network by ID.
 func DeleteNetworkID(stateDriver core.StateDriver, netID string) error {
 	nwCfg := &mastercfg.CfgNetworkState{}
@@ -555,23 +729,9 @@ func DeleteNetworkID(stateDriver core.StateDriver, netID string) error {
 		}
 	}
 
-	gCfg := &gstate.Cfg{}
-	gCfg.StateDriver = stateDriver
-	err = gCfg.Read(nwCfg.Tenant)
-	if err != nil {
-		log.Errorf("error reading tenant info for %q. Error: %s", nwCfg.Tenant, err)
-		return err
-	}
-
-	// Free resource associated with the network
-	err = freeNetworkResources(stateDriver, nwCfg, gCfg)
+	err = deleteNetworkState(stateDriver, netID)
 	if err != nil {
-		return err
-	}
-
-	err = nwCfg.Clear()
-	if err != nil {
-		log.Errorf("error writing nw config. Error: %s", err)
+		log.Errorf("error deleting nw state. Error: %s", err)
 		return err
 	}
 
@@ -609,37 +769,256 @@ func DeleteNetworks(stateDriver core.StateDriver, tenant *intent.ConfigTenant) e
 			continue
 		}
 
-		err = freeNetworkResources(stateDriver, nwCfg, gCfg)
+		err = deleteNetworkState(stateDriver, networkID)
 		if err != nil {
 			return err
 		}
+	}
+
+	return err
+}
+
+// initNetworkReservations sets the excluded and reserved addresses of a
+// network. The excluded addresses are marked as in use so that they are
+// never allocated.
+func initNetworkReservations(nwCfg *mastercfg.CfgNetworkState, network intent.ConfigNetwork) error {
+	exclusions, err := netutils.ParseIPRanges(nwCfg.SubnetIP, nwCfg.SubnetLen, network.IPExclusions)
+	if err != nil {
+		return err
+	}
+	for _, hostRange := range exclusions {
+		for hostID := hostRange.Min; hostID <= hostRange.Max; hostID++ {
+			nwCfg.IPAllocMap.Set(hostID)
+		}
+	}
+	nwCfg.IPExclusions = network.IPExclusions
 
-		err = nwCfg.Clear()
+	if len(network.IPReservations) == 0 {
+		return nil
+	}
+
+	reservedBy := make(map[string]string)
+	nwCfg.IPReservations = make(map[string]string)
+	for name, ipAddress := range network.IPReservations {
+		hostID, err := netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, ipAddress)
 		if err != nil {
-			log.Errorf("error when writing nw config. Error: %s", err)
 			return err
 		}
+		if nwCfg.IPAllocMap.Test(hostID) || ipAddress == nwCfg.Gateway {
+			return core.ConflictErrorf("reserved address %s of %s is not available", ipAddress, name)
+		}
+		if other, ok := reservedBy[ipAddress]; ok {
+			return core.InvalidErrorf("address %s is reserved for both %s and %s", ipAddress, other, name)
+		}
+		reservedBy[ipAddress] = name
+		nwCfg.IPReservations[name] = ipAddress
 	}
 
-	return err
+	return nil
+}
+
+// networkReservedAddress returns the address reserved for the endpoint, by
+// its name or by its service name, if any.
+func networkReservedAddress(nwCfg *mastercfg.CfgNetworkState, ep *intent.ConfigEP) string {
+	if ipAddress, ok := nwCfg.IPReservations[ep.Container]; ok {
+		return ipAddress
+	}
+	if ep.ServiceName != "" {
+		return nwCfg.IPReservations[ep.ServiceName]
+	}
+
+	return ""
+}
+
+// checkReservedAddress fails if the address is reserved for a name other
+// than the endpoint's name or service name. Any reservation fails when there
+// is no endpoint.
+func checkReservedAddress(nwCfg *mastercfg.CfgNetworkState, ipAddress string, ep *intent.ConfigEP) error {
+	for name, resAddr := range nwCfg.IPReservations {
+		if resAddr != ipAddress {
+			continue
+		}
+		if ep == nil || (name != ep.Container && name != ep.ServiceName) {
+			return core.ConflictErrorf("address %s is reserved for %s", ipAddress, name)
+		}
+	}
+
+	return nil
+}
+
+// isExcludedAddress returns true if the host number is in the excluded
+// ranges of the network
+func isExcludedAddress(nwCfg *mastercfg.CfgNetworkState, hostID uint) (bool, error) {
+	exclusions, err := netutils.ParseIPRanges(nwCfg.SubnetIP, nwCfg.SubnetLen, nwCfg.IPExclusions)
+	if err != nil {
+		return false, err
+	}
+
+	for _, hostRange := range exclusions {
+		if hostRange.Contains(hostID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// updateNetworkState re-reads the network state, applies update to it and
+// writes it back, retrying if the state was modified concurrently.
+func updateNetworkState(nwCfg *mastercfg.CfgNetworkState, update func() error) error {
+	return core.RetryOnVersionConflict(func() error {
+		err := nwCfg.ReadForUpdate(nwCfg.ID)
+		if err != nil {
+			return err
+		}
+
+		err = update()
+		if err != nil {
+			return err
+		}
+
+		return nwCfg.WriteIfUnchanged()
+	})
+}
+
+// Allocate an address from the network. An address held for the lease key
+// is preferred, and the allocated address is leased to it. A requested
+// address handed out by the docker IPAM driver is claimed as allocated.
+func networkAllocAddress(nwCfg *mastercfg.CfgNetworkState, reqAddr, leaseKey string) (string, error) {
+	var ipAddress string
+
+	err := updateNetworkState(nwCfg, func() (err error) {
+		if claimIPAMAddress(nwCfg, reqAddr) {
+			ipAddress = reqAddr
+			return nil
+		}
+		ipAddress, err = allocIPv4Address(nwCfg, reqAddr, leaseKey)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error writing nw config. Error: %s", err)
+		return "", err
+	}
+
+	return ipAddress, nil
+}
+
+// networkAllocIPv6Address allocates an IPv6 address from the network. The
+// requested address is allocated if one is passed, or claimed if it was
+// handed out by the docker IPAM driver.
+func networkAllocIPv6Address(nwCfg *mastercfg.CfgNetworkState, reqAddr string) (string, error) {
+	var ipAddress string
+
+	err := updateNetworkState(nwCfg, func() (err error) {
+		if claimIPAMAddress(nwCfg, reqAddr) {
+			ipAddress = net.ParseIP(reqAddr).String()
+			return nil
+		}
+		ipAddress, err = allocIPv6Address(nwCfg, reqAddr)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error writing nw config. Error: %s", err)
+		return "", err
+	}
+
+	return ipAddress, nil
 }
 
-// Allocate an address from the network
-func networkAllocAddress(nwCfg *mastercfg.CfgNetworkState, reqAddr string) (string, error) {
+// networkAllocIPAMAddress allocates an IPv4 or IPv6 address of the network
+// for the docker IPAM driver. The address is recorded as handed out, for
+// the endpoint that docker then creates with it to claim. An IPv4 address
+// is leased to the lease key, if one is passed.
+func networkAllocIPAMAddress(nwCfg *mastercfg.CfgNetworkState, reqAddr, leaseKey string, isIPv6 bool) (string, error) {
+	var ipAddress string
+
+	if !isIPv6 && reqAddr != "" {
+		err := checkReservedAddress(nwCfg, reqAddr, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := updateNetworkState(nwCfg, func() (err error) {
+		if isIPv6 {
+			ipAddress, err = allocIPv6Address(nwCfg, reqAddr)
+		} else {
+			ipAddress, err = allocIPv4Address(nwCfg, reqAddr, leaseKey)
+		}
+		if err != nil {
+			return err
+		}
+
+		if nwCfg.IPAMAddrs == nil {
+			nwCfg.IPAMAddrs = make(map[string]bool)
+		}
+		nwCfg.IPAMAddrs[ipAddress] = true
+		return nil
+	})
+	if err != nil {
+		log.Errorf("error writing nw config. Error: %s", err)
+		return "", err
+	}
+
+	return ipAddress, nil
+}
+
+// claimIPAMAddress claims an address handed out by the docker IPAM driver,
+// which is already allocated. Returns false if it wasn't handed out.
+func claimIPAMAddress(nwCfg *mastercfg.CfgNetworkState, ipAddress string) bool {
+	addr := net.ParseIP(ipAddress)
+	if addr == nil || !nwCfg.IPAMAddrs[addr.String()] {
+		return false
+	}
+
+	delete(nwCfg.IPAMAddrs, addr.String())
+	return true
+}
+
+// allocIPv4Address allocates an address in the network state, which is
+// written by the caller.
+func allocIPv4Address(nwCfg *mastercfg.CfgNetworkState, reqAddr, leaseKey string) (string, error) {
 	var ipAddress string
 	var ipAddrValue uint
 	var found bool
 	var err error
 
-	// alloc address
-	if reqAddr == "" {
+	expireIPLeases(nwCfg, time.Now())
+
+	lease := nwCfg.IPLeases[leaseKey]
+	if leaseKey == "" {
+		lease = nil
+	}
+
+	// alloc address, the held one or skipping the reserved ones
+	if reqAddr == "" && lease != nil {
+		if lease.Expires.IsZero() {
+			return "", core.ConflictErrorf("address %s of lease %s is in use", lease.IPAddress, leaseKey)
+		}
+
+		ipAddrValue, err = netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, lease.IPAddress)
+		if err != nil {
+			return "", err
+		}
+		ipAddress = lease.IPAddress
+	} else if reqAddr == "" {
+		reserved := make(map[uint]bool)
+		for _, resAddr := range nwCfg.IPReservations {
+			hostID, err := netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, resAddr)
+			if err == nil {
+				reserved[hostID] = true
+			}
+		}
+
 		ipAddrValue, found = nwCfg.IPAllocMap.NextClear(0)
+		for found && reserved[ipAddrValue] {
+			ipAddrValue, found = nwCfg.IPAllocMap.NextClear(ipAddrValue + 1)
+		}
 		if !found {
 			log.Errorf("auto allocation failed - address exhaustion in subnet %s/%d",
 				nwCfg.SubnetIP, nwCfg.SubnetLen)
-			err = core.Errorf("auto allocation failed - address exhaustion in subnet %s/%d",
+			return "", core.ExhaustedErrorf("auto allocation failed - address exhaustion in subnet %s/%d",
 				nwCfg.SubnetIP, nwCfg.SubnetLen)
-			return "", err
 		}
 
 		ipAddress, err = netutils.GetSubnetIP(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, ipAddrValue)
@@ -655,21 +1034,93 @@ func networkAllocAddress(nwCfg *mastercfg.CfgNetworkState, reqAddr string) (stri
 			return "", err
 		}
 
+		excluded, err := isExcludedAddress(nwCfg, ipAddrValue)
+		if err != nil {
+			return "", err
+		}
+		if excluded {
+			return "", core.InvalidErrorf("address %s is excluded from allocation", reqAddr)
+		}
+
+		// only the address held for the lease may be in use
+		heldKey, held := heldIPLease(nwCfg, reqAddr)
+		if held && heldKey != leaseKey {
+			return "", core.ConflictErrorf("address %s is held for lease %s", reqAddr, heldKey)
+		}
+		if nwCfg.IPAllocMap.Test(ipAddrValue) && !held {
+			return "", core.ConflictErrorf("address %s is already in use", reqAddr)
+		}
+
 		ipAddress = reqAddr
+
+		// the address held for the lease is replaced by the requested one
+		if lease != nil && !lease.Expires.IsZero() && lease.IPAddress != reqAddr {
+			releaseIPLease(nwCfg, lease)
+		}
 	}
 
 	// Set the bitmap
 	nwCfg.IPAllocMap.Set(ipAddrValue)
 
-	err = nwCfg.Write()
-	if err != nil {
-		log.Errorf("error writing nw config. Error: %s", err)
-		return "", err
+	if leaseKey != "" {
+		if nwCfg.IPLeases == nil {
+			nwCfg.IPLeases = make(map[string]*mastercfg.IPLease)
+		}
+		nwCfg.IPLeases[leaseKey] = &mastercfg.IPLease{IPAddress: ipAddress}
 	}
 
 	return ipAddress, nil
 }
 
+// allocIPv6Address allocates an IPv6 address in the network state, which is
+// written by the caller.
+func allocIPv6Address(nwCfg *mastercfg.CfgNetworkState, reqAddr string) (string, error) {
+	var ipAddress string
+	var err error
+
+	if nwCfg.IPv6Subnet == "" {
+		return "", core.InvalidErrorf("network %s has no IPv6 subnet", nwCfg.ID)
+	}
+	if nwCfg.IPv6AllocMap == nil {
+		nwCfg.IPv6AllocMap = map[string]bool{}
+	}
+
+	if reqAddr == "" {
+		ipAddress, err = netutils.GetNextIPv6HostIP(nwCfg.IPv6Subnet,
+			nwCfg.IPv6SubnetLen, nwCfg.IPv6AllocMap)
+		if err != nil {
+			log.Errorf("auto allocation failed in subnet %s/%d. Error: %s",
+				nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen, err)
+			return "", err
+		}
+	} else {
+		if !netutils.IsIPv6InSubnet(nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen, reqAddr) {
+			return "", core.InvalidErrorf("IPv6 address %s is not in subnet %s/%d",
+				reqAddr, nwCfg.IPv6Subnet, nwCfg.IPv6SubnetLen)
+		}
+
+		ipAddress = net.ParseIP(reqAddr).String()
+		if nwCfg.IPv6AllocMap[ipAddress] {
+			return "", core.ConflictErrorf("IPv6 address %s is already in use", reqAddr)
+		}
+	}
+
+	nwCfg.IPv6AllocMap[ipAddress] = true
+	return ipAddress, nil
+}
+
+// networkReleaseIPv6Address releases an IPv6 address of the network
+func networkReleaseIPv6Address(nwCfg *mastercfg.CfgNetworkState, ipAddress string) error {
+	addr := net.ParseIP(ipAddress)
+	if addr == nil {
+		return core.InvalidErrorf("invalid IPv6 address %s", ipAddress)
+	}
+
+	delete(nwCfg.IPv6AllocMap, addr.String())
+	delete(nwCfg.IPAMAddrs, addr.String())
+	return nil
+}
+
 // networkReleaseAddress release the ip address
 func networkReleaseAddress(nwCfg *mastercfg.CfgNetworkState, ipAddress string) error {
 	ipAddrValue, err := netutils.GetIPNumber(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, ipAddress)
@@ -679,7 +1130,15 @@ func networkReleaseAddress(nwCfg *mastercfg.CfgNetworkState, ipAddress string) e
 		return err
 	}
 
-	nwCfg.IPAllocMap.Clear(ipAddrValue)
+	// excluded addresses stay in use, leased ones are held
+	excluded, err := isExcludedAddress(nwCfg, ipAddrValue)
+	if err != nil {
+		return err
+	}
+	if !excluded && !holdIPLease(nwCfg, ipAddress, time.Now()) {
+		nwCfg.IPAllocMap.Clear(ipAddrValue)
+	}
+	delete(nwCfg.IPAMAddrs, ipAddress)
 	nwCfg.EpCount--
 
 	return nil
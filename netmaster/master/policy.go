@@ -16,15 +16,15 @@ limitations under the License.
 package master
 
 import (
+	"github.com/contiv/netplugin/contivModel"
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
-	"github.com/contiv/objmodel/contivModel"
 
 	log "github.com/Sirupsen/logrus"
 )
 
 // EpgPolicyExists is a well known exported error
-var EpgPolicyExists = core.Errorf("Epg policy exists")
+var EpgPolicyExists = core.ConflictErrorf("Epg policy exists")
 
 // PolicyAttach attaches a policy to an endpoint and adds associated rules to policyDB
 func PolicyAttach(epg *contivModel.EndpointGroup, policy *contivModel.Policy) error {
@@ -55,7 +55,7 @@ func PolicyDetach(epg *contivModel.EndpointGroup, policy *contivModel.Policy) er
 	gp := mastercfg.FindEpgPolicy(epgpKey)
 	if gp == nil {
 		log.Errorf("Epg policy %s does not exist", epgpKey)
-		return core.Errorf("epg policy does not exist")
+		return core.NotFoundErrorf("epg policy does not exist")
 	}
 
 	// Delete all rules within the policy
@@ -90,7 +90,7 @@ func PolicyAddRule(policy *contivModel.Policy, rule *contivModel.Rule) error {
 		gp := mastercfg.FindEpgPolicy(gpKey)
 		if gp == nil {
 			log.Errorf("Failed to find the epg policy %s", gpKey)
-			return core.Errorf("epg policy not found")
+			return core.NotFoundErrorf("epg policy not found")
 		}
 
 		// Add the Rule
@@ -120,7 +120,7 @@ func PolicyDelRule(policy *contivModel.Policy, rule *contivModel.Rule) error {
 		gp := mastercfg.FindEpgPolicy(gpKey)
 		if gp == nil {
 			log.Errorf("Failed to find the epg policy %s", gpKey)
-			return core.Errorf("epg policy not found")
+			return core.NotFoundErrorf("epg policy not found")
 		}
 
 		// delete the Rule
@@ -0,0 +1,261 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"strconv"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/gstate"
+	"github.com/contiv/netplugin/netmaster/intent"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// tenantQuota returns the quota set in a tenant's intent
+func tenantQuota(tenant *intent.ConfigTenant) gstate.QuotaParams {
+	return gstate.QuotaParams{
+		MaxNetworks:       tenant.MaxNetworks,
+		MaxEndpoints:      tenant.MaxEndpoints,
+		MaxEndpointGroups: tenant.MaxEndpointGroups,
+		MaxVLANs:          tenant.MaxVLANs,
+	}
+}
+
+// Kinds of objects counted against a tenant's quota
+const (
+	usageNetworks       = "networks"
+	usageEndpoints      = "endpoints"
+	usageEndpointGroups = "endpoint groups"
+	usageVLANs          = "vlans"
+)
+
+// readAllOrNone reads all the states of a type, treating a missing key as
+// no state
+func readAllOrNone(readAll func() ([]core.State, error)) ([]core.State, error) {
+	states, err := readAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// countTenantUsage counts the objects of a tenant in the state. It's only
+// used for tenants whose usage was never recorded, such as the ones created
+// before usage was tracked.
+func countTenantUsage(stateDriver core.StateDriver, tenantName string) (*mastercfg.TenantUsageState, error) {
+	usage := &mastercfg.TenantUsageState{VLANs: make(map[string]int)}
+	useVLAN := func(vlan int) {
+		usage.VLANs[strconv.Itoa(vlan)]++
+	}
+
+	readNet := &mastercfg.CfgNetworkState{}
+	readNet.StateDriver = stateDriver
+	nwCfgs, err := readAllOrNone(readNet.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+	tenantNets := make(map[string]bool)
+	for _, state := range nwCfgs {
+		nwCfg := state.(*mastercfg.CfgNetworkState)
+		if nwCfg.Tenant != tenantName {
+			continue
+		}
+		tenantNets[nwCfg.ID] = true
+		usage.Networks++
+		if nwCfg.PktTagType == "vlan" {
+			useVLAN(nwCfg.PktTag)
+		}
+	}
+
+	readEpg := &mastercfg.EndpointGroupState{}
+	readEpg.StateDriver = stateDriver
+	epgCfgs, err := readAllOrNone(readEpg.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range epgCfgs {
+		epgCfg := state.(*mastercfg.EndpointGroupState)
+		if epgCfg.Tenant != tenantName {
+			continue
+		}
+		usage.EndpointGroups++
+		if epgCfg.PktTagType == "vlan" {
+			useVLAN(epgCfg.PktTag)
+		}
+	}
+
+	readEp := &mastercfg.CfgEndpointState{}
+	readEp.StateDriver = stateDriver
+	epCfgs, err := readAllOrNone(readEp.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range epCfgs {
+		if tenantNets[state.(*mastercfg.CfgEndpointState).NetID] {
+			usage.Endpoints++
+		}
+	}
+
+	return usage, nil
+}
+
+// initTenantUsage records that a new tenant has no objects
+func initTenantUsage(stateDriver core.StateDriver, tenantName string) error {
+	usage := &mastercfg.TenantUsageState{}
+	usage.StateDriver = stateDriver
+	usage.ID = tenantName
+
+	err := usage.WriteIfUnchanged()
+	if core.IsVersionConflict(err) {
+		// recorded already
+		return nil
+	}
+
+	return err
+}
+
+// updateTenantUsage adds count objects of a kind to the usage of a tenant,
+// or removes them if count is negative, failing if an addition exceeds the
+// tenant's quota. A vlan other than zero is used or released by the
+// objects. The usage is written if it was not changed since it was read,
+// so it is to be called in a transaction that is run again on a conflict.
+func updateTenantUsage(stateDriver core.StateDriver, tenantName, kind string, count, vlan int) error {
+	quota, err := readTenantQuota(stateDriver, tenantName)
+	if err != nil {
+		return err
+	}
+
+	usage := &mastercfg.TenantUsageState{}
+	usage.StateDriver = stateDriver
+	err = usage.ReadForUpdate(tenantName)
+	if core.ErrIfKeyExists(err) != nil {
+		return err
+	}
+	if err != nil {
+		usage, err = countTenantUsage(stateDriver, tenantName)
+		if err != nil {
+			return err
+		}
+		usage.StateDriver = stateDriver
+	}
+	usage.ID = tenantName
+
+	used, limit := &usage.Networks, quota.MaxNetworks
+	switch kind {
+	case usageEndpoints:
+		used, limit = &usage.Endpoints, quota.MaxEndpoints
+	case usageEndpointGroups:
+		used, limit = &usage.EndpointGroups, quota.MaxEndpointGroups
+	}
+
+	if count > 0 {
+		err = checkQuota(tenantName, kind, *used, count, limit)
+		if err != nil {
+			return err
+		}
+	}
+	*used += count
+	if *used < 0 {
+		*used = 0
+	}
+
+	if vlan != 0 {
+		vlanKey := strconv.Itoa(vlan)
+		if usage.VLANs == nil {
+			usage.VLANs = make(map[string]int)
+		}
+		if count > 0 && usage.VLANs[vlanKey] == 0 {
+			err = checkQuota(tenantName, usageVLANs, len(usage.VLANs), 1, quota.MaxVLANs)
+			if err != nil {
+				return err
+			}
+		}
+		usage.VLANs[vlanKey] += count
+		if usage.VLANs[vlanKey] <= 0 {
+			delete(usage.VLANs, vlanKey)
+		}
+	}
+
+	return usage.WriteIfUnchanged()
+}
+
+// readTenantUsage returns the recorded usage of a tenant, or counts it if it
+// was never recorded
+func readTenantUsage(stateDriver core.StateDriver, tenantName string) (*mastercfg.TenantUsageState, error) {
+	usage := &mastercfg.TenantUsageState{}
+	usage.StateDriver = stateDriver
+	err := usage.Read(tenantName)
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+	if err != nil {
+		return countTenantUsage(stateDriver, tenantName)
+	}
+
+	return usage, nil
+}
+
+// checkQuota fails if adding count objects to used ones exceeds the limit
+func checkQuota(tenantName, resource string, used, count, limit int) error {
+	if limit > 0 && used+count > limit {
+		log.Errorf("tenant %s is at its quota of %d %s", tenantName, limit, resource)
+		return core.ErrQuotaExceeded(tenantName, resource, limit)
+	}
+
+	return nil
+}
+
+// readTenantQuota returns the quota of a tenant
+func readTenantQuota(stateDriver core.StateDriver, tenantName string) (*gstate.QuotaParams, error) {
+	gCfg := &gstate.Cfg{}
+	gCfg.StateDriver = stateDriver
+	err := gCfg.Read(tenantName)
+	if err != nil {
+		log.Errorf("error reading tenant cfg state. Error: %s", err)
+		return nil, err
+	}
+
+	return &gCfg.Quota, nil
+}
+
+// CheckEndpointGroupQuota fails early if the tenant can't have another
+// endpoint group, using its own vlan if newVLAN is true. The quota is
+// enforced when the group is created.
+func CheckEndpointGroupQuota(stateDriver core.StateDriver, tenantName string, newVLAN bool) error {
+	quota, err := readTenantQuota(stateDriver, tenantName)
+	if err != nil || (quota.MaxEndpointGroups == 0 && (!newVLAN || quota.MaxVLANs == 0)) {
+		return err
+	}
+
+	usage := &mastercfg.TenantUsageState{}
+	usage.StateDriver = stateDriver
+	err = usage.Read(tenantName)
+	if err != nil {
+		// counted when the group is created
+		return core.ErrIfKeyExists(err)
+	}
+
+	err = checkQuota(tenantName, usageEndpointGroups, usage.EndpointGroups, 1,
+		quota.MaxEndpointGroups)
+	if err != nil || !newVLAN {
+		return err
+	}
+
+	return checkQuota(tenantName, usageVLANs, len(usage.VLANs), 1, quota.MaxVLANs)
+}
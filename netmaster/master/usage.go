@@ -0,0 +1,317 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/gstate"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/resources"
+	"github.com/contiv/netplugin/utils/netutils"
+	"github.com/jainvipin/bitset"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ResourceUsage is the utilisation of an allocatable resource
+type ResourceUsage struct {
+	Total     uint     // values in the resource
+	Used      uint     // values allocated
+	Free      uint     // values not allocated
+	Allocated []string // allocated values
+}
+
+// QuotaUsage is the number of objects of a kind, along with the quota of them
+type QuotaUsage struct {
+	Used  int // objects in use
+	Limit int // quota of the objects, zero being no limit
+}
+
+// TenantResourceUsage is the utilisation of the resources of a tenant, and
+// of its quota
+type TenantResourceUsage struct {
+	Tenant         string        // tenant name
+	VLANs          ResourceUsage // vlans for vlan networks
+	VXLANs         ResourceUsage // vxlans for vxlan networks
+	LocalVLANs     ResourceUsage // local vlans mapped to the vxlans
+	Subnets        ResourceUsage // subnets for networks without one
+	Networks       QuotaUsage    // networks of the tenant
+	Endpoints      QuotaUsage    // endpoints in the tenant's networks
+	EndpointGroups QuotaUsage    // endpoint groups of the tenant
+	VLANQuota      QuotaUsage    // vlans used by the tenant's networks and groups
+}
+
+// ResourceUsageReport is the utilisation of the resources of the tenants
+type ResourceUsageReport struct {
+	Global          TenantResourceUsage   // totals of the tenants, without allocated values
+	UnassignedVLANs uint                  // vlans not assigned to any tenant
+	Tenants         []TenantResourceUsage // utilisation per tenant
+}
+
+// NetworkIPUsage is the address utilisation of a network. Only the IPv4
+// host addresses are counted, as IPv6 subnets are allocated sparsely.
+type NetworkIPUsage struct {
+	NetworkID     string   // network name and tenant
+	Subnet        string   // IPv4 subnet in CIDR notation
+	Total         uint     // host addresses in the subnet
+	Used          uint     // host addresses allocated
+	Free          uint     // host addresses not allocated
+	Allocated     []string // allocated addresses
+	IPv6Subnet    string   // IPv6 subnet in CIDR notation
+	IPv6Allocated []string // allocated IPv6 addresses
+}
+
+// bitsetUsage returns the usage of a resource with the values in all, of
+// which the ones in free are not allocated. format turns a value into a
+// string for the list of allocated values.
+func bitsetUsage(all, free *bitset.BitSet, format func(uint) string) ResourceUsage {
+	usage := ResourceUsage{}
+	if all == nil {
+		return usage
+	}
+
+	for value, found := all.NextSet(0); found; value, found = all.NextSet(value + 1) {
+		usage.Total++
+		if free != nil && free.Test(value) {
+			continue
+		}
+		usage.Used++
+		usage.Allocated = append(usage.Allocated, format(value))
+	}
+	usage.Free = usage.Total - usage.Used
+
+	return usage
+}
+
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// getTenantResourceUsage returns the resource and quota utilisation of a
+// tenant
+func getTenantResourceUsage(stateDriver core.StateDriver, gCfg *gstate.Cfg) (*TenantResourceUsage, error) {
+	tenantName := gCfg.Tenant
+	usage := &TenantResourceUsage{Tenant: tenantName}
+
+	objects, err := readTenantUsage(stateDriver, tenantName)
+	if err != nil {
+		return nil, err
+	}
+	usage.Networks = QuotaUsage{Used: objects.Networks, Limit: gCfg.Quota.MaxNetworks}
+	usage.Endpoints = QuotaUsage{Used: objects.Endpoints, Limit: gCfg.Quota.MaxEndpoints}
+	usage.EndpointGroups = QuotaUsage{Used: objects.EndpointGroups,
+		Limit: gCfg.Quota.MaxEndpointGroups}
+	usage.VLANQuota = QuotaUsage{Used: len(objects.VLANs), Limit: gCfg.Quota.MaxVLANs}
+
+	vlanCfg := &resources.AutoVLANCfgResource{}
+	vlanCfg.StateDriver = stateDriver
+	vlanOper := &resources.AutoVLANOperResource{}
+	vlanOper.StateDriver = stateDriver
+	if vlanCfg.Read(tenantName) == nil {
+		err := vlanOper.Read(tenantName)
+		if err != nil {
+			return nil, err
+		}
+		usage.VLANs = bitsetUsage(vlanCfg.VLANs, vlanOper.FreeVLANs, formatUint)
+	}
+
+	vxlanCfg := &resources.AutoVXLANCfgResource{}
+	vxlanCfg.StateDriver = stateDriver
+	vxlanOper := &resources.AutoVXLANOperResource{}
+	vxlanOper.StateDriver = stateDriver
+	if vxlanCfg.Read(tenantName) == nil {
+		err := vxlanOper.Read(tenantName)
+		if err != nil {
+			return nil, err
+		}
+
+		// the vxlan resource holds offsets from the tenant's lowest vxlan
+		gOper := &gstate.Oper{}
+		gOper.StateDriver = stateDriver
+		err = gOper.Read(tenantName)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.VXLANs = bitsetUsage(vxlanCfg.VXLANs, vxlanOper.FreeVXLANs, func(value uint) string {
+			return formatUint(value + gOper.FreeVXLANsStart)
+		})
+		usage.LocalVLANs = bitsetUsage(vxlanCfg.LocalVLANs, vxlanOper.FreeLocalVLANs, formatUint)
+	}
+
+	subnetCfg := &resources.AutoSubnetCfgResource{}
+	subnetCfg.StateDriver = stateDriver
+	subnetOper := &resources.AutoSubnetOperResource{}
+	subnetOper.StateDriver = stateDriver
+	if subnetCfg.Read(tenantName) == nil {
+		err := subnetOper.Read(tenantName)
+		if err != nil {
+			return nil, err
+		}
+
+		poolSize := subnetCfg.AllocSubnetLen - subnetCfg.SubnetPoolLen
+		subnetPool := subnetCfg.SubnetPool.String()
+		usage.Subnets = bitsetUsage(netutils.CreateBitset(poolSize).Complement(),
+			subnetOper.FreeSubnets, func(value uint) string {
+				subnetIP, _ := netutils.GetSubnetIP(subnetPool, subnetCfg.SubnetPoolLen,
+					subnetCfg.AllocSubnetLen, value)
+				return subnetIP + "/" + formatUint(subnetCfg.AllocSubnetLen)
+			})
+	}
+
+	return usage, nil
+}
+
+// addResourceUsage adds the totals of a resource to the global ones
+func addResourceUsage(global *ResourceUsage, usage ResourceUsage) {
+	global.Total += usage.Total
+	global.Used += usage.Used
+	global.Free += usage.Free
+}
+
+// GetResourceUsage returns the resource and quota utilisation of all
+// tenants, or of a single tenant if tenantName is not empty
+func GetResourceUsage(stateDriver core.StateDriver, tenantName string) (*ResourceUsageReport, error) {
+	readCfg := &gstate.Cfg{}
+	readCfg.StateDriver = stateDriver
+
+	gCfgs := []core.State{readCfg}
+	if tenantName != "" {
+		err := readCfg.Read(tenantName)
+		if err != nil {
+			log.Errorf("error reading tenant cfg state. Error: %s", err)
+			return nil, err
+		}
+	} else {
+		var err error
+		gCfgs, err = readAllOrNone(readCfg.ReadAll)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(tenantCfgsByName(gCfgs))
+	}
+
+	report := &ResourceUsageReport{Tenants: []TenantResourceUsage{}}
+	for _, state := range gCfgs {
+		usage, err := getTenantResourceUsage(stateDriver, state.(*gstate.Cfg))
+		if err != nil {
+			return nil, err
+		}
+
+		addResourceUsage(&report.Global.VLANs, usage.VLANs)
+		addResourceUsage(&report.Global.VXLANs, usage.VXLANs)
+		addResourceUsage(&report.Global.LocalVLANs, usage.LocalVLANs)
+		addResourceUsage(&report.Global.Subnets, usage.Subnets)
+		report.Global.Networks.Used += usage.Networks.Used
+		report.Global.Endpoints.Used += usage.Endpoints.Used
+		report.Global.EndpointGroups.Used += usage.EndpointGroups.Used
+		report.Global.VLANQuota.Used += usage.VLANQuota.Used
+		report.Tenants = append(report.Tenants, *usage)
+	}
+
+	availableVLANs, err := gstate.GetAvailableVLANs(stateDriver)
+	if err != nil {
+		return nil, err
+	}
+	report.UnassignedVLANs = availableVLANs.Count()
+
+	return report, nil
+}
+
+type tenantCfgsByName []core.State
+
+func (l tenantCfgsByName) Len() int      { return len(l) }
+func (l tenantCfgsByName) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l tenantCfgsByName) Less(i, j int) bool {
+	return l[i].(*gstate.Cfg).Tenant < l[j].(*gstate.Cfg).Tenant
+}
+
+// NetworkIPUsageOf returns the address utilisation of a network. The
+// subnet and broadcast addresses are not counted, while excluded addresses
+// and the ones held for a lease count as used.
+func NetworkIPUsageOf(nwCfg *mastercfg.CfgNetworkState) NetworkIPUsage {
+	usage := NetworkIPUsage{NetworkID: nwCfg.ID}
+
+	if nwCfg.SubnetIP != "" {
+		usage.Subnet = nwCfg.SubnetIP + "/" + formatUint(nwCfg.SubnetLen)
+
+		maxHosts := uint(0)
+		if nwCfg.SubnetLen < 31 {
+			maxHosts = uint(1<<(32-nwCfg.SubnetLen)) - 2
+		}
+		for hostID := uint(1); hostID <= maxHosts; hostID++ {
+			usage.Total++
+			if !nwCfg.IPAllocMap.Test(hostID) {
+				continue
+			}
+
+			ipAddress, err := netutils.GetSubnetIP(nwCfg.SubnetIP, nwCfg.SubnetLen, 32, hostID)
+			if err != nil {
+				continue
+			}
+			usage.Used++
+			usage.Allocated = append(usage.Allocated, ipAddress)
+		}
+		usage.Free = usage.Total - usage.Used
+	}
+
+	if nwCfg.IPv6Subnet != "" {
+		usage.IPv6Subnet = nwCfg.IPv6Subnet + "/" + formatUint(nwCfg.IPv6SubnetLen)
+		for ipAddress := range nwCfg.IPv6AllocMap {
+			usage.IPv6Allocated = append(usage.IPv6Allocated, ipAddress)
+		}
+		sort.Strings(usage.IPv6Allocated)
+	}
+
+	return usage
+}
+
+// GetNetworkIPUsage returns the address utilisation of all networks, or of
+// a single network if networkID is not empty
+func GetNetworkIPUsage(stateDriver core.StateDriver, networkID string) ([]NetworkIPUsage, error) {
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = stateDriver
+	if networkID != "" {
+		err := nwCfg.Read(networkID)
+		if err != nil {
+			return nil, err
+		}
+
+		return []NetworkIPUsage{NetworkIPUsageOf(nwCfg)}, nil
+	}
+
+	nwCfgs, err := readAllOrNone(nwCfg.ReadAll)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := []NetworkIPUsage{}
+	for _, state := range nwCfgs {
+		usages = append(usages, NetworkIPUsageOf(state.(*mastercfg.CfgNetworkState)))
+	}
+	sort.Sort(ipUsageByNetwork(usages))
+
+	return usages, nil
+}
+
+type ipUsageByNetwork []NetworkIPUsage
+
+func (u ipUsageByNetwork) Len() int           { return len(u) }
+func (u ipUsageByNetwork) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u ipUsageByNetwork) Less(i, j int) bool { return u[i].NetworkID < u[j].NetworkID }
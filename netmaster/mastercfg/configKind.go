@@ -0,0 +1,67 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mastercfg
+
+import (
+	"strings"
+
+	"github.com/contiv/netplugin/core"
+)
+
+// Kinds of the config states that can be watched together, under
+// StateConfigPath
+const (
+	NetworkKind       = "network"
+	EndpointKind      = "endpoint"
+	EndpointGroupKind = "endpointGroup"
+	PolicyKind        = "policy"
+)
+
+// configKinds are the key prefixes of the states of each kind, and a function
+// returning a new state of their type
+var configKinds = []struct {
+	prefix   string
+	kind     string
+	newState func() core.State
+}{
+	{networkConfigPathPrefix, NetworkKind, func() core.State { return &CfgNetworkState{} }},
+	{endpointConfigPathPrefix, EndpointKind, func() core.State { return &CfgEndpointState{} }},
+	{epGroupConfigPathPrefix, EndpointGroupKind, func() core.State { return &EndpointGroupState{} }},
+	{policyConfigPathPrefix, PolicyKind, func() core.State { return &EpgPolicy{} }},
+}
+
+// ConfigKinds returns the kinds of the config states
+func ConfigKinds() []string {
+	kinds := []string{}
+	for _, ck := range configKinds {
+		kinds = append(kinds, ck.kind)
+	}
+
+	return kinds
+}
+
+// ConfigStateOfKey returns the kind of the config state stored at key, and a
+// new state of its type to decode it. ok is false for the keys of the other
+// states.
+func ConfigStateOfKey(key string) (kind string, state core.State, ok bool) {
+	for _, ck := range configKinds {
+		if strings.HasPrefix(key, ck.prefix) {
+			return ck.kind, ck.newState(), true
+		}
+	}
+
+	return "", nil, false
+}
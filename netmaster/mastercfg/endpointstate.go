@@ -32,6 +32,7 @@ type CfgEndpointState struct {
 	EndpointGroupID int    `json:"endpointGroupId"`
 	AttachUUID      string `json:"attachUUID"`
 	IPAddress       string `json:"ipAddress"`
+	IPv6Address     string `json:"ipv6Address,omitempty"`
 	MacAddress      string `json:"macAddress"`
 	HomingHost      string `json:"homingHost"`
 	IntfName        string `json:"intfName"`
@@ -61,8 +62,43 @@ func (s *CfgEndpointState) WatchAll(rsps chan core.WatchState) error {
 		rsps)
 }
 
+// ListAll reads all state along with the revision of the store it was read
+// at, to be passed to WatchAllFrom.
+func (s *CfgEndpointState) ListAll() ([]core.State, uint64, error) {
+	return s.StateDriver.ListAllState(endpointConfigPathPrefix, s, json.Unmarshal)
+}
+
+// WatchAllFrom sends the state transitions made after revision through the
+// channel.
+func (s *CfgEndpointState) WatchAllFrom(revision uint64, rsps chan core.WatchState) error {
+	return s.StateDriver.WatchAllStateFrom(endpointConfigPathPrefix, s, json.Unmarshal,
+		revision, rsps)
+}
+
 // Clear removes the state.
 func (s *CfgEndpointState) Clear() error {
 	key := fmt.Sprintf(endpointConfigPath, s.ID)
 	return s.StateDriver.ClearState(key)
 }
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (s *CfgEndpointState) ReadForUpdate(id string) error {
+	*s = CfgEndpointState{CommonState: core.CommonState{StateDriver: s.StateDriver, ID: id}}
+	key := fmt.Sprintf(endpointConfigPath, id)
+	version, err := s.StateDriver.ReadStateVersion(key, s, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	s.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate. A state that was never read is only written
+// if it doesn't exist yet.
+func (s *CfgEndpointState) WriteIfUnchanged() error {
+	key := fmt.Sprintf(endpointConfigPath, s.ID)
+	return s.StateDriver.WriteStateIfVersion(key, s, json.Marshal, s.Version)
+}
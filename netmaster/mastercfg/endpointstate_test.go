@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 )
 
 const (
@@ -26,7 +27,9 @@ const (
 	epCfgKey = endpointConfigPathPrefix + testEpID
 )
 
-type testEpStateDriver struct{}
+type testEpStateDriver struct {
+	state.StubStateDriver
+}
 
 var epStateDriver = &testEpStateDriver{}
 
@@ -86,6 +89,16 @@ func (d *testEpStateDriver) WriteState(key string, value core.State,
 	return d.validateKey(key)
 }
 
+func (d *testEpStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testEpStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestCfgEndpointStateRead(t *testing.T) {
 	epCfg := &CfgEndpointState{}
 	epCfg.StateDriver = epStateDriver
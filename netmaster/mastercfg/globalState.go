@@ -36,6 +36,7 @@ const (
 type GlobConfig struct {
 	core.CommonState
 	NwInfraType string `json:"nw-infra-type"`
+	MACPrefix   string `json:"mac-prefix,omitempty"`
 }
 
 // Write the state
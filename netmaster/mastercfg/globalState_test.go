@@ -19,13 +19,16 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 )
 
 const (
 	gCfgKey = globalConfigPath
 )
 
-type testglobalStateDriver struct{}
+type testglobalStateDriver struct {
+	state.StubStateDriver
+}
 
 var gcStateDriver = &testglobalStateDriver{}
 
@@ -84,6 +87,16 @@ func (d *testglobalStateDriver) WriteState(key string, value core.State,
 	return d.validateKey(key)
 }
 
+func (d *testglobalStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testglobalStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestGlobConfigRead(t *testing.T) {
 	gcCfg := &GlobConfig{}
 	gcCfg.StateDriver = gcStateDriver
@@ -18,6 +18,7 @@ package mastercfg
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/jainvipin/bitset"
@@ -38,21 +39,45 @@ const (
 )
 
 // CfgNetworkState implements the State interface for a network implemented using
-// vlans with ovs. The state is stored as Json objects.
+// vlans with ovs. The state is stored as Json objects. A network has an IPv4
+// subnet, an IPv6 subnet or both. IPv6 addresses are allocated sparsely, with
+// IPv6AllocMap holding the addresses in use. IPAMAddrs are the addresses
+// handed out by the docker IPAM driver that no endpoint has claimed yet.
+// IPv4 addresses in IPExclusions are never allocated, and those in
+// IPReservations are only allocated to the endpoint or service they are
+// reserved for. IPLeases keep the IPv4 address of an endpoint identity for
+// IPLeaseHoldTime seconds after it's released.
 type CfgNetworkState struct {
 	core.CommonState
-	Tenant            string        `json:"tenant"`
-	NetworkName       string        `json:"networkName"`
-	PktTagType        string        `json:"pktTagType"`
-	PktTag            int           `json:"pktTag"`
-	ExtPktTag         int           `json:"extPktTag"`
-	SubnetIP          string        `json:"subnetIP"`
-	SubnetLen         uint          `json:"subnetLen"`
-	Gateway           string        `json:"gateway"`
-	EpCount           int           `json:"epCount"`
-	IPAllocMap        bitset.BitSet `json:"ipAllocMap"`
-	SubnetIsAllocated bool          `json:"subnetIsAllocated"`
-	DNSServer         string        `json:"dnsServer"`
+	Tenant                string              `json:"tenant"`
+	NetworkName           string              `json:"networkName"`
+	PktTagType            string              `json:"pktTagType"`
+	PktTag                int                 `json:"pktTag"`
+	ExtPktTag             int                 `json:"extPktTag"`
+	SubnetIP              string              `json:"subnetIP"`
+	SubnetLen             uint                `json:"subnetLen"`
+	Gateway               string              `json:"gateway"`
+	EpCount               int                 `json:"epCount"`
+	IPAllocMap            bitset.BitSet       `json:"ipAllocMap"`
+	SubnetIsAllocated     bool                `json:"subnetIsAllocated"`
+	DNSServer             string              `json:"dnsServer"`
+	IPv6Subnet            string              `json:"ipv6Subnet,omitempty"`
+	IPv6SubnetLen         uint                `json:"ipv6SubnetLen,omitempty"`
+	IPv6Gateway           string              `json:"ipv6Gateway,omitempty"`
+	IPv6AllocMap          map[string]bool     `json:"ipv6AllocMap,omitempty"`
+	IPv6SubnetIsAllocated bool                `json:"ipv6SubnetIsAllocated,omitempty"`
+	IPAMAddrs             map[string]bool     `json:"ipamAddrs,omitempty"`
+	IPExclusions          string              `json:"ipExclusions,omitempty"`
+	IPReservations        map[string]string   `json:"ipReservations,omitempty"`
+	IPLeaseHoldTime       int                 `json:"ipLeaseHoldTime,omitempty"`
+	IPLeases              map[string]*IPLease `json:"ipLeases,omitempty"`
+}
+
+// IPLease is the address held for a stable endpoint identity, such as a pod
+// name. Expires is zero while an endpoint uses the address.
+type IPLease struct {
+	IPAddress string    `json:"ipAddress"`
+	Expires   time.Time `json:"expires"`
 }
 
 // Write the state.
@@ -78,8 +103,43 @@ func (s *CfgNetworkState) WatchAll(rsps chan core.WatchState) error {
 		rsps)
 }
 
+// ListAll reads all state along with the revision of the store it was read
+// at, to be passed to WatchAllFrom.
+func (s *CfgNetworkState) ListAll() ([]core.State, uint64, error) {
+	return s.StateDriver.ListAllState(networkConfigPathPrefix, s, json.Unmarshal)
+}
+
+// WatchAllFrom sends the state transitions made after revision through the
+// channel.
+func (s *CfgNetworkState) WatchAllFrom(revision uint64, rsps chan core.WatchState) error {
+	return s.StateDriver.WatchAllStateFrom(networkConfigPathPrefix, s, json.Unmarshal,
+		revision, rsps)
+}
+
 // Clear removes the state.
 func (s *CfgNetworkState) Clear() error {
 	key := fmt.Sprintf(networkConfigPath, s.ID)
 	return s.StateDriver.ClearState(key)
 }
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (s *CfgNetworkState) ReadForUpdate(id string) error {
+	*s = CfgNetworkState{CommonState: core.CommonState{StateDriver: s.StateDriver, ID: id}}
+	key := fmt.Sprintf(networkConfigPath, id)
+	version, err := s.StateDriver.ReadStateVersion(key, s, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	s.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate. A state that was never read is only written
+// if it doesn't exist yet.
+func (s *CfgNetworkState) WriteIfUnchanged() error {
+	key := fmt.Sprintf(networkConfigPath, s.ID)
+	return s.StateDriver.WriteStateIfVersion(key, s, json.Marshal, s.Version)
+}
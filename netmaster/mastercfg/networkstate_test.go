@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 )
 
 const (
@@ -26,7 +27,9 @@ const (
 	nwCfgKey = networkConfigPathPrefix + testNwID
 )
 
-type testNwStateDriver struct{}
+type testNwStateDriver struct {
+	state.StubStateDriver
+}
 
 var nwStateDriver = &testNwStateDriver{}
 
@@ -86,6 +89,16 @@ func (d *testNwStateDriver) WriteState(key string, value core.State,
 	return d.validateKey(key)
 }
 
+func (d *testNwStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testNwStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestCfgNetworkStateRead(t *testing.T) {
 	nwCfg := &CfgNetworkState{}
 	nwCfg.StateDriver = nwStateDriver
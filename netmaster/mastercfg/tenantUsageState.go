@@ -0,0 +1,85 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mastercfg
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/contiv/netplugin/core"
+)
+
+const (
+	tenantUsagePathPrefix = StateConfigPath + "tenantUsage/"
+	tenantUsagePath       = tenantUsagePathPrefix + "%s"
+)
+
+// TenantUsageState counts the objects of a tenant that its quota limits. It
+// is updated along with the objects, so the quota is checked without
+// reading them. VLANs counts the users of each vlan, keyed by vlan number,
+// as groups may share their network's vlan.
+type TenantUsageState struct {
+	core.CommonState
+	Networks       int            `json:"networks"`
+	Endpoints      int            `json:"endpoints"`
+	EndpointGroups int            `json:"endpointGroups"`
+	VLANs          map[string]int `json:"vlans,omitempty"`
+}
+
+// Write the state.
+func (s *TenantUsageState) Write() error {
+	key := fmt.Sprintf(tenantUsagePath, s.ID)
+	return s.StateDriver.WriteState(key, s, json.Marshal)
+}
+
+// Read the state for a given identifier
+func (s *TenantUsageState) Read(id string) error {
+	key := fmt.Sprintf(tenantUsagePath, id)
+	return s.StateDriver.ReadState(key, s, json.Unmarshal)
+}
+
+// ReadAll state and return the collection.
+func (s *TenantUsageState) ReadAll() ([]core.State, error) {
+	return s.StateDriver.ReadAllState(tenantUsagePathPrefix, s, json.Unmarshal)
+}
+
+// Clear removes the state.
+func (s *TenantUsageState) Clear() error {
+	key := fmt.Sprintf(tenantUsagePath, s.ID)
+	return s.StateDriver.ClearState(key)
+}
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (s *TenantUsageState) ReadForUpdate(id string) error {
+	*s = TenantUsageState{CommonState: core.CommonState{StateDriver: s.StateDriver, ID: id}}
+	key := fmt.Sprintf(tenantUsagePath, id)
+	version, err := s.StateDriver.ReadStateVersion(key, s, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	s.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate. A state that was never read is only written
+// if it doesn't exist yet.
+func (s *TenantUsageState) WriteIfUnchanged() error {
+	key := fmt.Sprintf(tenantUsagePath, s.ID)
+	return s.StateDriver.WriteStateIfVersion(key, s, json.Marshal, s.Version)
+}
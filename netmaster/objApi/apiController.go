@@ -16,13 +16,19 @@ limitations under the License.
 package objApi
 
 import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/contiv/netplugin/contivModel"
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/netmaster/intent"
 	"github.com/contiv/netplugin/netmaster/master"
 	"github.com/contiv/netplugin/utils"
-	"github.com/contiv/objmodel/contivModel"
 	"github.com/contiv/objmodel/objdb/modeldb"
 
 	log "github.com/Sirupsen/logrus"
@@ -44,20 +50,29 @@ func NewAPIController(router *mux.Router) *APIController {
 	// initialize the model objects
 	contivModel.Init()
 
-	// Register Callbacks
-	contivModel.RegisterGlobalCallbacks(ctrler)
-	contivModel.RegisterAppCallbacks(ctrler)
-	contivModel.RegisterEndpointGroupCallbacks(ctrler)
-	contivModel.RegisterNetworkCallbacks(ctrler)
-	contivModel.RegisterPolicyCallbacks(ctrler)
-	contivModel.RegisterRuleCallbacks(ctrler)
-	contivModel.RegisterServiceCallbacks(ctrler)
-	contivModel.RegisterServiceInstanceCallbacks(ctrler)
-	contivModel.RegisterTenantCallbacks(ctrler)
-
-	// Register routes
+	// Register Callbacks, which record the changes in the audit log
+	audited := &auditedController{APIController: ctrler}
+	contivModel.RegisterGlobalCallbacks(audited)
+	contivModel.RegisterAppCallbacks(audited)
+	contivModel.RegisterEndpointGroupCallbacks(audited)
+	contivModel.RegisterNetworkCallbacks(audited)
+	contivModel.RegisterPolicyCallbacks(audited)
+	contivModel.RegisterRuleCallbacks(audited)
+	contivModel.RegisterServiceCallbacks(audited)
+	contivModel.RegisterServiceInstanceCallbacks(audited)
+	contivModel.RegisterTenantCallbacks(audited)
+
+	// Register routes. The tenant delete route takes precedence over the
+	// generated one to support cascading deletes
+	router.Path("/api/tenants/{key}/").Methods("DELETE").HandlerFunc(httpDeleteTenant)
 	contivModel.AddRoutes(router)
 
+	// rebuild the endpoint group IDs in use from the existing groups
+	err := master.InitEndpointGroupIDs()
+	if err != nil {
+		log.Fatalf("Error restoring endpoint group IDs. Err: %v", err)
+	}
+
 	return ctrler
 }
 
@@ -107,6 +122,7 @@ func (ac *APIController) GlobalCreate(global *contivModel.Global) error {
 	// Build global config
 	gCfg := intent.ConfigGlobal{
 		NwInfraType: global.NetworkInfraType,
+		MACPrefix:   global.MacPrefix,
 	}
 
 	// Create the object
@@ -132,6 +148,7 @@ func (ac *APIController) GlobalUpdate(global, params *contivModel.Global) error
 	// Build global config
 	gCfg := intent.ConfigGlobal{
 		NwInfraType: params.NetworkInfraType,
+		MACPrefix:   params.MacPrefix,
 	}
 
 	// Create the object
@@ -142,6 +159,7 @@ func (ac *APIController) GlobalUpdate(global, params *contivModel.Global) error
 	}
 
 	global.NetworkInfraType = params.NetworkInfraType
+	global.MacPrefix = params.MacPrefix
 	return nil
 }
 
@@ -157,12 +175,12 @@ func (ac *APIController) AppCreate(app *contivModel.App) error {
 
 	// Make sure tenant exists
 	if app.TenantName == "" {
-		return core.Errorf("Invalid tenant name")
+		return core.InvalidErrorf("Invalid tenant name")
 	}
 
 	tenant := contivModel.FindTenant(app.TenantName)
 	if tenant == nil {
-		return core.Errorf("Tenant not found")
+		return core.NotFoundErrorf("Tenant not found")
 	}
 
 	// Setup links
@@ -192,32 +210,61 @@ func (ac *APIController) AppUpdate(app, params *contivModel.App) error {
 func (ac *APIController) AppDelete(app *contivModel.App) error {
 	log.Infof("Received AppDelete: %+v", app)
 	DeleteAppNw(app)
+
+	// Remove link
+	tenant := contivModel.FindTenant(app.TenantName)
+	if tenant != nil {
+		modeldb.RemoveLinkSet(&tenant.LinkSets.Apps, app)
+		tenant.Write()
+	}
+
 	return nil
 }
 
-// FIXME: hack to allocate unique endpoint group ids
-var globalEpgID = 1
-
 // EndpointGroupCreate creates end point group
-func (ac *APIController) EndpointGroupCreate(endpointGroup *contivModel.EndpointGroup) error {
+func (ac *APIController) EndpointGroupCreate(endpointGroup *contivModel.EndpointGroup) (err error) {
 	log.Infof("Received EndpointGroupCreate: %+v", endpointGroup)
 
-	// assign unique endpoint group ids
-	endpointGroup.EndpointGroupID = globalEpgID
-	globalEpgID = globalEpgID + 1
-
 	// Find the tenant
 	tenant := contivModel.FindTenant(endpointGroup.TenantName)
 	if tenant == nil {
-		return core.Errorf("Tenant not found")
+		return core.NotFoundErrorf("Tenant not found")
+	}
+
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return err
+	}
+
+	// groups get their own vlan in aci mode
+	aciMode, err := master.IsAciConfigured()
+	if err != nil {
+		return err
 	}
 
+	err = master.CheckEndpointGroupQuota(stateDriver, endpointGroup.TenantName, aciMode)
+	if err != nil {
+		return err
+	}
+
+	// assign unique endpoint group ids
+	endpointGroup.EndpointGroupID, err = master.AllocEndpointGroupID()
+	if err != nil {
+		log.Errorf("Error allocating endpoint group ID. Err: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			master.FreeEndpointGroupID(endpointGroup.EndpointGroupID)
+		}
+	}()
+
 	// Setup links
 	modeldb.AddLink(&endpointGroup.Links.Tenant, tenant)
 	modeldb.AddLinkSet(&tenant.LinkSets.EndpointGroups, endpointGroup)
 
 	// Save the tenant too since we added the links
-	err := tenant.Write()
+	err = tenant.Write()
 	if err != nil {
 		return err
 	}
@@ -237,7 +284,7 @@ func (ac *APIController) EndpointGroupCreate(endpointGroup *contivModel.Endpoint
 		policy := contivModel.FindPolicy(policyKey)
 		if policy == nil {
 			log.Errorf("Could not find policy %s", policyName)
-			return core.Errorf("Policy not found")
+			return core.NotFoundErrorf("Policy not found")
 		}
 
 		// attach policy to epg
@@ -276,7 +323,7 @@ func (ac *APIController) EndpointGroupUpdate(endpointGroup, params *contivModel.
 			policy := contivModel.FindPolicy(policyKey)
 			if policy == nil {
 				log.Errorf("Could not find policy %s", policyName)
-				return core.Errorf("Policy not found")
+				return core.NotFoundErrorf("Policy not found")
 			}
 
 			// attach policy to epg
@@ -305,7 +352,7 @@ func (ac *APIController) EndpointGroupUpdate(endpointGroup, params *contivModel.
 			policy := contivModel.FindPolicy(policyKey)
 			if policy == nil {
 				log.Errorf("Could not find policy %s", policyName)
-				return core.Errorf("Policy not found")
+				return core.NotFoundErrorf("Policy not found")
 			}
 
 			// detach policy to epg
@@ -341,6 +388,13 @@ func (ac *APIController) EndpointGroupDelete(endpointGroup *contivModel.Endpoint
 		log.Errorf("Error creating endpoing group %+v. Err: %v", endpointGroup, err)
 	}
 
+	// release the endpoint group ID
+	err = master.FreeEndpointGroupID(endpointGroup.EndpointGroupID)
+	if err != nil {
+		log.Errorf("Error releasing endpoint group ID %d. Err: %v",
+			endpointGroup.EndpointGroupID, err)
+	}
+
 	// Detach the endpoint group from the Policies
 	for _, policyName := range endpointGroup.Policies {
 		policyKey := endpointGroup.TenantName + ":" + policyName
@@ -364,21 +418,45 @@ func (ac *APIController) EndpointGroupDelete(endpointGroup *contivModel.Endpoint
 		policy.Write()
 	}
 
+	// Remove link
+	tenant := contivModel.FindTenant(endpointGroup.TenantName)
+	if tenant != nil {
+		modeldb.RemoveLinkSet(&tenant.LinkSets.EndpointGroups, endpointGroup)
+		tenant.Write()
+	}
+
 	return nil
 }
 
+// parseIPReservations turns a list of name=ip reservations into a map
+func parseIPReservations(reservations []string) (map[string]string, error) {
+	ipReservations := make(map[string]string)
+	for _, reservation := range reservations {
+		fields := strings.Split(reservation, "=")
+		if len(fields) != 2 || fields[0] == "" || net.ParseIP(fields[1]) == nil {
+			return nil, core.InvalidErrorf("invalid ip reservation %s, correct 'name=10.1.1.5'", reservation)
+		}
+		if _, ok := ipReservations[fields[0]]; ok {
+			return nil, core.InvalidErrorf("duplicate ip reservation for %s", fields[0])
+		}
+		ipReservations[fields[0]] = fields[1]
+	}
+
+	return ipReservations, nil
+}
+
 // NetworkCreate creates network
 func (ac *APIController) NetworkCreate(network *contivModel.Network) error {
 	log.Infof("Received NetworkCreate: %+v", network)
 
 	// Make sure tenant exists
 	if network.TenantName == "" {
-		return core.Errorf("Invalid tenant name")
+		return core.InvalidErrorf("Invalid tenant name")
 	}
 
 	tenant := contivModel.FindTenant(network.TenantName)
 	if tenant == nil {
-		return core.Errorf("Tenant not found")
+		return core.NotFoundErrorf("Tenant not found")
 	}
 
 	// Setup links
@@ -398,13 +476,23 @@ func (ac *APIController) NetworkCreate(network *contivModel.Network) error {
 		return err
 	}
 
+	ipReservations, err := parseIPReservations(network.IpReservations)
+	if err != nil {
+		return err
+	}
+
 	// Build networ config
 	networkCfg := intent.ConfigNetwork{
-		Name:       network.NetworkName,
-		PktTagType: network.Encap,
-		PktTag:     network.PktTag,
-		SubnetCIDR: network.Subnet,
-		Gateway:    network.Gateway,
+		Name:            network.NetworkName,
+		PktTagType:      network.Encap,
+		PktTag:          network.PktTag,
+		SubnetCIDR:      network.Subnet,
+		Gateway:         network.Gateway,
+		IPv6SubnetCIDR:  network.Ipv6Subnet,
+		IPv6Gateway:     network.Ipv6Gateway,
+		IPExclusions:    network.IpExclusions,
+		IPReservations:  ipReservations,
+		IPLeaseHoldTime: network.IpLeaseHoldTime,
 	}
 
 	// Create the network
@@ -420,7 +508,7 @@ func (ac *APIController) NetworkCreate(network *contivModel.Network) error {
 // NetworkUpdate updates network
 func (ac *APIController) NetworkUpdate(network, params *contivModel.Network) error {
 	log.Infof("Received NetworkUpdate: %+v, params: %+v", network, params)
-	return core.Errorf("Cant change network parameters after its created")
+	return core.InvalidErrorf("Cant change network parameters after its created")
 }
 
 // NetworkDelete deletes network
@@ -430,7 +518,7 @@ func (ac *APIController) NetworkDelete(network *contivModel.Network) error {
 	// Find the tenant
 	tenant := contivModel.FindTenant(network.TenantName)
 	if tenant == nil {
-		return core.Errorf("Tenant not found")
+		return core.NotFoundErrorf("Tenant not found")
 	}
 
 	// Remove link
@@ -461,7 +549,19 @@ func (ac *APIController) NetworkDelete(network *contivModel.Network) error {
 // PolicyCreate creates policy
 func (ac *APIController) PolicyCreate(policy *contivModel.Policy) error {
 	log.Infof("Received PolicyCreate: %+v", policy)
-	return nil
+
+	// Find the tenant
+	tenant := contivModel.FindTenant(policy.TenantName)
+	if tenant == nil {
+		return core.NotFoundErrorf("Tenant not found")
+	}
+
+	// Setup links
+	modeldb.AddLink(&policy.Links.Tenant, tenant)
+	modeldb.AddLinkSet(&tenant.LinkSets.Policies, policy)
+
+	// Save the tenant too since we added the links
+	return tenant.Write()
 }
 
 // PolicyUpdate updates policy
@@ -476,7 +576,7 @@ func (ac *APIController) PolicyDelete(policy *contivModel.Policy) error {
 
 	// Check if any endpoint group is using the Policy
 	if len(policy.LinkSets.EndpointGroups) != 0 {
-		return core.Errorf("Policy is being used")
+		return core.ConflictErrorf("Policy is being used")
 	}
 
 	// Delete all associated Rules
@@ -488,6 +588,13 @@ func (ac *APIController) PolicyDelete(policy *contivModel.Policy) error {
 		}
 	}
 
+	// Remove link
+	tenant := contivModel.FindTenant(policy.TenantName)
+	if tenant != nil {
+		modeldb.RemoveLinkSet(&tenant.LinkSets.Policies, policy)
+		tenant.Write()
+	}
+
 	return nil
 }
 
@@ -501,7 +608,7 @@ func (ac *APIController) RuleCreate(rule *contivModel.Rule) error {
 	policy := contivModel.FindPolicy(policyKey)
 	if policy == nil {
 		log.Errorf("Error finding policy %s", policyKey)
-		return core.Errorf("Policy not found")
+		return core.NotFoundErrorf("Policy not found")
 	}
 
 	// link the rule to policy
@@ -538,7 +645,7 @@ func (ac *APIController) RuleDelete(rule *contivModel.Rule) error {
 	policy := contivModel.FindPolicy(policyKey)
 	if policy == nil {
 		log.Errorf("Error finding policy %s", policyKey)
-		return core.Errorf("Policy not found")
+		return core.NotFoundErrorf("Policy not found")
 	}
 
 	// unlink the rule from policy
@@ -558,25 +665,150 @@ func (ac *APIController) RuleDelete(rule *contivModel.Rule) error {
 	return nil
 }
 
+// defaultServiceEpgName returns the name of the endpoint group created for
+// a service on a network when the service doesn't specify any
+func defaultServiceEpgName(service *contivModel.Service, netName string) string {
+	return service.AppName + "." + service.ServiceName + "." + netName
+}
+
+// serviceNetworks returns the networks of a service, defaulting to private
+func serviceNetworks(service *contivModel.Service) []string {
+	if len(service.Networks) == 0 {
+		return []string{"private"}
+	}
+
+	return service.Networks
+}
+
+// serviceEpgNames returns the endpoint groups of a service, which default to
+// one per network
+func serviceEpgNames(service *contivModel.Service, networks []string) []string {
+	if len(service.EndpointGroups) != 0 {
+		return service.EndpointGroups
+	}
+
+	epgNames := []string{}
+	for _, netName := range networks {
+		epgNames = append(epgNames, defaultServiceEpgName(service, netName))
+	}
+
+	return epgNames
+}
+
+// linkServiceNetwork links a service with a network
+func linkServiceNetwork(service *contivModel.Service, netName string) error {
+	netKey := service.TenantName + ":" + netName
+	network := contivModel.FindNetwork(netKey)
+	if network == nil {
+		log.Errorf("Service: %s could not find network %s", service.Key, netKey)
+		return core.NotFoundErrorf("Network not found")
+	}
+
+	// Link the network
+	modeldb.AddLinkSet(&service.LinkSets.Networks, network)
+	modeldb.AddLinkSet(&network.LinkSets.Services, service)
+
+	// save the network
+	return network.Write()
+}
+
+// unlinkServiceNetwork removes the links between a service and a network
+func unlinkServiceNetwork(service *contivModel.Service, netName string) error {
+	netKey := service.TenantName + ":" + netName
+	network := contivModel.FindNetwork(netKey)
+	if network == nil {
+		log.Errorf("Service: %s could not find network %s", service.Key, netKey)
+		return nil
+	}
+
+	// Remove links
+	modeldb.RemoveLinkSet(&service.LinkSets.Networks, network)
+	modeldb.RemoveLinkSet(&network.LinkSets.Services, service)
+
+	// save the network
+	return network.Write()
+}
+
+// createDefaultServiceEpg creates the default endpoint group of a service on
+// a network
+func createDefaultServiceEpg(service *contivModel.Service, netName string) error {
+	// params for default endpoint group
+	dfltEpgName := defaultServiceEpgName(service, netName)
+	endpointGroup := contivModel.EndpointGroup{
+		Key:         service.TenantName + ":" + dfltEpgName,
+		TenantName:  service.TenantName,
+		NetworkName: netName,
+		GroupName:   dfltEpgName,
+	}
+
+	// Create default endpoint group for the service
+	err := contivModel.CreateEndpointGroup(&endpointGroup)
+	if err != nil {
+		log.Errorf("Error creating endpoint group: %+v, Err: %v", endpointGroup, err)
+		return err
+	}
+
+	return nil
+}
+
+// linkServiceEpg links a service with an endpoint group
+func linkServiceEpg(service *contivModel.Service, epgName string) error {
+	endpointGroup := contivModel.FindEndpointGroup(service.TenantName + ":" + epgName)
+	if endpointGroup == nil {
+		log.Errorf("Error: could not find endpoint group: %s", epgName)
+		return core.NotFoundErrorf("could not find endpointGroup")
+	}
+
+	// setup links
+	modeldb.AddLinkSet(&service.LinkSets.EndpointGroups, endpointGroup)
+	modeldb.AddLinkSet(&endpointGroup.LinkSets.Services, service)
+
+	// save the endpointGroup
+	return endpointGroup.Write()
+}
+
+// unlinkServiceEpg removes the links between a service and an endpoint
+// group. The endpoint group is deleted if it was created for the service.
+func unlinkServiceEpg(service *contivModel.Service, epgName string) error {
+	endpointGroup := contivModel.FindEndpointGroup(service.TenantName + ":" + epgName)
+	if endpointGroup == nil {
+		log.Errorf("Error: could not find endpoint group: %s", epgName)
+		return nil
+	}
+
+	// Remove links
+	modeldb.RemoveLinkSet(&service.LinkSets.EndpointGroups, endpointGroup)
+	modeldb.RemoveLinkSet(&endpointGroup.LinkSets.Services, service)
+
+	if epgName == defaultServiceEpgName(service, endpointGroup.NetworkName) &&
+		len(endpointGroup.LinkSets.Services) == 0 {
+		log.Infof("Deleting default endpoint group %s of service %s", epgName, service.Key)
+		return contivModel.DeleteEndpointGroup(endpointGroup.Key)
+	}
+
+	// save the endpointGroup
+	return endpointGroup.Write()
+}
+
 // ServiceCreate creates service
 func (ac *APIController) ServiceCreate(service *contivModel.Service) error {
 	log.Infof("Received ServiceCreate: %+v", service)
 
 	// check params
 	if (service.TenantName == "") || (service.AppName == "") {
-		return core.Errorf("Invalid parameters")
+		return core.InvalidErrorf("Invalid parameters")
 	}
 
 	// Make sure tenant exists
 	tenant := contivModel.FindTenant(service.TenantName)
 	if tenant == nil {
-		return core.Errorf("Tenant not found")
+		return core.NotFoundErrorf("Tenant not found")
 	}
 
 	// Find the app this service belongs to
 	app := contivModel.FindApp(service.TenantName + ":" + service.AppName)
 	if app == nil {
-		return core.Errorf("App not found")
+		return core.NotFoundErrorf("App not found")
 	}
 
 	// Setup links
@@ -590,25 +822,11 @@ func (ac *APIController) ServiceCreate(service *contivModel.Service) error {
 	}
 
 	// Check if user specified any networks
-	if len(service.Networks) == 0 {
-		service.Networks = append(service.Networks, "private")
-	}
+	service.Networks = serviceNetworks(service)
 
 	// link service with network
 	for _, netName := range service.Networks {
-		netKey := service.TenantName + ":" + netName
-		network := contivModel.FindNetwork(netKey)
-		if network == nil {
-			log.Errorf("Service: %s could not find network %s", service.Key, netKey)
-			return core.Errorf("Network not found")
-		}
-
-		// Link the network
-		modeldb.AddLinkSet(&service.LinkSets.Networks, network)
-		modeldb.AddLinkSet(&network.LinkSets.Services, service)
-
-		// save the network
-		err := network.Write()
+		err = linkServiceNetwork(service, netName)
 		if err != nil {
 			return err
 		}
@@ -618,41 +836,17 @@ func (ac *APIController) ServiceCreate(service *contivModel.Service) error {
 	if len(service.EndpointGroups) == 0 {
 		// Create one default endpointGroup per network
 		for _, netName := range service.Networks {
-			// params for default endpoint group
-			dfltEpgName := service.AppName + "." + service.ServiceName + "." + netName
-			endpointGroup := contivModel.EndpointGroup{
-				Key:         service.TenantName + ":" + dfltEpgName,
-				TenantName:  service.TenantName,
-				NetworkName: netName,
-				GroupName:   dfltEpgName,
-			}
-
-			// Create default endpoint group for the service
-			err = contivModel.CreateEndpointGroup(&endpointGroup)
+			err = createDefaultServiceEpg(service, netName)
 			if err != nil {
-				log.Errorf("Error creating endpoint group: %+v, Err: %v", endpointGroup, err)
 				return err
 			}
-
-			// Add the endpoint group to the list
-			service.EndpointGroups = append(service.EndpointGroups, dfltEpgName)
 		}
+		service.EndpointGroups = serviceEpgNames(service, service.Networks)
 	}
 
 	// Link the service and endpoint group
 	for _, epgName := range service.EndpointGroups {
-		endpointGroup := contivModel.FindEndpointGroup(service.TenantName + ":" + epgName)
-		if endpointGroup == nil {
-			log.Errorf("Error: could not find endpoint group: %s", epgName)
-			return core.Errorf("could not find endpointGroup")
-		}
-
-		// setup links
-		modeldb.AddLinkSet(&service.LinkSets.EndpointGroups, endpointGroup)
-		modeldb.AddLinkSet(&endpointGroup.LinkSets.Services, service)
-
-		// save the endpointGroup
-		err = endpointGroup.Write()
+		err = linkServiceEpg(service, epgName)
 		if err != nil {
 			return err
 		}
@@ -661,15 +855,126 @@ func (ac *APIController) ServiceCreate(service *contivModel.Service) error {
 	return nil
 }
 
-// ServiceUpdate updates service
+// ServiceUpdate updates the networks and endpoint groups of a service
 func (ac *APIController) ServiceUpdate(service, params *contivModel.Service) error {
 	log.Infof("Received ServiceUpdate: %+v, params: %+v", service, params)
+
+	networks := serviceNetworks(params)
+	epgNames := serviceEpgNames(params, networks)
+	useDefaultEpgs := len(params.EndpointGroups) == 0
+
+	// Look for network adds
+	for _, netName := range networks {
+		if stringInSlice(netName, service.Networks) {
+			continue
+		}
+
+		err := linkServiceNetwork(service, netName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the missing default endpoint groups
+	if useDefaultEpgs {
+		for _, netName := range networks {
+			epgKey := service.TenantName + ":" + defaultServiceEpgName(service, netName)
+			if contivModel.FindEndpointGroup(epgKey) != nil {
+				continue
+			}
+
+			err := createDefaultServiceEpg(service, netName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Look for endpoint group adds
+	for _, epgName := range epgNames {
+		if !stringInSlice(epgName, service.EndpointGroups) {
+			err := linkServiceEpg(service, epgName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// now look for endpoint group removals, before the networks they are on
+	for _, epgName := range service.EndpointGroups {
+		if !stringInSlice(epgName, epgNames) {
+			err := unlinkServiceEpg(service, epgName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// now look for network removals
+	for _, netName := range service.Networks {
+		if !stringInSlice(netName, networks) {
+			err := unlinkServiceNetwork(service, netName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Update the service. The parameters are what gets saved, so they get
+	// the links too
+	service.Networks = networks
+	service.EndpointGroups = epgNames
+	params.Networks = networks
+	params.EndpointGroups = epgNames
+	params.LinkSets = service.LinkSets
+	params.Links.App.ObjType = service.Links.App.ObjType
+	params.Links.App.ObjKey = service.Links.App.ObjKey
+
 	return nil
 }
 
 // ServiceDelete deletes service
 func (ac *APIController) ServiceDelete(service *contivModel.Service) error {
 	log.Infof("Received ServiceDelete: %+v", service)
+
+	// Delete the service instances
+	for instKey := range service.LinkSets.Instances {
+		err := contivModel.DeleteServiceInstance(instKey)
+		if err != nil {
+			log.Errorf("Error deleting service instance %s. Err: %v", instKey, err)
+			return err
+		}
+	}
+
+	// Unlink the endpoint groups, deleting the default ones
+	for _, epgName := range service.EndpointGroups {
+		err := unlinkServiceEpg(service, epgName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Unlink the networks
+	for _, netName := range service.Networks {
+		err := unlinkServiceNetwork(service, netName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Unlink the app
+	app := contivModel.FindApp(service.TenantName + ":" + service.AppName)
+	if app != nil {
+		modeldb.RemoveLinkSet(&app.LinkSets.Services, service)
+		modeldb.RemoveLink(&service.Links.App, app)
+
+		// Save the app too since we removed the links
+		err := app.Write()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -683,14 +988,15 @@ func (ac *APIController) ServiceInstanceCreate(serviceInstance *contivModel.Serv
 	service := contivModel.FindService(serviceKey)
 	if service == nil {
 		log.Errorf("Service %s not found for instance: %+v", serviceKey, inst)
-		return core.Errorf("Service not found")
+		return core.NotFoundErrorf("Service not found")
 	}
 
 	// Add links
 	modeldb.AddLinkSet(&service.LinkSets.Instances, inst)
 	modeldb.AddLink(&inst.Links.Service, service)
 
-	return nil
+	// Save the service too since we added the links
+	return service.Write()
 }
 
 // ServiceInstanceUpdate updates a service instance
@@ -702,6 +1008,22 @@ func (ac *APIController) ServiceInstanceUpdate(serviceInstance, params *contivMo
 // ServiceInstanceDelete deletes a service instance
 func (ac *APIController) ServiceInstanceDelete(serviceInstance *contivModel.ServiceInstance) error {
 	log.Infof("Received ServiceInstanceDelete: %+v", serviceInstance)
+	inst := serviceInstance
+
+	// Remove links
+	serviceKey := inst.TenantName + ":" + inst.AppName + ":" + inst.ServiceName
+	service := contivModel.FindService(serviceKey)
+	if service != nil {
+		modeldb.RemoveLinkSet(&service.LinkSets.Instances, inst)
+		modeldb.RemoveLink(&inst.Links.Service, service)
+
+		// Save the service too since we removed the links
+		err := service.Write()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -710,7 +1032,7 @@ func (ac *APIController) TenantCreate(tenant *contivModel.Tenant) error {
 	log.Infof("Received TenantCreate: %+v", tenant)
 
 	if tenant.TenantName == "" {
-		return core.Errorf("Invalid tenant name")
+		return core.InvalidErrorf("Invalid tenant name")
 	}
 
 	// Get the state driver
@@ -728,6 +1050,15 @@ func (ac *APIController) TenantCreate(tenant *contivModel.Tenant) error {
 		AllocSubnetLen: uint(tenant.SubnetLen),
 		VLANs:          tenant.Vlans,
 		VXLANs:         tenant.Vxlans,
+		MACPrefix:      tenant.MacPrefix,
+
+		IPv6SubnetPool:     tenant.Ipv6SubnetPool,
+		AllocIPv6SubnetLen: uint(tenant.Ipv6SubnetLen),
+
+		MaxNetworks:       tenant.MaxNetworks,
+		MaxEndpoints:      tenant.MaxEndpoints,
+		MaxEndpointGroups: tenant.MaxEndpointGroups,
+		MaxVLANs:          tenant.MaxVlans,
 	}
 
 	// Create the tenant
@@ -744,21 +1075,201 @@ func (ac *APIController) TenantCreate(tenant *contivModel.Tenant) error {
 func (ac *APIController) TenantUpdate(tenant, params *contivModel.Tenant) error {
 	log.Infof("Received TenantUpdate: %+v, params: %+v", tenant, params)
 
-	return core.Errorf("Cant change tenant parameters after its created")
+	if params.TenantName != tenant.TenantName {
+		return core.InvalidErrorf("Cant change tenant name after its created")
+	}
+
+	// Get the state driver
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return err
+	}
+
+	// Build tenant config, keeping the tenant's default net type
+	tenantCfg := intent.ConfigTenant{
+		Name:           params.TenantName,
+		DefaultNetwork: params.DefaultNetwork,
+		SubnetPool:     params.SubnetPool,
+		AllocSubnetLen: uint(params.SubnetLen),
+		VLANs:          params.Vlans,
+		VXLANs:         params.Vxlans,
+		MACPrefix:      params.MacPrefix,
+
+		IPv6SubnetPool:     params.Ipv6SubnetPool,
+		AllocIPv6SubnetLen: uint(params.Ipv6SubnetLen),
+
+		MaxNetworks:       params.MaxNetworks,
+		MaxEndpoints:      params.MaxEndpoints,
+		MaxEndpointGroups: params.MaxEndpointGroups,
+		MaxVLANs:          params.MaxVlans,
+	}
+
+	// Resize the tenant's resources
+	err = master.UpdateTenant(stateDriver, &tenantCfg)
+	if err != nil {
+		log.Errorf("Error updating tenant {%+v}. Err: %v", tenant, err)
+		return err
+	}
+
+	tenant.DefaultNetwork = params.DefaultNetwork
+	tenant.SubnetPool = params.SubnetPool
+	tenant.SubnetLen = params.SubnetLen
+	tenant.Vlans = params.Vlans
+	tenant.Vxlans = params.Vxlans
+	tenant.MacPrefix = params.MacPrefix
+	tenant.Ipv6SubnetPool = params.Ipv6SubnetPool
+	tenant.Ipv6SubnetLen = params.Ipv6SubnetLen
+	tenant.MaxNetworks = params.MaxNetworks
+	tenant.MaxEndpoints = params.MaxEndpoints
+	tenant.MaxEndpointGroups = params.MaxEndpointGroups
+	tenant.MaxVlans = params.MaxVlans
+	params.LinkSets = tenant.LinkSets
+
+	return nil
+}
+
+// tenantObjectTypes are the types of the model objects that belong to a
+// tenant, in the order they are deleted: services and apps before the
+// endpoint groups, and those before the networks and policies they use.
+var tenantObjectTypes = []struct {
+	objType string
+	del     func(r *http.Request, key string) error
+}{
+	{"service", contivModel.DeleteServiceFor},
+	{"app", contivModel.DeleteAppFor},
+	{"endpointGroup", contivModel.DeleteEndpointGroupFor},
+	{"policy", contivModel.DeletePolicyFor},
+	{"network", contivModel.DeleteNetworkFor},
+}
+
+// tenantObjects returns the keys of the model objects of type objType that
+// belong to a tenant. The objects are found by their tenant name, so that
+// the ones without a link from the tenant are found as well.
+func tenantObjects(objType, tenantName string) ([]string, error) {
+	values, err := modeldb.ReadAllObj(objType)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, value := range values {
+		obj := struct {
+			Key        string `json:"key"`
+			TenantName string `json:"tenantName"`
+		}{}
+		err = json.Unmarshal([]byte(value), &obj)
+		if err != nil {
+			log.Errorf("Error decoding %s %s. Err: %v", objType, value, err)
+			continue
+		}
+		if obj.TenantName == tenantName {
+			keys = append(keys, obj.Key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// tenantDependents returns the types and keys of the objects that belong to
+// a tenant
+func tenantDependents(tenant *contivModel.Tenant) ([]string, error) {
+	dependents := []string{}
+	for _, t := range tenantObjectTypes {
+		keys, err := tenantObjects(t.objType, tenant.TenantName)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			dependents = append(dependents, t.objType+" "+key)
+		}
+	}
+
+	return dependents, nil
+}
+
+// deleteTenantDependents deletes the objects that belong to a tenant, in the
+// order of tenantObjectTypes, on behalf of the request r.
+func deleteTenantDependents(r *http.Request, tenant *contivModel.Tenant) error {
+	log.Infof("Deleting the objects of tenant %s", tenant.TenantName)
+
+	for _, t := range tenantObjectTypes {
+		keys, err := tenantObjects(t.objType, tenant.TenantName)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			err = t.del(r, key)
+			if err != nil {
+				log.Errorf("Error deleting %s %s. Err: %v", t.objType, key, err)
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
-// TenantDelete deletes a tenant
+// httpDeleteTenant deletes a tenant. With the cascade query parameter set,
+// the objects of the tenant are deleted first.
+func httpDeleteTenant(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	err := func() error {
+		cascade := false
+		if value := r.URL.Query().Get("cascade"); value != "" {
+			var err error
+			cascade, err = strconv.ParseBool(value)
+			if err != nil {
+				return core.InvalidErrorf("Invalid cascade value %q", value)
+			}
+		}
+
+		tenant := contivModel.FindTenant(key)
+		if tenant == nil {
+			return core.NotFoundErrorf("Tenant %s not found", key)
+		}
+		if cascade {
+			err := deleteTenantDependents(r, tenant)
+			if err != nil {
+				return err
+			}
+		}
+
+		return contivModel.DeleteTenantFor(r, key)
+	}()
+	if err != nil {
+		log.Errorf("Handler for %s %s returned error: %s", r.Method, r.URL, err)
+		core.WriteHTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(key)
+}
+
+// TenantDelete deletes a tenant. It fails while the tenant has objects.
 func (ac *APIController) TenantDelete(tenant *contivModel.Tenant) error {
 	log.Infof("Received TenantDelete: %+v", tenant)
 
+	// Refuse to orphan the objects of the tenant
+	dependents, err := tenantDependents(tenant)
+	if err != nil {
+		return err
+	}
+	if len(dependents) != 0 {
+		return core.ConflictErrorf("Tenant %s has dependent objects: %v. Delete them first or use cascade",
+			tenant.TenantName, dependents)
+	}
+
 	// Get the state driver
 	stateDriver, err := utils.GetStateDriver()
 	if err != nil {
 		return err
 	}
 
-	// FIXME: Should we walk all objects under the tenant and delete it?
-
 	// Delete the tenant
 	err = master.DeleteTenantID(stateDriver, tenant.TenantName)
 	if err != nil {
@@ -0,0 +1,434 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objApi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/contiv/netplugin/contivModel"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/audit"
+	"github.com/contiv/netplugin/resources"
+	"github.com/contiv/netplugin/state"
+	"github.com/contiv/netplugin/utils"
+	"github.com/contiv/objmodel/objdb"
+	"github.com/contiv/objmodel/objdb/modeldb"
+	"github.com/gorilla/mux"
+)
+
+// fakeEtcd serves the etcd v2 keys API from memory, so that the model
+// objects can be persisted through objdb without an etcd server
+type fakeEtcd struct {
+	mutex sync.Mutex
+	keys  map[string]string
+	index uint64
+}
+
+type fakeEtcdNode struct {
+	Key           string          `json:"key"`
+	Value         string          `json:"value,omitempty"`
+	Dir           bool            `json:"dir,omitempty"`
+	Nodes         []*fakeEtcdNode `json:"nodes,omitempty"`
+	ModifiedIndex uint64          `json:"modifiedIndex"`
+	CreatedIndex  uint64          `json:"createdIndex"`
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v2/keys")
+	node := &fakeEtcdNode{Key: key, ModifiedIndex: f.index, CreatedIndex: f.index}
+	action := strings.ToLower(r.Method)
+
+	switch r.Method {
+	case "PUT":
+		r.ParseForm()
+		f.index++
+		f.keys[key] = r.Form.Get("value")
+		node.Value = f.keys[key]
+		node.ModifiedIndex = f.index
+		action = "set"
+	case "GET":
+		value, ok := f.keys[key]
+		if ok {
+			node.Value = value
+			break
+		}
+		node.Dir = true
+		for k, v := range f.keys {
+			if strings.HasPrefix(k, key+"/") {
+				node.Nodes = append(node.Nodes, &fakeEtcdNode{Key: k, Value: v})
+			}
+		}
+		if len(node.Nodes) == 0 {
+			f.notFound(w, key)
+			return
+		}
+	case "DELETE":
+		if _, ok := f.keys[key]; !ok {
+			f.notFound(w, key)
+			return
+		}
+		f.index++
+		delete(f.keys, key)
+	}
+
+	w.Header().Set("X-Etcd-Index", strconv.FormatUint(f.index, 10))
+	json.NewEncoder(w).Encode(map[string]interface{}{"action": action, "node": node})
+}
+
+// hasObj tells if a model object is persisted
+func (f *fakeEtcd) hasObj(objType, key string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, ok := f.keys["/contiv.io/obj/modeldb/"+objType+"/"+key]
+	return ok
+}
+
+func (f *fakeEtcd) notFound(w http.ResponseWriter, key string) {
+	w.Header().Set("X-Etcd-Index", strconv.FormatUint(f.index, 10))
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errorCode": 100, "message": "Key not found", "cause": key, "index": f.index,
+	})
+}
+
+// setupModel points objdb to a fake etcd holding the given model objects,
+// over a fake state driver, and creates an API controller that restores
+// them. The returned function releases them.
+func setupModel(t *testing.T, objs ...modeldb.ModelObj) (*fakeEtcd, *mux.Router, func()) {
+	etcd := &fakeEtcd{keys: make(map[string]string)}
+	server := httptest.NewServer(etcd)
+	if err := objdb.GetPlugin("etcd").Init([]string{server.URL}); err != nil {
+		t.Fatalf("objdb init failed. Error: %s", err)
+	}
+
+	config := &core.Config{V: &state.FakeStateDriverConfig{}}
+	cfgBytes, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("marshalling configuration failed. Error: %s", err)
+	}
+	sd, err := utils.NewStateDriver("fakedriver", string(cfgBytes))
+	if err != nil {
+		t.Fatalf("failed to init statedriver. Error: %s", err)
+	}
+	if _, err := resources.NewStateResourceManager(sd); err != nil {
+		t.Fatalf("failed to init resource manager. Error: %s", err)
+	}
+
+	for _, obj := range objs {
+		if err := obj.Write(); err != nil {
+			t.Fatalf("error writing %s %s. Error: %s", obj.GetType(), obj.GetKey(), err)
+		}
+	}
+
+	router := mux.NewRouter()
+	NewAPIController(router)
+
+	return etcd, router, func() {
+		resources.ReleaseStateResourceManager()
+		utils.ReleaseStateDriver()
+		server.Close()
+	}
+}
+
+// linkKeys returns the sorted keys of a link set
+func linkKeys(linkSet map[string]modeldb.Link) []string {
+	keys := []string{}
+	for key := range linkSet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestServiceEpgNames(t *testing.T) {
+	service := &contivModel.Service{
+		AppName:     "app",
+		ServiceName: "web",
+	}
+
+	networks := serviceNetworks(service)
+	if !reflect.DeepEqual(networks, []string{"private"}) {
+		t.Fatalf("unexpected default networks %v", networks)
+	}
+
+	service.Networks = []string{"net1", "net2"}
+	networks = serviceNetworks(service)
+	epgNames := serviceEpgNames(service, networks)
+	if !reflect.DeepEqual(epgNames, []string{"app.web.net1", "app.web.net2"}) {
+		t.Fatalf("unexpected default endpoint groups %v", epgNames)
+	}
+
+	service.EndpointGroups = []string{"epg1"}
+	epgNames = serviceEpgNames(service, networks)
+	if !reflect.DeepEqual(epgNames, []string{"epg1"}) {
+		t.Fatalf("unexpected endpoint groups %v", epgNames)
+	}
+}
+
+func TestServiceInstanceLinks(t *testing.T) {
+	service := &contivModel.Service{
+		Key:         "tenant1:app1:web",
+		TenantName:  "tenant1",
+		AppName:     "app1",
+		ServiceName: "web",
+	}
+	_, _, teardown := setupModel(t, service)
+	defer teardown()
+
+	for _, id := range []string{"1", "2"} {
+		err := contivModel.CreateServiceInstance(&contivModel.ServiceInstance{
+			Key:         "tenant1:app1:web:" + id,
+			TenantName:  "tenant1",
+			AppName:     "app1",
+			ServiceName: "web",
+			InstanceID:  id,
+		})
+		if err != nil {
+			t.Fatalf("error creating service instance %s. Error: %s", id, err)
+		}
+	}
+
+	if err := contivModel.DeleteServiceInstance("tenant1:app1:web:1"); err != nil {
+		t.Fatalf("error deleting service instance. Error: %s", err)
+	}
+
+	// the links are saved with the service
+	saved := &contivModel.Service{}
+	if err := modeldb.ReadObj("service", service.Key, saved); err != nil {
+		t.Fatalf("error reading service. Error: %s", err)
+	}
+	instances := linkKeys(saved.LinkSets.Instances)
+	if !reflect.DeepEqual(instances, []string{"tenant1:app1:web:2"}) {
+		t.Fatalf("unexpected saved service instances %v", instances)
+	}
+
+	instances = linkKeys(contivModel.FindService(service.Key).LinkSets.Instances)
+	if !reflect.DeepEqual(instances, []string{"tenant1:app1:web:2"}) {
+		t.Fatalf("unexpected service instances %v", instances)
+	}
+}
+
+func TestServiceDeleteInstances(t *testing.T) {
+	app := &contivModel.App{Key: "tenant1:app1", TenantName: "tenant1", AppName: "app1"}
+	service := &contivModel.Service{
+		Key:         "tenant1:app1:web",
+		TenantName:  "tenant1",
+		AppName:     "app1",
+		ServiceName: "web",
+	}
+	inst := &contivModel.ServiceInstance{
+		Key:         "tenant1:app1:web:1",
+		TenantName:  "tenant1",
+		AppName:     "app1",
+		ServiceName: "web",
+		InstanceID:  "1",
+	}
+	modeldb.AddLinkSet(&app.LinkSets.Services, service)
+	modeldb.AddLink(&service.Links.App, app)
+	modeldb.AddLinkSet(&service.LinkSets.Instances, inst)
+	modeldb.AddLink(&inst.Links.Service, service)
+
+	etcd, _, teardown := setupModel(t, app, service, inst)
+	defer teardown()
+
+	if err := contivModel.DeleteService(service.Key); err != nil {
+		t.Fatalf("error deleting service. Error: %s", err)
+	}
+
+	if contivModel.FindServiceInstance(inst.Key) != nil || etcd.hasObj("serviceInstance", inst.Key) {
+		t.Fatalf("service instance exists after the service was deleted")
+	}
+	if etcd.hasObj("service", service.Key) {
+		t.Fatalf("service exists after it was deleted")
+	}
+
+	saved := &contivModel.App{}
+	if err := modeldb.ReadObj("app", app.Key, saved); err != nil {
+		t.Fatalf("error reading app. Error: %s", err)
+	}
+	if services := linkKeys(saved.LinkSets.Services); len(services) != 0 {
+		t.Fatalf("app still links to services %v", services)
+	}
+}
+
+// deleteTenant deletes a tenant through the REST API, returning the status
+// and error kind of the response
+func deleteTenant(router *mux.Router, url string) (int, core.ErrorKind) {
+	return modelRequest(router, "DELETE", url, "")
+}
+
+// modelRequest makes a request to the REST API, returning the status and
+// error kind of the response
+func modelRequest(router *mux.Router, method, url, body string) (int, core.ErrorKind) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	rsp := httptest.NewRecorder()
+	router.ServeHTTP(rsp, req)
+
+	if rsp.Code == http.StatusOK {
+		return rsp.Code, core.KindUnknown
+	}
+	return rsp.Code, core.ErrorKindOf(core.DecodeHTTPError(rsp.Code, rsp.Body.Bytes()))
+}
+
+func TestTenantDeleteDependents(t *testing.T) {
+	tenant := &contivModel.Tenant{Key: "tenant1", TenantName: "tenant1"}
+	// the app is not linked from the tenant
+	app := &contivModel.App{Key: "tenant1:app1", TenantName: "tenant1", AppName: "app1"}
+	other := &contivModel.App{Key: "tenant2:app1", TenantName: "tenant2", AppName: "app1"}
+
+	etcd, router, teardown := setupModel(t, tenant, app, other)
+	defer teardown()
+
+	dependents, err := tenantDependents(tenant)
+	if err != nil {
+		t.Fatalf("error finding the tenant's objects. Error: %s", err)
+	}
+	if !reflect.DeepEqual(dependents, []string{"app tenant1:app1"}) {
+		t.Fatalf("unexpected tenant objects %v", dependents)
+	}
+
+	status, kind := deleteTenant(router, "/api/tenants/tenant1/")
+	if status != http.StatusConflict || kind != core.KindConflict {
+		t.Fatalf("delete of a tenant with objects returned %d %q, expected a conflict",
+			status, kind)
+	}
+	if !etcd.hasObj("tenant", tenant.Key) {
+		t.Fatalf("tenant with objects was deleted")
+	}
+
+	status, kind = deleteTenant(router, "/api/tenants/tenant1/?cascade=yes")
+	if status != http.StatusBadRequest || kind != core.KindInvalid {
+		t.Fatalf("delete with an invalid cascade returned %d %q", status, kind)
+	}
+
+	status, _ = deleteTenant(router, "/api/tenants/tenant1/?cascade=true")
+	if status != http.StatusOK {
+		t.Fatalf("cascading delete returned %d", status)
+	}
+	if etcd.hasObj("tenant", tenant.Key) || etcd.hasObj("app", app.Key) {
+		t.Fatalf("tenant or its app exists after a cascading delete")
+	}
+	if !etcd.hasObj("app", other.Key) {
+		t.Fatalf("app of another tenant was deleted")
+	}
+
+	status, kind = deleteTenant(router, "/api/tenants/tenant1/")
+	if status != http.StatusNotFound || kind != core.KindNotFound {
+		t.Fatalf("delete of a missing tenant returned %d %q", status, kind)
+	}
+}
+
+func TestModelErrorStatus(t *testing.T) {
+	_, router, teardown := setupModel(t)
+	defer teardown()
+
+	for _, test := range []struct {
+		method, url, body string
+		status            int
+		kind              core.ErrorKind
+	}{
+		{"GET", "/api/apps/tenant1:app1/", "", http.StatusNotFound, core.KindNotFound},
+		{"DELETE", "/api/apps/tenant1:app1/", "", http.StatusNotFound, core.KindNotFound},
+		{"POST", "/api/networks/tenant1:net1/", "{", http.StatusBadRequest, core.KindInvalid},
+		{"POST", "/api/networks/tenant1:net1/",
+			`{"tenantName": "tenant1", "networkName": "net1", "encap": "gre",
+			"subnet": "10.1.1.0/24", "gateway": "10.1.1.254"}`,
+			http.StatusBadRequest, core.KindInvalid},
+		{"POST", "/api/networks/tenant1:net1/",
+			`{"tenantName": "tenant1", "networkName": "net1", "encap": "vlan",
+			"subnet": "10.1.1.0/24", "gateway": "10.1.1.254"}`,
+			http.StatusNotFound, core.KindNotFound},
+	} {
+		status, kind := modelRequest(router, test.method, test.url, test.body)
+		if status != test.status || kind != test.kind {
+			t.Fatalf("%s %s returned %d %q, expected %d %q", test.method, test.url,
+				status, kind, test.status, test.kind)
+		}
+	}
+}
+
+// memorySink keeps the audit records in memory
+type memorySink struct {
+	mutex   sync.Mutex
+	records []*audit.Record
+}
+
+func (s *memorySink) Write(rec *audit.Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memorySink) Query(filter *audit.Filter) ([]*audit.Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.records, nil
+}
+
+func TestAuditCaller(t *testing.T) {
+	tenant := &contivModel.Tenant{Key: "tenant1", TenantName: "tenant1"}
+	app := &contivModel.App{Key: "tenant1:app1", TenantName: "tenant1", AppName: "app1"}
+	other := &contivModel.App{Key: "tenant2:app1", TenantName: "tenant2", AppName: "app1"}
+	_, router, teardown := setupModel(t, tenant, app, other)
+	defer teardown()
+
+	sink := &memorySink{}
+	audit.SetSink(sink)
+	defer audit.SetSink(nil)
+
+	alice := audit.Caller{Name: "alice", Address: "10.1.1.1:5000"}
+	(&APIController{router: router}).AuditRequests(func(r *http.Request) audit.Caller {
+		return alice
+	})
+	defer contivModel.RegisterRequestCallbacks(nil)
+
+	// the changes of a request, including its cascading deletes, are the
+	// caller's
+	status, _ := deleteTenant(router, "/api/tenants/tenant1/?cascade=true")
+	if status != http.StatusOK {
+		t.Fatalf("cascading delete returned %d", status)
+	}
+
+	// changes made without a request are netmaster's
+	err := contivModel.DeleteApp(other.Key)
+	if err != nil {
+		t.Fatalf("error deleting app. Error: %s", err)
+	}
+
+	callers := []string{}
+	for _, rec := range sink.records {
+		callers = append(callers, rec.Kind+" "+rec.Key+" "+rec.Caller.Name)
+	}
+	expCallers := []string{"app tenant1:app1 alice", "tenant tenant1 alice", "app tenant2:app1 netmaster"}
+	if !reflect.DeepEqual(callers, expCallers) {
+		t.Fatalf("unexpected audit callers %v, expected %v", callers, expCallers)
+	}
+}
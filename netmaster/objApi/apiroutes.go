@@ -0,0 +1,65 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objApi
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/contiv/netplugin/contivModel"
+	"github.com/contiv/netplugin/netmaster/master"
+)
+
+// APIRoutes describes the REST routes of the model objects, which
+// contivModel.AddRoutes adds under /api/, for the description of the API.
+// They have no handlers of their own.
+func APIRoutes() []master.APIRoute {
+	routes := []master.APIRoute{}
+	for _, obj := range []struct {
+		path string
+		list interface{}
+	}{
+		{"apps", []contivModel.App{}},
+		{"endpointGroups", []contivModel.EndpointGroup{}},
+		{"globals", []contivModel.Global{}},
+		{"networks", []contivModel.Network{}},
+		{"policys", []contivModel.Policy{}},
+		{"rules", []contivModel.Rule{}},
+		{"services", []contivModel.Service{}},
+		{"serviceInstances", []contivModel.ServiceInstance{}},
+		{"tenants", []contivModel.Tenant{}},
+		{"volumes", []contivModel.Volume{}},
+		{"volumeProfiles", []contivModel.VolumeProfile{}},
+	} {
+		objType := reflect.TypeOf(obj.list).Elem()
+		objValue := reflect.New(objType).Elem().Interface()
+		path := fmt.Sprintf("/%s/{key}/", obj.path)
+
+		routes = append(routes,
+			master.APIRoute{Method: "GET", Path: fmt.Sprintf("/%s/", obj.path),
+				Summary: fmt.Sprintf("List the %s objects", objType.Name()), Response: obj.list},
+			master.APIRoute{Method: "GET", Path: path,
+				Summary: fmt.Sprintf("Get a %s", objType.Name()), Response: objValue},
+			master.APIRoute{Method: "POST", Path: path,
+				Summary: fmt.Sprintf("Create or update a %s", objType.Name()), Request: objValue, Response: objValue},
+			master.APIRoute{Method: "PUT", Path: path,
+				Summary: fmt.Sprintf("Create or update a %s", objType.Name()), Request: objValue, Response: objValue},
+			master.APIRoute{Method: "DELETE", Path: path,
+				Summary: fmt.Sprintf("Delete a %s", objType.Name()), Response: ""})
+	}
+
+	return routes
+}
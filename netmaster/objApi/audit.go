@@ -0,0 +1,273 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objApi
+
+import (
+	"net/http"
+
+	"github.com/contiv/netplugin/contivModel"
+	"github.com/contiv/netplugin/netmaster/audit"
+)
+
+// AuditRequests names the caller that caller returns in the audit records
+// of the changes requests make. Changes made without a request, and the
+// changes the callbacks make to other objects, are recorded as netmaster's.
+func (ac *APIController) AuditRequests(caller func(r *http.Request) audit.Caller) {
+	contivModel.RegisterRequestCallbacks(func(r *http.Request) *contivModel.CallbackHandlers {
+		return auditedCallbacks(&auditedController{ac, caller(r)})
+	})
+}
+
+// auditedController calls the callbacks of the controller and records the
+// changes they make in the audit log, on behalf of caller
+type auditedController struct {
+	*APIController
+	caller audit.Caller
+}
+
+// auditedCallbacks returns the model callback handlers of an audited
+// controller
+func auditedCallbacks(ac *auditedController) *contivModel.CallbackHandlers {
+	return &contivModel.CallbackHandlers{
+		AppCb:             ac,
+		EndpointGroupCb:   ac,
+		GlobalCb:          ac,
+		NetworkCb:         ac,
+		PolicyCb:          ac,
+		RuleCb:            ac,
+		ServiceCb:         ac,
+		ServiceInstanceCb: ac,
+		TenantCb:          ac,
+	}
+}
+
+// record logs the change of an object, before and after being its state
+// before and after the change
+func (ac *auditedController) record(kind, key, tenant string, op audit.Operation,
+	before, after interface{}, err error) {
+	caller := ac.caller
+	if caller.Name == "" {
+		caller = audit.Netmaster
+	}
+
+	audit.Log(&audit.Record{
+		Caller:    caller,
+		Kind:      kind,
+		Key:       key,
+		Tenant:    tenant,
+		Operation: op,
+		Before:    audit.State(before),
+		After:     audit.State(after),
+	}, err)
+}
+
+// GlobalCreate records the creation of a global
+func (ac *auditedController) GlobalCreate(global *contivModel.Global) error {
+	err := ac.APIController.GlobalCreate(global)
+	ac.record("global", global.Key, "", audit.OpCreate, nil, global, err)
+	return err
+}
+
+// GlobalUpdate records the update of a global
+func (ac *auditedController) GlobalUpdate(global, params *contivModel.Global) error {
+	before := audit.State(global)
+	err := ac.APIController.GlobalUpdate(global, params)
+	ac.record("global", global.Key, "", audit.OpUpdate, before, params, err)
+	return err
+}
+
+// GlobalDelete records the deletion of a global
+func (ac *auditedController) GlobalDelete(global *contivModel.Global) error {
+	err := ac.APIController.GlobalDelete(global)
+	ac.record("global", global.Key, "", audit.OpDelete, global, nil, err)
+	return err
+}
+
+// AppCreate records the creation of an app
+func (ac *auditedController) AppCreate(app *contivModel.App) error {
+	err := ac.APIController.AppCreate(app)
+	ac.record("app", app.Key, app.TenantName, audit.OpCreate, nil, app, err)
+	return err
+}
+
+// AppUpdate records the update of an app
+func (ac *auditedController) AppUpdate(app, params *contivModel.App) error {
+	before := audit.State(app)
+	err := ac.APIController.AppUpdate(app, params)
+	ac.record("app", app.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// AppDelete records the deletion of an app
+func (ac *auditedController) AppDelete(app *contivModel.App) error {
+	err := ac.APIController.AppDelete(app)
+	ac.record("app", app.Key, app.TenantName, audit.OpDelete, app, nil, err)
+	return err
+}
+
+// EndpointGroupCreate records the creation of an endpointGroup
+func (ac *auditedController) EndpointGroupCreate(endpointGroup *contivModel.EndpointGroup) error {
+	err := ac.APIController.EndpointGroupCreate(endpointGroup)
+	ac.record("endpointGroup", endpointGroup.Key, endpointGroup.TenantName, audit.OpCreate, nil, endpointGroup, err)
+	return err
+}
+
+// EndpointGroupUpdate records the update of an endpointGroup
+func (ac *auditedController) EndpointGroupUpdate(endpointGroup, params *contivModel.EndpointGroup) error {
+	before := audit.State(endpointGroup)
+	err := ac.APIController.EndpointGroupUpdate(endpointGroup, params)
+	ac.record("endpointGroup", endpointGroup.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// EndpointGroupDelete records the deletion of an endpointGroup
+func (ac *auditedController) EndpointGroupDelete(endpointGroup *contivModel.EndpointGroup) error {
+	err := ac.APIController.EndpointGroupDelete(endpointGroup)
+	ac.record("endpointGroup", endpointGroup.Key, endpointGroup.TenantName, audit.OpDelete, endpointGroup, nil, err)
+	return err
+}
+
+// NetworkCreate records the creation of a network
+func (ac *auditedController) NetworkCreate(network *contivModel.Network) error {
+	err := ac.APIController.NetworkCreate(network)
+	ac.record("network", network.Key, network.TenantName, audit.OpCreate, nil, network, err)
+	return err
+}
+
+// NetworkUpdate records the update of a network
+func (ac *auditedController) NetworkUpdate(network, params *contivModel.Network) error {
+	before := audit.State(network)
+	err := ac.APIController.NetworkUpdate(network, params)
+	ac.record("network", network.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// NetworkDelete records the deletion of a network
+func (ac *auditedController) NetworkDelete(network *contivModel.Network) error {
+	err := ac.APIController.NetworkDelete(network)
+	ac.record("network", network.Key, network.TenantName, audit.OpDelete, network, nil, err)
+	return err
+}
+
+// PolicyCreate records the creation of a policy
+func (ac *auditedController) PolicyCreate(policy *contivModel.Policy) error {
+	err := ac.APIController.PolicyCreate(policy)
+	ac.record("policy", policy.Key, policy.TenantName, audit.OpCreate, nil, policy, err)
+	return err
+}
+
+// PolicyUpdate records the update of a policy
+func (ac *auditedController) PolicyUpdate(policy, params *contivModel.Policy) error {
+	before := audit.State(policy)
+	err := ac.APIController.PolicyUpdate(policy, params)
+	ac.record("policy", policy.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// PolicyDelete records the deletion of a policy
+func (ac *auditedController) PolicyDelete(policy *contivModel.Policy) error {
+	err := ac.APIController.PolicyDelete(policy)
+	ac.record("policy", policy.Key, policy.TenantName, audit.OpDelete, policy, nil, err)
+	return err
+}
+
+// RuleCreate records the creation of a rule
+func (ac *auditedController) RuleCreate(rule *contivModel.Rule) error {
+	err := ac.APIController.RuleCreate(rule)
+	ac.record("rule", rule.Key, rule.TenantName, audit.OpCreate, nil, rule, err)
+	return err
+}
+
+// RuleUpdate records the update of a rule
+func (ac *auditedController) RuleUpdate(rule, params *contivModel.Rule) error {
+	before := audit.State(rule)
+	err := ac.APIController.RuleUpdate(rule, params)
+	ac.record("rule", rule.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// RuleDelete records the deletion of a rule
+func (ac *auditedController) RuleDelete(rule *contivModel.Rule) error {
+	err := ac.APIController.RuleDelete(rule)
+	ac.record("rule", rule.Key, rule.TenantName, audit.OpDelete, rule, nil, err)
+	return err
+}
+
+// ServiceCreate records the creation of a service
+func (ac *auditedController) ServiceCreate(service *contivModel.Service) error {
+	err := ac.APIController.ServiceCreate(service)
+	ac.record("service", service.Key, service.TenantName, audit.OpCreate, nil, service, err)
+	return err
+}
+
+// ServiceUpdate records the update of a service
+func (ac *auditedController) ServiceUpdate(service, params *contivModel.Service) error {
+	before := audit.State(service)
+	err := ac.APIController.ServiceUpdate(service, params)
+	ac.record("service", service.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// ServiceDelete records the deletion of a service
+func (ac *auditedController) ServiceDelete(service *contivModel.Service) error {
+	err := ac.APIController.ServiceDelete(service)
+	ac.record("service", service.Key, service.TenantName, audit.OpDelete, service, nil, err)
+	return err
+}
+
+// ServiceInstanceCreate records the creation of a serviceInstance
+func (ac *auditedController) ServiceInstanceCreate(serviceInstance *contivModel.ServiceInstance) error {
+	err := ac.APIController.ServiceInstanceCreate(serviceInstance)
+	ac.record("serviceInstance", serviceInstance.Key, serviceInstance.TenantName, audit.OpCreate, nil, serviceInstance, err)
+	return err
+}
+
+// ServiceInstanceUpdate records the update of a serviceInstance
+func (ac *auditedController) ServiceInstanceUpdate(serviceInstance, params *contivModel.ServiceInstance) error {
+	before := audit.State(serviceInstance)
+	err := ac.APIController.ServiceInstanceUpdate(serviceInstance, params)
+	ac.record("serviceInstance", serviceInstance.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// ServiceInstanceDelete records the deletion of a serviceInstance
+func (ac *auditedController) ServiceInstanceDelete(serviceInstance *contivModel.ServiceInstance) error {
+	err := ac.APIController.ServiceInstanceDelete(serviceInstance)
+	ac.record("serviceInstance", serviceInstance.Key, serviceInstance.TenantName, audit.OpDelete, serviceInstance, nil, err)
+	return err
+}
+
+// TenantCreate records the creation of a tenant
+func (ac *auditedController) TenantCreate(tenant *contivModel.Tenant) error {
+	err := ac.APIController.TenantCreate(tenant)
+	ac.record("tenant", tenant.Key, tenant.TenantName, audit.OpCreate, nil, tenant, err)
+	return err
+}
+
+// TenantUpdate records the update of a tenant
+func (ac *auditedController) TenantUpdate(tenant, params *contivModel.Tenant) error {
+	before := audit.State(tenant)
+	err := ac.APIController.TenantUpdate(tenant, params)
+	ac.record("tenant", tenant.Key, params.TenantName, audit.OpUpdate, before, params, err)
+	return err
+}
+
+// TenantDelete records the deletion of a tenant
+func (ac *auditedController) TenantDelete(tenant *contivModel.Tenant) error {
+	err := ac.APIController.TenantDelete(tenant)
+	ac.record("tenant", tenant.Key, tenant.TenantName, audit.OpDelete, tenant, nil, err)
+	return err
+}
@@ -10,11 +10,11 @@ import (
 	"strconv"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/contivModel"
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/netmaster/master"
 	"github.com/contiv/netplugin/netmaster/mastercfg"
 	"github.com/contiv/netplugin/utils"
-	"github.com/contiv/objmodel/contivModel"
 )
 
 const (
@@ -0,0 +1,152 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/utils"
+)
+
+// RegisterHandler registers the webhook in the request, replacing the
+// webhook of the same id if there's one
+func RegisterHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	wh := &Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(wh); err != nil {
+		return nil, core.InvalidErrorf("invalid request. Error: %s", err)
+	}
+	if err := wh.Validate(); err != nil {
+		return nil, err
+	}
+
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	wh.StateDriver = stateDriver
+	if err := wh.Write(); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Registered webhook %s for %s", wh.ID, wh.URL)
+	wh.Secret = ""
+	return wh, nil
+}
+
+// ListHandler lists the webhooks, without their secrets
+func ListHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	whCfg := &Webhook{}
+	whCfg.StateDriver = stateDriver
+	states, err := whCfg.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	whs := []*Webhook{}
+	for _, state := range states {
+		wh := state.(*Webhook)
+		wh.Secret = ""
+		whs = append(whs, wh)
+	}
+	sort.Sort(webhooksByID(whs))
+
+	return whs, nil
+}
+
+// DeleteHandler removes the registration of the webhook in the request
+func DeleteHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	wh := &Webhook{}
+	wh.StateDriver = stateDriver
+	if err := wh.Read(vars["id"]); err != nil {
+		return nil, err
+	}
+	if err := wh.Clear(); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Removed webhook %s", wh.ID)
+	return "success", nil
+}
+
+// ListDeadLettersHandler lists the notifications that couldn't be delivered,
+// oldest first
+func ListDeadLettersHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	dlCfg := &DeadLetter{}
+	dlCfg.StateDriver = stateDriver
+	states, err := dlCfg.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	dls := []*DeadLetter{}
+	for _, state := range states {
+		dls = append(dls, state.(*DeadLetter))
+	}
+	sort.Sort(deadLettersByTime(dls))
+
+	return dls, nil
+}
+
+// DeleteDeadLetterHandler removes the dead letter in the request
+func DeleteDeadLetterHandler(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	stateDriver, err := utils.GetStateDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	dl := &DeadLetter{}
+	dl.StateDriver = stateDriver
+	if err := dl.Read(vars["id"]); err != nil {
+		return nil, err
+	}
+	if err := dl.Clear(); err != nil {
+		return nil, err
+	}
+
+	return "success", nil
+}
+
+type webhooksByID []*Webhook
+
+func (w webhooksByID) Len() int           { return len(w) }
+func (w webhooksByID) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
+func (w webhooksByID) Less(i, j int) bool { return w[i].ID < w[j].ID }
+
+type deadLettersByTime []*DeadLetter
+
+func (d deadLettersByTime) Len() int           { return len(d) }
+func (d deadLettersByTime) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d deadLettersByTime) Less(i, j int) bool { return d[i].Time.Before(d[j].Time) }
@@ -0,0 +1,236 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/events"
+	"github.com/contiv/netplugin/netmaster/master"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+// Defaults of the deliveries of the notifications
+const (
+	defaultMaxAttempts    = 5
+	defaultBackoff        = time.Second
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// Dispatcher sends the notifications to the webhooks registered in the
+// state store. A request that fails is retried after Backoff, doubled after
+// every attempt, and the notification is added to the dead letters after
+// MaxAttempts attempts. Notifications are delivered independently, so they
+// may not arrive in order.
+type Dispatcher struct {
+	StateDriver core.StateDriver
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+
+	// sequence number of the notification ids
+	seq uint64
+}
+
+// NewDispatcher returns a dispatcher of the webhooks in the state store
+func NewDispatcher(stateDriver core.StateDriver) *Dispatcher {
+	return &Dispatcher{
+		StateDriver: stateDriver,
+		Client:      &http.Client{Timeout: defaultRequestTimeout},
+		MaxAttempts: defaultMaxAttempts,
+		Backoff:     defaultBackoff,
+	}
+}
+
+// Notify sends a notification of a type, of a tenant, with data to the
+// webhooks that want it, in the background
+func (d *Dispatcher) Notify(typ, tenant string, data interface{}) {
+	d.notify(typ, tenant, data, nil)
+}
+
+// notify sends a notification to the webhooks that want it and that match
+// selects, if set
+func (d *Dispatcher) notify(typ, tenant string, data interface{}, match func(wh *Webhook) bool) {
+	whCfg := &Webhook{}
+	whCfg.StateDriver = d.StateDriver
+	whs, err := whCfg.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		log.Errorf("Error reading the webhooks to notify of %s. Err: %v", typ, err)
+		return
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("Error encoding %s notification data %+v. Err: %v", typ, data, err)
+		return
+	}
+
+	n := &Notification{
+		ID:     fmt.Sprintf("%d-%d", time.Now().UnixNano(), atomic.AddUint64(&d.seq, 1)),
+		Type:   typ,
+		Time:   time.Now(),
+		Tenant: tenant,
+		Data:   body,
+	}
+
+	for _, state := range whs {
+		wh := state.(*Webhook)
+		if wh.wants(typ, tenant) && (match == nil || match(wh)) {
+			go d.deliver(wh, n)
+		}
+	}
+}
+
+// deliver sends a notification to a webhook, retrying until it succeeds or
+// runs out of attempts. Notifications that are not delivered are kept in
+// the dead letters.
+func (d *Dispatcher) deliver(wh *Webhook, n *Notification) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		log.Errorf("Error encoding notification %+v. Err: %v", n, err)
+		return
+	}
+
+	backoff := d.Backoff
+	for attempt := 1; ; attempt++ {
+		if err = d.post(wh, n, body); err == nil {
+			return
+		}
+		log.Warnf("Attempt %d to notify webhook %s of %s %s failed. Error: %s",
+			attempt, wh.ID, n.Type, n.ID, err)
+
+		if attempt == d.MaxAttempts {
+			d.addDeadLetter(wh, n, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes the request of a notification to a webhook
+func (d *Dispatcher) post(wh *Webhook, n *Notification, body []byte) error {
+	req, err := http.NewRequest("POST", wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Contiv-Event", n.Type)
+	req.Header.Set("X-Contiv-Delivery", n.ID)
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.Secret, body))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return core.Errorf("webhook responded with status %q", resp.Status)
+	}
+
+	return nil
+}
+
+// addDeadLetter keeps a notification that couldn't be delivered to a webhook
+func (d *Dispatcher) addDeadLetter(wh *Webhook, n *Notification, attempts int, err error) {
+	log.Errorf("Giving up notifying webhook %s of %s %s after %d attempts. Error: %s",
+		wh.ID, n.Type, n.ID, attempts, err)
+
+	dl := &DeadLetter{
+		Webhook:      wh.ID,
+		URL:          wh.URL,
+		Notification: n,
+		Attempts:     attempts,
+		Error:        err.Error(),
+		Time:         time.Now(),
+	}
+	dl.ID = n.ID + "-" + wh.ID
+	dl.StateDriver = d.StateDriver
+	if err := dl.Write(); err != nil {
+		log.Errorf("Error writing dead letter %s. Err: %v", dl.ID, err)
+	}
+}
+
+// WatchEvents notifies the webhooks of the endpoint and network events of
+// the broker after revision. It doesn't return.
+func (d *Dispatcher) WatchEvents(broker *events.Broker, revision uint64) {
+	for {
+		evs, current, changed, err := broker.Since(revision)
+		if err != nil {
+			log.Errorf("Missed the events after revision %d, their webhooks are not notified. Error: %s",
+				revision, err)
+			revision = broker.Revision()
+			continue
+		}
+
+		for _, ev := range evs {
+			d.notifyEvent(ev)
+		}
+
+		revision = current
+		<-changed
+	}
+}
+
+// notifyEvent notifies the webhooks of an event
+func (d *Dispatcher) notifyEvent(ev *events.Event) {
+	switch {
+	case ev.Kind == mastercfg.EndpointKind && ev.Type == events.TypeCreate:
+		d.Notify(EndpointCreated, ev.Tenant, ev.State)
+	case ev.Kind == mastercfg.EndpointKind && ev.Type == events.TypeDelete:
+		d.Notify(EndpointDeleted, ev.Tenant, ev.State)
+	case ev.Kind == mastercfg.NetworkKind && ev.Type == events.TypeCreate:
+		d.Notify(NetworkCreated, ev.Tenant, ev.State)
+	case ev.Kind == mastercfg.NetworkKind && ev.Type == events.TypeUpdate:
+		d.notifyIPUsage(ev)
+	}
+}
+
+// notifyIPUsage notifies the webhooks whose threshold the address
+// utilisation of a network crossed in an update
+func (d *Dispatcher) notifyIPUsage(ev *events.Event) {
+	prev, curr := &mastercfg.CfgNetworkState{}, &mastercfg.CfgNetworkState{}
+	if err := json.Unmarshal(ev.Prev, prev); err != nil {
+		log.Errorf("Error decoding network %s state. Err: %v", ev.ID, err)
+		return
+	}
+	if err := json.Unmarshal(ev.State, curr); err != nil {
+		log.Errorf("Error decoding network %s state. Err: %v", ev.ID, err)
+		return
+	}
+
+	prevUsage, currUsage := master.NetworkIPUsageOf(prev), master.NetworkIPUsageOf(curr)
+	if currUsage.Total == 0 || currUsage.Used <= prevUsage.Used {
+		return
+	}
+
+	prevPercent := int(prevUsage.Used * 100 / currUsage.Total)
+	currPercent := int(currUsage.Used * 100 / currUsage.Total)
+	d.notify(IPPoolThreshold, ev.Tenant, currUsage, func(wh *Webhook) bool {
+		threshold := wh.ipUsageThreshold()
+		return prevPercent < threshold && currPercent >= threshold
+	})
+}
@@ -0,0 +1,218 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook notifies the URLs registered by the users of netmaster of
+// the lifecycle events of the endpoints, networks and hosts, with signed
+// json POST requests.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+// Types of the notifications
+const (
+	// EndpointCreated is sent when an endpoint is created, with its state
+	EndpointCreated = "endpoint.created"
+	// EndpointDeleted is sent when an endpoint is deleted, with its state
+	EndpointDeleted = "endpoint.deleted"
+	// NetworkCreated is sent when a network is created, with its state
+	NetworkCreated = "network.created"
+	// IPPoolThreshold is sent when the addresses of a network in use cross
+	// the threshold of a webhook, with the address utilisation of the network
+	IPPoolThreshold = "ip-pool.threshold"
+	// HostDown is sent when the netplugin of a host goes away, with its
+	// service info
+	HostDown = "host.down"
+)
+
+// types are the types of the notifications
+var types = []string{EndpointCreated, EndpointDeleted, NetworkCreated, IPPoolThreshold, HostDown}
+
+// defaultIPUsageThreshold is the percentage of the addresses of a network in
+// use that webhooks are notified at, unless they set their own threshold
+const defaultIPUsageThreshold = 80
+
+const (
+	webhookPathPrefix    = mastercfg.StateBasePath + "webhooks/"
+	webhookPath          = webhookPathPrefix + "%s"
+	deadLetterPathPrefix = mastercfg.StateBasePath + "webhook-dead-letters/"
+	deadLetterPath       = deadLetterPathPrefix + "%s"
+)
+
+// Webhook is the registration of a URL to notify of the events of Types, or
+// of all events if it's empty. Webhooks of a tenant are only notified of
+// the events of their tenant. Requests are signed with Secret, if set.
+type Webhook struct {
+	core.CommonState
+	URL              string   `json:"url"`
+	Secret           string   `json:"secret,omitempty"`
+	Types            []string `json:"types,omitempty"`
+	Tenant           string   `json:"tenant,omitempty"`
+	IPUsageThreshold int      `json:"ipUsageThreshold,omitempty"`
+}
+
+// Validate checks the registration of a webhook
+func (wh *Webhook) Validate() error {
+	if wh.ID == "" {
+		return core.InvalidErrorf("a webhook needs an id")
+	}
+
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return core.InvalidErrorf("invalid webhook url %q, an http or https url is needed", wh.URL)
+	}
+
+	for _, typ := range wh.Types {
+		if !validType(typ) {
+			return core.InvalidErrorf("invalid notification type %q", typ)
+		}
+	}
+
+	if wh.IPUsageThreshold < 0 || wh.IPUsageThreshold > 100 {
+		return core.InvalidErrorf("invalid ip usage threshold %d, it's a percentage",
+			wh.IPUsageThreshold)
+	}
+
+	return nil
+}
+
+// validType returns true if typ is a type of notifications
+func validType(typ string) bool {
+	for _, t := range types {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wants returns true if the webhook is notified of the notifications of a
+// type, of a tenant
+func (wh *Webhook) wants(typ, tenant string) bool {
+	if wh.Tenant != "" && wh.Tenant != tenant {
+		return false
+	}
+	if len(wh.Types) == 0 {
+		return true
+	}
+
+	for _, t := range wh.Types {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ipUsageThreshold returns the percentage of the addresses of a network in
+// use the webhook is notified at
+func (wh *Webhook) ipUsageThreshold() int {
+	if wh.IPUsageThreshold == 0 {
+		return defaultIPUsageThreshold
+	}
+
+	return wh.IPUsageThreshold
+}
+
+// Write the state.
+func (wh *Webhook) Write() error {
+	key := fmt.Sprintf(webhookPath, wh.ID)
+	return wh.StateDriver.WriteState(key, wh, json.Marshal)
+}
+
+// Read the state for a given identifier
+func (wh *Webhook) Read(id string) error {
+	key := fmt.Sprintf(webhookPath, id)
+	return wh.StateDriver.ReadState(key, wh, json.Unmarshal)
+}
+
+// ReadAll state and return the collection.
+func (wh *Webhook) ReadAll() ([]core.State, error) {
+	return wh.StateDriver.ReadAllState(webhookPathPrefix, wh, json.Unmarshal)
+}
+
+// Clear removes the state.
+func (wh *Webhook) Clear() error {
+	key := fmt.Sprintf(webhookPath, wh.ID)
+	return wh.StateDriver.ClearState(key)
+}
+
+// Notification is the body of the requests to the webhooks. ID is unique
+// to a notification, and the same in the retries of a request.
+type Notification struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Time   time.Time       `json:"time"`
+	Tenant string          `json:"tenant,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// DeadLetter is a notification that couldn't be delivered to a webhook
+type DeadLetter struct {
+	core.CommonState
+	Webhook      string        `json:"webhook"`
+	URL          string        `json:"url"`
+	Notification *Notification `json:"notification"`
+	Attempts     int           `json:"attempts"`
+	Error        string        `json:"error"`
+	Time         time.Time     `json:"time"`
+}
+
+// Write the state.
+func (dl *DeadLetter) Write() error {
+	key := fmt.Sprintf(deadLetterPath, dl.ID)
+	return dl.StateDriver.WriteState(key, dl, json.Marshal)
+}
+
+// Read the state for a given identifier
+func (dl *DeadLetter) Read(id string) error {
+	key := fmt.Sprintf(deadLetterPath, id)
+	return dl.StateDriver.ReadState(key, dl, json.Unmarshal)
+}
+
+// ReadAll state and return the collection.
+func (dl *DeadLetter) ReadAll() ([]core.State, error) {
+	return dl.StateDriver.ReadAllState(deadLetterPathPrefix, dl, json.Unmarshal)
+}
+
+// Clear removes the state.
+func (dl *DeadLetter) Clear() error {
+	key := fmt.Sprintf(deadLetterPath, dl.ID)
+	return dl.StateDriver.ClearState(key)
+}
+
+// SignatureHeader is the header of the signature of the requests to the
+// webhooks with a secret
+const SignatureHeader = "X-Contiv-Signature"
+
+// Sign returns the signature of a request body with a secret: the hex
+// encoded HMAC-SHA256 of the body, prefixed by "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,276 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/events"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/state"
+)
+
+// receiver is a webhook receiver failing the first requests
+type receiver struct {
+	t        *testing.T
+	secret   string
+	rcvd     chan *Notification
+	mutex    sync.Mutex
+	failures int
+	requests int
+}
+
+func newReceiver(t *testing.T, secret string, failures int) (*receiver, *httptest.Server) {
+	rcv := &receiver{t: t, secret: secret, failures: failures, rcvd: make(chan *Notification, 10)}
+	return rcv, httptest.NewServer(rcv)
+}
+
+func (rcv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mutex.Lock()
+	rcv.requests++
+	fail := rcv.requests <= rcv.failures
+	rcv.mutex.Unlock()
+
+	if fail {
+		http.Error(w, "not now", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		rcv.t.Errorf("error reading notification. Error: %s", err)
+		return
+	}
+	if rcv.secret != "" && r.Header.Get(SignatureHeader) != Sign(rcv.secret, body) {
+		rcv.t.Errorf("invalid signature %q of notification %s", r.Header.Get(SignatureHeader), body)
+	}
+
+	n := &Notification{}
+	if err := json.Unmarshal(body, n); err != nil {
+		rcv.t.Errorf("error decoding notification %s. Error: %s", body, err)
+	}
+	rcv.rcvd <- n
+}
+
+// expect waits for notifications of types, in any order
+func (rcv *receiver) expect(types ...string) []*Notification {
+	ns := []*Notification{}
+	rcvdTypes := []string{}
+	for range types {
+		select {
+		case n := <-rcv.rcvd:
+			ns = append(ns, n)
+			rcvdTypes = append(rcvdTypes, n.Type)
+		case <-time.After(5 * time.Second):
+			rcv.t.Fatalf("received %v, expected notifications %v", rcvdTypes, types)
+		}
+	}
+
+	expTypes := append([]string{}, types...)
+	sort.Strings(expTypes)
+	sort.Strings(rcvdTypes)
+	if !reflect.DeepEqual(rcvdTypes, expTypes) {
+		rcv.t.Fatalf("received %v, expected notifications %v", rcvdTypes, types)
+	}
+
+	return ns
+}
+
+// requestCount returns the number of requests received
+func (rcv *receiver) requestCount() int {
+	rcv.mutex.Lock()
+	defer rcv.mutex.Unlock()
+
+	return rcv.requests
+}
+
+func newTestDispatcher() (*Dispatcher, *state.FakeStateDriver) {
+	stateDriver := &state.FakeStateDriver{}
+	stateDriver.Init(nil)
+
+	d := NewDispatcher(stateDriver)
+	d.MaxAttempts = 3
+	d.Backoff = time.Millisecond
+	return d, stateDriver
+}
+
+func registerWebhook(t *testing.T, stateDriver core.StateDriver, wh *Webhook) {
+	wh.StateDriver = stateDriver
+	if err := wh.Validate(); err != nil {
+		t.Fatalf("invalid webhook %+v. Error: %s", wh, err)
+	}
+	if err := wh.Write(); err != nil {
+		t.Fatalf("error writing webhook. Error: %s", err)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	d, stateDriver := newTestDispatcher()
+	defer stateDriver.Deinit()
+
+	rcv, srv := newReceiver(t, "s3cret", 0)
+	defer srv.Close()
+	blueRcv, blueSrv := newReceiver(t, "", 0)
+	defer blueSrv.Close()
+
+	wh := &Webhook{URL: srv.URL, Secret: "s3cret", Types: []string{HostDown, NetworkCreated}}
+	wh.ID = "ops"
+	registerWebhook(t, stateDriver, wh)
+	blueWh := &Webhook{URL: blueSrv.URL, Tenant: "blue"}
+	blueWh.ID = "blue"
+	registerWebhook(t, stateDriver, blueWh)
+
+	d.Notify(HostDown, "", map[string]string{"HostAddr": "10.0.0.2"})
+	n := rcv.expect(HostDown)[0]
+	if string(n.Data) != `{"HostAddr":"10.0.0.2"}` || n.ID == "" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+
+	d.Notify(NetworkCreated, "blue", map[string]string{"networkName": "net1"})
+	rcv.expect(NetworkCreated)
+	blueRcv.expect(NetworkCreated)
+
+	d.Notify(EndpointCreated, "red", map[string]string{"id": "ep1"})
+	d.Notify(EndpointCreated, "blue", map[string]string{"id": "ep2"})
+	if n := blueRcv.expect(EndpointCreated)[0]; n.Tenant != "blue" {
+		t.Fatalf("webhook of tenant blue got %+v", n)
+	}
+
+	select {
+	case n := <-rcv.rcvd:
+		t.Fatalf("unexpected notification %+v", n)
+	case n := <-blueRcv.rcvd:
+		t.Fatalf("unexpected notification %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRetry(t *testing.T) {
+	d, stateDriver := newTestDispatcher()
+	defer stateDriver.Deinit()
+
+	// the first webhook gets the notification on the last attempt, the
+	// second never does
+	rcv, srv := newReceiver(t, "", 2)
+	defer srv.Close()
+	deadRcv, deadSrv := newReceiver(t, "", 100)
+	defer deadSrv.Close()
+
+	wh := &Webhook{URL: srv.URL}
+	wh.ID = "flaky"
+	registerWebhook(t, stateDriver, wh)
+	deadWh := &Webhook{URL: deadSrv.URL}
+	deadWh.ID = "down"
+	registerWebhook(t, stateDriver, deadWh)
+
+	d.Notify(HostDown, "", map[string]string{"HostAddr": "10.0.0.2"})
+	n := rcv.expect(HostDown)[0]
+
+	dlCfg := &DeadLetter{}
+	dlCfg.StateDriver = stateDriver
+	for i := 0; i < 100; i++ {
+		dls, err := dlCfg.ReadAll()
+		if err != nil {
+			t.Fatalf("error reading dead letters. Error: %s", err)
+		}
+		if len(dls) == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		dl := dls[0].(*DeadLetter)
+		if len(dls) != 1 || dl.Webhook != "down" || dl.Attempts != 3 || dl.Notification.ID != n.ID {
+			t.Fatalf("unexpected dead letters %+v", dls)
+		}
+		if deadRcv.requestCount() != 3 {
+			t.Fatalf("webhook got %d requests, expected 3", deadRcv.requestCount())
+		}
+		return
+	}
+	t.Fatalf("no dead letter for the webhook that's down")
+}
+
+func TestWatchEvents(t *testing.T) {
+	d, stateDriver := newTestDispatcher()
+	defer stateDriver.Deinit()
+
+	broker := events.NewBroker(stateDriver, 100)
+	go broker.Run()
+	for broker.Revision() == 0 {
+		stateDriver.Write(mastercfg.StateConfigPath+"test", []byte("{}"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	go d.WatchEvents(broker, broker.Revision())
+
+	rcv, srv := newReceiver(t, "", 0)
+	defer srv.Close()
+	wh := &Webhook{URL: srv.URL, IPUsageThreshold: 75}
+	wh.ID = "all"
+	registerWebhook(t, stateDriver, wh)
+
+	// the network has 6 addresses, the threshold is crossed by the fifth
+	nwCfg := &mastercfg.CfgNetworkState{Tenant: "blue", NetworkName: "net1",
+		SubnetIP: "10.1.1.0", SubnetLen: 29}
+	nwCfg.ID = "net1.blue"
+	nwCfg.StateDriver = stateDriver
+	epCfg := &mastercfg.CfgEndpointState{NetID: "net1.blue"}
+	epCfg.ID = "net1.blue-ep1"
+	epCfg.StateDriver = stateDriver
+
+	for _, change := range []func() error{
+		nwCfg.Write,
+		epCfg.Write,
+		func() error { nwCfg.IPAllocMap.Set(1); return nwCfg.Write() },
+		func() error { nwCfg.IPAllocMap.Set(2).Set(3).Set(4).Set(5); return nwCfg.Write() },
+		func() error { nwCfg.IPAllocMap.Set(6); return nwCfg.Write() },
+		epCfg.Clear,
+	} {
+		if err := change(); err != nil {
+			t.Fatalf("error changing state. Error: %s", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	ns := rcv.expect(NetworkCreated, EndpointCreated, IPPoolThreshold, EndpointDeleted)
+	for _, n := range ns {
+		if n.Tenant != "blue" {
+			t.Fatalf("notification %+v is not of tenant blue", n)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, wh := range []*Webhook{
+		{URL: "http://receiver"},
+		{CommonState: core.CommonState{ID: "wh"}, URL: "receiver:8080"},
+		{CommonState: core.CommonState{ID: "wh"}, URL: "ftp://receiver"},
+		{CommonState: core.CommonState{ID: "wh"}, URL: "http://receiver", Types: []string{"host.up"}},
+		{CommonState: core.CommonState{ID: "wh"}, URL: "http://receiver", IPUsageThreshold: 101},
+	} {
+		if err := wh.Validate(); core.ErrorKindOf(err) != core.KindInvalid {
+			t.Fatalf("invalid webhook %+v validated, err %v", wh, err)
+		}
+	}
+}
@@ -16,14 +16,17 @@ limitations under the License.
 package cluster
 
 import (
+	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/master"
 	"github.com/contiv/netplugin/netplugin/plugin"
 	"github.com/contiv/netplugin/utils/netutils"
 	"github.com/contiv/objmodel/objdb"
@@ -38,14 +41,46 @@ import (
 // Database of master nodes
 var masterDB = make(map[string]*core.ServiceInfo)
 
+// REST API ports of the master nodes, by host address
+var masterPorts = make(map[string]int)
+
+// objdb client and address of this node, set by Init
+var (
+	clusterObjdb objdb.ObjdbApi
+	clusterIP    string
+)
+
+// token of the node, to authenticate the requests to the master
+var masterToken string
+
+// scheme and client of the requests to the master
+var (
+	masterScheme = "http"
+	masterClient = http.DefaultClient
+)
+
+// SetMasterToken sets the token of the node, which the requests to the
+// master are authenticated with
+func SetMasterToken(token string) {
+	masterToken = token
+}
+
 func masterKey(srvInfo core.ServiceInfo) string {
 	return srvInfo.HostAddr + ":" + fmt.Sprintf("%d", srvInfo.Port)
 }
 
-// Add a master node
-func addMaster(netplugin *plugin.NetPlugin, srvInfo core.ServiceInfo) error {
+// SetMasterTLS makes the requests to the master over https, with the tls
+// config
+func SetMasterTLS(tlsCfg *tls.Config) {
+	masterScheme = "https"
+	masterClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+}
+
+// Add a master node, whose REST API listens on restPort
+func addMaster(netplugin *plugin.NetPlugin, srvInfo core.ServiceInfo, restPort int) error {
 	// save it in db
 	masterDB[masterKey(srvInfo)] = &srvInfo
+	masterPorts[srvInfo.HostAddr] = restPort
 
 	// tell the plugin about the master
 	return netplugin.AddMaster(srvInfo)
@@ -55,6 +90,7 @@ func addMaster(netplugin *plugin.NetPlugin, srvInfo core.ServiceInfo) error {
 func deleteMaster(netplugin *plugin.NetPlugin, srvInfo core.ServiceInfo) error {
 	// delete from the db
 	delete(masterDB, masterKey(srvInfo))
+	delete(masterPorts, srvInfo.HostAddr)
 
 	// tel plugin about it
 	return netplugin.DeleteMaster(srvInfo)
@@ -70,17 +106,21 @@ func httpPost(url string, req interface{}, resp interface{}) error {
 	}
 
 	// Perform HTTP POST operation
-	res, err := http.Post(url, "application/json", strings.NewReader(string(jsonStr)))
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonStr)))
 	if err != nil {
-		log.Errorf("Error during http get. Err: %v", err)
 		return err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if masterToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+masterToken)
+	}
 
-	// Check the response code
-	if res.StatusCode != http.StatusOK {
-		log.Errorf("HTTP error response. Status: %s, StatusCode: %d", res.Status, res.StatusCode)
-		return errors.New("HTTP Error response")
+	res, err := masterClient.Do(httpReq)
+	if err != nil {
+		log.Errorf("Error during http get. Err: %v", err)
+		return err
 	}
+	defer res.Body.Close()
 
 	// Read the entire response
 	body, err := ioutil.ReadAll(res.Body)
@@ -89,6 +129,12 @@ func httpPost(url string, req interface{}, resp interface{}) error {
 		return err
 	}
 
+	// Check the response code
+	if res.StatusCode != http.StatusOK {
+		log.Errorf("HTTP error response. Status: %s, StatusCode: %d", res.Status, res.StatusCode)
+		return core.DecodeHTTPError(res.StatusCode, body)
+	}
+
 	// Convert response json to struct
 	err = json.Unmarshal(body, resp)
 	if err != nil {
@@ -101,24 +147,31 @@ func httpPost(url string, req interface{}, resp interface{}) error {
 	return nil
 }
 
-// MasterPostReq makes a POST request to master node
+// MasterPostReq makes a POST request to master node. The request is made to
+// the next master only if it fails with a transient error, so callers can
+// check the error with core.IsTransient to decide whether to retry.
 func MasterPostReq(path string, req interface{}, resp interface{}) error {
-	for _, master := range masterDB {
-		url := "http://" + master.HostAddr + ":9999" + path
+	for _, masterInfo := range masterDB {
+		hostPort := net.JoinHostPort(masterInfo.HostAddr, strconv.Itoa(masterPorts[masterInfo.HostAddr]))
+		url := masterScheme + "://" + hostPort + master.APIVersionPrefix + path
 
 		log.Infof("Making REST request to url: %s", url)
 
 		err := httpPost(url, req, resp)
-		if err != nil {
-			log.Warnf("Error making POST request: Err: %v", err)
-			// continue and try making POST call to next master
-		} else {
+		if err == nil {
 			return nil
 		}
+		if !core.IsTransient(err) {
+			log.Errorf("Error making POST request: Err: %v", err)
+			return err
+		}
+
+		log.Warnf("Error making POST request: Err: %v", err)
+		// continue and try making POST call to next master
 	}
 
 	log.Errorf("Error making POST request. All master failed")
-	return errors.New("POST request failed")
+	return core.UnavailableErrorf("POST request failed")
 }
 
 // Register netplugin with service registry
@@ -162,7 +215,7 @@ func peerDiscoveryLoop(netplugin *plugin.NetPlugin, objdbClient objdb.ObjdbApi,
 	}
 
 	// Get a list of all existing netplugin nodes
-	nodeList, err := objdbClient.GetService("netplugin")
+	nodeList, err := peerHosts(objdbClient, localIP)
 	if err != nil {
 		log.Errorf("Error getting node list from objdb. Err: %v", err)
 	}
@@ -171,15 +224,7 @@ func peerDiscoveryLoop(netplugin *plugin.NetPlugin, objdbClient objdb.ObjdbApi,
 
 	// walk each node and add it as a PeerHost
 	for _, node := range nodeList {
-		// Ignore if its our own info
-		if node.HostAddr == localIP {
-			continue
-		}
-		// add the node
-		err := netplugin.AddPeerHost(core.ServiceInfo{
-			HostAddr: node.HostAddr,
-			Port:     ofnet.OFNET_AGENT_PORT,
-		})
+		err := netplugin.AddPeerHost(node)
 		if err != nil {
 			log.Errorf("Error adding node {%+v}. Err: %v", node, err)
 		}
@@ -199,7 +244,7 @@ func peerDiscoveryLoop(netplugin *plugin.NetPlugin, objdbClient objdb.ObjdbApi,
 		err := addMaster(netplugin, core.ServiceInfo{
 			HostAddr: master.HostAddr,
 			Port:     ofnet.OFNET_MASTER_PORT,
-		})
+		}, master.Port)
 		if err != nil {
 			log.Errorf("Error adding master {%+v}. Err: %v", master, err)
 		}
@@ -256,7 +301,7 @@ func peerDiscoveryLoop(netplugin *plugin.NetPlugin, objdbClient objdb.ObjdbApi,
 				err := addMaster(netplugin, core.ServiceInfo{
 					HostAddr: nodeInfo.HostAddr,
 					Port:     ofnet.OFNET_MASTER_PORT,
-				})
+				}, nodeInfo.Port)
 				if err != nil {
 					log.Errorf("Error adding master {%+v}. Err: %v", nodeInfo, err)
 				}
@@ -266,7 +311,7 @@ func peerDiscoveryLoop(netplugin *plugin.NetPlugin, objdbClient objdb.ObjdbApi,
 				// Delete the master
 				err := deleteMaster(netplugin, core.ServiceInfo{
 					HostAddr: nodeInfo.HostAddr,
-					Port:     nodeInfo.Port,
+					Port:     ofnet.OFNET_MASTER_PORT,
 				})
 				if err != nil {
 					log.Errorf("Error deleting master {%+v}. Err: %v", nodeInfo, err)
@@ -295,10 +340,45 @@ func GetLocalAddr() (string, error) {
 	return netutils.GetFirstLocalAddr()
 }
 
+// peerHosts returns the netplugin nodes registered in objdb other than the
+// one at localIP, as the peer hosts to have VTEPs for
+func peerHosts(objdbClient objdb.ObjdbApi, localIP string) ([]core.ServiceInfo, error) {
+	nodeList, err := objdbClient.GetService("netplugin")
+	if err != nil {
+		return nil, err
+	}
+
+	peers := []core.ServiceInfo{}
+	for _, node := range nodeList {
+		// Ignore if its our own info
+		if node.HostAddr == localIP {
+			continue
+		}
+		peers = append(peers, core.ServiceInfo{
+			HostAddr: node.HostAddr,
+			Port:     ofnet.OFNET_AGENT_PORT,
+		})
+	}
+
+	return peers, nil
+}
+
+// PeerHosts returns the other netplugin nodes registered in objdb. It
+// requires Init to have been called.
+func PeerHosts() ([]core.ServiceInfo, error) {
+	if clusterObjdb == nil {
+		return nil, core.Errorf("cluster is not initialized")
+	}
+
+	return peerHosts(clusterObjdb, clusterIP)
+}
+
 // Init initializes the cluster module
 func Init(netplugin *plugin.NetPlugin, localIP string) error {
 	// Create an objdb client
 	objdbClient := client.NewClient()
+	clusterObjdb = objdbClient
+	clusterIP = localIP
 
 	// Register ourselves
 	registerService(objdbClient, localIP)
@@ -25,6 +25,8 @@ import (
 	"net/url"
 	"os"
 	"os/user"
+	"strings"
+	"time"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/mgmtfn/dockplugin"
@@ -42,6 +44,9 @@ import (
 // a daemon based on etcd client's Watch interface to trigger plugin's
 // network provisioning interfaces
 
+// interval to wait before resuming a failed watch
+const watchRetryInterval = time.Second
+
 type cliOpts struct {
 	hostLabel  string
 	pluginMode string // plugin could be docker | kubernetes
@@ -52,36 +57,100 @@ type cliOpts struct {
 	ctrlIP     string // IP address to be used by control protocols
 	vtepIP     string // IP address to be used by the VTEP
 	vlanIntf   string // Uplink interface for VLAN switching
+
+	reconcileInterval time.Duration // zero disables reconciliation
+	masterTokenFile   string        // token to authenticate with netmaster
+}
+
+// watchedState tracks the states programmed from the state store and the
+// revision of the store they reflect, to resume watching after a failure.
+type watchedState struct {
+	revision uint64
+	states   map[string]core.State
 }
 
-func skipHost(vtepIP, homingHost, myHostLabel string) bool {
-	return (vtepIP == "" && homingHost != myHostLabel ||
-		vtepIP != "" && homingHost == myHostLabel)
+func newWatchedState() *watchedState {
+	return &watchedState{states: make(map[string]core.State)}
 }
 
-func processCurrentState(netPlugin *plugin.NetPlugin, opts cliOpts) error {
+// syncNetworks programs the networks in the state store and removes the
+// known networks that are no longer there, along with their endpoints.
+func syncNetworks(netPlugin *plugin.NetPlugin, nws, ews *watchedState) error {
 	readNet := &mastercfg.CfgNetworkState{}
 	readNet.StateDriver = netPlugin.StateDriver
-	netCfgs, err := readNet.ReadAll()
-	if err == nil {
-		for idx, netCfg := range netCfgs {
-			net := netCfg.(*mastercfg.CfgNetworkState)
-			log.Debugf("read net key[%d] %s, populating state \n", idx, net.ID)
-			processNetEvent(netPlugin, net, false)
+	netCfgs, revision, err := readNet.ListAll()
+	if err != nil {
+		return err
+	}
+
+	networks := map[string]core.State{}
+	for idx, netCfg := range netCfgs {
+		net := netCfg.(*mastercfg.CfgNetworkState)
+		log.Debugf("read net key[%d] %s, populating state \n", idx, net.ID)
+		processNetEvent(netPlugin, net, false)
+		networks[net.ID] = net
+	}
+
+	for id, net := range nws.states {
+		if _, ok := networks[id]; !ok {
+			log.Infof("network %s was removed while not being watched", id)
+			removeNetwork(netPlugin, net.(*mastercfg.CfgNetworkState), ews)
 		}
 	}
 
+	nws.revision = revision
+	nws.states = networks
+	return nil
+}
+
+// syncEndpoints programs the endpoints in the state store that are homed on
+// this host and removes the known endpoints that no longer are.
+func syncEndpoints(netPlugin *plugin.NetPlugin, opts cliOpts, nws, ews *watchedState) error {
 	readEp := &mastercfg.CfgEndpointState{}
 	readEp.StateDriver = netPlugin.StateDriver
-	epCfgs, err := readEp.ReadAll()
-	if err == nil {
-		for idx, epCfg := range epCfgs {
-			ep := epCfg.(*mastercfg.CfgEndpointState)
-			log.Debugf("read ep key[%d] %s, populating state \n", idx, ep.ID)
-			processEpState(netPlugin, opts, ep.ID)
+	epCfgs, revision, err := readEp.ListAll()
+	if err != nil {
+		return err
+	}
+
+	endpoints := map[string]core.State{}
+	for idx, epCfg := range epCfgs {
+		ep := epCfg.(*mastercfg.CfgEndpointState)
+		log.Debugf("read ep key[%d] %s, populating state \n", idx, ep.ID)
+		if plugin.SkipHost(ep.VtepIP, ep.HomingHost, opts.hostLabel) {
+			continue
+		}
+
+		ensureNetwork(netPlugin, ep.NetID, nws)
+		processEpEvent(netPlugin, ep.ID, false)
+		endpoints[ep.ID] = ep
+	}
+
+	for id := range ews.states {
+		if _, ok := endpoints[id]; !ok {
+			log.Infof("endpoint %s was removed while not being watched", id)
+			processEpEvent(netPlugin, id, true)
 		}
 	}
 
+	ews.revision = revision
+	ews.states = endpoints
+	return nil
+}
+
+func processCurrentState(netPlugin *plugin.NetPlugin, opts cliOpts, nws, ews *watchedState) error {
+	err := syncNetworks(netPlugin, nws, ews)
+	if err != nil {
+		log.Errorf("Error reading networks. Err: %v", err)
+		return err
+	}
+
+	err = syncEndpoints(netPlugin, opts, nws, ews)
+	if err != nil {
+		log.Errorf("Error reading endpoints. Err: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -111,92 +180,202 @@ func processNetEvent(netPlugin *plugin.NetPlugin, nwCfg *mastercfg.CfgNetworkSta
 	return
 }
 
-// processEpState restores endpoint state
-func processEpState(netPlugin *plugin.NetPlugin, opts cliOpts, epID string) error {
+// processEpEvent creates or deletes an endpoint homed on this host
+func processEpEvent(netPlugin *plugin.NetPlugin, epID string, isDelete bool) (err error) {
 	// take a lock to ensure we are programming one event at a time.
-	// Also network create events need to be processed before endpoint creates
-	// and reverse shall happen for deletes. That order is ensured by netmaster,
-	// so we don't need to worry about that here
 	netPlugin.Lock()
 	defer func() { netPlugin.Unlock() }()
 
-	// read endpoint config
-	epCfg := &mastercfg.CfgEndpointState{}
-	epCfg.StateDriver = netPlugin.StateDriver
-	err := epCfg.Read(epID)
+	operStr := ""
+	if isDelete {
+		err = netPlugin.DeleteEndpoint(epID)
+		operStr = "delete"
+	} else {
+		err = netPlugin.CreateEndpoint(epID)
+		operStr = "create"
+	}
 	if err != nil {
-		log.Errorf("Failed to read config for ep '%s' \n", epID)
-		return err
+		log.Errorf("Endpoint operation %s failed for ep %s. Error: %s", operStr, epID, err)
+	} else {
+		log.Infof("Endpoint operation %s succeeded for ep %s", operStr, epID)
 	}
 
-	// if the endpoint is not for this host, ignore it
-	if skipHost(epCfg.VtepIP, epCfg.HomingHost, opts.hostLabel) {
-		log.Infof("skipping mismatching host for ep %s. EP's host %s (my host: %s)",
-			epID, epCfg.HomingHost, opts.hostLabel)
-		return nil
+	return
+}
+
+// ensureNetwork programs the network of an endpoint, if its create event
+// wasn't received yet. The network and endpoint events are watched
+// separately, so their relative order isn't guaranteed.
+func ensureNetwork(netPlugin *plugin.NetPlugin, netID string, nws *watchedState) {
+	if _, ok := nws.states[netID]; ok {
+		return
 	}
 
-	// Create the endpoint
-	err = netPlugin.CreateEndpoint(epID)
-	if err != nil {
-		log.Errorf("Endpoint operation create failed. Error: %s", err)
-		return err
+	nwCfg := &mastercfg.CfgNetworkState{}
+	nwCfg.StateDriver = netPlugin.StateDriver
+	if err := nwCfg.Read(netID); err != nil {
+		log.Errorf("Failed to read config for network %s. Error: %s", netID, err)
+		return
 	}
 
-	log.Infof("Endpoint operation create succeeded")
+	processNetEvent(netPlugin, nwCfg, false)
+	nws.states[netID] = nwCfg
+}
+
+// removeNetwork deletes a network, after deleting its endpoints homed on
+// this host whose delete events weren't received yet.
+func removeNetwork(netPlugin *plugin.NetPlugin, nwCfg *mastercfg.CfgNetworkState,
+	ews *watchedState) {
+	for id, epCfg := range ews.states {
+		if epCfg.(*mastercfg.CfgEndpointState).NetID == nwCfg.ID {
+			processEpEvent(netPlugin, id, true)
+			delete(ews.states, id)
+		}
+	}
 
-	return err
+	processNetEvent(netPlugin, nwCfg, true)
 }
 
-func processStateEvent(netPlugin *plugin.NetPlugin, opts cliOpts, rsps chan core.WatchState) {
-	for {
-		// block on change notifications
-		rsp := <-rsps
-
-		// For now we deal with only create and delete events
-		currentState := rsp.Curr
-		isDelete := false
-		eventStr := "create"
-		if rsp.Curr == nil {
-			currentState = rsp.Prev
-			isDelete = true
-			eventStr = "delete"
-		} else if rsp.Prev != nil {
-			// XXX: late host binding modifies the ep-cfg state to update the host-label.
-			// Need to treat it as Create, revisit to see if we can prevent this
-			// by just triggering create once instead.
-			log.Debugf("Received a modify event, treating it as a 'create'")
+func processNetworkWatchEvent(netPlugin *plugin.NetPlugin, rsp core.WatchState,
+	nws, ews *watchedState) {
+	// For now we deal with only create and delete events
+	if rsp.Curr == nil {
+		nwCfg := rsp.Prev.(*mastercfg.CfgNetworkState)
+		log.Infof("Received %q for network: %q", "delete", nwCfg.ID)
+		removeNetwork(netPlugin, nwCfg, ews)
+		delete(nws.states, nwCfg.ID)
+		return
+	}
+
+	nwCfg := rsp.Curr.(*mastercfg.CfgNetworkState)
+	if rsp.Prev != nil {
+		log.Debugf("Received a modify event, treating it as a 'create'")
+	}
+	log.Infof("Received %q for network: %q", "create", nwCfg.ID)
+	processNetEvent(netPlugin, nwCfg, false)
+	nws.states[nwCfg.ID] = nwCfg
+}
+
+func processEndpointWatchEvent(netPlugin *plugin.NetPlugin, opts cliOpts, rsp core.WatchState,
+	nws, ews *watchedState) {
+	if rsp.Curr == nil {
+		epCfg := rsp.Prev.(*mastercfg.CfgEndpointState)
+		log.Infof("Received %q for endpoint: %q", "delete", epCfg.ID)
+		if _, ok := ews.states[epCfg.ID]; ok {
+			processEpEvent(netPlugin, epCfg.ID, true)
+			delete(ews.states, epCfg.ID)
 		}
+		return
+	}
 
-		if nwCfg, ok := currentState.(*mastercfg.CfgNetworkState); ok {
-			log.Infof("Received %q for network: %q", eventStr, nwCfg.ID)
-			processNetEvent(netPlugin, nwCfg, isDelete)
+	epCfg := rsp.Curr.(*mastercfg.CfgEndpointState)
+	eventStr := "create"
+	if rsp.Prev != nil {
+		eventStr = "modify"
+	}
+	log.Infof("Received %q for endpoint: %q", eventStr, epCfg.ID)
+
+	// a modify can move the endpoint to another host, e.g. on late host binding
+	if plugin.SkipHost(epCfg.VtepIP, epCfg.HomingHost, opts.hostLabel) {
+		if _, ok := ews.states[epCfg.ID]; ok {
+			log.Infof("endpoint %s moved to host %s", epCfg.ID, epCfg.HomingHost)
+			processEpEvent(netPlugin, epCfg.ID, true)
+			delete(ews.states, epCfg.ID)
 		}
+		return
 	}
+
+	// creating an existing endpoint updates it to match its config
+	ensureNetwork(netPlugin, epCfg.NetID, nws)
+	processEpEvent(netPlugin, epCfg.ID, false)
+	ews.states[epCfg.ID] = epCfg
 }
 
-func handleNetworkEvents(netPlugin *plugin.NetPlugin, opts cliOpts, retErr chan error) {
-	rsps := make(chan core.WatchState)
-	go processStateEvent(netPlugin, opts, rsps)
-	cfg := mastercfg.CfgNetworkState{}
-	cfg.StateDriver = netPlugin.StateDriver
-	retErr <- cfg.WatchAll(rsps)
-	return
+// stateWatch is a watch of a state that is resumed after failures.
+type stateWatch struct {
+	name    string
+	watched *watchedState
+	watch   func(revision uint64, rsps chan core.WatchState) error
+	resync  func() error
+
+	rsps chan core.WatchState
+	errs chan error
 }
 
-func handleEvents(netPlugin *plugin.NetPlugin, opts cliOpts) error {
-	recvErr := make(chan error, 1)
-	go handleNetworkEvents(netPlugin, opts, recvErr)
+// start watches the state from the revision of the last change processed.
+func (sw *stateWatch) start() {
+	sw.rsps = make(chan core.WatchState)
+	sw.errs = make(chan error, 1)
+	go func(revision uint64, rsps chan core.WatchState, errs chan error) {
+		errs <- sw.watch(revision, rsps)
+	}(sw.watched.revision, sw.rsps, sw.errs)
+}
 
-	err := <-recvErr
-	if err != nil {
-		log.Errorf("Failure occured. Error: %s", err)
-		return err
+// restart resumes a failed watch. The state is read again if the store no
+// longer has the changes since the last one processed.
+func (sw *stateWatch) restart(err error) error {
+	if core.IsRevisionCompacted(err) {
+		log.Infof("Missed %s events, re-reading the %s state", sw.name, sw.name)
+		err = sw.resync()
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Errorf("The %s watch failed, resuming from revision %d. Error: %s",
+			sw.name, sw.watched.revision, err)
+		time.Sleep(watchRetryInterval)
 	}
 
+	sw.start()
 	return nil
 }
 
+func handleEvents(netPlugin *plugin.NetPlugin, opts cliOpts, nws, ews *watchedState) error {
+	netCfg := mastercfg.CfgNetworkState{}
+	netCfg.StateDriver = netPlugin.StateDriver
+	netWatch := &stateWatch{
+		name:    "network",
+		watched: nws,
+		watch:   netCfg.WatchAllFrom,
+		resync:  func() error { return syncNetworks(netPlugin, nws, ews) },
+	}
+
+	epCfg := mastercfg.CfgEndpointState{}
+	epCfg.StateDriver = netPlugin.StateDriver
+	epWatch := &stateWatch{
+		name:    "endpoint",
+		watched: ews,
+		watch:   epCfg.WatchAllFrom,
+		resync:  func() error { return syncEndpoints(netPlugin, opts, nws, ews) },
+	}
+
+	// both watches are served by this loop, so that the network and endpoint
+	// events are processed one at a time
+	netWatch.start()
+	epWatch.start()
+	for {
+		var err error
+
+		select {
+		case rsp := <-netWatch.rsps:
+			processNetworkWatchEvent(netPlugin, rsp, nws, ews)
+			nws.revision = rsp.Revision
+		case rsp := <-epWatch.rsps:
+			processEndpointWatchEvent(netPlugin, opts, rsp, nws, ews)
+			ews.revision = rsp.Revision
+		case err = <-netWatch.errs:
+			err = netWatch.restart(err)
+		case err = <-epWatch.errs:
+			err = epWatch.restart(err)
+		}
+
+		if err != nil {
+			log.Errorf("Failure occured. Error: %s", err)
+			return err
+		}
+	}
+}
+
 func configureSyslog(syslogParam string) {
 	var err error
 	var hook log.Hook
@@ -285,6 +464,14 @@ func main() {
 		"vlan-if",
 		defVlanIntf,
 		"My VTEP ip address")
+	flagSet.DurationVar(&opts.reconcileInterval,
+		"reconcile-interval",
+		time.Minute,
+		"Interval to repair differences between the state store and OVS at, 0 to disable")
+	flagSet.StringVar(&opts.masterTokenFile,
+		"netmaster-token-file",
+		"",
+		"File with the token of the node, to authenticate with netmaster")
 
 	err = flagSet.Parse(os.Args[1:])
 	if err != nil {
@@ -310,6 +497,14 @@ func main() {
 		configureSyslog(opts.syslog)
 	}
 
+	if opts.masterTokenFile != "" {
+		token, err := ioutil.ReadFile(opts.masterTokenFile)
+		if err != nil {
+			log.Fatalf("reading netmaster token from file failed. Error: %s", err)
+		}
+		cluster.SetMasterToken(strings.TrimSpace(string(token)))
+	}
+
 	if flagSet.NFlag() < 1 {
 		log.Infof("host-label not specified, using default (%s)", opts.hostLabel)
 	}
@@ -377,6 +572,14 @@ func main() {
 		pluginConfig.Instance.VlanIntf = opts.vlanIntf
 	}
 
+	if pluginConfig.NetmasterTLS.Enabled() {
+		tlsCfg, err := pluginConfig.NetmasterTLS.ClientTLSConfig()
+		if err != nil {
+			log.Fatalf("Failed to init netmaster TLS. Error: %s", err)
+		}
+		cluster.SetMasterTLS(tlsCfg)
+	}
+
 	// Initialize appropriate plugin
 	switch opts.pluginMode {
 	case "docker":
@@ -396,15 +599,26 @@ func main() {
 	}
 
 	// Process all current state
-	processCurrentState(netPlugin, opts)
+	nws := newWatchedState()
+	ews := newWatchedState()
+	err = processCurrentState(netPlugin, opts, nws, ews)
+	if err != nil {
+		log.Fatalf("Failed to process current state. Error: %s", err)
+	}
 
 	// Initialize clustering
 	cluster.Init(netPlugin, opts.ctrlIP)
 
+	// Periodically repair what the events missed
+	if opts.reconcileInterval > 0 {
+		go netPlugin.RunReconciler(opts.reconcileInterval, cluster.PeerHosts,
+			make(chan bool))
+	}
+
 	//logger := log.New(os.Stdout, "go-etcd: ", log.LstdFlags)
 	//etcd.SetLogger(logger)
 
-	if err := handleEvents(netPlugin, opts); err != nil {
+	if err := handleEvents(netPlugin, opts, nws, ews); err != nil {
 		os.Exit(1)
 	}
 }
@@ -32,6 +32,9 @@ type Config struct {
 		State    string `json:"state"`
 	}
 	Instance core.InstanceInfo `json:"plugin-instance"`
+	// certificates of the requests to netmaster, which is reached over
+	// https when set
+	NetmasterTLS core.TLSConfig `json:"netmaster-tls"`
 }
 
 // NetPlugin is the configuration struct for the plugin bus. Network and
@@ -42,6 +45,7 @@ type NetPlugin struct {
 	ConfigFile    string
 	NetworkDriver core.NetworkDriver
 	StateDriver   core.StateDriver
+	hostLabel     string
 }
 
 // Init initializes the NetPlugin instance via the configuration string passed.
@@ -51,6 +55,8 @@ func (p *NetPlugin) Init(pluginConfig Config, configStr string) error {
 		return core.Errorf("empty host-label passed")
 	}
 
+	p.hostLabel = pluginConfig.Instance.HostLabel
+
 	// initialize state driver
 	p.StateDriver, err = utils.NewStateDriver(pluginConfig.Drivers.State, configStr)
 	if err != nil {
@@ -0,0 +1,130 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// SkipHost checks if an endpoint with the given VTEP and homing host is not
+// to be programmed on the host with label myHostLabel. Local endpoints are
+// programmed on their homing host, and remote ones on all the other hosts.
+func SkipHost(vtepIP, homingHost, myHostLabel string) bool {
+	return (vtepIP == "" && homingHost != myHostLabel ||
+		vtepIP != "" && homingHost == myHostLabel)
+}
+
+// localEndpoints returns the IDs of the endpoints in the state store that
+// are to be programmed on this host.
+func (p *NetPlugin) localEndpoints() ([]string, error) {
+	readNet := &mastercfg.CfgNetworkState{}
+	readNet.StateDriver = p.StateDriver
+	netCfgs, err := readNet.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	networks := make(map[string]bool)
+	for _, netCfg := range netCfgs {
+		networks[netCfg.(*mastercfg.CfgNetworkState).ID] = true
+	}
+
+	readEp := &mastercfg.CfgEndpointState{}
+	readEp.StateDriver = p.StateDriver
+	epCfgs, err := readEp.ReadAll()
+	if core.ErrIfKeyExists(err) != nil {
+		return nil, err
+	}
+
+	epIDs := []string{}
+	for _, epCfg := range epCfgs {
+		ep := epCfg.(*mastercfg.CfgEndpointState)
+		// an endpoint can't be programmed without its network
+		if !networks[ep.NetID] || SkipHost(ep.VtepIP, ep.HomingHost, p.hostLabel) {
+			continue
+		}
+		epIDs = append(epIDs, ep.ID)
+	}
+
+	return epIDs, nil
+}
+
+// Reconcile compares the endpoints in the state store and the given peer
+// hosts with the ones programmed by the network driver and repairs the
+// differences. It returns the changes made.
+func (p *NetPlugin) Reconcile(peers []core.ServiceInfo) ([]core.ReconcileAction, error) {
+	reconciler, ok := p.NetworkDriver.(core.Reconciler)
+	if !ok {
+		return nil, core.Errorf("network driver doesn't support reconciliation")
+	}
+
+	// take the lock to not interleave with the processing of state events
+	p.Lock()
+	defer p.Unlock()
+
+	epIDs, err := p.localEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := reconciler.ReconcileEndpoints(epIDs)
+	actions = append(actions, reconciler.ReconcilePeerHosts(peers)...)
+	return actions, nil
+}
+
+// RunReconciler runs Reconcile every interval with the peer hosts returned
+// by listPeers, logging the changes made, until stopCh is closed.
+func (p *NetPlugin) RunReconciler(interval time.Duration,
+	listPeers func() ([]core.ServiceInfo, error), stopCh chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		peers, err := listPeers()
+		if err != nil {
+			log.Errorf("Error listing peer hosts to reconcile. Error: %s", err)
+			continue
+		}
+
+		actions, err := p.Reconcile(peers)
+		if err != nil {
+			log.Errorf("Reconciliation failed. Error: %s", err)
+			continue
+		}
+
+		for _, action := range actions {
+			if action.Error != "" {
+				log.Errorf("Reconciliation failed to %s %s %s (%s). Error: %s",
+					action.Action, action.Kind, action.ID, action.Reason, action.Error)
+			} else {
+				log.Infof("Reconciliation did %s %s %s (%s)",
+					action.Action, action.Kind, action.ID, action.Reason)
+			}
+		}
+		log.Debugf("Reconciliation done, %d changes made", len(actions))
+	}
+}
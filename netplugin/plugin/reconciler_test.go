@@ -0,0 +1,107 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+	"github.com/contiv/netplugin/state"
+)
+
+// testReconcileDriver records the endpoints and peers it is asked to
+// reconcile
+type testReconcileDriver struct {
+	core.NetworkDriver
+	epIDs []string
+	peers []core.ServiceInfo
+}
+
+func (d *testReconcileDriver) ReconcileEndpoints(epIDs []string) []core.ReconcileAction {
+	d.epIDs = epIDs
+	actions := []core.ReconcileAction{}
+	for _, id := range epIDs {
+		actions = append(actions, core.ReconcileAction{Kind: "endpoint", ID: id, Action: "create"})
+	}
+	return actions
+}
+
+func (d *testReconcileDriver) ReconcilePeerHosts(peers []core.ServiceInfo) []core.ReconcileAction {
+	d.peers = peers
+	actions := []core.ReconcileAction{}
+	for _, peer := range peers {
+		actions = append(actions, core.ReconcileAction{Kind: "vtep", ID: peer.HostAddr, Action: "create"})
+	}
+	return actions
+}
+
+func TestNetPluginReconcile(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+	defer sd.Deinit()
+
+	nw := &mastercfg.CfgNetworkState{}
+	nw.StateDriver = sd
+	nw.ID = "net1"
+	if err := nw.Write(); err != nil {
+		t.Fatalf("error writing network. Error: %s", err)
+	}
+
+	eps := []mastercfg.CfgEndpointState{
+		{NetID: "net1", HomingHost: "host1"},
+		{NetID: "net1", HomingHost: "host2"},
+		{NetID: "net1", HomingHost: "host2", VtepIP: "10.1.1.2"},
+		{NetID: "net1", HomingHost: "host1", VtepIP: "10.1.1.1"},
+		{NetID: "net2", HomingHost: "host1"},
+	}
+	for i := range eps {
+		ep := &eps[i]
+		ep.StateDriver = sd
+		ep.ID = fmt.Sprintf("ep%d", i)
+		if err := ep.Write(); err != nil {
+			t.Fatalf("error writing endpoint. Error: %s", err)
+		}
+	}
+
+	driver := &testReconcileDriver{}
+	p := &NetPlugin{NetworkDriver: driver, StateDriver: sd, hostLabel: "host1"}
+	peers := []core.ServiceInfo{{HostAddr: "10.1.1.2"}}
+	actions, err := p.Reconcile(peers)
+	if err != nil {
+		t.Fatalf("reconcile failed. Error: %s", err)
+	}
+
+	sort.Strings(driver.epIDs)
+	if len(driver.epIDs) != 2 || driver.epIDs[0] != "ep0" || driver.epIDs[1] != "ep2" {
+		t.Fatalf("unexpected local endpoints %v", driver.epIDs)
+	}
+	if len(driver.peers) != 1 || driver.peers[0].HostAddr != "10.1.1.2" {
+		t.Fatalf("unexpected peers %+v", driver.peers)
+	}
+	if len(actions) != 3 {
+		t.Fatalf("unexpected actions %+v", actions)
+	}
+}
+
+func TestNetPluginReconcileUnsupported(t *testing.T) {
+	p := &NetPlugin{}
+	if _, err := p.Reconcile(nil); err == nil {
+		t.Fatalf("reconcile succeeded without a reconciling driver")
+	}
+}
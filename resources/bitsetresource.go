@@ -0,0 +1,287 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"github.com/contiv/netplugin/core"
+	"github.com/jainvipin/bitset"
+)
+
+// BitsetResource is a building block for resources that allocate values from
+// a set. The values are the bits of Values offset by Start, and the free ones
+// are kept in a BitsetOperResource. Both states are stored under the
+// resource description, so the type can be registered under any number of
+// descriptions. As a core.Resource it's initialized with a *bitset.BitSet
+// of the values, and allocates them as uint. Types that embed it to take a
+// different config must implement ReadAll with ReadAllAs.
+type BitsetResource struct {
+	ResourceState
+	Start  uint           `json:"start"`
+	Values *bitset.BitSet `json:"values"`
+}
+
+// Write the state.
+func (r *BitsetResource) Write() error {
+	return r.WriteAs(r)
+}
+
+// Read the state.
+func (r *BitsetResource) Read(id string) error {
+	return r.ReadAs(id, r)
+}
+
+// ReadAll the state for this resource.
+func (r *BitsetResource) ReadAll() ([]core.State, error) {
+	return r.ReadAllAs(r)
+}
+
+// Init the Resource. Requires a *bitset.BitSet.
+func (r *BitsetResource) Init(rsrcCfg interface{}) error {
+	values, ok := rsrcCfg.(*bitset.BitSet)
+	if !ok {
+		return core.Errorf("Invalid type for %s resource config", r.Desc)
+	}
+
+	return r.InitValues(values, 0)
+}
+
+// InitValues initializes the resource with the bits of values offset by
+// start, all of them free.
+func (r *BitsetResource) InitValues(values *bitset.BitSet, start uint) error {
+	return r.initValues(values, start, values.Clone())
+}
+
+// initValues initializes the resource with the bits of values offset by
+// start, of which the bits of free are not allocated.
+func (r *BitsetResource) initValues(values *bitset.BitSet, start uint, free *bitset.BitSet) error {
+	r.Values = values
+	r.Start = start
+	err := r.Write()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			r.Clear()
+		}
+	}()
+
+	oper := r.operState()
+	oper.FreeValues = free
+	err = oper.Write()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reinit redefines the values of the resource. Requires a *bitset.BitSet
+// that includes all the values in use.
+func (r *BitsetResource) Reinit(rsrcCfg interface{}) error {
+	values, ok := rsrcCfg.(*bitset.BitSet)
+	if !ok {
+		return core.Errorf("Invalid type for %s resource config", r.Desc)
+	}
+
+	return r.ReinitValues(values, 0)
+}
+
+// ReinitValues redefines the values of the resource as the bits of values
+// offset by start. It fails if a value in use would be left out.
+func (r *BitsetResource) ReinitValues(values *bitset.BitSet, start uint) error {
+	prevValues, prevStart := r.Values, r.Start
+	return core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		free := values.Clone()
+		stranded := []uint{}
+		inUse := prevValues.Difference(oper.FreeValues)
+		for i, ok := inUse.NextSet(0); ok; i, ok = inUse.NextSet(i + 1) {
+			value := i + prevStart
+			if value < start || !values.Test(value-start) {
+				stranded = append(stranded, value)
+				continue
+			}
+			free.Clear(value - start)
+		}
+		if len(stranded) != 0 {
+			return core.ConflictErrorf("%s values %v are in use and not in the new range", r.Desc, stranded)
+		}
+
+		r.Values = values
+		r.Start = start
+		err = r.Write()
+		if err != nil {
+			return err
+		}
+
+		oper.FreeValues = free
+		return oper.WriteIfUnchanged()
+	})
+}
+
+// Deinit the resource.
+func (r *BitsetResource) Deinit() {
+	oper := r.operState()
+	err := oper.Read(r.ID)
+	if err != nil {
+		// continue cleanup
+	} else {
+		err = oper.Clear()
+		if err != nil {
+			// continue cleanup
+		}
+	}
+
+	r.Clear()
+}
+
+// Allocate the lowest free value.
+func (r *BitsetResource) Allocate() (interface{}, error) {
+	var value uint
+
+	err := core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		bit, ok := oper.FreeValues.NextSet(0)
+		if !ok {
+			return core.ExhaustedErrorf("no %s values available.", r.Desc)
+		}
+
+		oper.FreeValues.Clear(bit)
+		value = bit + r.Start
+
+		return oper.WriteIfUnchanged()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// AllocateValue allocates a specific value. It fails if the value is not in
+// the resource or is already allocated.
+func (r *BitsetResource) AllocateValue(value uint) error {
+	bit, err := r.valueBit(value)
+	if err != nil {
+		return err
+	}
+
+	return core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		if !oper.FreeValues.Test(bit) {
+			return core.ConflictErrorf("%s value %d is already allocated", r.Desc, value)
+		}
+		oper.FreeValues.Clear(bit)
+
+		return oper.WriteIfUnchanged()
+	})
+}
+
+// Deallocate the value. Requires a uint.
+func (r *BitsetResource) Deallocate(value interface{}) error {
+	val, ok := value.(uint)
+	if !ok {
+		return core.Errorf("Invalid type for %s value", r.Desc)
+	}
+
+	bit, err := r.valueBit(val)
+	if err != nil {
+		return err
+	}
+
+	return core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		if oper.FreeValues.Test(bit) {
+			return nil
+		}
+		oper.FreeValues.Set(bit)
+
+		return oper.WriteIfUnchanged()
+	})
+}
+
+// valueBit returns the bit of a value of the resource
+func (r *BitsetResource) valueBit(value uint) (uint, error) {
+	if value < r.Start || !r.Values.Test(value-r.Start) {
+		return 0, core.Errorf("%s value %d is not in resource %s", r.Desc, value, r.ID)
+	}
+
+	return value - r.Start, nil
+}
+
+// operState returns the oper state of the resource, to be read
+func (r *BitsetResource) operState() *BitsetOperResource {
+	oper := &BitsetOperResource{}
+	oper.Desc = r.Desc
+	oper.StateDriver = r.StateDriver
+	oper.ID = r.ID
+	return oper
+}
+
+// BitsetOperResource holds the free values of a BitsetResource.
+type BitsetOperResource struct {
+	ResourceOperState
+	FreeValues *bitset.BitSet `json:"freeValues"`
+}
+
+// Write the state.
+func (r *BitsetOperResource) Write() error {
+	return r.WriteAs(r)
+}
+
+// Read the state.
+func (r *BitsetOperResource) Read(id string) error {
+	return r.ReadAs(id, r)
+}
+
+// ReadAll state for this path.
+func (r *BitsetOperResource) ReadAll() ([]core.State, error) {
+	return r.ReadAllAs(r)
+}
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (r *BitsetOperResource) ReadForUpdate(id string) error {
+	*r = BitsetOperResource{ResourceOperState: r.ResourceOperState}
+	return r.ReadForUpdateAs(id, r)
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate.
+func (r *BitsetOperResource) WriteIfUnchanged() error {
+	return r.WriteIfUnchangedAs(r)
+}
@@ -0,0 +1,132 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"github.com/contiv/netplugin/core"
+	"github.com/jainvipin/bitset"
+)
+
+const (
+	// AutoEPGResource is the name of the endpoint group ID pool
+	AutoEPGResource = "auto-epg"
+
+	// maxIDPoolSize is the largest span of IDs in a pool
+	maxIDPoolSize = 1 << 24
+)
+
+// IDRange is an inclusive range of IDs
+type IDRange struct {
+	Min uint `json:"min"`
+	Max uint `json:"max"`
+}
+
+// IDPoolCfg is the config of an IDPoolResource whose IDs in InUse are
+// allocated from the start, such as when a pool is rebuilt from the state.
+type IDPoolCfg struct {
+	Ranges []IDRange
+	InUse  []uint
+}
+
+// IDPoolResource implements the Resource interface for a pool of integer
+// IDs, such as endpoint group IDs or port numbers. It's initialized with
+// the []IDRange of the pool, or an IDPoolCfg, and allocates the IDs as
+// uint, lowest first. It's registered with RegisterResource under the
+// description of each pool.
+type IDPoolResource struct {
+	BitsetResource
+}
+
+// idRangesBitset returns the IDs of the ranges as offsets from the lowest
+// one, which is returned as well.
+func idRangesBitset(ranges []IDRange) (*bitset.BitSet, uint, error) {
+	if len(ranges) == 0 {
+		return nil, 0, core.Errorf("no ID ranges specified")
+	}
+
+	start, end := ranges[0].Min, ranges[0].Max
+	for _, idRange := range ranges {
+		if idRange.Min > idRange.Max {
+			return nil, 0, core.Errorf("invalid ID range %d-%d", idRange.Min, idRange.Max)
+		}
+		if idRange.Min < start {
+			start = idRange.Min
+		}
+		if idRange.Max > end {
+			end = idRange.Max
+		}
+	}
+	if end-start >= maxIDPoolSize {
+		return nil, 0, core.Errorf("ID ranges span more than %d IDs", maxIDPoolSize)
+	}
+
+	ids := bitset.New(end - start + 1)
+	for _, idRange := range ranges {
+		for id := idRange.Min; id <= idRange.Max; id++ {
+			ids.Set(id - start)
+		}
+	}
+
+	return ids, start, nil
+}
+
+// ReadAll the state for this resource.
+func (r *IDPoolResource) ReadAll() ([]core.State, error) {
+	return r.ReadAllAs(r)
+}
+
+// Init the Resource. Requires a []IDRange or an *IDPoolCfg.
+func (r *IDPoolResource) Init(rsrcCfg interface{}) error {
+	cfg, ok := rsrcCfg.(*IDPoolCfg)
+	if !ok {
+		ranges, ok := rsrcCfg.([]IDRange)
+		if !ok {
+			return core.Errorf("Invalid type for %s resource config", r.Desc)
+		}
+		cfg = &IDPoolCfg{Ranges: ranges}
+	}
+
+	ids, start, err := idRangesBitset(cfg.Ranges)
+	if err != nil {
+		return err
+	}
+
+	free := ids.Clone()
+	for _, id := range cfg.InUse {
+		if id < start || !ids.Test(id-start) {
+			return core.Errorf("%s ID %d in use is not in the pool", r.Desc, id)
+		}
+		free.Clear(id - start)
+	}
+
+	return r.initValues(ids, start, free)
+}
+
+// Reinit redefines the ranges of the pool. Requires a []IDRange that
+// includes all the IDs in use.
+func (r *IDPoolResource) Reinit(rsrcCfg interface{}) error {
+	ranges, ok := rsrcCfg.([]IDRange)
+	if !ok {
+		return core.Errorf("Invalid type for %s resource config", r.Desc)
+	}
+
+	ids, start, err := idRangesBitset(ranges)
+	if err != nil {
+		return err
+	}
+
+	return r.ReinitValues(ids, start)
+}
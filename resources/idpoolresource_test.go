@@ -0,0 +1,215 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/contiv/netplugin/state"
+)
+
+const testIDPoolDesc = "test-id-pool"
+
+func newTestIDPool(t *testing.T, ranges []IDRange) *StateResourceManager {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	if err := RegisterResource(testIDPoolDesc, &IDPoolResource{}); err != nil {
+		t.Fatalf("id pool registration failed. Error: %s", err)
+	}
+
+	rm := &StateResourceManager{stateDriver: sd}
+	if err := rm.DefineResource(testResourceID, testIDPoolDesc, ranges); err != nil {
+		UnregisterResource(testIDPoolDesc)
+		t.Fatalf("id pool definition failed. Error: %s", err)
+	}
+
+	return rm
+}
+
+func allocateIDs(t *testing.T, rm *StateResourceManager, expIDs []uint) {
+	for _, expID := range expIDs {
+		id, err := rm.AllocateResourceVal(testResourceID, testIDPoolDesc)
+		if err != nil {
+			t.Fatalf("id allocation failed. Error: %s", err)
+		}
+		if id.(uint) != expID {
+			t.Fatalf("allocated id %d, expected %d", id, expID)
+		}
+	}
+}
+
+func TestIDPoolResourceAllocate(t *testing.T) {
+	rm := newTestIDPool(t, []IDRange{{Min: 100, Max: 101}, {Min: 200, Max: 200}})
+	defer UnregisterResource(testIDPoolDesc)
+
+	allocateIDs(t, rm, []uint{100, 101, 200})
+
+	if _, err := rm.AllocateResourceVal(testResourceID, testIDPoolDesc); err == nil {
+		t.Fatalf("id allocation succeeded with no free IDs")
+	}
+
+	if err := rm.DeallocateResourceVal(testResourceID, testIDPoolDesc, uint(101)); err != nil {
+		t.Fatalf("id deallocation failed. Error: %s", err)
+	}
+	if err := rm.DeallocateResourceVal(testResourceID, testIDPoolDesc, uint(150)); err == nil {
+		t.Fatalf("deallocation of an id outside the pool succeeded")
+	}
+	allocateIDs(t, rm, []uint{101})
+
+	if err := rm.UndefineResource(testResourceID, testIDPoolDesc); err != nil {
+		t.Fatalf("id pool undefinition failed. Error: %s", err)
+	}
+	oper := &BitsetOperResource{}
+	oper.Desc = testIDPoolDesc
+	oper.StateDriver = rm.stateDriver
+	if err := oper.Read(testResourceID); err == nil {
+		t.Fatalf("id pool oper state not cleared on undefine")
+	}
+}
+
+func TestIDPoolResourceAllocateValue(t *testing.T) {
+	rm := newTestIDPool(t, []IDRange{{Min: 10, Max: 20}})
+	defer UnregisterResource(testIDPoolDesc)
+
+	rsrc, found, err := rm.findResource(testResourceID, testIDPoolDesc)
+	if err != nil || !found {
+		t.Fatalf("id pool not found. Error: %v", err)
+	}
+	pool := rsrc.(*IDPoolResource)
+
+	if err := pool.AllocateValue(10); err != nil {
+		t.Fatalf("allocation of id 10 failed. Error: %s", err)
+	}
+	if err := pool.AllocateValue(10); err == nil {
+		t.Fatalf("duplicate allocation of id 10 succeeded")
+	}
+	if err := pool.AllocateValue(21); err == nil {
+		t.Fatalf("allocation of an id outside the pool succeeded")
+	}
+
+	allocateIDs(t, rm, []uint{11})
+}
+
+func TestIDPoolResourceRedefine(t *testing.T) {
+	rm := newTestIDPool(t, []IDRange{{Min: 10, Max: 12}})
+	defer UnregisterResource(testIDPoolDesc)
+
+	allocateIDs(t, rm, []uint{10, 11})
+
+	err := rm.RedefineResource(testResourceID, testIDPoolDesc, []IDRange{{Min: 11, Max: 15}})
+	if err == nil {
+		t.Fatalf("redefinition leaving out id 10 in use succeeded")
+	}
+
+	err = rm.RedefineResource(testResourceID, testIDPoolDesc,
+		[]IDRange{{Min: 5, Max: 6}, {Min: 10, Max: 11}})
+	if err != nil {
+		t.Fatalf("id pool redefinition failed. Error: %s", err)
+	}
+
+	allocateIDs(t, rm, []uint{5, 6})
+	if _, err := rm.AllocateResourceVal(testResourceID, testIDPoolDesc); err == nil {
+		t.Fatalf("id allocation succeeded with no free IDs")
+	}
+}
+
+func TestIDPoolResourceInUse(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	rm := &StateResourceManager{stateDriver: sd}
+	err := rm.DefineResource(testResourceID, AutoEPGResource, &IDPoolCfg{
+		Ranges: []IDRange{{Min: 1, Max: 4}},
+		InUse:  []uint{1, 3},
+	})
+	if err != nil {
+		t.Fatalf("epg id pool definition failed. Error: %s", err)
+	}
+
+	for _, expID := range []uint{2, 4} {
+		id, err := rm.AllocateResourceVal(testResourceID, AutoEPGResource)
+		if err != nil || id.(uint) != expID {
+			t.Fatalf("allocated epg id %v, expected %d. Error: %v", id, expID, err)
+		}
+	}
+	if _, err := rm.AllocateResourceVal(testResourceID, AutoEPGResource); err == nil {
+		t.Fatalf("epg id allocation succeeded with no free IDs")
+	}
+
+	// IDs in use from the start are released like allocated ones
+	if err := rm.DeallocateResourceVal(testResourceID, AutoEPGResource, uint(3)); err != nil {
+		t.Fatalf("epg id deallocation failed. Error: %s", err)
+	}
+	if id, err := rm.AllocateResourceVal(testResourceID, AutoEPGResource); err != nil || id.(uint) != 3 {
+		t.Fatalf("allocated epg id %v, expected the freed ID 3. Error: %v", id, err)
+	}
+
+	err = rm.DefineResource("other", AutoEPGResource, &IDPoolCfg{
+		Ranges: []IDRange{{Min: 1, Max: 4}},
+		InUse:  []uint{5},
+	})
+	if err == nil {
+		t.Fatalf("epg id pool definition with an ID in use outside the pool succeeded")
+	}
+}
+
+func TestIDPoolResourceInvalidConfig(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	if err := RegisterResource(testIDPoolDesc, &IDPoolResource{}); err != nil {
+		t.Fatalf("id pool registration failed. Error: %s", err)
+	}
+	defer UnregisterResource(testIDPoolDesc)
+
+	rm := &StateResourceManager{stateDriver: sd}
+	for _, rsrcCfg := range []interface{}{
+		[]IDRange{},
+		[]IDRange{{Min: 20, Max: 10}},
+		[]IDRange{{Min: 0, Max: maxIDPoolSize}},
+		"1-10",
+	} {
+		if err := rm.DefineResource(testResourceID, testIDPoolDesc, rsrcCfg); err == nil {
+			t.Fatalf("id pool definition with config %v succeeded", rsrcCfg)
+		}
+	}
+}
+
+func TestRegisterResource(t *testing.T) {
+	if err := RegisterResource(testIDPoolDesc, &IDPoolResource{}); err != nil {
+		t.Fatalf("id pool registration failed. Error: %s", err)
+	}
+	defer UnregisterResource(testIDPoolDesc)
+
+	if err := RegisterResource(testIDPoolDesc, &IDPoolResource{}); err == nil {
+		t.Fatalf("duplicate registration succeeded")
+	}
+	if err := RegisterResource(AutoVLANResource, &IDPoolResource{}); err == nil {
+		t.Fatalf("registration over a builtin resource succeeded")
+	}
+	if err := RegisterResource("invalid/desc", &IDPoolResource{}); err == nil {
+		t.Fatalf("registration with an invalid description succeeded")
+	}
+
+	found := false
+	for _, desc := range RegisteredResources() {
+		found = found || desc == testIDPoolDesc
+	}
+	if !found {
+		t.Fatalf("%s not in registered resources %v", testIDPoolDesc, RegisteredResources())
+	}
+}
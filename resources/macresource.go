@@ -0,0 +1,247 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/utils/netutils"
+)
+
+const (
+	// AutoMACResource is the name of the resource, for storing state.
+	AutoMACResource = "auto-mac"
+	// StaticMACResource is the description of the resource that tracks the
+	// static mac addresses with none of the prefixes of the AutoMACResource
+	// resources.
+	StaticMACResource = "static-mac"
+)
+
+// AutoMACCfgResource implements the Resource interface for an 'auto-mac'
+// resource. 'auto-mac' resource allocates MAC addresses with the prefix
+// that is the ID of the resource, such as 02:02. The addresses are
+// allocated sparsely, so that large prefixes don't take up space. As a
+// StaticMACResource it only tracks the addresses allocated by AllocateMAC,
+// which may have any prefix.
+type AutoMACCfgResource struct {
+	ResourceState
+}
+
+// Write the state.
+func (r *AutoMACCfgResource) Write() error {
+	return r.WriteAs(r)
+}
+
+// Read the state.
+func (r *AutoMACCfgResource) Read(id string) error {
+	return r.ReadAs(id, r)
+}
+
+// ReadAll the state for this resource.
+func (r *AutoMACCfgResource) ReadAll() ([]core.State, error) {
+	return r.ReadAllAs(r)
+}
+
+// Init the Resource. Requires a []string of the addresses with the prefix
+// that are already in use.
+func (r *AutoMACCfgResource) Init(rsrcCfg interface{}) error {
+	inUse, ok := rsrcCfg.([]string)
+	if !ok {
+		return core.Errorf("Invalid type for mac resource config")
+	}
+	if r.Desc != StaticMACResource {
+		if _, err := netutils.ParseMACPrefix(r.ID); err != nil {
+			return err
+		}
+	}
+
+	err := r.Write()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			r.Clear()
+		}
+	}()
+
+	oper := r.operState()
+	oper.NextHostID = 1
+	oper.AllocatedMACs = map[string]bool{}
+	for _, mac := range inUse {
+		oper.AllocatedMACs[mac] = true
+	}
+	err = oper.Write()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reinit is not supported, as the prefix of the resource is its ID.
+func (r *AutoMACCfgResource) Reinit(rsrcCfg interface{}) error {
+	return core.Errorf("mac resource %s can not be redefined", r.ID)
+}
+
+// Deinit the resource.
+func (r *AutoMACCfgResource) Deinit() {
+	oper := r.operState()
+	err := oper.Read(r.ID)
+	if err != nil {
+		// continue cleanup
+	} else {
+		err = oper.Clear()
+		if err != nil {
+			// continue cleanup
+		}
+	}
+
+	r.Clear()
+}
+
+// Allocate a resource. Addresses are allocated in turn, so that a released
+// address isn't reused right away.
+func (r *AutoMACCfgResource) Allocate() (interface{}, error) {
+	var mac string
+
+	if r.Desc == StaticMACResource {
+		return nil, core.Errorf("%s resource only allocates specific addresses", r.Desc)
+	}
+
+	numHosts := uint64(1) << uint(8*(6-(len(r.ID)+1)/3))
+	err := core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		// at most len(AllocatedMACs) addresses can be skipped
+		found := false
+		hostID := oper.NextHostID
+		for i := uint64(0); i <= uint64(len(oper.AllocatedMACs)) && i < numHosts-1; i++ {
+			if hostID == 0 || hostID >= numHosts {
+				hostID = 1
+			}
+			mac = netutils.GetMACAddress(r.ID, hostID)
+			if !oper.AllocatedMACs[mac] {
+				found = true
+				break
+			}
+			hostID++
+		}
+		if !found {
+			return core.ExhaustedErrorf("no mac addresses available with prefix %s.", r.ID)
+		}
+
+		oper.AllocatedMACs[mac] = true
+		oper.NextHostID = hostID + 1
+
+		return oper.WriteIfUnchanged()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mac, nil
+}
+
+// AllocateMAC allocates a specific address. It fails if the address is
+// already allocated.
+func (r *AutoMACCfgResource) AllocateMAC(mac string) error {
+	return core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		if oper.AllocatedMACs[mac] {
+			return core.ConflictErrorf("mac address %s is already in use", mac)
+		}
+		oper.AllocatedMACs[mac] = true
+
+		return oper.WriteIfUnchanged()
+	})
+}
+
+// Deallocate the resource. Requires a string.
+func (r *AutoMACCfgResource) Deallocate(value interface{}) error {
+	mac, ok := value.(string)
+	if !ok {
+		return core.Errorf("Invalid type for mac value")
+	}
+
+	return core.RetryOnVersionConflict(func() error {
+		oper := r.operState()
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		if !oper.AllocatedMACs[mac] {
+			return nil
+		}
+		delete(oper.AllocatedMACs, mac)
+
+		return oper.WriteIfUnchanged()
+	})
+}
+
+// operState returns the oper state of the resource, to be read
+func (r *AutoMACCfgResource) operState() *AutoMACOperResource {
+	oper := &AutoMACOperResource{}
+	oper.Desc = r.Desc
+	oper.StateDriver = r.StateDriver
+	oper.ID = r.ID
+	return oper
+}
+
+// AutoMACOperResource is an implementation of core.State. NextHostID is
+// the host number of the next address to try to allocate.
+type AutoMACOperResource struct {
+	ResourceOperState
+	NextHostID    uint64          `json:"nextHostID"`
+	AllocatedMACs map[string]bool `json:"allocatedMACs"`
+}
+
+// Write the state.
+func (r *AutoMACOperResource) Write() error {
+	return r.WriteAs(r)
+}
+
+// Read the state.
+func (r *AutoMACOperResource) Read(id string) error {
+	return r.ReadAs(id, r)
+}
+
+// ReadAll state for this path.
+func (r *AutoMACOperResource) ReadAll() ([]core.State, error) {
+	return r.ReadAllAs(r)
+}
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (r *AutoMACOperResource) ReadForUpdate(id string) error {
+	*r = AutoMACOperResource{ResourceOperState: r.ResourceOperState}
+	return r.ReadForUpdateAs(id, r)
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate.
+func (r *AutoMACOperResource) WriteIfUnchanged() error {
+	return r.WriteIfUnchangedAs(r)
+}
@@ -0,0 +1,121 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/contiv/netplugin/state"
+)
+
+func TestAutoMACCfgResourceAllocate(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	rsrc := &AutoMACCfgResource{}
+	rsrc.SetDescription(AutoMACResource)
+	rsrc.StateDriver = sd
+	rsrc.ID = "02:02:02:02:02"
+	if err := rsrc.Init([]string{"02:02:02:02:02:02"}); err != nil {
+		t.Fatalf("mac resource init failed. Error: %s", err)
+	}
+
+	// addresses in use are skipped, and released ones aren't reused right away
+	for _, expMAC := range []string{"02:02:02:02:02:01", "02:02:02:02:02:03"} {
+		mac, err := rsrc.Allocate()
+		if err != nil {
+			t.Fatalf("mac allocation failed. Error: %s", err)
+		}
+		if mac.(string) != expMAC {
+			t.Fatalf("allocated mac %s, expected %s", mac, expMAC)
+		}
+	}
+	if err := rsrc.Deallocate("02:02:02:02:02:01"); err != nil {
+		t.Fatalf("mac deallocation failed. Error: %s", err)
+	}
+	if mac, err := rsrc.Allocate(); err != nil || mac.(string) != "02:02:02:02:02:04" {
+		t.Fatalf("allocated mac %v, expected 02:02:02:02:02:04. Error: %v", mac, err)
+	}
+
+	if err := rsrc.AllocateMAC("02:02:02:02:02:05"); err != nil {
+		t.Fatalf("static mac allocation failed. Error: %s", err)
+	}
+	if err := rsrc.AllocateMAC("02:02:02:02:02:05"); err == nil {
+		t.Fatalf("duplicate static mac allocation succeeded")
+	}
+
+	// the rest of the 255 addresses, wrapping around to the released one
+	for i := 0; i < 251; i++ {
+		if _, err := rsrc.Allocate(); err != nil {
+			t.Fatalf("mac allocation %d failed. Error: %s", i, err)
+		}
+	}
+	if _, err := rsrc.Allocate(); err == nil {
+		t.Fatalf("mac allocation succeeded with no free addresses")
+	}
+
+	rsrc.Deinit()
+	oper := &AutoMACOperResource{}
+	oper.Desc = AutoMACResource
+	oper.StateDriver = sd
+	if err := oper.Read(rsrc.ID); err == nil {
+		t.Fatalf("mac oper state not cleared on deinit")
+	}
+}
+
+func TestAutoMACCfgResourceInvalidPrefix(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	for _, prefix := range []string{"", "01:00", "02:02:02:02:02:02", "zz"} {
+		rsrc := &AutoMACCfgResource{}
+		rsrc.SetDescription(AutoMACResource)
+		rsrc.StateDriver = sd
+		rsrc.ID = prefix
+		if err := rsrc.Init([]string{}); err == nil {
+			t.Fatalf("mac resource init with prefix %q succeeded", prefix)
+		}
+	}
+}
+
+func TestStaticMACResource(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	rsrc := &AutoMACCfgResource{}
+	rsrc.SetDescription(StaticMACResource)
+	rsrc.StateDriver = sd
+	rsrc.ID = "static"
+	if err := rsrc.Init([]string{"0a:00:00:00:00:01"}); err != nil {
+		t.Fatalf("static mac resource init failed. Error: %s", err)
+	}
+
+	if _, err := rsrc.Allocate(); err == nil {
+		t.Fatalf("mac allocation from the static mac resource succeeded")
+	}
+	if err := rsrc.AllocateMAC("0a:00:00:00:00:01"); err == nil {
+		t.Fatalf("allocation of a static mac in use succeeded")
+	}
+	if err := rsrc.AllocateMAC("0c:00:00:00:00:01"); err != nil {
+		t.Fatalf("static mac allocation failed. Error: %s", err)
+	}
+	if err := rsrc.Deallocate("0a:00:00:00:00:01"); err != nil {
+		t.Fatalf("static mac deallocation failed. Error: %s", err)
+	}
+	if err := rsrc.AllocateMAC("0a:00:00:00:00:01"); err != nil {
+		t.Fatalf("allocation of a released static mac failed. Error: %s", err)
+	}
+}
@@ -0,0 +1,134 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/drivers"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+const (
+	resourceConfigPathPrefix = mastercfg.StateConfigPath + "resources/%s/"
+	resourceConfigPath       = resourceConfigPathPrefix + "%s"
+	resourceOperPathPrefix   = drivers.StateOperPath + "resources/%s/"
+	resourceOperPath         = resourceOperPathPrefix + "%s"
+)
+
+// ResourceState is a building block for the config state of resources. It's
+// stored under the resource description, so the type that embeds it can be
+// registered under any number of descriptions. The embedding type
+// implements Write, Read and ReadAll by passing itself to WriteAs, ReadAs
+// and ReadAllAs, to be stored as itself.
+type ResourceState struct {
+	core.CommonState
+	Desc string `json:"desc"`
+}
+
+// SetDescription sets the description the resource is used as.
+func (r *ResourceState) SetDescription(desc string) {
+	r.Desc = desc
+}
+
+// Description is a description of this resource.
+func (r *ResourceState) Description() string {
+	return r.Desc
+}
+
+// WriteAs writes s, the state that embeds r.
+func (r *ResourceState) WriteAs(s core.State) error {
+	if r.Desc == "" {
+		return core.Errorf("resource %q has no description", r.ID)
+	}
+	key := fmt.Sprintf(resourceConfigPath, r.Desc, r.ID)
+	return r.StateDriver.WriteState(key, s, json.Marshal)
+}
+
+// ReadAs reads s, the state that embeds r, for a given identifier.
+func (r *ResourceState) ReadAs(id string, s core.State) error {
+	key := fmt.Sprintf(resourceConfigPath, r.Desc, id)
+	return r.StateDriver.ReadState(key, s, json.Unmarshal)
+}
+
+// ReadAllAs reads the state of all the resources with the description of r
+// as states of the type of sType.
+func (r *ResourceState) ReadAllAs(sType core.State) ([]core.State, error) {
+	return r.StateDriver.ReadAllState(fmt.Sprintf(resourceConfigPathPrefix, r.Desc),
+		sType, json.Unmarshal)
+}
+
+// Clear the state.
+func (r *ResourceState) Clear() error {
+	key := fmt.Sprintf(resourceConfigPath, r.Desc, r.ID)
+	return r.StateDriver.ClearState(key)
+}
+
+// ResourceOperState is a building block for the oper state of resources,
+// which is stored under the resource description like a ResourceState. The
+// embedding type implements the core.State methods, ReadForUpdate and
+// WriteIfUnchanged by passing itself to the methods of the same name with
+// an As suffix.
+type ResourceOperState struct {
+	core.CommonState
+	Desc string `json:"desc"`
+}
+
+// WriteAs writes s, the state that embeds r.
+func (r *ResourceOperState) WriteAs(s core.State) error {
+	key := fmt.Sprintf(resourceOperPath, r.Desc, r.ID)
+	return r.StateDriver.WriteState(key, s, json.Marshal)
+}
+
+// ReadAs reads s, the state that embeds r, for a given identifier.
+func (r *ResourceOperState) ReadAs(id string, s core.State) error {
+	key := fmt.Sprintf(resourceOperPath, r.Desc, id)
+	return r.StateDriver.ReadState(key, s, json.Unmarshal)
+}
+
+// ReadAllAs reads the oper state of all the resources with the description
+// of r as states of the type of sType.
+func (r *ResourceOperState) ReadAllAs(sType core.State) ([]core.State, error) {
+	return r.StateDriver.ReadAllState(fmt.Sprintf(resourceOperPathPrefix, r.Desc),
+		sType, json.Unmarshal)
+}
+
+// Clear the state.
+func (r *ResourceOperState) Clear() error {
+	key := fmt.Sprintf(resourceOperPath, r.Desc, r.ID)
+	return r.StateDriver.ClearState(key)
+}
+
+// ReadForUpdateAs reads s, the state that embeds r, and records its version
+// for a subsequent WriteIfUnchangedAs.
+func (r *ResourceOperState) ReadForUpdateAs(id string, s core.State) error {
+	key := fmt.Sprintf(resourceOperPath, r.Desc, id)
+	version, err := r.StateDriver.ReadStateVersion(key, s, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	r.Version = version
+	return nil
+}
+
+// WriteIfUnchangedAs writes s, the state that embeds r, only if it has not
+// been modified since it was read by ReadForUpdateAs.
+func (r *ResourceOperState) WriteIfUnchangedAs(s core.State) error {
+	key := fmt.Sprintf(resourceOperPath, r.Desc, r.ID)
+	return r.StateDriver.WriteStateIfVersion(key, s, json.Marshal, r.Version)
+}
@@ -17,14 +17,80 @@ package resources
 
 import (
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/jainvipin/bitset"
 )
 
 var resourceRegistry = map[string]reflect.Type{
-	AutoVLANResource:   reflect.TypeOf(AutoVLANCfgResource{}),
-	AutoVXLANResource:  reflect.TypeOf(AutoVXLANCfgResource{}),
-	AutoSubnetResource: reflect.TypeOf(AutoSubnetCfgResource{}),
+	AutoVLANResource:       reflect.TypeOf(AutoVLANCfgResource{}),
+	AutoVXLANResource:      reflect.TypeOf(AutoVXLANCfgResource{}),
+	AutoSubnetResource:     reflect.TypeOf(AutoSubnetCfgResource{}),
+	AutoIPv6SubnetResource: reflect.TypeOf(BitsetResource{}),
+	AutoEPGResource:        reflect.TypeOf(IDPoolResource{}),
+	AutoMACResource:        reflect.TypeOf(AutoMACCfgResource{}),
+	StaticMACResource:      reflect.TypeOf(AutoMACCfgResource{}),
+}
+
+var resourceRegistryMutex sync.RWMutex
+
+// DescriptionSetter is implemented by resource types that can be registered
+// under several descriptions. The resource manager sets the description a
+// resource is used as before reading or initializing it.
+type DescriptionSetter interface {
+	SetDescription(desc string)
+}
+
+// RegisterResource makes the type of rsrc available to the resource manager
+// under the description desc. rsrc must be a pointer to a struct that
+// embeds core.CommonState.
+func RegisterResource(desc string, rsrc core.Resource) error {
+	if desc == "" || strings.Contains(desc, "/") {
+		return core.Errorf("invalid resource description: %q", desc)
+	}
+
+	rsrcType := reflect.TypeOf(rsrc)
+	if rsrcType == nil || rsrcType.Kind() != reflect.Ptr ||
+		rsrcType.Elem().Kind() != reflect.Struct {
+		return core.Errorf("resource %q must be a pointer to a struct", desc)
+	}
+	if _, ok := rsrcType.Elem().FieldByName("CommonState"); !ok {
+		return core.Errorf("The state structure %v is missing core.CommonState", rsrcType.Elem())
+	}
+
+	resourceRegistryMutex.Lock()
+	defer resourceRegistryMutex.Unlock()
+
+	if _, ok := resourceRegistry[desc]; ok {
+		return core.Errorf("resource %q is already registered", desc)
+	}
+	resourceRegistry[desc] = rsrcType.Elem()
+
+	return nil
+}
+
+// UnregisterResource removes the resource type registered under desc
+func UnregisterResource(desc string) {
+	resourceRegistryMutex.Lock()
+	defer resourceRegistryMutex.Unlock()
+
+	delete(resourceRegistry, desc)
+}
+
+// RegisteredResources returns the descriptions of the registered resources
+func RegisteredResources() []string {
+	resourceRegistryMutex.RLock()
+	defer resourceRegistryMutex.RUnlock()
+
+	descs := []string{}
+	for desc := range resourceRegistry {
+		descs = append(descs, desc)
+	}
+	sort.Strings(descs)
+	return descs
 }
 
 // StateResourceManager implements the core.ResourceManager interface.
@@ -70,6 +136,12 @@ func ReleaseStateResourceManager() {
 	gStateResourceManager = nil
 }
 
+// InTxn returns a resource manager that allocates and releases resources as
+// part of the passed state transaction.
+func (rm *StateResourceManager) InTxn(txn core.Txn) *StateResourceManager {
+	return &StateResourceManager{stateDriver: txn}
+}
+
 // Init initializes the resource manager
 func (rm *StateResourceManager) Init() error { return nil }
 
@@ -79,7 +151,9 @@ func (rm *StateResourceManager) Deinit() {}
 // XXX: It might be better to keep cache of resources and avoid frequent etcd reads
 func (rm *StateResourceManager) findResource(id, desc string) (core.Resource, bool, error) {
 	alreadyExists := false
+	resourceRegistryMutex.RLock()
 	rsrcType, ok := resourceRegistry[desc]
+	resourceRegistryMutex.RUnlock()
 	if !ok {
 		return nil, alreadyExists,
 			core.Errorf("No resource found for description: %q", desc)
@@ -95,6 +169,9 @@ func (rm *StateResourceManager) findResource(id, desc string) (core.Resource, bo
 	val.Elem().FieldByName("CommonState").FieldByName("ID").Set(reflect.ValueOf(id))
 
 	rsrc := val.Interface().(core.Resource)
+	if setter, ok := rsrc.(DescriptionSetter); ok {
+		setter.SetDescription(desc)
+	}
 	rsrcs, err := rsrc.ReadAll()
 	if core.ErrIfKeyExists(err) != nil {
 		return nil, alreadyExists, err
@@ -124,7 +201,7 @@ func (rm *StateResourceManager) DefineResource(id, desc string,
 	}
 
 	if alreadyExists {
-		return core.Errorf("Resource with id: %q already exists", id)
+		return core.ConflictErrorf("Resource with id: %q already exists", id)
 	}
 
 	err = rsrc.Init(rsrcCfg)
@@ -135,6 +212,23 @@ func (rm *StateResourceManager) DefineResource(id, desc string,
 	return nil
 }
 
+// RedefineResource changes the values of an existing resource, keeping the
+// allocated ones.
+func (rm *StateResourceManager) RedefineResource(id, desc string,
+	rsrcCfg interface{}) error {
+	rsrc, alreadyExists, err := rm.findResource(id, desc)
+	if err != nil {
+		return err
+	}
+
+	if !alreadyExists {
+		return core.Errorf("No resource found for description: %q and id: %q",
+			desc, id)
+	}
+
+	return rsrc.Reinit(rsrcCfg)
+}
+
 // UndefineResource deinitializes a resource.
 func (rm *StateResourceManager) UndefineResource(id, desc string) error {
 	// XXX: need to take care of distibuted updates, locks etc here
@@ -186,3 +280,14 @@ func (rm *StateResourceManager) DeallocateResourceVal(id, desc string,
 
 	return rsrc.Deallocate(value)
 }
+
+// valuesNotIn returns the values set in values but not in set.
+func valuesNotIn(values, set *bitset.BitSet) []uint {
+	missing := []uint{}
+	for i, ok := values.NextSet(0); ok; i, ok = values.NextSet(i + 1) {
+		if !set.Test(i) {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
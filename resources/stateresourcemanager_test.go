@@ -23,6 +23,7 @@ import (
 
 	"github.com/contiv/netplugin/core"
 	"github.com/contiv/netplugin/state"
+	"github.com/jainvipin/bitset"
 )
 
 const (
@@ -64,6 +65,10 @@ func (r *TestResource) Init(rsrcCfg interface{}) error {
 	return nil
 }
 
+func (r *TestResource) Reinit(rsrcCfg interface{}) error {
+	return nil
+}
+
 func (r *TestResource) Deinit() {
 }
 
@@ -289,3 +294,66 @@ func TestStateResourceManagerDeallocateiNonexistentResource(t *testing.T) {
 		t.Fatalf("Unexpected error. Error: %s", err)
 	}
 }
+
+func TestStateResourceManagerConcurrentAllocate(t *testing.T) {
+	sd := &state.FakeStateDriver{}
+	sd.Init(nil)
+
+	// two resource managers sharing a store behave like two netmasters
+	rms := []*StateResourceManager{
+		&StateResourceManager{stateDriver: sd},
+		&StateResourceManager{stateDriver: sd},
+	}
+
+	vlans := bitset.New(64)
+	for i := uint(1); i < 64; i++ {
+		vlans.Set(i)
+	}
+	err := rms[0].DefineResource(testResourceID, AutoVLANResource, vlans)
+	if err != nil {
+		t.Fatalf("Resource definition failed. Error: %s", err)
+	}
+
+	const numAllocators = 4
+	const numAllocs = 8
+
+	vals := make(chan uint, numAllocators*numAllocs)
+	errs := make(chan error, numAllocators)
+	for i := 0; i < numAllocators; i++ {
+		go func(rm *StateResourceManager) {
+			for j := 0; j < numAllocs; j++ {
+				val, err := rm.AllocateResourceVal(testResourceID, AutoVLANResource)
+				if err != nil {
+					errs <- err
+					return
+				}
+				vals <- val.(uint)
+			}
+			errs <- nil
+		}(rms[i%len(rms)])
+	}
+
+	for i := 0; i < numAllocators; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Resource allocation failed. Error: %s", err)
+		}
+	}
+	close(vals)
+
+	allocated := map[uint]bool{}
+	for val := range vals {
+		if allocated[val] {
+			t.Fatalf("vlan %d was allocated more than once", val)
+		}
+		allocated[val] = true
+	}
+
+	oper := &AutoVLANOperResource{}
+	oper.StateDriver = sd
+	if err := oper.Read(testResourceID); err != nil {
+		t.Fatalf("Failed to read oper state. Error: %s", err)
+	}
+	if free := oper.FreeVLANs.Count(); free != 63-numAllocators*numAllocs {
+		t.Fatalf("Unexpected number of free vlans: %d", free)
+	}
+}
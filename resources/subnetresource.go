@@ -34,6 +34,9 @@ import (
 const (
 	// AutoSubnetResource is the name of the resource, for storing state.
 	AutoSubnetResource = "auto-subnet"
+	// AutoIPv6SubnetResource is the name of the IPv6 subnet pool resource,
+	// a BitsetResource of the numbers of the subnets in the pool.
+	AutoIPv6SubnetResource = "auto-ipv6-subnet"
 )
 
 const (
@@ -118,6 +121,72 @@ func (r *AutoSubnetCfgResource) Init(rsrcCfg interface{}) error {
 	return nil
 }
 
+// Reinit moves the resource to a new subnet pool. Requires a
+// *AutoSubnetCfgResource. The subnets in use must be in the new pool and,
+// if any are, the allocated subnet length can not change.
+func (r *AutoSubnetCfgResource) Reinit(rsrcCfg interface{}) error {
+	cfg, ok := rsrcCfg.(*AutoSubnetCfgResource)
+	if !ok {
+		return core.Errorf("Invalid type for subnet resource config")
+	}
+
+	if cfg.AllocSubnetLen < cfg.SubnetPoolLen {
+		return core.Errorf("AllocSubnetLen should be greater than or equal to SubnetPoolLen")
+	}
+
+	prev := *r
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoSubnetOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		freeSubnets := netutils.CreateBitset(cfg.AllocSubnetLen - cfg.SubnetPoolLen).Complement()
+		stranded := []string{}
+		prevSize := uint(1) << (prev.AllocSubnetLen - prev.SubnetPoolLen)
+		for i := uint(0); i < prevSize; i++ {
+			if oper.FreeSubnets.Test(i) {
+				continue
+			}
+
+			subnetIP, err := netutils.GetSubnetIP(prev.SubnetPool.String(),
+				prev.SubnetPoolLen, prev.AllocSubnetLen, i)
+			if err != nil {
+				return err
+			}
+
+			if cfg.AllocSubnetLen != prev.AllocSubnetLen {
+				stranded = append(stranded, fmt.Sprintf("%s/%d", subnetIP, prev.AllocSubnetLen))
+				continue
+			}
+
+			subnet, err := netutils.GetIPNumber(cfg.SubnetPool.String(),
+				cfg.SubnetPoolLen, cfg.AllocSubnetLen, subnetIP)
+			if err != nil {
+				stranded = append(stranded, fmt.Sprintf("%s/%d", subnetIP, prev.AllocSubnetLen))
+				continue
+			}
+			freeSubnets.Clear(subnet)
+		}
+		if len(stranded) != 0 {
+			return core.ConflictErrorf("subnets %v are in use and not in the new pool", stranded)
+		}
+
+		r.SubnetPool = cfg.SubnetPool
+		r.SubnetPoolLen = cfg.SubnetPoolLen
+		r.AllocSubnetLen = cfg.AllocSubnetLen
+		err = r.Write()
+		if err != nil {
+			return err
+		}
+
+		oper.FreeSubnets = freeSubnets
+		return oper.WriteIfUnchanged()
+	})
+}
+
 // Deinit the state
 func (r *AutoSubnetCfgResource) Deinit() {
 	oper := &AutoSubnetOperResource{}
@@ -143,44 +212,42 @@ func (r *AutoSubnetCfgResource) Description() string {
 // Allocate a new subnet. Returns an interface{} which for this method is always
 // SubnetIPLenPair.
 func (r *AutoSubnetCfgResource) Allocate() (interface{}, error) {
-	oper := &AutoSubnetOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return nil, err
-	}
+	var subnet uint
 
-	subnet, ok := oper.FreeSubnets.NextSet(0)
-	if !ok {
-		return nil, core.Errorf("no subnets available.")
-	}
+	err := core.RetryOnVersionConflict(func() error {
+		oper := &AutoSubnetOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		subnet, ok = oper.FreeSubnets.NextSet(0)
+		if !ok {
+			return core.ExhaustedErrorf("no subnets available.")
+		}
 
-	oper.FreeSubnets.Clear(subnet)
+		oper.FreeSubnets.Clear(subnet)
 
-	var subnetIP string
-	subnetIP, err = netutils.GetSubnetIP(r.SubnetPool.String(), r.SubnetPoolLen,
-		r.AllocSubnetLen, subnet)
+		return oper.WriteIfUnchanged()
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	pair := SubnetIPLenPair{IP: net.ParseIP(subnetIP), Len: r.AllocSubnetLen}
-	err = oper.Write()
+	subnetIP, err := netutils.GetSubnetIP(r.SubnetPool.String(), r.SubnetPoolLen,
+		r.AllocSubnetLen, subnet)
 	if err != nil {
 		return nil, err
 	}
+
+	pair := SubnetIPLenPair{IP: net.ParseIP(subnetIP), Len: r.AllocSubnetLen}
 	return pair, nil
 }
 
 // Deallocate the resource. Must be passed a SubnetIPLenPair.
 func (r *AutoSubnetCfgResource) Deallocate(value interface{}) error {
-	oper := &AutoSubnetOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return err
-	}
-
 	pair, ok := value.(SubnetIPLenPair)
 	if !ok {
 		return core.Errorf("Invalid type for subnet value")
@@ -191,23 +258,27 @@ func (r *AutoSubnetCfgResource) Deallocate(value interface{}) error {
 			r.AllocSubnetLen, pair.Len)
 	}
 
-	var subnet uint
-	subnet, err = netutils.GetIPNumber(r.SubnetPool.String(), r.SubnetPoolLen,
+	subnet, err := netutils.GetIPNumber(r.SubnetPool.String(), r.SubnetPoolLen,
 		pair.Len, pair.IP.String())
 	if err != nil {
 		return err
 	}
 
-	if oper.FreeSubnets.Test(subnet) {
-		return nil
-	}
-	oper.FreeSubnets.Set(subnet)
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoSubnetOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
 
-	err = oper.Write()
-	if err != nil {
-		return err
-	}
-	return nil
+		if oper.FreeSubnets.Test(subnet) {
+			return nil
+		}
+		oper.FreeSubnets.Set(subnet)
+
+		return oper.WriteIfUnchanged()
+	})
 }
 
 // AutoSubnetOperResource is an implementation of core.State relating to subnets.
@@ -239,3 +310,24 @@ func (r *AutoSubnetOperResource) Clear() error {
 	key := fmt.Sprintf(subnetResourceOperPath, r.ID)
 	return r.StateDriver.ClearState(key)
 }
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (r *AutoSubnetOperResource) ReadForUpdate(id string) error {
+	*r = AutoSubnetOperResource{CommonState: core.CommonState{StateDriver: r.StateDriver, ID: id}}
+	key := fmt.Sprintf(subnetResourceOperPath, id)
+	version, err := r.StateDriver.ReadStateVersion(key, r, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	r.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate.
+func (r *AutoSubnetOperResource) WriteIfUnchanged() error {
+	key := fmt.Sprintf(subnetResourceOperPath, r.ID)
+	return r.StateDriver.WriteStateIfVersion(key, r, json.Marshal, r.Version)
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 	"github.com/jainvipin/bitset"
 
 	log "github.com/Sirupsen/logrus"
@@ -249,6 +250,7 @@ var subnetRsrcValidationStateMap = map[string]*subnetRsrcValidator{
 }
 
 type testSubnetRsrcStateDriver struct {
+	state.StubStateDriver
 }
 
 func (d *testSubnetRsrcStateDriver) Init(config *core.Config) error {
@@ -325,6 +327,16 @@ func (d *testSubnetRsrcStateDriver) WriteState(key string, value core.State,
 	return d.validate(key, value, subnetResourceOpWrite)
 }
 
+func (d *testSubnetRsrcStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testSubnetRsrcStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestAutoSubnetCfgResourceInit(t *testing.T) {
 	rsrc := &AutoSubnetCfgResource{}
 	rsrc.StateDriver = subnetRsrcStateDriver
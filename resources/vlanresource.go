@@ -97,6 +97,39 @@ func (r *AutoVLANCfgResource) Init(rsrcCfg interface{}) error {
 	return nil
 }
 
+// Reinit redefines the vlans of the resource. Requires a *bitset.BitSet that
+// includes all the vlans in use.
+func (r *AutoVLANCfgResource) Reinit(rsrcCfg interface{}) error {
+	vlans, ok := rsrcCfg.(*bitset.BitSet)
+	if !ok {
+		return core.Errorf("Invalid type for vlan resource config")
+	}
+
+	prevVLANs := r.VLANs
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoVLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		inUse := prevVLANs.Difference(oper.FreeVLANs)
+		if stranded := valuesNotIn(inUse, vlans); len(stranded) != 0 {
+			return core.ConflictErrorf("vlans %v are in use and not in the new range", stranded)
+		}
+
+		r.VLANs = vlans
+		err = r.Write()
+		if err != nil {
+			return err
+		}
+
+		oper.FreeVLANs = vlans.Difference(inUse)
+		return oper.WriteIfUnchanged()
+	})
+}
+
 // Deinit the resource.
 func (r *AutoVLANCfgResource) Deinit() {
 	oper := &AutoVLANOperResource{}
@@ -121,21 +154,26 @@ func (r *AutoVLANCfgResource) Description() string {
 
 // Allocate a resource.
 func (r *AutoVLANCfgResource) Allocate() (interface{}, error) {
-	oper := &AutoVLANOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return nil, err
-	}
+	var vlan uint
 
-	vlan, ok := oper.FreeVLANs.NextSet(0)
-	if !ok {
-		return nil, core.Errorf("no vlans available.")
-	}
+	err := core.RetryOnVersionConflict(func() error {
+		oper := &AutoVLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
 
-	oper.FreeVLANs.Clear(vlan)
+		var ok bool
+		vlan, ok = oper.FreeVLANs.NextSet(0)
+		if !ok {
+			return core.ExhaustedErrorf("no vlans available.")
+		}
 
-	err = oper.Write()
+		oper.FreeVLANs.Clear(vlan)
+
+		return oper.WriteIfUnchanged()
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -144,27 +182,26 @@ func (r *AutoVLANCfgResource) Allocate() (interface{}, error) {
 
 // Deallocate the resource.
 func (r *AutoVLANCfgResource) Deallocate(value interface{}) error {
-	oper := &AutoVLANOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return err
-	}
-
 	vlan, ok := value.(uint)
 	if !ok {
 		return core.Errorf("Invalid type for vlan value")
 	}
-	if oper.FreeVLANs.Test(vlan) {
-		return nil
-	}
-	oper.FreeVLANs.Set(vlan)
 
-	err = oper.Write()
-	if err != nil {
-		return err
-	}
-	return nil
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoVLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		if oper.FreeVLANs.Test(vlan) {
+			return nil
+		}
+		oper.FreeVLANs.Set(vlan)
+
+		return oper.WriteIfUnchanged()
+	})
 }
 
 // AutoVLANOperResource is an implementation of core.State.
@@ -196,3 +233,24 @@ func (r *AutoVLANOperResource) Clear() error {
 	key := fmt.Sprintf(vLANResourceOperPath, r.ID)
 	return r.StateDriver.ClearState(key)
 }
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (r *AutoVLANOperResource) ReadForUpdate(id string) error {
+	*r = AutoVLANOperResource{CommonState: core.CommonState{StateDriver: r.StateDriver, ID: id}}
+	key := fmt.Sprintf(vLANResourceOperPath, id)
+	version, err := r.StateDriver.ReadStateVersion(key, r, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	r.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate.
+func (r *AutoVLANOperResource) WriteIfUnchanged() error {
+	key := fmt.Sprintf(vLANResourceOperPath, r.ID)
+	return r.StateDriver.WriteStateIfVersion(key, r, json.Marshal, r.Version)
+}
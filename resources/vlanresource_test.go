@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 	"github.com/jainvipin/bitset"
 
 	log "github.com/Sirupsen/logrus"
@@ -226,6 +227,7 @@ var vlanRsrcValidationStateMap = map[string]*vlanRsrcValidator{
 }
 
 type testVlanRsrcStateDriver struct {
+	state.StubStateDriver
 }
 
 func (d *testVlanRsrcStateDriver) Init(config *core.Config) error {
@@ -302,6 +304,16 @@ func (d *testVlanRsrcStateDriver) WriteState(key string, value core.State,
 	return d.validate(key, value, vLANResourceOperWrite)
 }
 
+func (d *testVlanRsrcStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testVlanRsrcStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestAutoVLANCfgResourceInit(t *testing.T) {
 	rsrc := &AutoVLANCfgResource{}
 	rsrc.StateDriver = vlanRsrcStateDriver
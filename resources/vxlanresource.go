@@ -38,20 +38,33 @@ const (
 )
 
 // AutoVXLANCfgResource implements the Resource interface for an 'auto-vxlan' resource.
-// 'auto-vxlan' resource allocates a vxlan from a range of vxlan encaps specified
-// at time of resource instantiation
+// 'auto-vxlan' resource allocates a vxlan from the ranges of vxlan encaps specified
+// at time of resource instantiation. VXLANs holds the vxlans of all the ranges
+// as offsets from the lowest one, and LocalVLANs holds one vlan per vxlan.
 type AutoVXLANCfgResource struct {
 	core.CommonState
 	VXLANs     *bitset.BitSet `json:"vxlans"`
 	LocalVLANs *bitset.BitSet `json:"LocalVLANs"`
 }
 
-// VXLANVLANPair Pairs a VXLAN tag with a VLAN tag.
+// VXLANVLANPair Pairs a VXLAN tag with a VLAN tag. The VXLAN is an offset from
+// the lowest vxlan of the resource's ranges.
 type VXLANVLANPair struct {
 	VXLAN uint
 	VLAN  uint
 }
 
+// AutoVXLANReinitCfg is the config to redefine an 'auto-vxlan' resource with.
+type AutoVXLANReinitCfg struct {
+	VXLANs *bitset.BitSet
+	// LocalVLANs are the vlans that can be taken as local vlans, in
+	// addition to the ones the resource already has.
+	LocalVLANs *bitset.BitSet
+	// Shift is how far the first vxlan of the new range is from the first
+	// vxlan of the current one.
+	Shift int
+}
+
 // Write the state.
 func (r *AutoVXLANCfgResource) Write() error {
 	key := fmt.Sprintf(vXLANResourceConfigPath, r.ID)
@@ -105,6 +118,67 @@ func (r *AutoVXLANCfgResource) Init(rsrcCfg interface{}) error {
 	return nil
 }
 
+// Reinit redefines the vxlans of the resource. Requires a *AutoVXLANReinitCfg.
+// The vxlans in use, and their local vlans, are kept. One local vlan is kept
+// reserved for each vxlan in the new range.
+func (r *AutoVXLANCfgResource) Reinit(rsrcCfg interface{}) error {
+	cfg, ok := rsrcCfg.(*AutoVXLANReinitCfg)
+	if !ok {
+		return core.Errorf("Invalid vxlan resource config.")
+	}
+
+	prevVXLANs := r.VXLANs
+	prevLocalVLANs := r.LocalVLANs
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoVXLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		// the vxlans are offsets from the start of the range, move the ones
+		// in use to the new start
+		inUse := bitset.New(cfg.VXLANs.Len())
+		stranded := 0
+		prevInUse := prevVXLANs.Difference(oper.FreeVXLANs)
+		for i, ok := prevInUse.NextSet(0); ok; i, ok = prevInUse.NextSet(i + 1) {
+			vxlan := int(i) - cfg.Shift
+			if vxlan < 0 || !cfg.VXLANs.Test(uint(vxlan)) {
+				stranded++
+				continue
+			}
+			inUse.Set(uint(vxlan))
+		}
+		if stranded != 0 {
+			return core.ConflictErrorf("%d vxlans in use are not in the new range", stranded)
+		}
+
+		localInUse := prevLocalVLANs.Difference(oper.FreeLocalVLANs)
+		localVLANs := localInUse.Clone()
+		needed := cfg.VXLANs.Count()
+		for _, candidates := range []*bitset.BitSet{prevLocalVLANs, cfg.LocalVLANs} {
+			for i, ok := candidates.NextSet(0); ok && localVLANs.Count() < needed; i, ok = candidates.NextSet(i + 1) {
+				localVLANs.Set(i)
+			}
+		}
+		if localVLANs.Count() < needed {
+			return core.ExhaustedErrorf("not enough local vlans available for %d vxlans", needed)
+		}
+
+		r.VXLANs = cfg.VXLANs
+		r.LocalVLANs = localVLANs
+		err = r.Write()
+		if err != nil {
+			return err
+		}
+
+		oper.FreeVXLANs = cfg.VXLANs.Difference(inUse)
+		oper.FreeLocalVLANs = localVLANs.Difference(localInUse)
+		return oper.WriteIfUnchanged()
+	})
+}
+
 // Deinit the resource.
 func (r *AutoVXLANCfgResource) Deinit() {
 	oper := &AutoVXLANOperResource{}
@@ -129,27 +203,32 @@ func (r *AutoVXLANCfgResource) Description() string {
 
 // Allocate allocates a new resource.
 func (r *AutoVXLANCfgResource) Allocate() (interface{}, error) {
-	oper := &AutoVXLANOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return nil, err
-	}
+	var vxlan, vlan uint
 
-	vxlan, ok := oper.FreeVXLANs.NextSet(0)
-	if !ok {
-		return nil, core.Errorf("no vxlans available.")
-	}
+	err := core.RetryOnVersionConflict(func() error {
+		oper := &AutoVXLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
 
-	vlan, ok := oper.FreeLocalVLANs.NextSet(0)
-	if !ok {
-		return nil, core.Errorf("no local vlans available.")
-	}
+		var ok bool
+		vxlan, ok = oper.FreeVXLANs.NextSet(0)
+		if !ok {
+			return core.ExhaustedErrorf("no vxlans available.")
+		}
 
-	oper.FreeVXLANs.Clear(vxlan)
-	oper.FreeLocalVLANs.Clear(vlan)
+		vlan, ok = oper.FreeLocalVLANs.NextSet(0)
+		if !ok {
+			return core.ExhaustedErrorf("no local vlans available.")
+		}
 
-	err = oper.Write()
+		oper.FreeVXLANs.Clear(vxlan)
+		oper.FreeLocalVLANs.Clear(vlan)
+
+		return oper.WriteIfUnchanged()
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -158,27 +237,26 @@ func (r *AutoVXLANCfgResource) Allocate() (interface{}, error) {
 
 // Deallocate removes and cleans up a resource.
 func (r *AutoVXLANCfgResource) Deallocate(value interface{}) error {
-	oper := &AutoVXLANOperResource{}
-	oper.StateDriver = r.StateDriver
-	err := oper.Read(r.ID)
-	if err != nil {
-		return err
-	}
-
 	pair, ok := value.(VXLANVLANPair)
 	if !ok {
 		return core.Errorf("Invalid type for vxlan-vlan pair")
 	}
-	vxlan := pair.VXLAN
-	oper.FreeVXLANs.Set(vxlan)
-	vlan := pair.VLAN
-	oper.FreeLocalVLANs.Set(vlan)
 
-	err = oper.Write()
-	if err != nil {
-		return err
-	}
-	return nil
+	return core.RetryOnVersionConflict(func() error {
+		oper := &AutoVXLANOperResource{}
+		oper.StateDriver = r.StateDriver
+		err := oper.ReadForUpdate(r.ID)
+		if err != nil {
+			return err
+		}
+
+		vxlan := pair.VXLAN
+		oper.FreeVXLANs.Set(vxlan)
+		vlan := pair.VLAN
+		oper.FreeLocalVLANs.Set(vlan)
+
+		return oper.WriteIfUnchanged()
+	})
 }
 
 // AutoVXLANOperResource is an implementation of core.State
@@ -211,3 +289,24 @@ func (r *AutoVXLANOperResource) Clear() error {
 	key := fmt.Sprintf(vXLANResourceOperPath, r.ID)
 	return r.StateDriver.ClearState(key)
 }
+
+// ReadForUpdate resets and reads the state, and records its version for a
+// subsequent WriteIfUnchanged. Nothing an earlier attempt left is kept, as
+// unmarshalling merges maps and skips the fields missing from the value.
+func (r *AutoVXLANOperResource) ReadForUpdate(id string) error {
+	*r = AutoVXLANOperResource{CommonState: core.CommonState{StateDriver: r.StateDriver, ID: id}}
+	key := fmt.Sprintf(vXLANResourceOperPath, id)
+	version, err := r.StateDriver.ReadStateVersion(key, r, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	r.Version = version
+	return nil
+}
+
+// WriteIfUnchanged writes the state only if it has not been modified since
+// it was read by ReadForUpdate.
+func (r *AutoVXLANOperResource) WriteIfUnchanged() error {
+	key := fmt.Sprintf(vXLANResourceOperPath, r.ID)
+	return r.StateDriver.WriteStateIfVersion(key, r, json.Marshal, r.Version)
+}
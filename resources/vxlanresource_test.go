@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/state"
 	"github.com/jainvipin/bitset"
 
 	log "github.com/Sirupsen/logrus"
@@ -270,6 +271,7 @@ var vxlanRsrcValidationStateMap = map[string]*vxlanRsrcValidator{
 }
 
 type testVXLANRsrcStateDriver struct {
+	state.StubStateDriver
 }
 
 func (d *testVXLANRsrcStateDriver) Init(config *core.Config) error {
@@ -346,6 +348,16 @@ func (d *testVXLANRsrcStateDriver) WriteState(key string, value core.State,
 	return d.validate(key, value, vXLANResourceOpWrite)
 }
 
+func (d *testVXLANRsrcStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 1, d.ReadState(key, value, unmarshal)
+}
+
+func (d *testVXLANRsrcStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return d.WriteState(key, value, marshal)
+}
+
 func TestAutoVXLANCfgResourceInit(t *testing.T) {
 	rsrc := &AutoVXLANCfgResource{}
 	rsrc.StateDriver = vxlanRsrcStateDriver
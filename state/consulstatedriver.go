@@ -16,7 +16,11 @@ limitations under the License.
 package state
 
 import (
+	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/hashicorp/consul/api"
@@ -25,15 +29,38 @@ import (
 )
 
 // ConsulStateDriverConfig encapsulates the configuration parameters to
-// initialize consul client
+// initialize consul client. Consul is reached over https when TLS is set.
 type ConsulStateDriverConfig struct {
 	Consul api.Config
+	TLS    core.TLSConfig `json:"consul-tls"`
 }
 
 // ConsulStateDriver implements the StateDriver interface for a consul based distributed
 // key-value store used to store config and runtime state for the netplugin.
 type ConsulStateDriver struct {
 	Client *api.Client
+
+	// consul doesn't keep a history of changes, so the keys seen by a watch
+	// are kept to resume it from the revision it stopped at. They are kept
+	// in memory for watchSnapshotTTL at most, so a watch can only be
+	// resumed for a while within the lifetime of the process.
+	watchMutex     sync.Mutex
+	watchSnapshots map[string]*watchSnapshot
+}
+
+const (
+	// watchSnapshotTTL is how long the keys of a list or a stopped watch are
+	// kept for a watch to be resumed from
+	watchSnapshotTTL = 5 * time.Minute
+	// maxWatchSnapshots is the most snapshots kept, the oldest ones being
+	// released first
+	maxWatchSnapshots = 64
+)
+
+// watchSnapshot is the keys under a base key as of a revision
+type watchSnapshot struct {
+	kvCache map[string]*api.KVPair
+	saved   time.Time
 }
 
 // Init the driver with a core.Config.
@@ -50,6 +77,18 @@ func (d *ConsulStateDriver) Init(config *core.Config) error {
 		return core.Errorf("Invalid config type passed!")
 	}
 
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := cfg.TLS.ClientTLSConfig()
+		if err != nil {
+			return err
+		}
+
+		cfg.Consul.Scheme = "https"
+		cfg.Consul.HttpClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		}
+	}
+
 	d.Client, err = api.NewClient(&cfg.Consul)
 	if err != nil {
 		return err
@@ -85,7 +124,7 @@ func (d *ConsulStateDriver) Read(key string) ([]byte, error) {
 	// Consul returns success and a nil kv when a key is not found,
 	// translate it to 'Key not found' error
 	if kv == nil {
-		return []byte{}, core.Errorf("Key not found")
+		return []byte{}, core.NotFoundErrorf("Key not found")
 	}
 
 	return kv.Value, err
@@ -101,7 +140,7 @@ func (d *ConsulStateDriver) ReadAll(baseKey string) ([][]byte, error) {
 	// Consul returns success and a nil kv when a key is not found,
 	// translate it to 'Key not found' error
 	if kvs == nil {
-		return nil, core.Errorf("Key not found")
+		return nil, core.NotFoundErrorf("Key not found")
 	}
 
 	values := [][]byte{}
@@ -112,49 +151,47 @@ func (d *ConsulStateDriver) ReadAll(baseKey string) ([][]byte, error) {
 	return values, nil
 }
 
+// ReadVersion reads the value at key along with its modify index.
+func (d *ConsulStateDriver) ReadVersion(key string) ([]byte, uint64, error) {
+	key = processKey(key)
+	kv, _, err := d.Client.KV().Get(key, nil)
+	if err != nil {
+		return []byte{}, 0, err
+	}
+	// Consul returns success and a nil kv when a key is not found,
+	// translate it to 'Key not found' error
+	if kv == nil {
+		return []byte{}, 0, core.NotFoundErrorf("Key not found")
+	}
+
+	return kv.Value, kv.ModifyIndex, nil
+}
+
+// WriteIfVersion writes value to key if its modify index matches version.
+// Consul treats a zero index as a create-only write.
+func (d *ConsulStateDriver) WriteIfVersion(key string, value []byte, version uint64) error {
+	key = processKey(key)
+	ok, _, err := d.Client.KV().CAS(&api.KVPair{Key: key, Value: value,
+		ModifyIndex: version}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return core.ErrVersionConflict(key)
+	}
+
+	return nil
+}
+
 func (d *ConsulStateDriver) channelConsulEvents(baseKey string, kvCache map[string]*api.KVPair,
 	consulRsps chan api.KVPairs, rsps chan [2][]byte, retErr chan error, stop chan bool) {
 	for {
 		select {
 		// block on change notifications
 		case kvs := <-consulRsps:
-			kvsRcvd := map[string]*api.KVPair{}
-			// Generate Create/Modifiy events for the keys recvd
-			for _, kv := range kvs {
-				// XXX: The logic below assumes that the node returned is always a node
-				// of interest. Eg: If we set a watch on /a/b/c, then we are mostly
-				// interested in changes in that directory i.e. changes to /a/b/c/d1..d2
-				// This works for now as the constructs like network and endpoints that
-				// need to be watched are organized as above. Need to revisit when
-				// this assumption changes.
-				kvsRcvd[kv.Key] = kv
-				rsp := [2][]byte{nil, nil}
-				rsp[0] = kv.Value
-				if kvSeen, ok := kvCache[kv.Key]; !ok {
-					log.Infof("Received create for key: %q, kv: %+v", kv.Key, kv)
-				} else if kvSeen.ModifyIndex != kv.ModifyIndex {
-					log.Infof("Received modify for key: %q, kv: %+v", kv.Key, kv)
-					rsp[1] = kvSeen.Value
-				} else {
-					// no changes to the key, skipping
-					log.Debugf("Skipping key with no changes: %s", kv.Key)
-					continue
-				}
-				//update the map of seen keys
-				kvCache[kv.Key] = kv
-
+			for _, change := range diffConsulKVs(kvCache, kvs) {
 				//channel the translated response
-				rsps <- rsp
-			}
-
-			// Generate Delete events for missing keys
-			for key, kv := range kvCache {
-				if _, ok := kvsRcvd[key]; !ok {
-					log.Infof("Received delete for key: %q, Pair: %+v", kv.Key, kv)
-					rsps <- [2][]byte{nil, kv.Value}
-					// remove this key from the map of seen keys
-					delete(kvCache, key)
-				}
+				rsps <- change.values
 			}
 
 		case <-stop:
@@ -164,6 +201,57 @@ func (d *ConsulStateDriver) channelConsulEvents(baseKey string, kvCache map[stri
 	}
 }
 
+// consulKVChange is a change of the value of a key, with its current and
+// previous values
+type consulKVChange struct {
+	key    string
+	values [2][]byte
+}
+
+// diffConsulKVs returns the create, modify and delete events that turn the
+// keys in kvCache into kvs, and updates kvCache accordingly.
+func diffConsulKVs(kvCache map[string]*api.KVPair, kvs api.KVPairs) []consulKVChange {
+	rsps := []consulKVChange{}
+	kvsRcvd := map[string]*api.KVPair{}
+	// Generate Create/Modifiy events for the keys recvd
+	for _, kv := range kvs {
+		// XXX: The logic below assumes that the node returned is always a node
+		// of interest. Eg: If we set a watch on /a/b/c, then we are mostly
+		// interested in changes in that directory i.e. changes to /a/b/c/d1..d2
+		// This works for now as the constructs like network and endpoints that
+		// need to be watched are organized as above. Need to revisit when
+		// this assumption changes.
+		kvsRcvd[kv.Key] = kv
+		rsp := [2][]byte{nil, nil}
+		rsp[0] = kv.Value
+		if kvSeen, ok := kvCache[kv.Key]; !ok {
+			log.Infof("Received create for key: %q, kv: %+v", kv.Key, kv)
+		} else if kvSeen.ModifyIndex != kv.ModifyIndex {
+			log.Infof("Received modify for key: %q, kv: %+v", kv.Key, kv)
+			rsp[1] = kvSeen.Value
+		} else {
+			// no changes to the key, skipping
+			log.Debugf("Skipping key with no changes: %s", kv.Key)
+			continue
+		}
+		//update the map of seen keys
+		kvCache[kv.Key] = kv
+		rsps = append(rsps, consulKVChange{key: kv.Key, values: rsp})
+	}
+
+	// Generate Delete events for missing keys
+	for key, kv := range kvCache {
+		if _, ok := kvsRcvd[key]; !ok {
+			log.Infof("Received delete for key: %q, Pair: %+v", kv.Key, kv)
+			rsps = append(rsps, consulKVChange{key: kv.Key, values: [2][]byte{nil, kv.Value}})
+			// remove this key from the map of seen keys
+			delete(kvCache, key)
+		}
+	}
+
+	return rsps
+}
+
 // WatchAll state transitions from baseKey
 func (d *ConsulStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
 	baseKey = processKey(baseKey)
@@ -227,6 +315,26 @@ func (d *ConsulStateDriver) ClearState(key string) error {
 	return err
 }
 
+// ClearIfVersion removes key if its modify index matches version.
+func (d *ConsulStateDriver) ClearIfVersion(key string, version uint64) error {
+	// a key that exists never has a zero index
+	if version == 0 {
+		return core.ErrVersionConflict(key)
+	}
+
+	key = processKey(key)
+	ok, _, err := d.Client.KV().DeleteCAS(&api.KVPair{Key: key,
+		ModifyIndex: version}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return core.ErrVersionConflict(key)
+	}
+
+	return nil
+}
+
 // ReadState reads key into a core.State with the unmarshalling function.
 func (d *ConsulStateDriver) ReadState(key string, value core.State,
 	unmarshal func([]byte, interface{}) error) error {
@@ -270,6 +378,128 @@ func (d *ConsulStateDriver) WatchAllState(baseKey string, sType core.State,
 
 }
 
+// saveWatchSnapshot records the keys under baseKey as of revision, and
+// releases the snapshots that expired or are in excess.
+func (d *ConsulStateDriver) saveWatchSnapshot(baseKey string, revision uint64,
+	kvCache map[string]*api.KVPair) {
+	d.watchMutex.Lock()
+	defer d.watchMutex.Unlock()
+
+	if d.watchSnapshots == nil {
+		d.watchSnapshots = make(map[string]*watchSnapshot)
+	}
+	now := time.Now()
+	d.watchSnapshots[fmt.Sprintf("%s@%d", baseKey, revision)] = &watchSnapshot{
+		kvCache: kvCache,
+		saved:   now,
+	}
+
+	d.pruneWatchSnapshots(now)
+}
+
+// pruneWatchSnapshots releases the snapshots saved more than
+// watchSnapshotTTL before now, and the oldest ones beyond
+// maxWatchSnapshots. Must be called with watchMutex held.
+func (d *ConsulStateDriver) pruneWatchSnapshots(now time.Time) {
+	for snapshotKey, snapshot := range d.watchSnapshots {
+		if now.Sub(snapshot.saved) > watchSnapshotTTL {
+			delete(d.watchSnapshots, snapshotKey)
+		}
+	}
+
+	for len(d.watchSnapshots) > maxWatchSnapshots {
+		oldestKey := ""
+		for snapshotKey, snapshot := range d.watchSnapshots {
+			if oldestKey == "" ||
+				snapshot.saved.Before(d.watchSnapshots[oldestKey].saved) {
+				oldestKey = snapshotKey
+			}
+		}
+		delete(d.watchSnapshots, oldestKey)
+	}
+}
+
+// takeWatchSnapshot returns and forgets the keys under baseKey as of
+// revision, if they were recorded.
+func (d *ConsulStateDriver) takeWatchSnapshot(baseKey string,
+	revision uint64) (map[string]*api.KVPair, bool) {
+	d.watchMutex.Lock()
+	defer d.watchMutex.Unlock()
+
+	d.pruneWatchSnapshots(time.Now())
+
+	snapshotKey := fmt.Sprintf("%s@%d", baseKey, revision)
+	snapshot, ok := d.watchSnapshots[snapshotKey]
+	if !ok {
+		return nil, false
+	}
+	delete(d.watchSnapshots, snapshotKey)
+	return snapshot.kvCache, true
+}
+
+// ListAllState reads all state from baseKey along with the consul index it
+// was read at.
+func (d *ConsulStateDriver) ListAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, uint64, error) {
+	baseKey = processKey(baseKey)
+	kvs, qm, err := d.Client.KV().List(baseKey, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byteValues := [][]byte{}
+	kvCache := map[string]*api.KVPair{}
+	for _, kv := range kvs {
+		byteValues = append(byteValues, kv.Value)
+		kvCache[kv.Key] = kv
+	}
+
+	states, err := unmarshalAllState(d, byteValues, sType, unmarshal)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	d.saveWatchSnapshot(baseKey, qm.LastIndex, kvCache)
+	return states, qm.LastIndex, nil
+}
+
+// WatchAllStateFrom watches all state from the baseKey, starting after the
+// consul index passed as revision. Only revisions returned by ListAllState
+// or by a watch of this driver, in the same process and no longer than
+// watchSnapshotTTL ago, can be resumed from. Others fail with an error
+// satisfying core.IsRevisionCompacted, and the state shall be listed again.
+func (d *ConsulStateDriver) WatchAllStateFrom(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error, revision uint64,
+	rsps chan core.WatchState) error {
+	baseKey = processKey(baseKey)
+	kvCache, ok := d.takeWatchSnapshot(baseKey, revision)
+	if !ok {
+		return core.ErrRevisionCompacted(revision)
+	}
+
+	for {
+		kvs, qm, err := d.Client.KV().List(baseKey, &api.QueryOptions{WaitIndex: revision})
+		if err != nil {
+			log.Errorf("consul watch failed for key %q. Error: %s", baseKey, err)
+			// all changes up to revision were delivered, allow resuming
+			d.saveWatchSnapshot(baseKey, revision, kvCache)
+			return err
+		}
+
+		revision = qm.LastIndex
+		for _, change := range diffConsulKVs(kvCache, kvs) {
+			rsp, err := newWatchState(d, sType, unmarshal, change.values, revision)
+			if err != nil {
+				return err
+			}
+			// keys are stored without their leading slash
+			rsp.Key = "/" + change.key
+
+			rsps <- rsp
+		}
+	}
+}
+
 // WriteState writes a value of core.State into a key with a given marshalling function.
 func (d *ConsulStateDriver) WriteState(key string, value core.State,
 	marshal func(interface{}) ([]byte, error)) error {
@@ -286,3 +516,36 @@ func (d *ConsulStateDriver) WriteState(key string, value core.State,
 
 	return nil
 }
+
+// ReadStateVersion reads key into a core.State and returns its version.
+func (d *ConsulStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	encodedState, version, err := d.ReadVersion(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = unmarshal(encodedState, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// WriteStateIfVersion writes a core.State into a key if the stored version
+// matches.
+func (d *ConsulStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	encodedState, err := marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return d.WriteIfVersion(key, encodedState, version)
+}
+
+// NewTxn starts a transaction on the state store.
+func (d *ConsulStateDriver) NewTxn() (core.Txn, error) {
+	return newStateTxn(d), nil
+}
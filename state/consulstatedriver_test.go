@@ -16,7 +16,9 @@ limitations under the License.
 package state
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/contiv/netplugin/core"
 	"github.com/hashicorp/consul/api"
@@ -101,3 +103,33 @@ func TestConsulStateDriverWatchAllStateDelete(t *testing.T) {
 	driver := setupConsulDriver(t)
 	commonTestStateDriverWatchAllStateDelete(t, driver)
 }
+
+func TestConsulStateDriverWatchSnapshotRelease(t *testing.T) {
+	driver := &ConsulStateDriver{}
+	kvCache := map[string]*api.KVPair{"a": {Key: "a"}}
+
+	driver.saveWatchSnapshot("base", 1, kvCache)
+	driver.watchSnapshots["base@1"].saved = time.Now().Add(-2 * watchSnapshotTTL)
+	if _, ok := driver.takeWatchSnapshot("base", 1); ok {
+		t.Fatalf("expired snapshot was resumed from")
+	}
+
+	for i := 0; i <= maxWatchSnapshots; i++ {
+		driver.saveWatchSnapshot("base", uint64(i+2), kvCache)
+		driver.watchSnapshots[fmt.Sprintf("base@%d", i+2)].saved =
+			time.Now().Add(time.Duration(i) * time.Millisecond)
+	}
+	if len(driver.watchSnapshots) > maxWatchSnapshots {
+		t.Fatalf("%d snapshots kept, expected at most %d",
+			len(driver.watchSnapshots), maxWatchSnapshots)
+	}
+	if _, ok := driver.takeWatchSnapshot("base", 2); ok {
+		t.Fatalf("oldest snapshot was not released")
+	}
+	if cache, ok := driver.takeWatchSnapshot("base", maxWatchSnapshots+2); !ok || cache["a"] == nil {
+		t.Fatalf("latest snapshot was released")
+	}
+	if _, ok := driver.takeWatchSnapshot("base", maxWatchSnapshots+2); ok {
+		t.Fatalf("snapshot was resumed from twice")
+	}
+}
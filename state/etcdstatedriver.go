@@ -16,7 +16,10 @@ limitations under the License.
 package state
 
 import (
+	"net"
+	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/contiv/go-etcd/etcd"
 	"github.com/contiv/netplugin/core"
@@ -26,13 +29,26 @@ import (
 
 const (
 	recursive = true
+
+	// etcd error codes returned for failed conditional writes
+	etcdErrKeyNotFound = 100
+	etcdErrTestFailed  = 101
+	etcdErrNodeExists  = 105
+
+	// etcd error code returned when watching from an index that is no
+	// longer in its event history
+	etcdErrEventIndexCleared = 401
+
+	// etcd client error code returned when none of the machines respond
+	etcdErrNotReachable = 501
 )
 
 // EtcdStateDriverConfig encapsulates the etcd endpoints used to communicate
-// with it.
+// with it. Machines are https urls when TLS is set.
 type EtcdStateDriverConfig struct {
 	Etcd struct {
 		Machines []string
+		TLS      core.TLSConfig
 	}
 }
 
@@ -56,6 +72,17 @@ func (d *EtcdStateDriver) Init(config *core.Config) error {
 
 	d.Client = etcd.NewClient(cfg.Etcd.Machines)
 
+	if cfg.Etcd.TLS.Enabled() {
+		tlsCfg, err := cfg.Etcd.TLS.ClientTLSConfig()
+		if err != nil {
+			return err
+		}
+
+		// keep the dial timeout and keepalive of the default transport
+		dialer := &net.Dialer{Timeout: time.Second, KeepAlive: time.Second}
+		d.Client.SetTransport(&http.Transport{TLSClientConfig: tlsCfg, Dial: dialer.Dial})
+	}
+
 	// Set strong consistency
 	d.Client.SetConsistency(etcd.STRONG_CONSISTENCY)
 
@@ -69,14 +96,14 @@ func (d *EtcdStateDriver) Deinit() {}
 func (d *EtcdStateDriver) Write(key string, value []byte) error {
 	_, err := d.Client.Set(key, string(value[:]), 0)
 
-	return err
+	return etcdError(err)
 }
 
 // Read state from key.
 func (d *EtcdStateDriver) Read(key string) ([]byte, error) {
 	resp, err := d.Client.Get(key, false, false)
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, etcdError(err)
 	}
 
 	return []byte(resp.Node.Value), err
@@ -86,7 +113,7 @@ func (d *EtcdStateDriver) Read(key string) ([]byte, error) {
 func (d *EtcdStateDriver) ReadAll(baseKey string) ([][]byte, error) {
 	resp, err := d.Client.Get(baseKey, true, false)
 	if err != nil {
-		return nil, err
+		return nil, etcdError(err)
 	}
 
 	values := [][]byte{}
@@ -97,6 +124,63 @@ func (d *EtcdStateDriver) ReadAll(baseKey string) ([][]byte, error) {
 	return values, nil
 }
 
+// ReadVersion reads the value at key along with its modified index.
+func (d *EtcdStateDriver) ReadVersion(key string) ([]byte, uint64, error) {
+	resp, err := d.Client.Get(key, false, false)
+	if err != nil {
+		return []byte{}, 0, etcdError(err)
+	}
+
+	return []byte(resp.Node.Value), resp.Node.ModifiedIndex, nil
+}
+
+// WriteIfVersion writes value to key if its modified index matches version.
+func (d *EtcdStateDriver) WriteIfVersion(key string, value []byte, version uint64) error {
+	var err error
+
+	if version == 0 {
+		_, err = d.Client.Create(key, string(value[:]), 0)
+	} else {
+		_, err = d.Client.CompareAndSwap(key, string(value[:]), 0, "", version)
+	}
+
+	if isEtcdConflict(err) {
+		return core.ErrVersionConflict(key)
+	}
+
+	return etcdError(err)
+}
+
+// etcdErrorCode returns the code and etcd index of an etcd error, or zeros
+// for other errors
+func etcdErrorCode(err error) (int, uint64) {
+	switch etcdErr := err.(type) {
+	case *etcd.EtcdError:
+		return etcdErr.ErrorCode, etcdErr.Index
+	case etcd.EtcdError:
+		return etcdErr.ErrorCode, etcdErr.Index
+	default:
+		return 0, 0
+	}
+}
+
+// etcdError returns err as an error of kind core.KindUnavailable if etcd
+// could not be reached
+func etcdError(err error) error {
+	if code, _ := etcdErrorCode(err); code == etcdErrNotReachable {
+		return core.UnavailableErrorf("etcd is not reachable. Error: %s", err)
+	}
+
+	return err
+}
+
+// isEtcdConflict checks if an etcd error is due to a failed precondition
+func isEtcdConflict(err error) bool {
+	code, _ := etcdErrorCode(err)
+	return code == etcdErrKeyNotFound || code == etcdErrTestFailed ||
+		code == etcdErrNodeExists
+}
+
 func (d *EtcdStateDriver) channelEtcdEvents(etcdRsps chan *etcd.Response,
 	rsps chan [2][]byte) {
 	for {
@@ -150,7 +234,17 @@ func (d *EtcdStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
 // ClearState removes key from etcd.
 func (d *EtcdStateDriver) ClearState(key string) error {
 	_, err := d.Client.Delete(key, false)
-	return err
+	return etcdError(err)
+}
+
+// ClearIfVersion removes key if its modified index matches version.
+func (d *EtcdStateDriver) ClearIfVersion(key string, version uint64) error {
+	_, err := d.Client.CompareAndDelete(key, "", version)
+	if isEtcdConflict(err) {
+		return core.ErrVersionConflict(key)
+	}
+
+	return etcdError(err)
 }
 
 // ReadState reads key into a core.State with the unmarshalling function.
@@ -174,17 +268,25 @@ func (d *EtcdStateDriver) ReadState(key string, value core.State,
 // XXX: move this to some common file
 func readAllStateCommon(d core.StateDriver, baseKey string, sType core.State,
 	unmarshal func([]byte, interface{}) error) ([]core.State, error) {
-	stateType := reflect.TypeOf(sType)
-	sliceType := reflect.SliceOf(stateType)
-	values := reflect.MakeSlice(sliceType, 0, 1)
-
 	byteValues, err := d.ReadAll(baseKey)
 	if err != nil {
 		return nil, err
 	}
+
+	return unmarshalAllState(d, byteValues, sType, unmarshal)
+}
+
+// unmarshalAllState unmarshals (given a function) the values into a list of
+// core.State objects bound to the driver.
+func unmarshalAllState(d core.StateDriver, byteValues [][]byte, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, error) {
+	stateType := reflect.TypeOf(sType)
+	sliceType := reflect.SliceOf(stateType)
+	values := reflect.MakeSlice(sliceType, 0, 1)
+
 	for _, byteValue := range byteValues {
 		value := reflect.New(stateType)
-		err = unmarshal(byteValue, value.Interface())
+		err := unmarshal(byteValue, value.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -223,31 +325,10 @@ func channelStateEvents(d core.StateDriver, sType core.State,
 		// block on change notifications
 		byteRsp := <-byteRsps
 
-		rsp := core.WatchState{Curr: nil, Prev: nil}
-		for i := 0; i < 2; i++ {
-			if byteRsp[i] == nil {
-				continue
-			}
-			stateType := reflect.TypeOf(sType)
-			value := reflect.New(stateType)
-			err := unmarshal(byteRsp[i], value.Interface())
-			if err != nil {
-				retErr <- err
-				return
-			}
-			if !value.Elem().Elem().FieldByName("CommonState").IsValid() {
-				retErr <- core.Errorf("The state structure %v is missing core.CommonState",
-					stateType)
-				return
-			}
-			//the following works as every core.State is expected to embed core.CommonState struct
-			value.Elem().Elem().FieldByName("CommonState").FieldByName("StateDriver").Set(reflect.ValueOf(d))
-			switch i {
-			case 0:
-				rsp.Curr = value.Elem().Interface().(core.State)
-			case 1:
-				rsp.Prev = value.Elem().Interface().(core.State)
-			}
+		rsp, err := newWatchState(d, sType, unmarshal, byteRsp, 0)
+		if err != nil {
+			retErr <- err
+			return
 		}
 
 		//channel the translated response
@@ -255,6 +336,39 @@ func channelStateEvents(d core.StateDriver, sType core.State,
 	}
 }
 
+// newWatchState unmarshals (given a function) the current and previous value
+// of a change into a core.WatchState.
+func newWatchState(d core.StateDriver, sType core.State,
+	unmarshal func([]byte, interface{}) error, byteRsp [2][]byte,
+	revision uint64) (core.WatchState, error) {
+	rsp := core.WatchState{Curr: nil, Prev: nil, Revision: revision}
+	for i := 0; i < 2; i++ {
+		if byteRsp[i] == nil {
+			continue
+		}
+		stateType := reflect.TypeOf(sType)
+		value := reflect.New(stateType)
+		err := unmarshal(byteRsp[i], value.Interface())
+		if err != nil {
+			return rsp, err
+		}
+		if !value.Elem().Elem().FieldByName("CommonState").IsValid() {
+			return rsp, core.Errorf("The state structure %v is missing core.CommonState",
+				stateType)
+		}
+		//the following works as every core.State is expected to embed core.CommonState struct
+		value.Elem().Elem().FieldByName("CommonState").FieldByName("StateDriver").Set(reflect.ValueOf(d))
+		switch i {
+		case 0:
+			rsp.Curr = value.Elem().Interface().(core.State)
+		case 1:
+			rsp.Prev = value.Elem().Interface().(core.State)
+		}
+	}
+
+	return rsp, nil
+}
+
 // WatchAllState watches all state from the baseKey.
 func (d *EtcdStateDriver) WatchAllState(baseKey string, sType core.State,
 	unmarshal func([]byte, interface{}) error, rsps chan core.WatchState) error {
@@ -273,6 +387,68 @@ func (d *EtcdStateDriver) WatchAllState(baseKey string, sType core.State,
 
 }
 
+// ListAllState reads all state from baseKey along with the etcd index it
+// was read at.
+func (d *EtcdStateDriver) ListAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, uint64, error) {
+	byteValues := [][]byte{}
+	var revision uint64
+
+	resp, err := d.Client.Get(baseKey, true, false)
+	if err == nil {
+		for _, node := range resp.Node.Nodes {
+			byteValues = append(byteValues, []byte(node.Value))
+		}
+		revision = resp.EtcdIndex
+	} else if code, index := etcdErrorCode(err); code == etcdErrKeyNotFound {
+		// no state yet, watch from the index the lookup was made at
+		revision = index
+	} else {
+		return nil, 0, etcdError(err)
+	}
+
+	states, err := unmarshalAllState(d, byteValues, sType, unmarshal)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return states, revision, nil
+}
+
+// WatchAllStateFrom watches all state from the baseKey, starting after the
+// etcd index passed as revision.
+func (d *EtcdStateDriver) WatchAllStateFrom(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error, revision uint64,
+	rsps chan core.WatchState) error {
+	for {
+		etcdRsp, err := d.Client.Watch(baseKey, revision+1, recursive, nil, nil)
+		if err != nil {
+			if code, _ := etcdErrorCode(err); code == etcdErrEventIndexCleared {
+				return core.ErrRevisionCompacted(revision)
+			}
+			log.Errorf("etcd watch failed. Error: %s", err)
+			return err
+		}
+
+		byteRsp := [2][]byte{nil, nil}
+		if etcdRsp.Node.Value != "" {
+			byteRsp[0] = []byte(etcdRsp.Node.Value)
+		}
+		if etcdRsp.PrevNode != nil && etcdRsp.PrevNode.Value != "" {
+			byteRsp[1] = []byte(etcdRsp.PrevNode.Value)
+		}
+
+		revision = etcdRsp.Node.ModifiedIndex
+		rsp, err := newWatchState(d, sType, unmarshal, byteRsp, revision)
+		if err != nil {
+			return err
+		}
+		rsp.Key = etcdRsp.Node.Key
+
+		rsps <- rsp
+	}
+}
+
 // WriteState writes a value of core.State into a key with a given marshalling function.
 func (d *EtcdStateDriver) WriteState(key string, value core.State,
 	marshal func(interface{}) ([]byte, error)) error {
@@ -288,3 +464,36 @@ func (d *EtcdStateDriver) WriteState(key string, value core.State,
 
 	return nil
 }
+
+// ReadStateVersion reads key into a core.State and returns its version.
+func (d *EtcdStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	encodedState, version, err := d.ReadVersion(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = unmarshal(encodedState, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// WriteStateIfVersion writes a core.State into a key if the stored version
+// matches.
+func (d *EtcdStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	encodedState, err := marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return d.WriteIfVersion(key, encodedState, version)
+}
+
+// NewTxn starts a transaction on the state store.
+func (d *EtcdStateDriver) NewTxn() (core.Txn, error) {
+	return newStateTxn(d), nil
+}
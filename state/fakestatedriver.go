@@ -2,6 +2,7 @@ package state
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/contiv/netplugin/core"
 
@@ -9,7 +10,16 @@ import (
 )
 
 type valueData struct {
-	value []byte
+	value   []byte
+	version uint64
+}
+
+// fakeWatchEvent is a change made to a key, kept to serve watches.
+type fakeWatchEvent struct {
+	key      string
+	value    []byte
+	prev     []byte
+	revision uint64
 }
 
 // FakeStateDriverConfig represents the configuration of the fake statedriver,
@@ -20,6 +30,11 @@ type FakeStateDriverConfig struct{}
 // unit-tests
 type FakeStateDriver struct {
 	TestState map[string]valueData
+
+	mutex        sync.Mutex
+	lastRevision uint64
+	events       []fakeWatchEvent
+	eventCond    *sync.Cond
 }
 
 // Init the driver
@@ -31,28 +46,92 @@ func (d *FakeStateDriver) Init(config *core.Config) error {
 
 // Deinit the driver
 func (d *FakeStateDriver) Deinit() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	d.TestState = nil
+	d.watchCond().Broadcast()
+}
+
+// watchCond returns the condition signalled on every change. Must be called
+// with the mutex held.
+func (d *FakeStateDriver) watchCond() *sync.Cond {
+	if d.eventCond == nil {
+		d.eventCond = sync.NewCond(&d.mutex)
+	}
+	return d.eventCond
+}
+
+// write stores value at key with a new version. Must be called with the
+// mutex held.
+func (d *FakeStateDriver) write(key string, value []byte) {
+	prev := d.TestState[key].value
+
+	d.lastRevision++
+	d.TestState[key] = valueData{value: value, version: d.lastRevision}
+	d.recordEvent(key, value, prev)
+}
+
+// recordEvent appends a change to the history served to watches. Must be
+// called with the mutex held.
+func (d *FakeStateDriver) recordEvent(key string, value, prev []byte) {
+	d.events = append(d.events, fakeWatchEvent{
+		key:      key,
+		value:    value,
+		prev:     prev,
+		revision: d.lastRevision,
+	})
+	d.watchCond().Broadcast()
 }
 
 // Write value to key
 func (d *FakeStateDriver) Write(key string, value []byte) error {
-	val := valueData{value: value}
-	d.TestState[key] = val
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.write(key, value)
 
 	return nil
 }
 
 // Read value from key
 func (d *FakeStateDriver) Read(key string) ([]byte, error) {
+	value, _, err := d.ReadVersion(key)
+	return value, err
+}
+
+// ReadVersion reads the value and its version from key
+func (d *FakeStateDriver) ReadVersion(key string) ([]byte, uint64, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	if val, ok := d.TestState[key]; ok {
-		return val.value, nil
+		return val.value, val.version, nil
 	}
 
-	return []byte{}, core.Errorf("Key not found! key: %v", key)
+	return []byte{}, 0, core.NotFoundErrorf("Key not found! key: %v", key)
+}
+
+// WriteIfVersion writes value to key if the stored version matches
+func (d *FakeStateDriver) WriteIfVersion(key string, value []byte, version uint64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// a zero version matches a key that doesn't exist
+	if d.TestState[key].version != version {
+		return core.ErrVersionConflict(key)
+	}
+
+	d.write(key, value)
+
+	return nil
 }
 
 // ReadAll values from baseKey
 func (d *FakeStateDriver) ReadAll(baseKey string) ([][]byte, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	values := [][]byte{}
 
 	for key, val := range d.TestState {
@@ -70,9 +149,31 @@ func (d *FakeStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
 
 // ClearState clears key
 func (d *FakeStateDriver) ClearState(key string) error {
-	if _, ok := d.TestState[key]; ok {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if val, ok := d.TestState[key]; ok {
 		delete(d.TestState, key)
+		d.lastRevision++
+		d.recordEvent(key, nil, val.value)
+	}
+	return nil
+}
+
+// ClearIfVersion clears key if the stored version matches
+func (d *FakeStateDriver) ClearIfVersion(key string, version uint64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	val, ok := d.TestState[key]
+	if !ok || val.version != version {
+		return core.ErrVersionConflict(key)
 	}
+
+	delete(d.TestState, key)
+	d.lastRevision++
+	d.recordEvent(key, nil, val.value)
+
 	return nil
 }
 
@@ -104,6 +205,65 @@ func (d *FakeStateDriver) WatchAllState(baseKey string, sType core.State,
 	return core.Errorf("not supported")
 }
 
+// ListAllState reads all state from baseKey of a given type along with the
+// revision it was read at
+func (d *FakeStateDriver) ListAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, uint64, error) {
+	d.mutex.Lock()
+	values := [][]byte{}
+	for key, val := range d.TestState {
+		if strings.Contains(key, baseKey) {
+			values = append(values, val.value)
+		}
+	}
+	revision := d.lastRevision
+	d.mutex.Unlock()
+
+	states, err := unmarshalAllState(d, values, sType, unmarshal)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return states, revision, nil
+}
+
+// WatchAllStateFrom watches all state from baseKey of a given type, starting
+// after revision. It returns once the driver is deinitialized.
+func (d *FakeStateDriver) WatchAllStateFrom(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error, revision uint64,
+	rsps chan core.WatchState) error {
+	next := 0
+	for {
+		d.mutex.Lock()
+		for next < len(d.events) && d.events[next].revision <= revision {
+			next++
+		}
+		for next == len(d.events) && d.TestState != nil {
+			d.watchCond().Wait()
+		}
+		if d.TestState == nil {
+			d.mutex.Unlock()
+			return core.Errorf("state driver deinitialized")
+		}
+		event := d.events[next]
+		d.mutex.Unlock()
+
+		revision = event.revision
+		if !strings.Contains(event.key, baseKey) {
+			continue
+		}
+
+		rsp, err := newWatchState(d, sType, unmarshal,
+			[2][]byte{event.value, event.prev}, event.revision)
+		if err != nil {
+			return err
+		}
+		rsp.Key = event.key
+
+		rsps <- rsp
+	}
+}
+
 // WriteState writes a core.State to key.
 func (d *FakeStateDriver) WriteState(key string, value core.State,
 	marshal func(interface{}) ([]byte, error)) error {
@@ -120,6 +280,38 @@ func (d *FakeStateDriver) WriteState(key string, value core.State,
 	return nil
 }
 
+// ReadStateVersion unmarshals state into a core.State and returns its version
+func (d *FakeStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	encodedState, version, err := d.ReadVersion(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = unmarshal(encodedState, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// WriteStateIfVersion writes a core.State to key if the stored version matches
+func (d *FakeStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	encodedState, err := marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return d.WriteIfVersion(key, encodedState, version)
+}
+
+// NewTxn starts a transaction on the state store.
+func (d *FakeStateDriver) NewTxn() (core.Txn, error) {
+	return newStateTxn(d), nil
+}
+
 // DumpState is a debugging tool.
 func (d *FakeStateDriver) DumpState() {
 	for key := range d.TestState {
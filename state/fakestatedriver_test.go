@@ -0,0 +1,115 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/contiv/netplugin/core"
+)
+
+func writeTestState(t *testing.T, d core.StateDriver, key string, value int) {
+	state := &testState{IntField: value, StrField: key}
+	if err := d.WriteState(key, state, json.Marshal); err != nil {
+		t.Fatalf("failed to write %s. Error: %s", key, err)
+	}
+}
+
+// watchEvents watches baseKey from revision until count events are received.
+func watchEvents(t *testing.T, d *FakeStateDriver, baseKey string, revision uint64,
+	count int) []core.WatchState {
+	rsps := make(chan core.WatchState)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- d.WatchAllStateFrom(baseKey, &testState{}, json.Unmarshal,
+			revision, rsps)
+	}()
+
+	events := []core.WatchState{}
+	for len(events) < count {
+		select {
+		case rsp := <-rsps:
+			events = append(events, rsp)
+		case err := <-watchErr:
+			t.Fatalf("watch failed. Error: %s", err)
+		}
+	}
+
+	return events
+}
+
+func TestFakeStateDriverListWatch(t *testing.T) {
+	d := setupTxnDriver(t)
+	defer d.Deinit()
+
+	writeTestState(t, d, "/watch/a", 1)
+	writeTestState(t, d, "/watch/b", 1)
+	writeTestState(t, d, "/other/a", 1)
+
+	states, revision, err := d.ListAllState("/watch/", &testState{}, json.Unmarshal)
+	if err != nil {
+		t.Fatalf("failed to list state. Error: %s", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("listed %d states, expected 2", len(states))
+	}
+
+	// changes made after the listing, including ones to other keys
+	writeTestState(t, d, "/watch/a", 2)
+	writeTestState(t, d, "/other/a", 2)
+	if err := d.ClearState("/watch/b"); err != nil {
+		t.Fatalf("failed to clear state. Error: %s", err)
+	}
+	writeTestState(t, d, "/watch/c", 1)
+
+	events := watchEvents(t, d, "/watch/", revision, 3)
+	modify := events[0]
+	if modify.Curr.(*testState).IntField != 2 || modify.Prev.(*testState).IntField != 1 {
+		t.Fatalf("unexpected modify event %+v", modify)
+	}
+	if events[1].Curr != nil || events[1].Prev.(*testState).StrField != "/watch/b" {
+		t.Fatalf("unexpected delete event %+v", events[1])
+	}
+	if events[2].Prev != nil || events[2].Curr.(*testState).StrField != "/watch/c" {
+		t.Fatalf("unexpected create event %+v", events[2])
+	}
+	if modify.Revision <= revision || events[1].Revision <= modify.Revision {
+		t.Fatalf("event revisions %d, %d don't follow listing revision %d",
+			modify.Revision, events[1].Revision, revision)
+	}
+
+	// resuming from the first event neither repeats nor misses changes
+	events = watchEvents(t, d, "/watch/", modify.Revision, 2)
+	if events[0].Curr != nil || events[1].Curr.(*testState).StrField != "/watch/c" {
+		t.Fatalf("unexpected events after resume %+v", events)
+	}
+}
+
+func TestFakeStateDriverWatchStopsOnDeinit(t *testing.T) {
+	d := setupTxnDriver(t)
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- d.WatchAllStateFrom("/watch/", &testState{}, json.Unmarshal,
+			0, make(chan core.WatchState))
+	}()
+
+	d.Deinit()
+	if err := <-watchErr; err == nil {
+		t.Fatalf("watch did not fail after deinit")
+	}
+}
@@ -0,0 +1,193 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+)
+
+// leaseRecord is the value of a lease in the state store. Renewal increments
+// Term so that every renewal changes the version of the key.
+type leaseRecord struct {
+	Holder string `json:"holder"`
+	Term   uint64 `json:"term"`
+}
+
+// Lease is a lock kept in the state store that is held by at most one holder
+// at a time. The holder keeps the lease by renewing it at least once per ttl.
+// A lease that is not renewed is considered expired once it has been seen
+// unchanged for ttl, so expiry doesn't depend on the clocks of the holders
+// being in sync.
+type Lease struct {
+	mutex       sync.Mutex
+	stateDriver core.StateDriver
+	key         string
+	holder      string
+	ttl         time.Duration
+
+	record   leaseRecord
+	version  uint64
+	seenAt   time.Time // when version was first seen
+	deadline time.Time // when our hold on the lease runs out
+}
+
+// NewLease returns the lease kept at key, to be acquired on behalf of holder.
+func NewLease(d core.StateDriver, key, holder string, ttl time.Duration) *Lease {
+	return &Lease{stateDriver: d, key: key, holder: holder, ttl: ttl}
+}
+
+// TryAcquire acquires the lease if it's free or expired, or renews it if it's
+// already held. It returns whether the lease is held. It shall be called
+// periodically, well within ttl, to keep the lease or to take it over.
+func (l *Lease) TryAcquire() (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	value, version, err := l.stateDriver.ReadVersion(l.key)
+	if core.ErrIfKeyExists(err) != nil {
+		return l.isHeld(now), err
+	}
+
+	record := leaseRecord{}
+	if err == nil {
+		if err := json.Unmarshal(value, &record); err != nil {
+			return l.isHeld(now), err
+		}
+	} else {
+		// a lease that doesn't exist is free
+		version = 0
+	}
+
+	if version != l.version || l.seenAt.IsZero() {
+		l.record = record
+		l.version = version
+		l.seenAt = now
+	}
+
+	free := version == 0 || record.Holder == "" ||
+		now.Sub(l.seenAt) >= l.ttl
+	if record.Holder != l.holder && !free {
+		l.deadline = time.Time{}
+		return false, nil
+	}
+
+	record = leaseRecord{Holder: l.holder, Term: record.Term + 1}
+	value, err = json.Marshal(&record)
+	if err != nil {
+		return l.isHeld(now), err
+	}
+
+	err = l.stateDriver.WriteIfVersion(l.key, value, version)
+	if core.IsVersionConflict(err) {
+		// someone else renewed or acquired it in the meantime
+		l.deadline = time.Time{}
+		return false, nil
+	} else if err != nil {
+		return l.isHeld(now), err
+	}
+
+	// the others only start timing the lease once they see this write
+	l.record = record
+	l.deadline = now.Add(l.ttl)
+	return true, nil
+}
+
+// isHeld checks if our hold on the lease has run out. Must be called with the
+// mutex held.
+func (l *Lease) isHeld(now time.Time) bool {
+	return now.Before(l.deadline)
+}
+
+// IsHeld returns whether the lease is held and was renewed within ttl.
+func (l *Lease) IsHeld() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.isHeld(time.Now())
+}
+
+// Holder returns the holder of the lease as last read from the state store,
+// or an empty string if the lease is free.
+func (l *Lease) Holder() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.record.Holder
+}
+
+// Fence makes txn fail to commit with a version conflict unless the lease
+// still holds the value of our last renewal, so that a holder that lost the
+// lease can't commit changes. It fails if our hold on the lease ran out.
+func (l *Lease) Fence(txn core.Txn) error {
+	t, ok := txn.(*stateTxn)
+	if !ok {
+		return core.Errorf("transaction can't be fenced by lease %s", l.key)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.isHeld(time.Now()) {
+		return core.UnavailableErrorf("lease %s is not held", l.key)
+	}
+
+	value, err := json.Marshal(&l.record)
+	if err != nil {
+		return err
+	}
+
+	t.compare(l.key, value)
+	return nil
+}
+
+// Release gives up the lease if it's held, allowing another holder to
+// acquire it without waiting for it to expire.
+func (l *Lease) Release() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.isHeld(time.Now()) {
+		return nil
+	}
+	l.deadline = time.Time{}
+
+	value, version, err := l.stateDriver.ReadVersion(l.key)
+	if err != nil {
+		return err
+	}
+
+	record := leaseRecord{}
+	if err := json.Unmarshal(value, &record); err != nil {
+		return err
+	}
+	if record.Holder != l.holder {
+		return nil
+	}
+
+	record.Holder = ""
+	value, err = json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+
+	l.record = record
+	return l.stateDriver.WriteIfVersion(l.key, value, version)
+}
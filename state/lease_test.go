@@ -0,0 +1,132 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+)
+
+const testLeaseTTL = 50 * time.Millisecond
+
+func tryAcquire(t *testing.T, l *Lease, expHeld bool) {
+	held, err := l.TryAcquire()
+	if err != nil {
+		t.Fatalf("error acquiring lease. Error: %s", err)
+	}
+	if held != expHeld {
+		t.Fatalf("lease held by %s: %v, expected %v", l.holder, held, expHeld)
+	}
+}
+
+func TestLeaseExclusive(t *testing.T) {
+	d := setupTxnDriver(t)
+	a := NewLease(d, "/lease/test", "a", testLeaseTTL)
+	b := NewLease(d, "/lease/test", "b", testLeaseTTL)
+
+	tryAcquire(t, a, true)
+	tryAcquire(t, b, false)
+	if b.Holder() != "a" {
+		t.Fatalf("lease holder seen as %q, expected a", b.Holder())
+	}
+
+	// renewals keep the lease held past its ttl
+	for i := 0; i < 4; i++ {
+		time.Sleep(testLeaseTTL / 2)
+		tryAcquire(t, a, true)
+		tryAcquire(t, b, false)
+	}
+	if !a.IsHeld() || b.IsHeld() {
+		t.Fatalf("unexpected lease holders a: %v, b: %v", a.IsHeld(), b.IsHeld())
+	}
+}
+
+func TestLeaseTakeoverOnExpiry(t *testing.T) {
+	d := setupTxnDriver(t)
+	a := NewLease(d, "/lease/test", "a", testLeaseTTL)
+	b := NewLease(d, "/lease/test", "b", testLeaseTTL)
+
+	tryAcquire(t, a, true)
+	tryAcquire(t, b, false)
+
+	// a stops renewing; its hold runs out before b can take over
+	time.Sleep(testLeaseTTL)
+	if a.IsHeld() {
+		t.Fatalf("lease still held after ttl without renewal")
+	}
+	tryAcquire(t, b, true)
+	tryAcquire(t, a, false)
+	if a.Holder() != "b" {
+		t.Fatalf("lease holder seen as %q, expected b", a.Holder())
+	}
+}
+
+func TestLeaseRelease(t *testing.T) {
+	d := setupTxnDriver(t)
+	a := NewLease(d, "/lease/test", "a", testLeaseTTL)
+	b := NewLease(d, "/lease/test", "b", testLeaseTTL)
+
+	tryAcquire(t, a, true)
+	if err := a.Release(); err != nil {
+		t.Fatalf("error releasing lease. Error: %s", err)
+	}
+	if a.IsHeld() {
+		t.Fatalf("lease still held after release")
+	}
+
+	// a released lease can be acquired right away
+	tryAcquire(t, b, true)
+}
+
+func TestLeaseFence(t *testing.T) {
+	d := setupTxnDriver(t)
+	a := NewLease(d, "/lease/test", "a", testLeaseTTL)
+	b := NewLease(d, "/lease/test", "b", testLeaseTTL)
+
+	tryAcquire(t, a, true)
+	txn, _ := d.NewTxn()
+	txn.Write("/test/fenced", []byte("1"))
+	if err := a.Fence(txn); err != nil {
+		t.Fatalf("error fencing transaction. Error: %s", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("error committing fenced transaction. Error: %s", err)
+	}
+
+	// a transaction fenced before the lease changed hands doesn't commit
+	txn, _ = d.NewTxn()
+	txn.Write("/test/fenced", []byte("2"))
+	if err := a.Fence(txn); err != nil {
+		t.Fatalf("error fencing transaction. Error: %s", err)
+	}
+	if err := a.Release(); err != nil {
+		t.Fatalf("error releasing lease. Error: %s", err)
+	}
+	tryAcquire(t, b, true)
+	if err := txn.Commit(); !core.IsVersionConflict(err) {
+		t.Fatalf("fenced commit after losing the lease returned %v, expected a conflict", err)
+	}
+	if value, _ := d.Read("/test/fenced"); string(value) != "1" {
+		t.Fatalf("fenced key is %q after a failed commit, expected 1", value)
+	}
+
+	txn, _ = d.NewTxn()
+	if err := a.Fence(txn); core.ErrorKindOf(err) != core.KindUnavailable {
+		t.Fatalf("fencing without the lease returned %v, expected it unavailable", err)
+	}
+}
@@ -0,0 +1,133 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"github.com/contiv/netplugin/core"
+)
+
+// StubStateDriver implements core.StateDriver with methods that fail. Test
+// drivers embed it and implement the methods they expect to be called, so
+// they don't need to change as the interface grows.
+type StubStateDriver struct{}
+
+// errNotImplemented is returned by the methods of StubStateDriver
+func errNotImplemented(method string) error {
+	return core.Errorf("%s is not implemented by the stub state driver", method)
+}
+
+// Init is not implemented.
+func (d *StubStateDriver) Init(config *core.Config) error {
+	return errNotImplemented("Init")
+}
+
+// Deinit does nothing.
+func (d *StubStateDriver) Deinit() {
+}
+
+// Write is not implemented.
+func (d *StubStateDriver) Write(key string, value []byte) error {
+	return errNotImplemented("Write")
+}
+
+// Read is not implemented.
+func (d *StubStateDriver) Read(key string) ([]byte, error) {
+	return nil, errNotImplemented("Read")
+}
+
+// ReadAll is not implemented.
+func (d *StubStateDriver) ReadAll(baseKey string) ([][]byte, error) {
+	return nil, errNotImplemented("ReadAll")
+}
+
+// WatchAll is not implemented.
+func (d *StubStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
+	return errNotImplemented("WatchAll")
+}
+
+// ReadVersion is not implemented.
+func (d *StubStateDriver) ReadVersion(key string) ([]byte, uint64, error) {
+	return nil, 0, errNotImplemented("ReadVersion")
+}
+
+// WriteIfVersion is not implemented.
+func (d *StubStateDriver) WriteIfVersion(key string, value []byte, version uint64) error {
+	return errNotImplemented("WriteIfVersion")
+}
+
+// WriteState is not implemented.
+func (d *StubStateDriver) WriteState(key string, value core.State,
+	marshal func(interface{}) ([]byte, error)) error {
+	return errNotImplemented("WriteState")
+}
+
+// ReadState is not implemented.
+func (d *StubStateDriver) ReadState(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) error {
+	return errNotImplemented("ReadState")
+}
+
+// ReadAllState is not implemented.
+func (d *StubStateDriver) ReadAllState(baseKey string, stateType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, error) {
+	return nil, errNotImplemented("ReadAllState")
+}
+
+// WatchAllState is not implemented.
+func (d *StubStateDriver) WatchAllState(baseKey string, stateType core.State,
+	unmarshal func([]byte, interface{}) error, rsps chan core.WatchState) error {
+	return errNotImplemented("WatchAllState")
+}
+
+// ListAllState is not implemented.
+func (d *StubStateDriver) ListAllState(baseKey string, stateType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, uint64, error) {
+	return nil, 0, errNotImplemented("ListAllState")
+}
+
+// WatchAllStateFrom is not implemented.
+func (d *StubStateDriver) WatchAllStateFrom(baseKey string, stateType core.State,
+	unmarshal func([]byte, interface{}) error, revision uint64,
+	rsps chan core.WatchState) error {
+	return errNotImplemented("WatchAllStateFrom")
+}
+
+// ClearState is not implemented.
+func (d *StubStateDriver) ClearState(key string) error {
+	return errNotImplemented("ClearState")
+}
+
+// ClearIfVersion is not implemented.
+func (d *StubStateDriver) ClearIfVersion(key string, version uint64) error {
+	return errNotImplemented("ClearIfVersion")
+}
+
+// ReadStateVersion is not implemented.
+func (d *StubStateDriver) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	return 0, errNotImplemented("ReadStateVersion")
+}
+
+// WriteStateIfVersion is not implemented.
+func (d *StubStateDriver) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	return errNotImplemented("WriteStateIfVersion")
+}
+
+// NewTxn is not implemented.
+func (d *StubStateDriver) NewTxn() (core.Txn, error) {
+	return nil, errNotImplemented("NewTxn")
+}
@@ -0,0 +1,538 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	txnJournalPathPrefix = "/contiv.io/txn/"
+	txnJournalPath       = txnJournalPathPrefix + "%s"
+)
+
+// txnOp is a buffered write or clear of a single key. It also records the
+// value the key had when the transaction was committed, which is used to
+// roll the operation back or to find out if it was applied during recovery.
+// Every operation is applied and rolled back with a compare-and-swap on the
+// version recorded for the key, so that concurrent changes are never
+// overwritten.
+type txnOp struct {
+	Key         string `json:"key"`
+	Value       []byte `json:"value"`
+	Delete      bool   `json:"delete"`
+	PrevValue   []byte `json:"prevValue"`
+	PrevExists  bool   `json:"prevExists"`
+	PrevVersion uint64 `json:"prevVersion"`
+
+	// value and version observed by the transaction when it read the key
+	readValue   []byte
+	readVersion uint64
+	wasRead     bool
+	written     bool
+
+	// version of the key once the operation was applied
+	appliedVersion uint64
+}
+
+// txnJournal is the record of a transaction being committed. It is written
+// before any operation is applied and removed once all of them are, so
+// that a transaction interrupted by a crash can be completed by RecoverTxns,
+// or by the next transaction that reads one of its keys.
+type txnJournal struct {
+	ID  string  `json:"id"`
+	Ops []txnOp `json:"ops"`
+}
+
+// hasKey tells if the transaction has an operation on key
+func (j *txnJournal) hasKey(key string) bool {
+	for _, op := range j.Ops {
+		if op.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// the transactions this process is committing, whose journals must not
+	// be recovered
+	activeTxnsMutex sync.Mutex
+	activeTxns      = make(map[string]bool)
+)
+
+// setTxnActive marks the transaction id as being committed or not
+func setTxnActive(id string, active bool) {
+	activeTxnsMutex.Lock()
+	defer activeTxnsMutex.Unlock()
+
+	if active {
+		activeTxns[id] = true
+	} else {
+		delete(activeTxns, id)
+	}
+}
+
+// isTxnActive tells if the transaction id is being committed
+func isTxnActive(id string) bool {
+	activeTxnsMutex.Lock()
+	defer activeTxnsMutex.Unlock()
+
+	return activeTxns[id]
+}
+
+// stateTxn implements core.Txn on top of any core.StateDriver that supports
+// versioned writes. The etcd v2 API has no multi-key transactions, and the
+// consul client and servers in use predate the consul txn endpoint. Both
+// only provide single-key compare-and-swap, so atomicity is provided by the
+// journal.
+type stateTxn struct {
+	d        core.StateDriver
+	ops      map[string]*txnOp
+	keys     []string
+	compares []txnCompare
+	done     bool
+}
+
+// txnCompare is a key that must hold value for the transaction to commit
+type txnCompare struct {
+	key   string
+	value []byte
+}
+
+func newStateTxn(d core.StateDriver) *stateTxn {
+	return &stateTxn{d: d, ops: make(map[string]*txnOp)}
+}
+
+// op returns the operation for key, creating it if needed.
+func (t *stateTxn) op(key string) *txnOp {
+	op, ok := t.ops[key]
+	if !ok {
+		op = &txnOp{Key: key}
+		t.ops[key] = op
+		t.keys = append(t.keys, key)
+	}
+	return op
+}
+
+// compare makes Commit fail with a version conflict unless key holds value
+// in the store.
+func (t *stateTxn) compare(key string, value []byte) {
+	t.compares = append(t.compares, txnCompare{key: key, value: value})
+}
+
+// Init is not supported on a transaction.
+func (t *stateTxn) Init(config *core.Config) error {
+	return core.Errorf("not supported")
+}
+
+// Deinit aborts the transaction.
+func (t *stateTxn) Deinit() {
+	t.Abort()
+}
+
+// Write buffers a write of value to key.
+func (t *stateTxn) Write(key string, value []byte) error {
+	if t.done {
+		return core.Errorf("transaction already completed")
+	}
+
+	op := t.op(key)
+	op.Value = value
+	op.Delete = false
+	op.written = true
+	return nil
+}
+
+// Read returns the buffered value for key, or reads it from the store.
+func (t *stateTxn) Read(key string) ([]byte, error) {
+	value, _, err := t.ReadVersion(key)
+	return value, err
+}
+
+// ReadAll is passed through to the store, so it doesn't return the
+// buffered changes.
+func (t *stateTxn) ReadAll(baseKey string) ([][]byte, error) {
+	return t.d.ReadAll(baseKey)
+}
+
+// WatchAll is passed through to the store.
+func (t *stateTxn) WatchAll(baseKey string, rsps chan [2][]byte) error {
+	return t.d.WatchAll(baseKey, rsps)
+}
+
+// ReadVersion returns the buffered value for key along with the version it
+// had when first read, or reads it from the store.
+func (t *stateTxn) ReadVersion(key string) ([]byte, uint64, error) {
+	if op, ok := t.ops[key]; ok {
+		if op.written && op.Delete {
+			return []byte{}, 0, core.NotFoundErrorf("Key not found! key: %v", key)
+		} else if op.written {
+			return op.Value, op.readVersion, nil
+		} else if op.wasRead {
+			return op.readValue, op.readVersion, nil
+		}
+	}
+
+	// complete an interrupted transaction on the key before reading it
+	if err := recoverTxns(t.d, key); err != nil {
+		return []byte{}, 0, err
+	}
+
+	value, version, err := t.d.ReadVersion(key)
+	if err != nil {
+		return value, version, err
+	}
+
+	op := t.op(key)
+	op.readValue = value
+	op.readVersion = version
+	op.wasRead = true
+	return value, version, nil
+}
+
+// WriteIfVersion buffers a write of value to key. The version is checked
+// against the version the transaction read and again on Commit.
+func (t *stateTxn) WriteIfVersion(key string, value []byte, version uint64) error {
+	op, ok := t.ops[key]
+	if ok && op.wasRead && op.readVersion != version {
+		return core.ErrVersionConflict(key)
+	}
+
+	op = t.op(key)
+	op.readVersion = version
+	op.wasRead = true
+	return t.Write(key, value)
+}
+
+// ClearIfVersion buffers a removal of key. The version is checked against
+// the version the transaction read and again on Commit.
+func (t *stateTxn) ClearIfVersion(key string, version uint64) error {
+	op, ok := t.ops[key]
+	if ok && op.wasRead && op.readVersion != version {
+		return core.ErrVersionConflict(key)
+	}
+
+	op = t.op(key)
+	op.readVersion = version
+	op.wasRead = true
+	return t.ClearState(key)
+}
+
+// ClearState buffers a removal of key.
+func (t *stateTxn) ClearState(key string) error {
+	if t.done {
+		return core.Errorf("transaction already completed")
+	}
+
+	op := t.op(key)
+	op.Value = nil
+	op.Delete = true
+	op.written = true
+	return nil
+}
+
+// ReadState reads key into a core.State with the unmarshalling function.
+func (t *stateTxn) ReadState(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) error {
+	_, err := t.ReadStateVersion(key, value, unmarshal)
+	return err
+}
+
+// ReadStateVersion reads key into a core.State and returns its version.
+func (t *stateTxn) ReadStateVersion(key string, value core.State,
+	unmarshal func([]byte, interface{}) error) (uint64, error) {
+	encodedState, version, err := t.ReadVersion(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = unmarshal(encodedState, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// ReadAllState reads all state from baseKey. The states are bound to the
+// transaction, but reflect the contents of the store: keys written or
+// cleared by the transaction are read as they were before it.
+func (t *stateTxn) ReadAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, error) {
+	return readAllStateCommon(t, baseKey, sType, unmarshal)
+}
+
+// WatchAllState is passed through to the store.
+func (t *stateTxn) WatchAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error, rsps chan core.WatchState) error {
+	return t.d.WatchAllState(baseKey, sType, unmarshal, rsps)
+}
+
+// ListAllState is passed through to the store, like ReadAllState.
+func (t *stateTxn) ListAllState(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error) ([]core.State, uint64, error) {
+	return t.d.ListAllState(baseKey, sType, unmarshal)
+}
+
+// WatchAllStateFrom is passed through to the store.
+func (t *stateTxn) WatchAllStateFrom(baseKey string, sType core.State,
+	unmarshal func([]byte, interface{}) error, revision uint64,
+	rsps chan core.WatchState) error {
+	return t.d.WatchAllStateFrom(baseKey, sType, unmarshal, revision, rsps)
+}
+
+// WriteState buffers a write of a core.State.
+func (t *stateTxn) WriteState(key string, value core.State,
+	marshal func(interface{}) ([]byte, error)) error {
+	encodedState, err := marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return t.Write(key, encodedState)
+}
+
+// WriteStateIfVersion buffers a versioned write of a core.State.
+func (t *stateTxn) WriteStateIfVersion(key string, value core.State,
+	marshal func(interface{}) ([]byte, error), version uint64) error {
+	encodedState, err := marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return t.WriteIfVersion(key, encodedState, version)
+}
+
+// NewTxn is not supported on a transaction.
+func (t *stateTxn) NewTxn() (core.Txn, error) {
+	return nil, core.Errorf("nested transactions are not supported")
+}
+
+// Abort discards the buffered changes.
+func (t *stateTxn) Abort() {
+	t.done = true
+	t.ops = make(map[string]*txnOp)
+	t.keys = nil
+	t.compares = nil
+}
+
+// Commit applies the buffered changes as a unit.
+func (t *stateTxn) Commit() error {
+	if t.done {
+		return core.Errorf("transaction already completed")
+	}
+	defer t.Abort()
+
+	journal := &txnJournal{
+		ID: fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Int63()),
+	}
+
+	for _, c := range t.compares {
+		value, err := t.d.Read(c.key)
+		if core.ErrIfKeyExists(err) != nil {
+			return err
+		}
+		if err != nil || !bytes.Equal(value, c.value) {
+			return core.ErrVersionConflict(c.key)
+		}
+	}
+
+	// record the current value of every key to be modified, failing if a
+	// key changed since the transaction read it
+	for _, key := range t.keys {
+		op := t.ops[key]
+		if !op.written {
+			continue
+		}
+
+		value, version, err := t.d.ReadVersion(key)
+		if core.ErrIfKeyExists(err) != nil {
+			return err
+		}
+		op.PrevExists = err == nil
+		if op.PrevExists {
+			op.PrevValue = value
+			op.PrevVersion = version
+		}
+
+		if op.wasRead && op.readVersion != op.PrevVersion {
+			return core.ErrVersionConflict(key)
+		}
+
+		journal.Ops = append(journal.Ops, *op)
+	}
+
+	if len(journal.Ops) == 0 {
+		return nil
+	}
+
+	journalBytes, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+
+	setTxnActive(journal.ID, true)
+	defer setTxnActive(journal.ID, false)
+
+	journalKey := fmt.Sprintf(txnJournalPath, journal.ID)
+	err = t.d.Write(journalKey, journalBytes)
+	if err != nil {
+		return err
+	}
+
+	for i := range journal.Ops {
+		err = applyTxnOp(t.d, &journal.Ops[i])
+		if err != nil {
+			log.Errorf("transaction %s failed on key %s, rolling back. Error: %s",
+				journal.ID, journal.Ops[i].Key, err)
+			rollbackTxnOps(t.d, journal.Ops[:i])
+			break
+		}
+	}
+
+	if clearErr := t.d.ClearState(journalKey); clearErr != nil {
+		log.Errorf("error removing journal of transaction %s. Error: %s",
+			journal.ID, clearErr)
+	}
+
+	return err
+}
+
+// applyTxnOp applies a single operation, provided the key has not been
+// modified since the transaction recorded its value. The version of a
+// written key is recorded for the operation to be rolled back.
+func applyTxnOp(d core.StateDriver, op *txnOp) error {
+	if op.Delete {
+		if !op.PrevExists {
+			return nil
+		}
+		return d.ClearIfVersion(op.Key, op.PrevVersion)
+	}
+
+	err := d.WriteIfVersion(op.Key, op.Value, op.PrevVersion)
+	if err != nil {
+		return err
+	}
+
+	value, version, err := d.ReadVersion(op.Key)
+	if core.ErrIfKeyExists(err) != nil {
+		return err
+	}
+	if err != nil || !bytes.Equal(value, op.Value) {
+		// modified right after the write
+		return core.ErrVersionConflict(op.Key)
+	}
+	op.appliedVersion = version
+
+	return nil
+}
+
+// rollbackTxnOps restores the values that the operations overwrote. A key
+// that was modified since its operation was applied is left alone.
+func rollbackTxnOps(d core.StateDriver, ops []txnOp) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		var err error
+
+		op := ops[i]
+		switch {
+		case op.Delete && !op.PrevExists:
+			continue
+		case op.Delete:
+			err = d.WriteIfVersion(op.Key, op.PrevValue, 0)
+		case op.PrevExists:
+			err = d.WriteIfVersion(op.Key, op.PrevValue, op.appliedVersion)
+		default:
+			err = d.ClearIfVersion(op.Key, op.appliedVersion)
+		}
+		if err != nil {
+			log.Errorf("error rolling back key %s. Error: %s", op.Key, err)
+		}
+	}
+}
+
+// RecoverTxns completes the transactions whose commit was interrupted, such
+// as by a crash. Operations that were already applied, or whose key has been
+// modified since, are skipped. It shall be called before serving requests.
+func RecoverTxns(d core.StateDriver) error {
+	return recoverTxns(d, "")
+}
+
+// recoverTxns completes the interrupted transactions with an operation on
+// key, or all of them if key is empty. The transactions this process is
+// committing are left alone.
+func recoverTxns(d core.StateDriver, key string) error {
+	values, err := d.ReadAll(txnJournalPathPrefix)
+	if core.ErrIfKeyExists(err) != nil {
+		return err
+	}
+
+	for _, value := range values {
+		journal := &txnJournal{}
+		err = json.Unmarshal(value, journal)
+		if err != nil {
+			log.Errorf("error decoding transaction journal. Error: %s", err)
+			continue
+		}
+
+		if isTxnActive(journal.ID) || (key != "" && !journal.hasKey(key)) {
+			continue
+		}
+
+		err = recoverTxn(d, journal)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recoverTxn applies the operations of an interrupted transaction that were
+// not applied, and removes its journal.
+func recoverTxn(d core.StateDriver, journal *txnJournal) error {
+	log.Infof("Recovering transaction %s", journal.ID)
+	for _, op := range journal.Ops {
+		_, version, err := d.ReadVersion(op.Key)
+		if core.ErrIfKeyExists(err) != nil {
+			return err
+		}
+		exists := err == nil
+
+		if exists != op.PrevExists || version != op.PrevVersion {
+			// already applied or modified since
+			continue
+		}
+
+		err = applyTxnOp(d, &op)
+		if err != nil && !core.IsVersionConflict(err) {
+			return err
+		}
+	}
+
+	// the journal may have been removed by a concurrent recovery
+	err := d.ClearState(fmt.Sprintf(txnJournalPath, journal.ID))
+	return core.ErrIfKeyExists(err)
+}
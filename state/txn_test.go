@@ -0,0 +1,307 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/contiv/netplugin/core"
+)
+
+func setupTxnDriver(t *testing.T) *FakeStateDriver {
+	d := &FakeStateDriver{}
+	if err := d.Init(nil); err != nil {
+		t.Fatalf("driver init failed. Error: %s", err)
+	}
+
+	return d
+}
+
+func TestTxnCommit(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+	d.Write("/txn/b", []byte("b0"))
+
+	txn, err := d.NewTxn()
+	if err != nil {
+		t.Fatalf("failed to start txn. Error: %s", err)
+	}
+
+	txn.Write("/txn/a", []byte("a1"))
+	txn.ClearState("/txn/b")
+	txn.Write("/txn/c", []byte("c1"))
+
+	// buffered changes are visible through the txn, but not the driver
+	if value, _ := txn.Read("/txn/a"); string(value) != "a1" {
+		t.Fatalf("txn read returned %q, expected a1", value)
+	}
+	if _, err := txn.Read("/txn/b"); core.ErrIfKeyExists(err) != nil || err == nil {
+		t.Fatalf("txn read of cleared key returned %v", err)
+	}
+	if value, _ := d.Read("/txn/a"); string(value) != "a0" {
+		t.Fatalf("driver read returned %q before commit, expected a0", value)
+	}
+
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("txn commit failed. Error: %s", err)
+	}
+
+	if value, _ := d.Read("/txn/a"); string(value) != "a1" {
+		t.Fatalf("driver read returned %q after commit, expected a1", value)
+	}
+	if _, err := d.Read("/txn/b"); err == nil {
+		t.Fatalf("cleared key exists after commit")
+	}
+	if value, _ := d.Read("/txn/c"); string(value) != "c1" {
+		t.Fatalf("driver read returned %q after commit, expected c1", value)
+	}
+	if journals, _ := d.ReadAll(txnJournalPathPrefix); len(journals) != 0 {
+		t.Fatalf("journal was not removed after commit")
+	}
+}
+
+func TestTxnCommitConflict(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+	d.Write("/txn/b", []byte("b0"))
+
+	txn, _ := d.NewTxn()
+	_, version, err := txn.ReadVersion("/txn/b")
+	if err != nil {
+		t.Fatalf("txn read failed. Error: %s", err)
+	}
+	txn.Write("/txn/a", []byte("a1"))
+	txn.WriteIfVersion("/txn/b", []byte("b1"), version)
+
+	// a concurrent writer modifies a key the txn read
+	d.Write("/txn/b", []byte("b2"))
+
+	err = txn.Commit()
+	if !core.IsVersionConflict(err) {
+		t.Fatalf("txn commit returned %v, expected a version conflict", err)
+	}
+
+	if value, _ := d.Read("/txn/a"); string(value) != "a0" {
+		t.Fatalf("key modified by failed txn. value: %q", value)
+	}
+	if value, _ := d.Read("/txn/b"); string(value) != "b2" {
+		t.Fatalf("concurrent write was overwritten. value: %q", value)
+	}
+}
+
+func TestTxnAbort(t *testing.T) {
+	d := setupTxnDriver(t)
+
+	txn, _ := d.NewTxn()
+	txn.Write("/txn/a", []byte("a1"))
+	txn.Abort()
+
+	if err := txn.Commit(); err == nil {
+		t.Fatalf("commit of aborted txn succeeded")
+	}
+	if _, err := d.Read("/txn/a"); err == nil {
+		t.Fatalf("aborted txn was applied")
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+
+	ops := []txnOp{
+		{Key: "/txn/a", Value: []byte("a1"), PrevValue: []byte("a0"), PrevExists: true},
+		{Key: "/txn/b", Value: []byte("b1")},
+	}
+	_, ops[0].PrevVersion, _ = d.ReadVersion("/txn/a")
+	for i := range ops {
+		if err := applyTxnOp(d, &ops[i]); err != nil {
+			t.Fatalf("apply failed. Error: %s", err)
+		}
+	}
+
+	rollbackTxnOps(d, ops)
+
+	if value, _ := d.Read("/txn/a"); string(value) != "a0" {
+		t.Fatalf("rollback didn't restore value. value: %q", value)
+	}
+	if _, err := d.Read("/txn/b"); err == nil {
+		t.Fatalf("rollback didn't remove created key")
+	}
+}
+
+func TestTxnApplyDeleteConflict(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+
+	op := txnOp{Key: "/txn/a", Delete: true, PrevValue: []byte("a0"), PrevExists: true}
+	_, op.PrevVersion, _ = d.ReadVersion("/txn/a")
+
+	// a concurrent writer modifies the key before the delete is applied
+	d.Write("/txn/a", []byte("a2"))
+
+	if err := applyTxnOp(d, &op); !core.IsVersionConflict(err) {
+		t.Fatalf("apply returned %v, expected a version conflict", err)
+	}
+	if value, _ := d.Read("/txn/a"); string(value) != "a2" {
+		t.Fatalf("concurrent write was deleted. value: %q", value)
+	}
+}
+
+func TestTxnRollbackConflict(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+	d.Write("/txn/b", []byte("b0"))
+
+	ops := []txnOp{
+		{Key: "/txn/a", Value: []byte("a1"), PrevValue: []byte("a0"), PrevExists: true},
+		{Key: "/txn/b", Delete: true, PrevValue: []byte("b0"), PrevExists: true},
+		{Key: "/txn/c", Value: []byte("c1")},
+	}
+	_, ops[0].PrevVersion, _ = d.ReadVersion("/txn/a")
+	_, ops[1].PrevVersion, _ = d.ReadVersion("/txn/b")
+	for i := range ops {
+		if err := applyTxnOp(d, &ops[i]); err != nil {
+			t.Fatalf("apply failed. Error: %s", err)
+		}
+	}
+
+	// concurrent writers modify the keys before the rollback
+	d.Write("/txn/a", []byte("a2"))
+	d.Write("/txn/b", []byte("b2"))
+	d.Write("/txn/c", []byte("c2"))
+
+	rollbackTxnOps(d, ops)
+
+	for key, expValue := range map[string]string{"/txn/a": "a2", "/txn/b": "b2", "/txn/c": "c2"} {
+		if value, _ := d.Read(key); string(value) != expValue {
+			t.Fatalf("rollback overwrote a concurrent write to %s. value: %q", key, value)
+		}
+	}
+}
+
+func TestTxnClearIfVersion(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+
+	txn, _ := d.NewTxn()
+	_, version, err := txn.ReadVersion("/txn/a")
+	if err != nil {
+		t.Fatalf("txn read failed. Error: %s", err)
+	}
+	if err := txn.ClearIfVersion("/txn/a", version+1); !core.IsVersionConflict(err) {
+		t.Fatalf("clear with a stale version returned %v, expected a version conflict", err)
+	}
+	if err := txn.ClearIfVersion("/txn/a", version); err != nil {
+		t.Fatalf("clear failed. Error: %s", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("txn commit failed. Error: %s", err)
+	}
+	if _, err := d.Read("/txn/a"); err == nil {
+		t.Fatalf("cleared key exists after commit")
+	}
+}
+
+func TestRecoverTxns(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+	d.Write("/txn/b", []byte("b0"))
+	_, versionA, _ := d.ReadVersion("/txn/a")
+	_, versionB, _ := d.ReadVersion("/txn/b")
+
+	// a txn that crashed after applying its first operation
+	journal := &txnJournal{
+		ID: "crashed",
+		Ops: []txnOp{
+			{Key: "/txn/a", Value: []byte("a1"), PrevValue: []byte("a0"),
+				PrevExists: true, PrevVersion: versionA},
+			{Key: "/txn/b", Delete: true, PrevValue: []byte("b0"),
+				PrevExists: true, PrevVersion: versionB},
+			{Key: "/txn/c", Value: []byte("c1")},
+		},
+	}
+	applyTxnOp(d, &journal.Ops[0])
+	d.Write("/txn/a", []byte("a2"))
+
+	journalBytes, _ := json.Marshal(journal)
+	d.Write(fmt.Sprintf(txnJournalPath, journal.ID), journalBytes)
+
+	if err := RecoverTxns(d); err != nil {
+		t.Fatalf("recovery failed. Error: %s", err)
+	}
+
+	if value, _ := d.Read("/txn/a"); string(value) != "a2" {
+		t.Fatalf("recovery overwrote a later write. value: %q", value)
+	}
+	if _, err := d.Read("/txn/b"); err == nil {
+		t.Fatalf("recovery didn't apply the delete")
+	}
+	if value, _ := d.Read("/txn/c"); string(value) != "c1" {
+		t.Fatalf("recovery didn't apply the write. value: %q", value)
+	}
+	if journals, _ := d.ReadAll(txnJournalPathPrefix); len(journals) != 0 {
+		t.Fatalf("journal was not removed after recovery")
+	}
+}
+
+func TestRecoverTxnsOnRead(t *testing.T) {
+	d := setupTxnDriver(t)
+	d.Write("/txn/a", []byte("a0"))
+	_, versionA, _ := d.ReadVersion("/txn/a")
+
+	// a txn that crashed before applying its operations, and one being
+	// committed
+	crashed := &txnJournal{
+		ID: "crashed",
+		Ops: []txnOp{
+			{Key: "/txn/a", Value: []byte("a1"), PrevValue: []byte("a0"),
+				PrevExists: true, PrevVersion: versionA},
+			{Key: "/txn/b", Value: []byte("b1")},
+		},
+	}
+	active := &txnJournal{
+		ID:  "active",
+		Ops: []txnOp{{Key: "/txn/b", Value: []byte("b2")}},
+	}
+	for _, journal := range []*txnJournal{crashed, active} {
+		journalBytes, _ := json.Marshal(journal)
+		d.Write(fmt.Sprintf(txnJournalPath, journal.ID), journalBytes)
+	}
+	setTxnActive(active.ID, true)
+	defer setTxnActive(active.ID, false)
+
+	// reading a key of the crashed txn completes it
+	txn, _ := d.NewTxn()
+	value, err := txn.Read("/txn/b")
+	if err != nil || string(value) != "b1" {
+		t.Fatalf("txn read returned %q, %v, expected the recovered value", value, err)
+	}
+	if value, _ := d.Read("/txn/a"); string(value) != "a1" {
+		t.Fatalf("recovery on read didn't apply the write. value: %q", value)
+	}
+
+	journals, _ := d.ReadAll(txnJournalPathPrefix)
+	if len(journals) != 1 {
+		t.Fatalf("unexpected journals after recovery on read: %q", journals)
+	}
+	journal := &txnJournal{}
+	json.Unmarshal(journals[0], journal)
+	if journal.ID != active.ID {
+		t.Fatalf("the journal of the txn being committed was recovered")
+	}
+}
@@ -18,6 +18,7 @@ package netutils
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"net"
 	"strconv"
 	"strings"
@@ -147,6 +148,215 @@ func GetIPNumber(subnetIP string, subnetLen uint, allocSubnetLen uint, hostIP st
 	return uint(hostID), nil
 }
 
+// IsIPv6 returns true if ip is a valid IPv6 address.
+func IsIPv6(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && addr.To4() == nil
+}
+
+// IsIPv6InSubnet returns true if hostIP is an IPv6 host address of the subnet,
+// i.e. it is in the subnet and is not the subnet address itself.
+func IsIPv6InSubnet(subnetIP string, subnetLen uint, hostIP string) bool {
+	if !IsIPv6(subnetIP) || !IsIPv6(hostIP) || subnetLen > 128 {
+		return false
+	}
+
+	subnet := net.IPNet{
+		IP:   net.ParseIP(subnetIP).Mask(net.CIDRMask(int(subnetLen), 128)),
+		Mask: net.CIDRMask(int(subnetLen), 128),
+	}
+	host := net.ParseIP(hostIP)
+	return subnet.Contains(host) && !host.Equal(subnet.IP)
+}
+
+// GetNextIPv6HostIP returns the lowest IPv6 host address of the subnet that
+// is not in allocMap. The addresses in allocMap shall be in the form returned
+// by net.IP.String().
+func GetNextIPv6HostIP(subnetIP string, subnetLen uint, allocMap map[string]bool) (string, error) {
+	if !IsIPv6(subnetIP) || subnetLen > 128 {
+		return "", core.Errorf("invalid ipv6 subnet %s/%d", subnetIP, subnetLen)
+	}
+
+	mask := net.CIDRMask(int(subnetLen), 128)
+	base := new(big.Int).SetBytes(net.ParseIP(subnetIP).Mask(mask))
+	size := new(big.Int).Lsh(big.NewInt(1), 128-subnetLen)
+
+	// at most len(allocMap) addresses can be skipped
+	hostID := big.NewInt(1)
+	for i := 0; i <= len(allocMap) && hostID.Cmp(size) < 0; i++ {
+		hostIP := make(net.IP, net.IPv6len)
+		addr := new(big.Int).Add(base, hostID).Bytes()
+		copy(hostIP[net.IPv6len-len(addr):], addr)
+
+		if !allocMap[hostIP.String()] {
+			return hostIP.String(), nil
+		}
+		hostID.Add(hostID, big.NewInt(1))
+	}
+
+	return "", core.ExhaustedErrorf("no free address in subnet %s/%d", subnetIP, subnetLen)
+}
+
+// GetIPv6SubnetIP returns the subnet number subnetID of length
+// allocSubnetLen in the IPv6 subnet pool poolIP/poolLen.
+func GetIPv6SubnetIP(poolIP string, poolLen, allocSubnetLen, subnetID uint) (string, error) {
+	if !IsIPv6(poolIP) || poolLen > allocSubnetLen || allocSubnetLen > 128 {
+		return "", core.Errorf("invalid ipv6 subnet pool %s/%d for subnets of length %d",
+			poolIP, poolLen, allocSubnetLen)
+	}
+	if allocSubnetLen-poolLen < 64 && uint64(subnetID) >= uint64(1)<<(allocSubnetLen-poolLen) {
+		return "", core.Errorf("subnet id %d is beyond the capacity of pool %s/%d",
+			subnetID, poolIP, poolLen)
+	}
+
+	base := new(big.Int).SetBytes(net.ParseIP(poolIP).Mask(net.CIDRMask(int(poolLen), 128)))
+	offset := new(big.Int).Lsh(new(big.Int).SetUint64(uint64(subnetID)), 128-allocSubnetLen)
+
+	subnetIP := make(net.IP, net.IPv6len)
+	addr := new(big.Int).Add(base, offset).Bytes()
+	copy(subnetIP[net.IPv6len-len(addr):], addr)
+	return subnetIP.String(), nil
+}
+
+// GetIPv6SubnetNumber returns the number of the IPv6 subnet subnetIP of
+// length allocSubnetLen in the pool poolIP/poolLen. See GetIPv6SubnetIP.
+func GetIPv6SubnetNumber(poolIP string, poolLen, allocSubnetLen uint, subnetIP string) (uint, error) {
+	if !IsIPv6(poolIP) || poolLen > allocSubnetLen || allocSubnetLen > 128 {
+		return 0, core.Errorf("invalid ipv6 subnet pool %s/%d for subnets of length %d",
+			poolIP, poolLen, allocSubnetLen)
+	}
+
+	pool := net.IPNet{
+		IP:   net.ParseIP(poolIP).Mask(net.CIDRMask(int(poolLen), 128)),
+		Mask: net.CIDRMask(int(poolLen), 128),
+	}
+	subnet := net.ParseIP(subnetIP)
+	if !IsIPv6(subnetIP) || !pool.Contains(subnet) ||
+		!subnet.Equal(subnet.Mask(net.CIDRMask(int(allocSubnetLen), 128))) {
+		return 0, core.Errorf("%s/%d is not a subnet of pool %s/%d",
+			subnetIP, allocSubnetLen, poolIP, poolLen)
+	}
+
+	offset := new(big.Int).Sub(new(big.Int).SetBytes(subnet.To16()), new(big.Int).SetBytes(pool.IP))
+	return uint(offset.Rsh(offset, 128-allocSubnetLen).Uint64()), nil
+}
+
+// ParseMACPrefix parses a prefix of one to five bytes of MAC addresses, such
+// as 02:02, and returns it in the lower case form used by
+// net.HardwareAddr.String(). The prefix must be of unicast addresses.
+func ParseMACPrefix(prefix string) (string, error) {
+	octets := strings.Split(prefix, ":")
+	if len(octets) < 1 || len(octets) > 5 {
+		return "", core.InvalidErrorf("invalid mac prefix %q, correct '02:02'", prefix)
+	}
+
+	prefixBytes := make(net.HardwareAddr, len(octets))
+	for idx, octet := range octets {
+		value, err := strconv.ParseUint(octet, 16, 8)
+		if err != nil || len(octet) != 2 {
+			return "", core.InvalidErrorf("invalid mac prefix %q, correct '02:02'", prefix)
+		}
+		prefixBytes[idx] = byte(value)
+	}
+	if prefixBytes[0]&0x01 != 0 {
+		return "", core.InvalidErrorf("mac prefix %q is of multicast addresses", prefix)
+	}
+
+	return prefixBytes.String(), nil
+}
+
+// ParseUnicastMAC parses a unicast ethernet MAC address and returns it in
+// the form used by net.HardwareAddr.String().
+func ParseUnicastMAC(mac string) (string, error) {
+	macAddr, err := net.ParseMAC(mac)
+	if err != nil || len(macAddr) != 6 {
+		return "", core.InvalidErrorf("invalid mac address %q", mac)
+	}
+	if macAddr[0]&0x01 != 0 {
+		return "", core.InvalidErrorf("mac address %q is a multicast address", mac)
+	}
+
+	return macAddr.String(), nil
+}
+
+// MACHasPrefix returns true if the MAC address has the prefix, both in the
+// form used by net.HardwareAddr.String().
+func MACHasPrefix(mac, prefix string) bool {
+	return strings.HasPrefix(mac, prefix+":")
+}
+
+// MACPrefixesOverlap returns true if some MAC address has both prefixes,
+// in the form used by net.HardwareAddr.String().
+func MACPrefixesOverlap(prefix1, prefix2 string) bool {
+	return strings.HasPrefix(prefix1+":", prefix2+":") ||
+		strings.HasPrefix(prefix2+":", prefix1+":")
+}
+
+// GetMACAddress returns the MAC address with the prefix and the host number
+// hostID, the prefix being in the form used by net.HardwareAddr.String().
+func GetMACAddress(prefix string, hostID uint64) string {
+	numOctets := 6 - (len(prefix)+1)/3
+	mac := prefix
+	for idx := numOctets - 1; idx >= 0; idx-- {
+		mac += fmt.Sprintf(":%02x", byte(hostID>>(8*uint(idx))))
+	}
+
+	return mac
+}
+
+// HostRange represents a range of host numbers of a subnet, as returned by
+// GetIPNumber.
+type HostRange struct {
+	Min uint
+	Max uint
+}
+
+// Contains returns true if hostID is in the range.
+func (r HostRange) Contains(hostID uint) bool {
+	return hostID >= r.Min && hostID <= r.Max
+}
+
+// ParseIPRanges takes a string of IPv4 addresses and address ranges such as
+// 10.1.1.1-10.1.1.10,10.1.1.20 and turns it into a series of HostRange of
+// the subnet. All the addresses must be in the subnet.
+func ParseIPRanges(subnetIP string, subnetLen uint, ranges string) ([]HostRange, error) {
+	var err error
+
+	if ranges == "" {
+		return []HostRange{}, nil
+	}
+
+	rangesStr := strings.Split(ranges, ",")
+
+	hostRanges := make([]HostRange, len(rangesStr), len(rangesStr))
+	for idx, oneRangeStr := range rangesStr {
+		oneRangeStr = strings.Trim(oneRangeStr, " ")
+		ipAddrs := strings.Split(oneRangeStr, "-")
+		if len(ipAddrs) > 2 {
+			return nil, core.InvalidErrorf("invalid ip range %s, correct '10.1.1.1-10.1.1.10,10.1.1.20'",
+				oneRangeStr)
+		}
+		hostRanges[idx].Min, err = GetIPNumber(subnetIP, subnetLen, 32, strings.Trim(ipAddrs[0], " "))
+		if err != nil {
+			return nil, err
+		}
+		hostRanges[idx].Max = hostRanges[idx].Min
+		if len(ipAddrs) == 2 {
+			hostRanges[idx].Max, err = GetIPNumber(subnetIP, subnetLen, 32, strings.Trim(ipAddrs[1], " "))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if hostRanges[idx].Min > hostRanges[idx].Max {
+			return nil, core.InvalidErrorf("invalid ip range %s, start is greater than end",
+				oneRangeStr)
+		}
+	}
+
+	return hostRanges, nil
+}
+
 // TagRange represents a range of integers, intended for VLAN and VXLAN
 // tagging.
 type TagRange struct {
@@ -164,52 +374,62 @@ func ParseTagRanges(ranges string, tagType string) ([]TagRange, error) {
 	}
 
 	if tagType != "vlan" && tagType != "vxlan" {
-		return nil, core.Errorf("invalid tag type %s", tagType)
+		return nil, core.InvalidErrorf("invalid tag type %s", tagType)
 	}
 	rangesStr := strings.Split(ranges, ",")
 
-	if len(rangesStr) > 1 && tagType == "vxlan" {
-		return nil, core.Errorf("do not support more than 2 vxlan tag ranges")
-	}
-
 	tagRanges := make([]TagRange, len(rangesStr), len(rangesStr))
 	for idx, oneRangeStr := range rangesStr {
 		oneRangeStr = strings.Trim(oneRangeStr, " ")
 		tagNums := strings.Split(oneRangeStr, "-")
 		if len(tagNums) > 2 {
-			return nil, core.Errorf("invalid tags %s, correct '10-50,70-100'",
+			return nil, core.InvalidErrorf("invalid tags %s, correct '10-50,70-100'",
 				oneRangeStr)
 		}
 		tagRanges[idx].Min, err = strconv.Atoi(tagNums[0])
 		if err != nil {
-			return nil, core.Errorf("invalid integer %d conversion error '%s'",
+			return nil, core.InvalidErrorf("invalid integer %d conversion error '%s'",
 				tagRanges[idx].Min, err)
 		}
 		tagRanges[idx].Max, err = strconv.Atoi(tagNums[1])
 		if err != nil {
-			return nil, core.Errorf("invalid integer %d conversion error '%s'",
+			return nil, core.InvalidErrorf("invalid integer %d conversion error '%s'",
 				tagRanges[idx].Max, err)
 		}
 
 		if tagRanges[idx].Min > tagRanges[idx].Max {
-			return nil, core.Errorf("invalid range %s, min is greater than max",
+			return nil, core.InvalidErrorf("invalid range %s, min is greater than max",
 				oneRangeStr)
 		}
 		if tagType == "vlan" && tagRanges[idx].Max > 4095 {
-			return nil, core.Errorf("invalid range %s, vlan values exceed 4095 max allowed",
+			return nil, core.InvalidErrorf("invalid range %s, vlan values exceed 4095 max allowed",
 				oneRangeStr)
 		}
 		if tagType == "vxlan" && tagRanges[idx].Max > 65535 {
-			return nil, core.Errorf("invalid range %s, vlan values exceed 65535 max allowed",
+			return nil, core.InvalidErrorf("invalid range %s, vlan values exceed 65535 max allowed",
 				oneRangeStr)
 		}
 		if tagType == "vxlan" &&
 			(tagRanges[idx].Max-tagRanges[idx].Min > 16000) {
-			return nil, core.Errorf("does not allow vxlan range to exceed 16000 range %s",
+			return nil, core.InvalidErrorf("does not allow vxlan range to exceed 16000 range %s",
 				oneRangeStr)
 		}
 	}
 
+	// vxlans are allocated from all the ranges, so they must not overlap
+	if tagType == "vxlan" {
+		for i := range tagRanges {
+			for j := i + 1; j < len(tagRanges); j++ {
+				if tagRanges[i].Min <= tagRanges[j].Max &&
+					tagRanges[j].Min <= tagRanges[i].Max {
+					return nil, core.InvalidErrorf("invalid vxlan ranges %s, ranges %d-%d and %d-%d overlap",
+						ranges, tagRanges[i].Min, tagRanges[i].Max,
+						tagRanges[j].Min, tagRanges[j].Max)
+				}
+			}
+		}
+	}
+
 	return tagRanges, nil
 }
 
@@ -217,13 +437,17 @@ func ParseTagRanges(ranges string, tagType string) ([]TagRange, error) {
 func ParseCIDR(cidrStr string) (string, uint, error) {
 	strs := strings.Split(cidrStr, "/")
 	if len(strs) != 2 {
-		return "", 0, core.Errorf("invalid cidr format")
+		return "", 0, core.InvalidErrorf("invalid cidr format")
 	}
 
 	subnetStr := strs[0]
+	maxLen := 32
+	if IsIPv6(subnetStr) {
+		maxLen = 128
+	}
 	subnetLen, err := strconv.Atoi(strs[1])
-	if subnetLen > 32 || err != nil {
-		return "", 0, core.Errorf("invalid mask in gateway/mask specification ")
+	if subnetLen > maxLen || err != nil {
+		return "", 0, core.InvalidErrorf("invalid mask in gateway/mask specification ")
 	}
 
 	return subnetStr, uint(subnetLen), nil
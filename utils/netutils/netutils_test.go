@@ -125,11 +125,22 @@ func TestValidVxlanRange(t *testing.T) {
 	}
 }
 
-func TestInvalidVxlanMultipleRanges(t *testing.T) {
+func TestValidVxlanMultipleRanges(t *testing.T) {
 	rangeStr := "101-400, 10000-15000"
+	ranges, err := ParseTagRanges(rangeStr, "vxlan")
+	if err != nil {
+		t.Fatalf("error '%s' parsing valid vxlan ranges '%s'\n", err, rangeStr)
+	}
+	if len(ranges) != 2 || ranges[1].Min != 10000 || ranges[1].Max != 15000 {
+		t.Fatalf("vxlan ranges '%s' parsed incorrectly: %v\n", rangeStr, ranges)
+	}
+}
+
+func TestInvalidVxlanOverlappingRanges(t *testing.T) {
+	rangeStr := "10000-15000, 101-400, 14000-16000"
 	_, err := ParseTagRanges(rangeStr, "vxlan")
 	if err == nil {
-		t.Fatalf("successfully parsed invalid vxlan value '%s'\n", rangeStr)
+		t.Fatalf("successfully parsed overlapping vxlan ranges '%s'\n", rangeStr)
 	}
 }
 
@@ -198,6 +209,139 @@ func TestGetSubnetNumber(t *testing.T) {
 	}
 }
 
+func TestParseIPv6CIDR(t *testing.T) {
+	subnetIP, subnetLen, err := ParseCIDR("2001:db8::/64")
+	if err != nil || subnetIP != "2001:db8::" || subnetLen != 64 {
+		t.Fatalf("error parsing ipv6 cidr. Got %s/%d, Err: %v", subnetIP, subnetLen, err)
+	}
+
+	if _, _, err = ParseCIDR("2001:db8::/129"); err == nil {
+		t.Fatalf("successfully parsed invalid ipv6 cidr")
+	}
+	if _, _, err = ParseCIDR("10.1.1.0/64"); err == nil {
+		t.Fatalf("successfully parsed invalid ipv4 cidr")
+	}
+}
+
+func TestIsIPv6InSubnet(t *testing.T) {
+	testAddrs := map[string]bool{
+		"2001:db8::1":            true,
+		"2001:db8::ffff:1":       true,
+		"2001:db8::":             false,
+		"2001:db8:0:1::1":        false,
+		"10.1.1.1":               false,
+		"2001:db8:0:0:1:2:3:4:5": false,
+	}
+	for addr, exp := range testAddrs {
+		if IsIPv6InSubnet("2001:db8::", 64, addr) != exp {
+			t.Fatalf("expected %s in subnet 2001:db8::/64 to be %v", addr, exp)
+		}
+	}
+}
+
+func TestGetNextIPv6HostIP(t *testing.T) {
+	allocMap := map[string]bool{}
+	for _, exp := range []string{"2001:db8::1", "2001:db8::2", "2001:db8::3"} {
+		addr, err := GetNextIPv6HostIP("2001:db8::", 126, allocMap)
+		if err != nil {
+			t.Fatalf("error allocating ipv6 address. Err: %v", err)
+		}
+		if addr != exp {
+			t.Fatalf("expected ipv6 address %s, got %s", exp, addr)
+		}
+		allocMap[addr] = true
+	}
+
+	if addr, err := GetNextIPv6HostIP("2001:db8::", 126, allocMap); err == nil {
+		t.Fatalf("allocated %s from an exhausted subnet", addr)
+	}
+
+	delete(allocMap, "2001:db8::2")
+	addr, err := GetNextIPv6HostIP("2001:db8::", 126, allocMap)
+	if err != nil || addr != "2001:db8::2" {
+		t.Fatalf("expected the released address to be allocated. Got %s, Err: %v", addr, err)
+	}
+
+	addr, err = GetNextIPv6HostIP("2001:db8:1::", 48, allocMap)
+	if err != nil || addr != "2001:db8:1::1" {
+		t.Fatalf("expected 2001:db8:1::1 to be allocated. Got %s, Err: %v", addr, err)
+	}
+}
+
+func TestIPv6SubnetNumber(t *testing.T) {
+	subnetIP, err := GetIPv6SubnetIP("2001:db8::", 48, 64, 5)
+	if err != nil || subnetIP != "2001:db8:0:5::" {
+		t.Fatalf("expected subnet 2001:db8:0:5::, got %s. Err: %v", subnetIP, err)
+	}
+
+	subnetID, err := GetIPv6SubnetNumber("2001:db8::", 48, 64, subnetIP)
+	if err != nil || subnetID != 5 {
+		t.Fatalf("expected subnet number 5, got %d. Err: %v", subnetID, err)
+	}
+
+	if _, err = GetIPv6SubnetIP("2001:db8::", 48, 64, 1<<16); err == nil {
+		t.Fatalf("got a subnet beyond the capacity of the pool")
+	}
+	for _, subnetIP := range []string{"2001:db9::", "2001:db8::1", "10.1.1.0"} {
+		if _, err = GetIPv6SubnetNumber("2001:db8::", 48, 64, subnetIP); err == nil {
+			t.Fatalf("got a subnet number for %s", subnetIP)
+		}
+	}
+}
+
+func TestParseIPRanges(t *testing.T) {
+	hostRanges, err := ParseIPRanges("10.1.1.0", 24, "10.1.1.1-10.1.1.10, 10.1.1.20")
+	if err != nil {
+		t.Fatalf("error parsing ip ranges. Err: %v", err)
+	}
+	if len(hostRanges) != 2 || hostRanges[0] != (HostRange{1, 10}) ||
+		hostRanges[1] != (HostRange{20, 20}) {
+		t.Fatalf("unexpected host ranges %+v", hostRanges)
+	}
+	if !hostRanges[0].Contains(10) || hostRanges[0].Contains(11) {
+		t.Fatalf("unexpected host range membership for %+v", hostRanges[0])
+	}
+
+	for _, ranges := range []string{"10.1.2.1", "10.1.1.10-10.1.1.1",
+		"10.1.1.1-10.1.1.2-10.1.1.3", "10.1.1.x"} {
+		if _, err = ParseIPRanges("10.1.1.0", 24, ranges); err == nil {
+			t.Fatalf("successfully parsed invalid ip ranges %s", ranges)
+		}
+	}
+}
+
+func TestMACPrefix(t *testing.T) {
+	prefix, err := ParseMACPrefix("02:0A")
+	if err != nil || prefix != "02:0a" {
+		t.Fatalf("expected mac prefix 02:0a, got %q. Err: %v", prefix, err)
+	}
+
+	for _, prefix := range []string{"", "02:02:02:02:02:02", "03:02", "2:02", "02:xx"} {
+		if _, err = ParseMACPrefix(prefix); err == nil {
+			t.Fatalf("successfully parsed invalid mac prefix %q", prefix)
+		}
+	}
+
+	mac := GetMACAddress("02:0a", 0x010203)
+	if mac != "02:0a:00:01:02:03" || !MACHasPrefix(mac, "02:0a") || MACHasPrefix(mac, "02:0b") {
+		t.Fatalf("unexpected mac address %s", mac)
+	}
+
+	if !MACPrefixesOverlap("02:0a", "02:0a:01") || MACPrefixesOverlap("02:0a", "02:0b:01") ||
+		MACPrefixesOverlap("02:0a:01", "02:0a:02") {
+		t.Fatalf("unexpected mac prefix overlap")
+	}
+
+	if mac, err = ParseUnicastMAC("02:0A:00:01:02:03"); err != nil || mac != "02:0a:00:01:02:03" {
+		t.Fatalf("expected mac address 02:0a:00:01:02:03, got %q. Err: %v", mac, err)
+	}
+	for _, mac := range []string{"01:00:5e:00:00:01", "02:0a:00:01:02", "00:00:00:00:fe:80:00:00"} {
+		if _, err = ParseUnicastMAC(mac); err == nil {
+			t.Fatalf("successfully parsed invalid mac address %q", mac)
+		}
+	}
+}
+
 func TestGetAddrList(t *testing.T) {
 	addrList, err := GetNetlinkAddrList()
 	if err != nil {